@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/cache"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/mail"
+	"solecode/pkg/password"
+	"solecode/pkg/secretbox"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	repo "solecode/src/repository"
+	uc "solecode/src/usecase"
+	accountUC "solecode/src/usecase/account"
+	authUC "solecode/src/usecase/auth"
+	impersonationUC "solecode/src/usecase/impersonation"
+	lockoutUC "solecode/src/usecase/lockout"
+	oidcUC "solecode/src/usecase/oidc"
+	sessionUC "solecode/src/usecase/session"
+	twoFactorUC "solecode/src/usecase/twofactor"
+)
+
+// initUseCases wires the cache, repositories and use cases the same way for
+// the server and for CLI commands, so both share validation and cache
+// invalidation. Without Redis, noCache stands in for it. The returned
+// function closes the cache and the audit log.
+func initUseCases(cfg *config.Config, db *database.Cluster, useCache bool, noCache cache.CacheItf) (*uc.UseCases, func()) {
+	// Initialize cache (Redis or the stand-in when disabled)
+	cacheImpl := noCache
+	closeCache := func() {}
+	if useCache {
+		redisCache, err := cache.NewRedisCache(&cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis (use --cache=false to run without it): %v", err)
+		}
+		cacheImpl = redisCache
+		closeCache = func() { redisCache.Close() }
+	}
+
+	hasher, err := password.New(cfg.Password)
+	if err != nil {
+		log.Fatalf("Invalid password config: %v", err)
+	}
+
+	// Without configured keys tokens are signed with a key that lives as
+	// long as the process
+	var keys *token.KeySet
+	if len(cfg.Auth.Keys) > 0 {
+		keys, err = token.NewKeySet(cfg.Auth)
+	} else {
+		keys, err = token.NewEphemeralKeySet(cfg.Auth.Issuer)
+	}
+	if err != nil {
+		log.Fatalf("Invalid auth config: %v", err)
+	}
+
+	// Verification and reset links likewise only work while the process
+	// lives unless a secret is configured
+	var signer *token.ActionSigner
+	if cfg.Auth.ActionTokenSecret != "" {
+		signer, err = token.NewActionSigner([]byte(cfg.Auth.ActionTokenSecret))
+	} else {
+		signer, err = token.NewEphemeralActionSigner()
+	}
+	if err != nil {
+		log.Fatalf("Invalid auth config: %v", err)
+	}
+
+	mailer, err := openMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Invalid mail config: %v", err)
+	}
+	templates, err := mail.NewTemplates(cfg.Mail.TemplatesDir, cfg.Mail.DefaultLocale)
+	if err != nil {
+		log.Fatalf("Invalid mail templates: %v", err)
+	}
+
+	// Without an encryption key two-factor enrolment is unavailable
+	var box *secretbox.Box
+	if cfg.Auth.TOTP.EncryptionKey != "" {
+		key, err := secretbox.ParseKey(cfg.Auth.TOTP.EncryptionKey)
+		if err == nil {
+			box, err = secretbox.New(key)
+		}
+		if err != nil {
+			log.Fatalf("Invalid totp encryption_key: %v", err)
+		}
+	}
+
+	auditLog, closeAudit := openAuditLog(cfg.Logging.AuditFile)
+
+	dom := repo.InitRepository(db)
+
+	// Initialize all use cases
+	useCases := uc.InitUsecase(uc.Dependencies{
+		Repo:      *dom,
+		Cache:     cacheImpl,
+		Hasher:    hasher,
+		Keys:      keys,
+		AuditLog:  auditLog,
+		Mailer:    mailer,
+		Templates: templates,
+		Signer:    signer,
+		Box:       box,
+		Auth: authUC.Options{
+			AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+			RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+			SecondFactorTTL: cfg.Auth.TOTP.ChallengeTTL,
+		},
+		Lockout: lockoutUC.Options{
+			MaxAccountFailures: cfg.Auth.Lockout.MaxAccountFailures,
+			MaxIPFailures:      cfg.Auth.Lockout.MaxIPFailures,
+			Window:             cfg.Auth.Lockout.Window,
+			LockoutDuration:    cfg.Auth.Lockout.Duration,
+			DelayAfter:         cfg.Auth.Lockout.DelayAfter,
+			BaseDelay:          cfg.Auth.Lockout.BaseDelay,
+			MaxDelay:           cfg.Auth.Lockout.MaxDelay,
+		},
+		Account: accountUC.Options{
+			VerificationTTL:  cfg.Auth.VerificationTTL,
+			PasswordResetTTL: cfg.Auth.PasswordResetTTL,
+			LinkBaseURL:      cfg.Mail.LinkBaseURL,
+		},
+		TwoFactor: twoFactorUC.Options{
+			Issuer: cfg.Auth.TOTP.Issuer,
+			Skew:   cfg.Auth.TOTP.Skew,
+		},
+		Session: sessionUC.Options{
+			IdleTimeout:     cfg.Auth.Session.IdleTimeout,
+			AbsoluteTimeout: cfg.Auth.Session.AbsoluteTimeout,
+		},
+		OIDC: oidcUC.Options{
+			CodeTTL:        cfg.Auth.OIDC.CodeTTL,
+			AccessTokenTTL: cfg.Auth.OIDC.AccessTokenTTL,
+			IDTokenTTL:     cfg.Auth.OIDC.IDTokenTTL,
+		},
+		Impersonation: impersonationUC.Options{
+			TTL: cfg.Auth.Impersonation.TTL,
+		},
+	})
+	return useCases, func() {
+		closeCache()
+		closeAudit()
+	}
+}
+
+// openMailer returns the mailer cfg selects.
+func openMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	from := cfg.From
+	if from == "" {
+		from = "noreply@localhost"
+	}
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("smtp_host is required for the smtp driver")
+		}
+		port := cfg.SMTPPort
+		if port == 0 {
+			port = 587
+		}
+		return mail.NewSMTPMailer(cfg.SMTPHost, port, cfg.SMTPUsername, cfg.SMTPPassword, from), nil
+	case "", "file":
+		dir := cfg.DropDir
+		if dir == "" {
+			dir = "mail"
+		}
+		return mail.NewFileMailer(dir, from), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q, use smtp or file", cfg.Driver)
+	}
+}
+
+// openAuditLog appends audit events to path, or writes them to stderr when
+// path is empty.
+func openAuditLog(path string) (audit.Logger, func()) {
+	if path == "" {
+		return audit.NewJSONLogger(os.Stderr), func() {}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	return audit.NewJSONLogger(file), func() { file.Close() }
+}
+
+// cliContext returns the context CLI commands call use cases with. Whoever
+// runs the CLI can reach the database directly, so commands act as the
+// system principal and hold every permission.
+func cliContext() context.Context {
+	return entities.WithPrincipal(context.Background(), entities.SystemPrincipal())
+}
+
+// openUseCases connects to the configured database and returns the use
+// cases for a CLI command. The returned function releases everything.
+func openUseCases(useCache bool) (*uc.UseCases, func()) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.NewCluster(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	useCases, closeCache := initUseCases(cfg, db, useCache, cache.NewNullCache())
+	return useCases, func() {
+		closeCache()
+		db.Close()
+	}
+}
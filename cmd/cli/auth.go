@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"solecode/pkg/token"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	keygenAlgorithm string
+	keygenOut       string
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage token authentication",
+}
+
+var authKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a token signing key",
+	Long: "Generate a key for auth.keys: a PKCS#8 PEM private key for RS256 and EdDSA, or a random secret for HS256. " +
+		"To rotate, add the new key to auth.keys, point auth.signing_key at it and remove the old key once access_token_ttl has passed.",
+	Example: `  userapi auth keygen --algorithm EdDSA --out conf/keys/2026-10.pem
+  userapi auth keygen --algorithm HS256`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := token.GenerateKey(keygenAlgorithm)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if keygenOut == "" {
+			os.Stdout.Write(key)
+			return
+		}
+		if err := os.WriteFile(keygenOut, key, 0o600); err != nil {
+			log.Fatalf("❌ Failed to write key: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "🔒 Wrote %s key to %s\n", keygenAlgorithm, keygenOut)
+	},
+}
+
+func init() {
+	authKeygenCmd.Flags().StringVar(&keygenAlgorithm, "algorithm", token.EdDSA, "HS256, RS256 or EdDSA")
+	authKeygenCmd.Flags().StringVar(&keygenOut, "out", "", "write the key to this file (mode 0600) instead of stdout")
+
+	authCmd.AddCommand(authKeygenCmd)
+	rootCmd.AddCommand(authCmd)
+}
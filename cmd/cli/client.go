@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"solecode/src/entities"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	clientOutput       string
+	clientName         string
+	clientRedirectURIs []string
+	clientPublic       bool
+)
+
+var clientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Manage OAuth clients",
+	Long: "OAuth clients are the first-party apps that sign users in through this service with OpenID Connect. " +
+		"Confidential clients get a secret, of which only a hash is stored: it is printed once, when the client is created. " +
+		"Public clients, such as single-page and native apps, get none and rely on PKCE.",
+}
+
+var clientCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Register a client and print its ID and secret",
+	Example: `  userapi client create --name Wiki --redirect-uri https://wiki.example.com/callback
+  userapi client create --name CLI --redirect-uri http://127.0.0.1:8400/callback --public`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(false)
+		defer closeAll()
+
+		client, secret, err := useCases.OIDC.RegisterClient(cliContext(), clientName, clientRedirectURIs, clientPublic)
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to register client: %v", err)
+		}
+		if secret == "" {
+			fmt.Fprintf(os.Stderr, "✅ Registered public client %s\n", client.Name)
+			fmt.Println(client.ID)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "✅ Registered client %s; store the secret now, it cannot be shown again\n", client.Name)
+		fmt.Printf("client_id:     %s\nclient_secret: %s\n", client.ID, secret)
+	},
+}
+
+var clientListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List clients",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(false)
+		defer closeAll()
+
+		clients, err := useCases.OIDC.ListClients(cliContext())
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to list clients: %v", err)
+		}
+		if err := writeClients(os.Stdout, clientOutput, clients); err != nil {
+			log.Fatalf("Failed to write clients: %v", err)
+		}
+	},
+}
+
+var clientDeleteCmd = &cobra.Command{
+	Use:   "delete [client-id]",
+	Short: "Delete a client with the consents given to it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(false)
+		defer closeAll()
+
+		if err := useCases.OIDC.DeleteClient(cliContext(), args[0]); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to delete client: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "🗑️ Deleted client %s\n", args[0])
+	},
+}
+
+func init() {
+	clientListCmd.Flags().StringVarP(&clientOutput, "output", "o", "table", "output format: table or json")
+
+	clientCreateCmd.Flags().StringVar(&clientName, "name", "", "name shown to users on the consent prompt")
+	clientCreateCmd.Flags().StringSliceVar(&clientRedirectURIs, "redirect-uri", nil, "URI codes may be sent to; repeat or separate with commas")
+	clientCreateCmd.Flags().BoolVar(&clientPublic, "public", false, "register a client that cannot keep a secret")
+
+	clientCmd.AddCommand(clientCreateCmd)
+	clientCmd.AddCommand(clientListCmd)
+	clientCmd.AddCommand(clientDeleteCmd)
+
+	rootCmd.AddCommand(clientCmd)
+}
+
+// writeClients writes clients as an aligned table or a JSON array.
+func writeClients(w io.Writer, format string, clients []*entities.OAuthClient) error {
+	switch format {
+	case "json":
+		type clientJSON struct {
+			*entities.OAuthClient
+			Public bool `json:"public"`
+		}
+		out := make([]clientJSON, len(clients))
+		for i, client := range clients {
+			out[i] = clientJSON{OAuthClient: client, Public: client.Public()}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPUBLIC\tREDIRECT URIS\tCREATED")
+		for _, client := range clients {
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\n", client.ID, client.Name, client.Public(),
+				strings.Join(client.RedirectURIs, ","), client.CreatedAt.Format(time.RFC3339))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
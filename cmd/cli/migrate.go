@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultMigrationsDir is where migrate-create writes new files when
+// --migrations-dir is not set.
+const defaultMigrationsDir = "docs/migrations"
+
+var (
+	migrateTo          string
+	migrateSteps       int
+	migrateAll         bool
+	migrateDryRun      bool
+	migrateLockTimeout time.Duration
+
+	resolveApplied    bool
+	resolveRolledBack bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Run database migrations",
+	Long:  "Apply pending migrations. Subcommands roll back, redo or move the database to a specific version.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrations(func(ctx context.Context, m *migrate.Migrator) (*migrate.Result, error) {
+			return m.Up(ctx, migrateTo)
+		})
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations, optionally up to a version",
+	Args:  cobra.NoArgs,
+	Run:   migrateCmd.Run,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recent migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrations(func(ctx context.Context, m *migrate.Migrator) (*migrate.Result, error) {
+			if migrateAll {
+				return m.Goto(ctx, "0")
+			}
+			return m.Down(ctx, migrateSteps)
+		})
+	},
+}
+
+var migrateRedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Roll back and reapply the most recent migration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrations(func(ctx context.Context, m *migrate.Migrator) (*migrate.Result, error) {
+			return m.Redo(ctx)
+		})
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto [version]",
+	Short: "Migrate up or down to a version (0 rolls back everything)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrations(func(ctx context.Context, m *migrate.Migrator) (*migrate.Result, error) {
+			return m.Goto(ctx, args[0])
+		})
+	},
+}
+
+var legacyMigrateDownCmd = &cobra.Command{
+	Use:        "migrate-down",
+	Short:      "Rollback database migrations",
+	Deprecated: `use "migrate down" instead`,
+	Args:       cobra.NoArgs,
+	Run:        migrateDownCmd.Run,
+}
+
+var migrateRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Accept edited migration files by recording their current checksums",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repairChecksums()
+	},
+}
+
+var migrateResolveCmd = &cobra.Command{
+	Use:   "resolve [version]",
+	Short: "Clear a partially applied migration after fixing the schema by hand",
+	Long: "A migration that fails after some of its statements were committed is recorded as partial and blocks further migrations. " +
+		"Finish or undo its changes by hand, then record the outcome with --applied or --rolled-back.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		resolveMigration(args[0])
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "migrate-create [name]",
+	Short: "Create new migration files",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		createMigration(args[0])
+	},
+}
+
+func init() {
+	migrateCmd.PersistentFlags().BoolVar(&migrateDryRun, "dry-run", false, "print the plan and its SQL without touching the database")
+	migrateCmd.PersistentFlags().DurationVar(&migrateLockTimeout, "lock-timeout", 0, "how long to wait for another migration to finish (default database.migration_lock_timeout or 60s)")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "stop after applying this version")
+	migrateUpCmd.Flags().StringVar(&migrateTo, "to", "", "stop after applying this version")
+
+	for _, cmd := range []*cobra.Command{migrateDownCmd, legacyMigrateDownCmd} {
+		cmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of migrations to roll back")
+		cmd.Flags().BoolVar(&migrateAll, "all", false, "roll back every applied migration")
+	}
+	legacyMigrateDownCmd.Flags().DurationVar(&migrateLockTimeout, "lock-timeout", 0, "how long to wait for another migration to finish")
+	legacyMigrateDownCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print the plan and its SQL without touching the database")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateRedoCmd)
+	migrateCmd.AddCommand(migrateGotoCmd)
+	migrateCmd.AddCommand(migrateRepairCmd)
+
+	migrateResolveCmd.Flags().BoolVar(&resolveApplied, "applied", false, "the migration's changes are now fully in place")
+	migrateResolveCmd.Flags().BoolVar(&resolveRolledBack, "rolled-back", false, "the migration's changes have been undone")
+	migrateResolveCmd.MarkFlagsMutuallyExclusive("applied", "rolled-back")
+	migrateResolveCmd.MarkFlagsOneRequired("applied", "rolled-back")
+	migrateCmd.AddCommand(migrateResolveCmd)
+
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(legacyMigrateDownCmd)
+	rootCmd.AddCommand(migrateCreateCmd)
+}
+
+// runMigrations runs one of the Migrator's operations and prints its plan,
+// and with --dry-run the SQL it would have run.
+func runMigrations(run func(ctx context.Context, m *migrate.Migrator) (*migrate.Result, error)) {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	result, err := run(context.Background(), migrator)
+	if result != nil && result.DryRun && err == nil {
+		printPlan(result.Plan)
+		printPlanSQL(result.Plan)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	if len(result.Plan) == 0 {
+		fmt.Println("✅ Nothing to do, database is up to date")
+		return
+	}
+	fmt.Printf("✅ Migration plan completed successfully (%d step(s))\n", len(result.Done))
+}
+
+func printPlan(plan []migrate.Step) {
+	if len(plan) == 0 {
+		fmt.Println("✅ Nothing to do, database is up to date")
+		return
+	}
+
+	fmt.Println("📋 Migration plan:")
+	for _, step := range plan {
+		arrow := "↑"
+		if step.Direction == migrate.Down {
+			arrow = "↓"
+		}
+		fmt.Printf("   %s %-4s %s (%s)\n", arrow, step.Direction, step.Migration.Version, step.Migration.Name)
+	}
+	fmt.Println()
+}
+
+func printPlanSQL(plan []migrate.Step) {
+	if len(plan) == 0 {
+		return
+	}
+
+	fmt.Println("🔍 Dry run, no changes made. SQL that would run:")
+	for _, step := range plan {
+		fmt.Printf("\n-- %s %s (%s)\n", step.Direction, step.Migration.Version, step.Migration.Name)
+		if step.Migration.Go != nil {
+			fmt.Println("-- Go migration, no SQL to show")
+			continue
+		}
+		fmt.Println(strings.TrimSpace(step.SQL()))
+	}
+}
+
+// repairChecksums overwrites the recorded checksum of every applied
+// migration whose file differs from, or predates, the log entry.
+func repairChecksums() {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	repaired, err := migrator.Repair(context.Background())
+	if err != nil {
+		log.Fatalf("Repair failed: %v", err)
+	}
+
+	for _, migration := range repaired {
+		fmt.Printf("🔧 Recorded new checksum for %s (%s)\n", migration.Version, migration.Name)
+	}
+	fmt.Printf("✅ Repaired %d migration checksum(s)\n", len(repaired))
+}
+
+func resolveMigration(version string) {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	if err := migrator.Resolve(context.Background(), version, resolveApplied); err != nil {
+		log.Fatalf("Resolve failed: %v", err)
+	}
+
+	if resolveApplied {
+		fmt.Printf("✅ Recorded migration %s as applied\n", version)
+	} else {
+		fmt.Printf("✅ Recorded migration %s as rolled back\n", version)
+	}
+}
+
+// openMigrator connects to the configured database and returns a Migrator
+// for it along with the connection, which the caller closes.
+func openMigrator() (*migrate.Migrator, *sql.DB) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	dialect, err := database.DialectOf(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.NewDB(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	lockTimeout := migrateLockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = cfg.Database.MigrationLockTimeout
+	}
+
+	migrator := migrate.New(db, dialect, migrationsFS(), migrate.Options{
+		LockTimeout:  lockTimeout,
+		DryRun:       migrateDryRun,
+		GoMigrations: migrations.Go,
+		Logf: func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		},
+	})
+	return migrator, db
+}
+
+// migrationsFS returns --migrations-dir, or the migrations compiled into
+// the binary when it is not set.
+func migrationsFS() fs.FS {
+	if migrationsDir == "" {
+		return migrations.FS
+	}
+	return os.DirFS(migrationsDir)
+}
+
+func createMigration(name string) {
+	timestamp := time.Now().Format("20060102150405")
+
+	root := migrationsDir
+	if root == "" {
+		root = defaultMigrationsDir
+	}
+
+	fmt.Printf("✅ Created migration files:\n")
+
+	// Every dialect keeps its own copy of each migration
+	for _, dialect := range database.Dialects {
+		dir := filepath.Join(root, string(dialect))
+		upFile := filepath.Join(dir, fmt.Sprintf("%s_%s.up.sql", timestamp, name))
+		downFile := filepath.Join(dir, fmt.Sprintf("%s_%s.down.sql", timestamp, name))
+
+		// Create migrations directory if it doesn't exist
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatalf("Failed to create migrations directory: %v", err)
+		}
+
+		// Create up migration file
+		upContent := fmt.Sprintf("-- Migration: %s\n-- Version: %s\n-- Description: %s\n\n", name, timestamp, name)
+		if err := os.WriteFile(upFile, []byte(upContent), 0644); err != nil {
+			log.Fatalf("Failed to create up migration file: %v", err)
+		}
+
+		// Create down migration file
+		downContent := fmt.Sprintf("-- Rollback: %s\n-- Version: %s\n\n", name, timestamp)
+		if err := os.WriteFile(downFile, []byte(downContent), 0644); err != nil {
+			log.Fatalf("Failed to create down migration file: %v", err)
+		}
+
+		fmt.Printf("   Up (%s): %s\n", dialect, upFile)
+		fmt.Printf("   Down (%s): %s\n", dialect, downFile)
+	}
+}
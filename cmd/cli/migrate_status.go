@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"solecode/pkg/migrate"
+
+	"github.com/spf13/cobra"
+)
+
+var statusOutput string
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "migrate-status",
+	Short: "Show migration status",
+	Long:  "Show migration status and flag drift: edited or missing files, out-of-order versions and log entries without a file. Exits non-zero when drift is found.",
+	Run: func(cmd *cobra.Command, args []string) {
+		showMigrationStatus()
+	},
+}
+
+func init() {
+	migrateStatusCmd.Flags().StringVarP(&statusOutput, "output", "o", "text", "output format: text or json")
+
+	rootCmd.AddCommand(migrateStatusCmd)
+}
+
+func showMigrationStatus() {
+	if statusOutput != "text" && statusOutput != "json" {
+		log.Fatalf("Unknown output format %q", statusOutput)
+	}
+
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	report, err := migrator.Status(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to get migration status: %v", err)
+	}
+
+	if statusOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to encode status: %v", err)
+		}
+	} else {
+		printStatusReport(report)
+	}
+
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func printStatusReport(report *migrate.StatusReport) {
+	fmt.Println("📊 Migration Status:")
+	fmt.Println("====================")
+
+	for _, migration := range report.Migrations {
+		status := "❌ Pending"
+		switch migration.State {
+		case migrate.StateApplied:
+			status = "✅ Applied"
+		case migrate.StatePartial:
+			status = "⚠️  Partial"
+		case migrate.StateOrphaned:
+			status = "👻 Orphaned"
+		}
+		fmt.Printf("%s %s - %s\n", status, migration.Version, migration.Name)
+
+		for _, msg := range migration.Errors {
+			fmt.Printf("   ⛔ %s\n", msg)
+		}
+		for _, msg := range migration.Warnings {
+			fmt.Printf("   ⚠️  %s\n", msg)
+		}
+	}
+
+	fmt.Printf("\nTotal: %d migrations (%d applied, %d pending)\n",
+		report.Total, report.Applied, report.Pending)
+	if report.Errors > 0 || report.Warnings > 0 {
+		fmt.Printf("Drift: %d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"solecode/src/entities"
+	uc "solecode/src/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	roleOutput      string
+	roleCache       bool
+	roleUser        int64
+	roleDescription string
+	rolePermissions []string
+)
+
+var roleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Manage roles and their assignment to users",
+	Long: "Roles are named sets of permissions stored in the database; users hold the permissions of all their roles. " +
+		"Every user may read and update their own account without any role. The admin role, created by the migrations, holds every permission.",
+}
+
+var roleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List roles, or the roles of one user with --user",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(roleCache)
+		defer closeAll()
+
+		var roles []*entities.Role
+		var err error
+		if roleUser > 0 {
+			roles, err = useCases.Authz.UserRoles(cliContext(), roleUser)
+		} else {
+			roles, err = useCases.Authz.ListRoles(cliContext())
+		}
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to list roles: %v", err)
+		}
+		if err := writeRoles(os.Stdout, roleOutput, roles); err != nil {
+			log.Fatalf("Failed to write roles: %v", err)
+		}
+	},
+}
+
+var roleCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a role",
+	Example: `  userapi role create support --permission users:read --permission users:password
+  userapi role create auditor --permission users:read,roles:read --description "Reads everything"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(roleCache)
+		defer closeAll()
+
+		permissions := make([]entities.Permission, len(rolePermissions))
+		for i, permission := range rolePermissions {
+			permissions[i] = entities.Permission(strings.TrimSpace(permission))
+		}
+		role, err := useCases.Authz.CreateRole(cliContext(), args[0], roleDescription, permissions)
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to create role: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Created role %s\n", role.Name)
+	},
+}
+
+var roleDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a role and take it away from its users",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(roleCache)
+		defer closeAll()
+
+		if err := useCases.Authz.DeleteRole(cliContext(), args[0]); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to delete role: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "🗑️ Deleted role %s\n", args[0])
+	},
+}
+
+var roleGrantCmd = &cobra.Command{
+	Use:     "grant [user-id] [role...]",
+	Short:   "Give roles to a user",
+	Example: `  userapi role grant 1 admin`,
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRoleChange(args, "Granted", func(useCases *uc.UseCases) roleChangeFunc { return useCases.Authz.GrantRole })
+	},
+}
+
+var roleRevokeCmd = &cobra.Command{
+	Use:   "revoke [user-id] [role...]",
+	Short: "Take roles away from a user",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRoleChange(args, "Revoked", func(useCases *uc.UseCases) roleChangeFunc { return useCases.Authz.RevokeRole })
+	},
+}
+
+func init() {
+	roleCmd.PersistentFlags().StringVarP(&roleOutput, "output", "o", "table", "output format: table or json")
+	roleCmd.PersistentFlags().BoolVar(&roleCache, "cache", true, "invalidate cached permissions in Redis; --cache=false skips it")
+
+	roleListCmd.Flags().Int64Var(&roleUser, "user", 0, "list the roles of this user ID")
+	roleCreateCmd.Flags().StringVar(&roleDescription, "description", "", "what the role is for")
+	roleCreateCmd.Flags().StringSliceVar(&rolePermissions, "permission", nil, "permission to grant; repeat or separate with commas")
+
+	roleCmd.AddCommand(roleListCmd)
+	roleCmd.AddCommand(roleCreateCmd)
+	roleCmd.AddCommand(roleDeleteCmd)
+	roleCmd.AddCommand(roleGrantCmd)
+	roleCmd.AddCommand(roleRevokeCmd)
+
+	rootCmd.AddCommand(roleCmd)
+}
+
+type roleChangeFunc func(ctx context.Context, userID int64, name string) ([]*entities.Role, error)
+
+// runRoleChange applies the change picked from the use cases to the user
+// in args[0] for each role named after it, then writes the user's roles.
+func runRoleChange(args []string, verb string, pick func(useCases *uc.UseCases) roleChangeFunc) {
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || userID <= 0 {
+		log.Fatalf("Invalid user ID %q", args[0])
+	}
+
+	useCases, closeAll := openUseCases(roleCache)
+	defer closeAll()
+
+	change := pick(useCases)
+	var roles []*entities.Role
+	for _, name := range args[1:] {
+		roles, err = change(cliContext(), userID, name)
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to change roles of user %d: %v", userID, err)
+		}
+		fmt.Fprintf(os.Stderr, "🔧 %s role %s for user %d\n", verb, name, userID)
+	}
+	if err := writeRoles(os.Stdout, roleOutput, roles); err != nil {
+		log.Fatalf("Failed to write roles: %v", err)
+	}
+}
+
+// writeRoles writes roles as an aligned table or a JSON array.
+func writeRoles(w io.Writer, format string, roles []*entities.Role) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(roles)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tPERMISSIONS\tDESCRIPTION")
+		for _, role := range roles {
+			permissions := make([]string, len(role.Permissions))
+			for i, permission := range role.Permissions {
+				permissions[i] = string(permission)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", role.Name, strings.Join(permissions, ","), role.Description)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	seedFirstNames = []string{
+		"Ada", "Alan", "Alice", "Amir", "Ana", "Ben", "Carla", "Chen", "Daniel", "Diana",
+		"Elena", "Emma", "Farah", "George", "Grace", "Hana", "Ivan", "James", "Julia", "Kenji",
+		"Laura", "Leo", "Maria", "Mohamed", "Nina", "Omar", "Priya", "Rafael", "Sara", "Tom",
+	}
+	seedLastNames = []string{
+		"Anderson", "Brown", "Costa", "Davis", "Evans", "Fischer", "Garcia", "Hughes", "Ivanova", "Johnson",
+		"Kim", "Lopez", "Martin", "Nakamura", "O'Brien", "Patel", "Quinn", "Rossi", "Silva", "Taylor",
+		"Usman", "Valdez", "Walker", "Xu", "Yilmaz", "Zhang",
+	}
+	seedDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+// userGenerator produces pseudo-random users. The same seed always yields
+// the same sequence, so generated datasets are repeatable.
+type userGenerator struct {
+	rng  *rand.Rand
+	seed int64
+	n    int
+}
+
+func newUserGenerator(seed int64) *userGenerator {
+	return &userGenerator{rng: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+// Next returns the next user. Emails carry the seed and a sequence number
+// so they stay unique within a run and across runs with different seeds.
+func (g *userGenerator) Next() *entities.User {
+	g.n++
+	first := seedFirstNames[g.rng.Intn(len(seedFirstNames))]
+	last := seedLastNames[g.rng.Intn(len(seedLastNames))]
+	domain := seedDomains[g.rng.Intn(len(seedDomains))]
+
+	local := strings.ToLower(first + "." + strings.ReplaceAll(last, "'", ""))
+	return &entities.User{
+		Name:  first + " " + last,
+		Email: fmt.Sprintf("%s.%d.%d@%s", local, g.seed, g.n, domain),
+	}
+}
+
+// seedFixture is the layout of a fixture file.
+type seedFixture struct {
+	Users []seedFixtureUser `json:"users" yaml:"users"`
+}
+
+type seedFixtureUser struct {
+	Name  string `json:"name" yaml:"name" validate:"required,min=2,max=100,name"`
+	Email string `json:"email" yaml:"email" validate:"required,email"`
+}
+
+// readSeedFixture reads a YAML or JSON fixture file, chosen by extension,
+// and returns its users normalised and validated like the user use case
+// does.
+func readSeedFixture(path string) ([]*entities.User, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture seedFixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &fixture)
+	default:
+		return nil, fmt.Errorf("unknown fixture format %q, use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+
+	v := validator.New()
+	seen := make(map[string]int, len(fixture.Users))
+	users := make([]*entities.User, 0, len(fixture.Users))
+	for i, u := range fixture.Users {
+		u.Name = strings.TrimSpace(u.Name)
+		u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+		if err := v.ValidateStruct(u); err != nil {
+			return nil, fmt.Errorf("users[%d]: %w", i, err)
+		}
+		if j, ok := seen[u.Email]; ok {
+			return nil, fmt.Errorf("users[%d]: email %s duplicates users[%d]", i, u.Email, j)
+		}
+		seen[u.Email] = i
+		users = append(users, &entities.User{Name: u.Name, Email: u.Email})
+	}
+	return users, nil
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserGeneratorIsDeterministic(t *testing.T) {
+	a, b, c := newUserGenerator(42), newUserGenerator(42), newUserGenerator(43)
+
+	v := validator.New()
+	emails := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		userA, userB, userC := a.Next(), b.Next(), c.Next()
+		assert.Equal(t, userA, userB)
+		assert.NotEqual(t, userA.Email, userC.Email)
+
+		require.NoError(t, v.ValidateStruct(seedFixtureUser{Name: userA.Name, Email: userA.Email}))
+		assert.False(t, emails[userA.Email], "duplicate email %s", userA.Email)
+		emails[userA.Email] = true
+	}
+}
+
+func TestReadSeedFixture(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	want := []*entities.User{
+		{Name: "John Doe", Email: "john@example.com"},
+		{Name: "Jane Doe", Email: "jane@example.com"},
+	}
+
+	users, err := readSeedFixture(write("users.yaml", "users:\n  - name: John Doe\n    email: \" John@Example.com \"\n  - name: Jane Doe\n    email: jane@example.com\n"))
+	require.NoError(t, err)
+	assert.Equal(t, want, users)
+
+	users, err = readSeedFixture(write("users.json", `{"users": [{"name": "John Doe", "email": "JOHN@example.com"}, {"name": "Jane Doe", "email": "jane@example.com"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, want, users)
+
+	_, err = readSeedFixture(write("unknown.json", `{"users": [{"name": "John Doe", "email": "john@example.com", "age": 3}]}`))
+	assert.Error(t, err)
+
+	_, err = readSeedFixture(write("invalid.yaml", "users:\n  - name: John Doe\n    email: not-an-email\n"))
+	assert.ErrorContains(t, err, "users[0]: email")
+
+	_, err = readSeedFixture(write("dup.yaml", "users:\n  - {name: John Doe, email: john@example.com}\n  - {name: Johnny, email: JOHN@example.com}\n"))
+	assert.ErrorContains(t, err, "duplicates users[0]")
+
+	_, err = readSeedFixture(write("users.txt", ""))
+	assert.ErrorContains(t, err, "unknown fixture format")
+}
+
+func TestSeedUsers(t *testing.T) {
+	seedBatchSize, seedWorkers = 7, 3
+	t.Cleanup(func() { seedBatchSize, seedWorkers = 500, 4 })
+	ctx := context.Background()
+
+	repo := userRepository.NewMemoryUserRepository()
+	inserted, _, err := seedUsers(ctx, repo, 100, newUserGenerator(1).Next)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, inserted)
+
+	users, err := repo.List(ctx, userRepository.ListFilter{})
+	require.NoError(t, err)
+	assert.Len(t, users, 100)
+
+	// The same seed generates the same emails, so a second run collides
+	_, _, err = seedUsers(ctx, repo, 100, newUserGenerator(1).Next)
+	assert.ErrorIs(t, err, userRepository.ErrEmailExists)
+}
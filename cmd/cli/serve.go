@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/cache"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	soleCodeHttp "solecode/src/delivery/http"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const shutdownTimeout = 15 * time.Second
+
+var (
+	serveAddr     string
+	serveCache    bool
+	serveReplicas bool
+	serveSwagger  bool
+	serveMigrate  bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the HTTP API server",
+	Long:  "Run the HTTP API server. This is also what the binary does when run without a command.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runServer(cmd.Flags())
+	},
+}
+
+func init() {
+	flags := serveCmd.Flags()
+	flags.StringVar(&serveAddr, "addr", "", "listen address (default \":<server.port>\")")
+	flags.BoolVar(&serveCache, "cache", true, "use Redis; --cache=false keeps cache entries and refresh tokens in process memory")
+	flags.BoolVar(&serveReplicas, "replicas", true, "route reads to the configured read replicas")
+	flags.BoolVar(&serveSwagger, "swagger", true, "serve the Swagger UI")
+	flags.BoolVar(&serveMigrate, "migrate", false, "apply pending migrations on start (default database.auto_migrate)")
+
+	// The bare binary serves, so it accepts the same flags
+	rootCmd.Flags().AddFlagSet(flags)
+	rootCmd.Args = cobra.NoArgs
+	rootCmd.Run = serveCmd.Run
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServer(flags *pflag.FlagSet) {
+	// Load configuration
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !serveReplicas {
+		cfg.Database.Replicas = nil
+	}
+	autoMigrate := cfg.Database.AutoMigrate
+	if flags.Changed("migrate") {
+		autoMigrate = serveMigrate
+	}
+	addr := serveAddr
+	if addr == "" {
+		addr = ":" + cfg.Server.Port
+	}
+
+	// Initialize database
+	db, err := database.NewCluster(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Apply pending migrations, read from --migrations-dir like migrate
+	// does; the migration lock makes this safe when several instances
+	// start at once
+	if autoMigrate {
+		migrator := migrate.New(db.Primary(), db.Dialect(), migrationsFS(), migrate.Options{
+			LockTimeout:  cfg.Database.MigrationLockTimeout,
+			GoMigrations: migrations.Go,
+			Logf:         log.Printf,
+		})
+		if _, err := migrator.Up(context.Background(), ""); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
+	uc, closeCache := initUseCases(cfg, db, serveCache, cache.NewMemoryCache())
+	defer closeCache()
+	if serveCache {
+		log.Println("Redis cache connected successfully")
+	} else {
+		log.Println("Redis disabled; caching in process memory")
+	}
+	if len(cfg.Auth.Keys) == 0 {
+		log.Println("No auth keys configured; tokens are signed with an ephemeral key and stop working on restart")
+	}
+	if _, err := uc.OIDC.Configuration(); err != nil {
+		log.Printf("OpenID Connect provider disabled: %v", err)
+	}
+
+	// Initialize HTTP handler
+	userHandler := soleCodeHttp.NewUserHandler(*uc, soleCodeHttp.UserHandlerOptions{
+		BatchMaxOperations: cfg.Server.BatchMaxOperations,
+	})
+
+	sameSite, err := parseSameSite(cfg.Auth.Session.SameSite)
+	if err == nil && sameSite == http.SameSiteNoneMode && cfg.Auth.Session.InsecureCookie {
+		err = fmt.Errorf("browsers reject SameSite=None cookies without Secure")
+	}
+	if err != nil {
+		log.Fatalf("Invalid session config: %v", err)
+	}
+	authHandler := soleCodeHttp.NewAuthHandler(*uc, soleCodeHttp.AuthHandlerOptions{
+		SessionCookie:   cfg.Auth.Session.CookieName,
+		CookieDomain:    cfg.Auth.Session.CookieDomain,
+		InsecureCookies: cfg.Auth.Session.InsecureCookie,
+		SameSite:        sameSite,
+	})
+	roleHandler := soleCodeHttp.NewRoleHandler(*uc)
+	apiKeyHandler := soleCodeHttp.NewAPIKeyHandler(*uc)
+	accountHandler := soleCodeHttp.NewAccountHandler(*uc)
+	oidcHandler := soleCodeHttp.NewOIDCHandler(*uc, authHandler, soleCodeHttp.OIDCHandlerOptions{
+		InteractionURL: cfg.Auth.OIDC.InteractionURL,
+	})
+
+	trustedProxies, err := parseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trusted_proxies: %v", err)
+	}
+
+	// Initialize router
+	router := soleCodeHttp.NewRouter(userHandler, authHandler, roleHandler, apiKeyHandler, accountHandler, oidcHandler, soleCodeHttp.RouterOptions{
+		Swagger:        serveSwagger,
+		TrustedProxies: trustedProxies,
+	})
+
+	// Create server with timeouts
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router.GetHandler(),
+		ReadTimeout:  cfg.Server.Timeout,
+		WriteTimeout: cfg.Server.Timeout,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s", addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown failed: %v", err)
+		}
+	}
+}
+
+// parseTrustedProxies parses CIDR ranges and single addresses.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an address nor a CIDR range", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseSameSite maps the same_site setting to its cookie attribute; empty
+// means Lax.
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("same_site %q is not lax, strict or none", value)
+	}
+}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	userOutput      string
+	userCache       bool
+	userFile        string
+	userInputFormat string
+
+	userName  string
+	userEmail string
+
+	userListLimit          int
+	userListOffset         int
+	userListIncludeDeleted bool
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+	Long: "Manage users through the same use cases as the HTTP API, so validation, email normalisation and cache invalidation apply. " +
+		"Commands that change users accept many records with --file (\"-\" reads stdin) in CSV with a header row or JSON (an array or one object per line).",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create users",
+	Example: `  userapi user create --name "John Doe" --email john@example.com
+  userapi user create --file users.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		records := userRecordsFromFlags(cmd, nil, true)
+		runUserCommand(records, func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error) {
+			return useCases.User.CreateUser(ctx, r.Name, r.Email)
+		})
+	},
+}
+
+var userGetCmd = &cobra.Command{
+	Use:   "get [id...]",
+	Short: "Show users by ID",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		records := userRecordsFromFlags(cmd, args, false)
+		runUserCommand(records, func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error) {
+			return useCases.User.GetUser(ctx, r.ID)
+		})
+	},
+}
+
+var userUpdateCmd = &cobra.Command{
+	Use:   "update [id]",
+	Short: "Update users; fields that are not given keep their value",
+	Example: `  userapi user update 42 --email john.doe@example.com
+  userapi user update --file changes.json`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		records := userRecordsFromFlags(cmd, args, true)
+		runUserCommand(records, func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error) {
+			if r.Name == "" || r.Email == "" {
+				current, err := useCases.User.GetUser(ctx, r.ID)
+				if err != nil {
+					return nil, err
+				}
+				if r.Name == "" {
+					r.Name = current.Name
+				}
+				if r.Email == "" {
+					r.Email = current.Email
+				}
+			}
+			return useCases.User.UpdateUser(ctx, r.ID, r.Name, r.Email)
+		})
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [id...]",
+	Short: "Soft delete users",
+	Run: func(cmd *cobra.Command, args []string) {
+		records := userRecordsFromFlags(cmd, args, false)
+		runUserCommand(records, func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error) {
+			if err := useCases.User.DeleteUser(ctx, r.ID); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "🗑️  Deleted user %d\n", r.ID)
+			return nil, nil
+		})
+	},
+}
+
+var userRestoreCmd = &cobra.Command{
+	Use:   "restore [id...]",
+	Short: "Restore soft deleted users",
+	Run: func(cmd *cobra.Command, args []string) {
+		records := userRecordsFromFlags(cmd, args, false)
+		runUserCommand(records, func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error) {
+			return useCases.User.RestoreUser(ctx, r.ID)
+		})
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		users, err := useCases.User.ListUsers(cliContext(), userRepository.ListFilter{
+			Limit:          userListLimit,
+			Offset:         userListOffset,
+			IncludeDeleted: userListIncludeDeleted,
+		})
+		if err != nil {
+			log.Fatalf("Failed to list users: %v", err)
+		}
+
+		if err := writeUsers(os.Stdout, userOutput, users); err != nil {
+			log.Fatalf("Failed to write users: %v", err)
+		}
+	},
+}
+
+func init() {
+	userCmd.PersistentFlags().StringVarP(&userOutput, "output", "o", "table", "output format: table, json or csv")
+	userCmd.PersistentFlags().BoolVar(&userCache, "cache", true, "invalidate cached users in Redis; --cache=false skips it")
+
+	for _, cmd := range []*cobra.Command{userCreateCmd, userUpdateCmd, userDeleteCmd, userRestoreCmd} {
+		cmd.Flags().StringVarP(&userFile, "file", "f", "", `read records from a file, or "-" for stdin`)
+		cmd.Flags().StringVar(&userInputFormat, "input-format", "", "csv or json (default from the file extension, json for stdin)")
+	}
+	for _, cmd := range []*cobra.Command{userCreateCmd, userUpdateCmd} {
+		cmd.Flags().StringVar(&userName, "name", "", "user name")
+		cmd.Flags().StringVar(&userEmail, "email", "", "user email")
+	}
+
+	userListCmd.Flags().IntVar(&userListLimit, "limit", 0, "maximum number of users (0 for all)")
+	userListCmd.Flags().IntVar(&userListOffset, "offset", 0, "number of users to skip")
+	userListCmd.Flags().BoolVar(&userListIncludeDeleted, "include-deleted", false, "include soft deleted users")
+
+	userCmd.AddCommand(userCreateCmd)
+	userCmd.AddCommand(userGetCmd)
+	userCmd.AddCommand(userUpdateCmd)
+	userCmd.AddCommand(userDeleteCmd)
+	userCmd.AddCommand(userRestoreCmd)
+	userCmd.AddCommand(userListCmd)
+
+	rootCmd.AddCommand(userCmd)
+}
+
+// userRecordsFromFlags collects the records a command works on: from
+// --file, or from the ID arguments and, with withFields, --name and --email.
+func userRecordsFromFlags(cmd *cobra.Command, args []string, withFields bool) []userRecord {
+	if !isValidOutput(userOutput) {
+		log.Fatalf("Unknown output format %q", userOutput)
+	}
+
+	if userFile != "" {
+		if len(args) > 0 || userName != "" || userEmail != "" {
+			log.Fatalf("--file cannot be combined with IDs, --name or --email")
+		}
+		records, err := readUserRecords(userFile, userInputFormat)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", userFile, err)
+		}
+		return records
+	}
+
+	var records []userRecord
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid user ID %q", arg)
+		}
+		records = append(records, userRecord{Line: i + 1, ID: id})
+	}
+
+	if withFields {
+		if len(records) == 0 {
+			records = append(records, userRecord{Line: 1})
+		}
+		records[0].Name = userName
+		records[0].Email = userEmail
+	}
+
+	if len(records) == 0 {
+		log.Fatalf("Nothing to do: pass user IDs or --file")
+	}
+	return records
+}
+
+// runUserCommand applies fn to every record, writes the users it returns
+// and reports failures per record. It exits non-zero when any record
+// failed.
+func runUserCommand(records []userRecord, fn func(ctx context.Context, useCases *uc.UseCases, r userRecord) (*entities.User, error)) {
+	useCases, closeAll := openUseCases(userCache)
+	defer closeAll()
+
+	ctx := cliContext()
+	users := []*entities.User{}
+	failed := 0
+	for _, record := range records {
+		user, err := fn(ctx, useCases, record)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "❌ Record %d: %v\n", record.Line, err)
+			continue
+		}
+		if user != nil {
+			users = append(users, user)
+		}
+	}
+
+	if len(users) > 0 {
+		if err := writeUsers(os.Stdout, userOutput, users); err != nil {
+			log.Fatalf("Failed to write users: %v", err)
+		}
+	}
+
+	if len(records) > 1 {
+		fmt.Fprintf(os.Stderr, "📊 %d succeeded, %d failed\n", len(records)-failed, failed)
+	}
+	if failed > 0 {
+		closeAll()
+		os.Exit(1)
+	}
+}
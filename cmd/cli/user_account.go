@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var userMailLocale string
+
+const userMailNote = "The link only works on servers sharing auth.action_token_secret with this command; without one each process has its own."
+
+var userSendVerificationCmd = &cobra.Command{
+	Use:   "send-verification [id]",
+	Short: "Email a user a link that confirms their address",
+	Long:  "Send the verification email through the configured mailer. " + userMailNote,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid user ID %q", args[0])
+		}
+
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		if err := useCases.Account.SendVerification(cliContext(), id, userMailLocale); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to send verification email: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Sent verification email to user %d\n", id)
+	},
+}
+
+var userSendPasswordResetCmd = &cobra.Command{
+	Use:   "send-password-reset [email]",
+	Short: "Email a password reset link",
+	Long:  "Send the password reset email through the configured mailer, if a user has the address. " + userMailNote,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		if err := useCases.Account.RequestPasswordReset(cliContext(), args[0], userMailLocale); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to send password reset email: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Sent password reset email to %s if it belongs to a user\n", args[0])
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{userSendVerificationCmd, userSendPasswordResetCmd} {
+		cmd.Flags().StringVar(&userMailLocale, "locale", "", "language of the email, e.g. de; default from mail.default_locale")
+		userCmd.AddCommand(cmd)
+	}
+}
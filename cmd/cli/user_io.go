@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"solecode/src/entities"
+)
+
+// userRecord is one input row of a bulk user command.
+type userRecord struct {
+	Line  int    `json:"-"`
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// readUserRecords reads records from path, or stdin for "-", in format
+// (csv or json), guessing it from the extension when empty.
+func readUserRecords(path, format string) ([]userRecord, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	if format == "" {
+		format = "json"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return readUserCSV(r)
+	case "json":
+		return readUserJSON(r)
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+// readUserCSV reads CSV with a header row naming any of the columns id,
+// name and email.
+func readUserCSV(r io.Reader) ([]userRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []userRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := userRecord{Line: line, Name: field(row, "name"), Email: field(row, "email")}
+		if id := field(row, "id"); id != "" {
+			record.ID, err = strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid id %q", line, id)
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// readUserJSON reads a JSON array of records or a stream of records, one
+// per line.
+func readUserJSON(r io.Reader) ([]userRecord, error) {
+	reader := bufio.NewReader(r)
+	start, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+
+	var records []userRecord
+	if start == '[' {
+		if err := decoder.Decode(&records); err != nil {
+			return nil, err
+		}
+		for i := range records {
+			records[i].Line = i + 1
+		}
+		return records, nil
+	}
+
+	for n := 1; ; n++ {
+		var record userRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		record.Line = n
+		records = append(records, record)
+	}
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
+
+func isValidOutput(format string) bool {
+	return format == "table" || format == "json" || format == "csv"
+}
+
+// writeUsers writes users as an aligned table, a JSON array or CSV.
+func writeUsers(w io.Writer, format string, users []*entities.User) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(users)
+
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"id", "name", "email", "created_at", "updated_at", "deleted_at"})
+		for _, user := range users {
+			writer.Write([]string{
+				strconv.FormatInt(user.ID, 10), user.Name, user.Email,
+				user.CreatedAt.Format(time.RFC3339), user.UpdatedAt.Format(time.RFC3339), formatDeletedAt(user),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tCREATED\tDELETED")
+		for _, user := range users {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+				user.ID, user.Name, user.Email, user.CreatedAt.Format(time.RFC3339), formatDeletedAt(user))
+		}
+		return tw.Flush()
+	}
+}
+
+func formatDeletedAt(user *entities.User) string {
+	if user.DeletedAt == nil {
+		return ""
+	}
+	return user.DeletedAt.Format(time.RFC3339)
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"solecode/src/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadUserCSV(t *testing.T) {
+	records, err := readUserCSV(strings.NewReader("email, name\njohn@example.com, John Doe\njane@example.com,Jane Doe\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []userRecord{
+		{Line: 2, Name: "John Doe", Email: "john@example.com"},
+		{Line: 3, Name: "Jane Doe", Email: "jane@example.com"},
+	}, records)
+
+	_, err = readUserCSV(strings.NewReader("id\nabc\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReadUserJSON(t *testing.T) {
+	want := []userRecord{
+		{Line: 1, ID: 1, Email: "john@example.com"},
+		{Line: 2, ID: 2, Name: "Jane Doe"},
+	}
+
+	records, err := readUserJSON(strings.NewReader(` [{"id": 1, "email": "john@example.com"}, {"id": 2, "name": "Jane Doe"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, want, records)
+
+	records, err = readUserJSON(strings.NewReader("{\"id\": 1, \"email\": \"john@example.com\"}\n{\"id\": 2, \"name\": \"Jane Doe\"}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, want, records)
+
+	_, err = readUserJSON(strings.NewReader(`{"mail": "typo@example.com"}`))
+	assert.Error(t, err)
+}
+
+func TestWriteUsers(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []*entities.User{{ID: 7, Name: "John Doe", Email: "john@example.com", CreatedAt: created, UpdatedAt: created}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeUsers(&buf, "csv", users))
+	assert.Equal(t, "id,name,email,created_at,updated_at,deleted_at\n7,John Doe,john@example.com,2025-01-02T03:04:05Z,2025-01-02T03:04:05Z,\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeUsers(&buf, "table", users))
+	assert.Contains(t, buf.String(), "7   John Doe  john@example.com")
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var userUnlockIP string
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "unlock [id]",
+	Short: "Lift the login lockout of a user or, with --ip, of a client IP",
+	Long: "Clear failed logins and lockouts kept in Redis. Servers running with --cache=false " +
+		"keep them in process memory, where only DELETE /api/v1/users/{id}/lockout reaches them.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if userUnlockIP != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if !userCache {
+			log.Fatalf("❌ Lockouts live in Redis; unlocking needs --cache")
+		}
+
+		var id int64
+		if userUnlockIP == "" {
+			var err error
+			id, err = strconv.ParseInt(args[0], 10, 64)
+			if err != nil || id <= 0 {
+				log.Fatalf("Invalid user ID %q", args[0])
+			}
+		}
+
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		if userUnlockIP != "" {
+			if err := useCases.Lockout.UnlockIP(cliContext(), userUnlockIP); err != nil {
+				closeAll()
+				log.Fatalf("❌ Failed to unlock %s: %v", userUnlockIP, err)
+			}
+			fmt.Fprintf(os.Stderr, "✅ Unlocked client IP %s\n", userUnlockIP)
+			return
+		}
+		if err := useCases.Lockout.Unlock(cliContext(), id); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to unlock user %d: %v", id, err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Unlocked user %d\n", id)
+	},
+}
+
+func init() {
+	userUnlockCmd.Flags().StringVar(&userUnlockIP, "ip", "", "unlock this client IP instead of a user")
+	userCmd.AddCommand(userUnlockCmd)
+}
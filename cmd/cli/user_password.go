@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"solecode/pkg/validator"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var userSetPasswordCmd = &cobra.Command{
+	Use:   "set-password [id]",
+	Short: "Set a user's password without checking the current one",
+	Long: "Read the new password from the terminal without echoing it, or from the first line of stdin when it is not a terminal. " +
+		"The password must satisfy the same rules as over HTTP and is hashed with the configured algorithm.",
+	Example: `  userapi user set-password 42
+  printf '%s\n' "$NEW_PASSWORD" | userapi user set-password 42`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid user ID %q", args[0])
+		}
+
+		password, err := readPassword()
+		if err != nil {
+			log.Fatalf("Failed to read password: %v", err)
+		}
+
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		err = useCases.User.SetPassword(cliContext(), id, password)
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			closeAll()
+			log.Fatalf("❌ Invalid password: %s", validationErrors[0].Message)
+		}
+		if err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to set password: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "🔒 Password set for user %d\n", id)
+	},
+}
+
+func init() {
+	userCmd.AddCommand(userSetPasswordCmd)
+}
+
+// readPassword prompts twice on a terminal and reads one line otherwise.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "New password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(first), nil
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	userRepository "solecode/src/repository/user"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	transferFormat    string
+	importBatchSize   int
+	importReportPath  string
+	exportFile        string
+	exportDomain      string
+	exportCreatedFrom string
+	exportCreatedTo   string
+	exportAfterID     int64
+)
+
+var userImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import users from CSV or JSON Lines",
+	Long: "Stream users from a file, or stdin when it is \"-\" or omitted. CSV needs a header row naming the name and email columns; " +
+		"JSON Lines has one {\"name\", \"email\"} object per line. Rows are validated like single creates and deduplicated on the " +
+		"normalised email, and every skipped row is listed in the report. Exits non-zero when any row was invalid.",
+	Example: `  userapi user import partners.csv --report errors.csv
+  cat users.jsonl | userapi user import --format jsonl`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "-"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		importUsers(path)
+	},
+}
+
+var userExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export users as CSV or JSON Lines",
+	Long:  "Stream users ordered by ID without loading the whole table into memory.",
+	Example: `  userapi user export --file users.csv
+  userapi user export --format jsonl --domain example.com --created-from 2024-01-01`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exportUsers()
+	},
+}
+
+func init() {
+	userImportCmd.Flags().StringVar(&transferFormat, "format", "", "csv or jsonl (default from the file extension, csv for stdin)")
+	userImportCmd.Flags().IntVar(&importBatchSize, "batch-size", 500, "rows per INSERT statement")
+	userImportCmd.Flags().StringVar(&importReportPath, "report", "", "write the per-row error report to this file, as CSV or JSON by extension")
+
+	userExportCmd.Flags().StringVar(&transferFormat, "format", "", "csv or jsonl (default from the file extension, csv for stdout)")
+	userExportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "write to this file instead of stdout")
+	userExportCmd.Flags().BoolVar(&userListIncludeDeleted, "include-deleted", false, "include soft deleted users")
+	userExportCmd.Flags().IntVar(&userListLimit, "limit", 0, "maximum number of users (0 for all)")
+	userExportCmd.Flags().Int64Var(&exportAfterID, "after-id", 0, "only users with a greater ID")
+	userExportCmd.Flags().StringVar(&exportDomain, "domain", "", "only users with an email at this domain")
+	userExportCmd.Flags().StringVar(&exportCreatedFrom, "created-from", "", "only users created at or after, RFC 3339 or YYYY-MM-DD")
+	userExportCmd.Flags().StringVar(&exportCreatedTo, "created-to", "", "only users created before, RFC 3339 or YYYY-MM-DD")
+
+	userCmd.AddCommand(userImportCmd)
+	userCmd.AddCommand(userExportCmd)
+}
+
+func importUsers(path string) {
+	format, err := transferFormatFor(path)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	useCases, closeAll := openUseCases(userCache)
+	defer closeAll()
+
+	started := time.Now()
+	lastProgress := started
+	report, importErr := useCases.User.ImportUsers(cliContext(), r, userUC.ImportOptions{
+		Format:    format,
+		BatchSize: importBatchSize,
+		Progress: func(counts userUC.ImportCounts) {
+			if time.Since(lastProgress) >= 2*time.Second {
+				lastProgress = time.Now()
+				fmt.Fprintf(os.Stderr, "📊 %d rows read, %d imported\n", counts.Rows, counts.Imported)
+			}
+		},
+	})
+	if report == nil {
+		log.Fatalf("Import failed: %v", importErr)
+	}
+
+	if importReportPath != "" {
+		if err := writeImportReport(importReportPath, report.Errors); err != nil {
+			log.Printf("Failed to write report: %v", err)
+		}
+	} else {
+		for _, rowErr := range report.Errors {
+			fmt.Fprintf(os.Stderr, "❌ Row %d: %s (%s)\n", rowErr.Row, rowErr.Message, rowErr.Reason)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "📊 %d rows: %d imported, %d duplicates, %d invalid in %s\n",
+		report.Rows, report.Imported, report.Duplicates, report.Failed, time.Since(started).Round(time.Millisecond))
+
+	if importErr != nil {
+		closeAll()
+		log.Fatalf("Import stopped: %v", importErr)
+	}
+	if report.Failed > 0 {
+		closeAll()
+		os.Exit(1)
+	}
+}
+
+func exportUsers() {
+	path := exportFile
+	if path == "" {
+		path = "-"
+	}
+	format, err := transferFormatFor(path)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	filter := userRepository.ListFilter{
+		Limit:          userListLimit,
+		IncludeDeleted: userListIncludeDeleted,
+		AfterID:        exportAfterID,
+		EmailDomain:    strings.TrimPrefix(exportDomain, "@"),
+	}
+	if filter.CreatedFrom, err = parseDateFlag(exportCreatedFrom); err != nil {
+		log.Fatalf("Invalid --created-from: %v", err)
+	}
+	if filter.CreatedTo, err = parseDateFlag(exportCreatedTo); err != nil {
+		log.Fatalf("Invalid --created-to: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", path, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	useCases, closeAll := openUseCases(userCache)
+	defer closeAll()
+
+	started := time.Now()
+	n, err := useCases.User.ExportUsers(cliContext(), w, format, filter)
+	if err != nil {
+		closeAll()
+		log.Fatalf("Export failed after %d users: %v", n, err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Exported %d user(s) in %s\n", n, time.Since(started).Round(time.Millisecond))
+}
+
+// transferFormatFor returns --format, or guesses it from the extension of
+// path, falling back to CSV.
+func transferFormatFor(path string) (string, error) {
+	format := transferFormat
+	if format == "" {
+		format = userUC.FormatCSV
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jsonl", ".ndjson":
+			format = userUC.FormatJSONL
+		}
+	}
+	if format != userUC.FormatCSV && format != userUC.FormatJSONL {
+		return "", fmt.Errorf("unknown format %q, use csv or jsonl", format)
+	}
+	return format, nil
+}
+
+// parseDateFlag accepts RFC 3339 timestamps and plain dates, which mean
+// midnight UTC. An empty flag is the zero time.
+func parseDateFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// writeImportReport writes the skipped rows to path as CSV, or as a JSON
+// array when path ends in .json.
+func writeImportReport(path string, rows []userUC.ImportRowError) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"row", "email", "reason", "message"})
+	for _, row := range rows {
+		writer.Write([]string{strconv.Itoa(row.Row), row.Email, row.Reason, row.Message})
+	}
+	writer.Flush()
+	return writer.Error()
+}
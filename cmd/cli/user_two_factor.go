@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var userResetTwoFactorCmd = &cobra.Command{
+	Use:   "reset-2fa [id]",
+	Short: "Turn off two-factor authentication of a user who lost their app and recovery codes",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid user ID %q", args[0])
+		}
+
+		useCases, closeAll := openUseCases(userCache)
+		defer closeAll()
+
+		if err := useCases.TwoFactor.Reset(cliContext(), id); err != nil {
+			closeAll()
+			log.Fatalf("❌ Failed to reset two-factor authentication of user %d: %v", id, err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Reset two-factor authentication of user %d; they log in with their password alone\n", id)
+	},
+}
+
+func init() {
+	userCmd.AddCommand(userResetTwoFactorCmd)
+}
@@ -1,25 +1,13 @@
 package main
 
 import (
-	"log"
-	"net/http"
-	"os"
-	"time"
-
 	"solecode/cmd/cli"
 	_ "solecode/docs/swagger"
-	"solecode/pkg/cache"
-	soleCodeCache "solecode/pkg/cache"
-	"solecode/pkg/config"
-	"solecode/pkg/database"
-	soleCodeHttp "solecode/src/delivery/http"
-	repo "solecode/src/repository"
-	uc "solecode/src/usecase"
 )
 
 // @title SoleCode User API
 // @version 1.0
-// @description A REST API for user with MySQL and Redis
+// @description A REST API for user with MySQL and Redis. Every endpoint except those under /auth needs a bearer access token or an API key. Permissions come from the caller's roles; users without roles may read and update only themselves. Refusals are 403 application/problem+json responses.
 // @termsOfService http://swagger.io/terms/
 
 // @license.name MIT
@@ -28,66 +16,12 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 
-func main() {
-	// If command line arguments are provided, execute CLI
-	if len(os.Args) > 1 {
-		cli.Execute()
-		return
-	}
-
-	// Otherwise run as server
-	runServer()
-}
-
-func runServer() {
-	// Load configuration
-	cfg, err := config.LoadConfig("conf/conf.yaml")
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
-
-	// Initialize database
-	db, err := database.NewMySQLDB(&cfg.Database)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
-
-	// Initialize cache (Redis or NullCache as fallback)
-	var cacheImpl soleCodeCache.CacheItf
-	redisCache, err := cache.NewRedisCache(&cfg.Redis)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis, using null cache: %v", err)
-	} else {
-		defer redisCache.Close()
-		cacheImpl = redisCache
-		log.Println("Redis cache connected successfully")
-	}
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from POST /auth/login, sent as "Bearer <token>". Tokens are signed with HS256, RS256 or EdDSA; the public keys are served at /.well-known/jwks.json. API keys from POST /api-keys are sent as "ApiKey <key>" or "Bearer <key>" and act with their user's permissions, narrowed to the key's.
 
-	dom := repo.InitRepository(db)
-
-	// Initialize all use cases
-	uc := uc.InitUsecase(*dom, cacheImpl)
-	// Initialize HTTP handler
-	userHandler := soleCodeHttp.NewUserHandler(*uc)
-
-	// Initialize router
-	router := soleCodeHttp.NewRouter(userHandler)
-
-	// Get the base handler
-	handler := router.GetHandler()
-
-	// Create server with timeouts
-	server := &http.Server{
-		Addr:         ":" + cfg.Server.Port,
-		Handler:      handler,
-		ReadTimeout:  cfg.Server.Timeout,
-		WriteTimeout: cfg.Server.Timeout,
-		IdleTimeout:  60 * time.Second,
-	}
-	log.Printf("Server starting on port %s", cfg.Server.Port)
-
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server failed to start: %v", err)
-	}
+func main() {
+	// Without a command the binary runs the server; see "serve --help"
+	cli.Execute()
 }
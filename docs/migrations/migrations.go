@@ -0,0 +1,16 @@
+// Package migrations embeds the SQL migrations of every dialect so that a
+// built binary can migrate without the files shipped alongside it.
+package migrations
+
+import (
+	"embed"
+
+	"solecode/pkg/migrate"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
+
+// Go lists the migrations written in Go, such as data backfills. They share
+// the version sequence with the SQL files.
+var Go []migrate.GoMigration
@@ -0,0 +1,42 @@
+-- Migration: create_roles
+-- Version: 20261018100000
+-- Description: Create roles, their permissions and user role assignments, seeded with the admin role
+
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BIGINT NOT NULL,
+    role_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id),
+    INDEX idx_user_roles_role_id (role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Manages every user and role');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, p.permission FROM roles, (
+    SELECT 'users:read' AS permission
+    UNION ALL SELECT 'users:create'
+    UNION ALL SELECT 'users:update'
+    UNION ALL SELECT 'users:delete'
+    UNION ALL SELECT 'users:password'
+    UNION ALL SELECT 'roles:read'
+    UNION ALL SELECT 'roles:assign'
+    UNION ALL SELECT 'roles:manage'
+) p
+WHERE roles.name = 'admin';
@@ -0,0 +1,5 @@
+-- Rollback: create_api_keys
+-- Version: 20261018110000
+
+DELETE FROM role_permissions WHERE permission = 'apikeys:manage';
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,6 @@
+-- Rollback: create_two_factor
+-- Version: 20261018130000
+
+DELETE FROM role_permissions WHERE permission = 'users:two_factor';
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,6 @@
+-- Rollback: create_oauth
+-- Version: 20261018140000
+
+DELETE FROM role_permissions WHERE permission = 'clients:manage';
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_clients;
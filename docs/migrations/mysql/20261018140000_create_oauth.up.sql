@@ -0,0 +1,24 @@
+-- Migration: create_oauth
+-- Version: 20261018140000
+-- Description: Store OAuth clients and the consents users gave them, and let the admin role register clients
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id BIGINT NOT NULL,
+    client_id VARCHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id),
+    CONSTRAINT fk_oauth_consents_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_consents_client FOREIGN KEY (client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'clients:manage' FROM roles WHERE name = 'admin';
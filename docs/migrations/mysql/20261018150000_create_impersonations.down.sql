@@ -0,0 +1,6 @@
+-- Rollback: create_impersonations
+-- Version: 20261018150000
+
+DELETE FROM role_permissions WHERE permission = 'users:impersonate';
+DROP TABLE IF EXISTS impersonation_events;
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,32 @@
+-- Migration: create_impersonations
+-- Version: 20261018150000
+-- Description: Log admins acting as other users, and let the admin role impersonate
+
+-- The log outlives the users it names, so it has no foreign keys
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(64) PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NULL,
+    ended_at TIMESTAMP NULL,
+    INDEX idx_impersonations_admin_id (admin_id),
+    INDEX idx_impersonations_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS impersonation_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    impersonation_id VARCHAR(64) NOT NULL,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    method VARCHAR(16) NOT NULL DEFAULT '',
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    status INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_impersonation_events_impersonation_id (impersonation_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users:impersonate' FROM roles WHERE name = 'admin';
@@ -0,0 +1,4 @@
+-- Rollback: add_user_signin_generation
+-- Version: 20261018160000
+
+ALTER TABLE users DROP COLUMN signin_generation;
@@ -0,0 +1,4 @@
+-- Rollback: create_users_table
+-- Version: 20231001000001
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,4 @@
+-- Rollback: add_user_password_hash
+-- Version: 20261018090000
+
+ALTER TABLE users DROP COLUMN password_hash;
@@ -0,0 +1,5 @@
+-- Migration: add_user_password_hash
+-- Version: 20261018090000
+-- Description: Add the password hash column to users
+
+ALTER TABLE users ADD COLUMN password_hash TEXT NULL;
@@ -0,0 +1,40 @@
+-- Migration: create_roles
+-- Version: 20261018100000
+-- Description: Create roles, their permissions and user role assignments, seeded with the admin role
+
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles (role_id);
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Manages every user and role');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, p.permission FROM roles, (
+    SELECT 'users:read' AS permission
+    UNION ALL SELECT 'users:create'
+    UNION ALL SELECT 'users:update'
+    UNION ALL SELECT 'users:delete'
+    UNION ALL SELECT 'users:password'
+    UNION ALL SELECT 'roles:read'
+    UNION ALL SELECT 'roles:assign'
+    UNION ALL SELECT 'roles:manage'
+) p
+WHERE roles.name = 'admin';
@@ -0,0 +1,21 @@
+-- Migration: create_api_keys
+-- Version: 20261018110000
+-- Description: Create API keys and let the admin role manage them
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    permissions VARCHAR(1024) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'apikeys:manage' FROM roles WHERE name = 'admin';
@@ -0,0 +1,4 @@
+-- Rollback: add_user_verified_at
+-- Version: 20261018120000
+
+ALTER TABLE users DROP COLUMN verified_at;
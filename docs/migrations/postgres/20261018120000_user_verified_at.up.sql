@@ -0,0 +1,5 @@
+-- Migration: add_user_verified_at
+-- Version: 20261018120000
+-- Description: Record when users verified their email
+
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ NULL;
@@ -0,0 +1,22 @@
+-- Migration: create_oauth
+-- Version: 20261018140000
+-- Description: Store OAuth clients and the consents users gave them, and let the admin role register clients
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    scopes VARCHAR(255) NOT NULL,
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id)
+);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'clients:manage' FROM roles WHERE name = 'admin';
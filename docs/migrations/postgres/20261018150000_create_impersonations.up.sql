@@ -0,0 +1,34 @@
+-- Migration: create_impersonations
+-- Version: 20261018150000
+-- Description: Log admins acting as other users, and let the admin role impersonate
+
+-- The log outlives the users it names, so it has no foreign keys
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(64) PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_admin_id ON impersonations (admin_id);
+CREATE INDEX IF NOT EXISTS idx_impersonations_user_id ON impersonations (user_id);
+
+CREATE TABLE IF NOT EXISTS impersonation_events (
+    id BIGSERIAL PRIMARY KEY,
+    impersonation_id VARCHAR(64) NOT NULL,
+    admin_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    method VARCHAR(16) NOT NULL DEFAULT '',
+    path VARCHAR(1024) NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_events_impersonation_id ON impersonation_events (impersonation_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users:impersonate' FROM roles WHERE name = 'admin';
@@ -0,0 +1,6 @@
+-- Rollback: create_roles
+-- Version: 20261018100000
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,23 @@
+-- Migration: create_two_factor
+-- Version: 20261018130000
+-- Description: Store TOTP enrolments and recovery codes, and let the admin role reset them
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret VARCHAR(255) NOT NULL,
+    confirmed_at DATETIME NULL,
+    last_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users:two_factor' FROM roles WHERE name = 'admin';
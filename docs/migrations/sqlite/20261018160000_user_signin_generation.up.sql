@@ -0,0 +1,5 @@
+-- Migration: add_user_signin_generation
+-- Version: 20261018160000
+-- Description: Record the sign-in generation of users
+
+ALTER TABLE users ADD COLUMN signin_generation TEXT NULL;
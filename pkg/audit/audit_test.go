@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf).(*jsonLogger)
+	logger.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	ctx := WithRequest(context.Background(), RequestInfo{Method: "DELETE", Path: "/api/v1/users/2", RemoteAddr: "10.0.0.1"})
+	logger.Log(ctx, Event{Action: "authz.check", Outcome: Denied, ActorID: 1, TargetID: 2, Permission: "users:delete"})
+	logger.Log(context.Background(), Event{Action: "roles.set", Outcome: Success, System: true})
+	logger.Log(WithImpersonator(ctx, 1), Event{Action: "users.update", Outcome: Success, ActorID: 2, TargetID: 2})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var first map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "2026-10-18T12:00:00Z", first["time"])
+	assert.Equal(t, "denied", first["outcome"])
+	assert.Equal(t, "DELETE", first["method"])
+	assert.Equal(t, "/api/v1/users/2", first["path"])
+	assert.Equal(t, "10.0.0.1", first["remote_addr"])
+	assert.EqualValues(t, 2, first["target_id"])
+
+	assert.Equal(t, `{"time":"2026-10-18T12:00:00Z","action":"roles.set","outcome":"success","system":true}`, lines[1])
+
+	var third map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &third))
+	assert.EqualValues(t, 2, third["actor_id"])
+	assert.EqualValues(t, 1, third["impersonator_id"])
+	assert.NotContains(t, first, "impersonator_id")
+}
+
+func TestMemoryLogger(t *testing.T) {
+	logger := NewMemoryLogger()
+	logger.Log(context.Background(), Event{Action: "roles.set", Outcome: Success, System: true})
+	logger.Log(context.Background(), Event{Action: "users.update", Outcome: Success, ActorID: 2})
+
+	assert.Equal(t, []string{"roles.set", "users.update"}, logger.Actions())
+	require.Len(t, logger.Events(), 2)
+	assert.EqualValues(t, 2, logger.Events()[1].ActorID)
+
+	logger.Reset()
+	assert.Empty(t, logger.Events())
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryLogger keeps the events it is given, for tests.
+type MemoryLogger struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryLogger() *MemoryLogger {
+	return &MemoryLogger{}
+}
+
+func (l *MemoryLogger) Log(ctx context.Context, event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// Events returns the events logged so far, oldest first.
+func (l *MemoryLogger) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// Actions returns the actions of the events logged so far, oldest first.
+func (l *MemoryLogger) Actions() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	actions := make([]string, 0, len(l.events))
+	for _, event := range l.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// Reset forgets the events logged so far.
+func (l *MemoryLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = nil
+}
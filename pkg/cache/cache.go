@@ -19,6 +19,9 @@ type CacheItf interface {
 	Delete(key string) error
 	GetJSON(key string, v any) error
 	SetJSON(key string, v any, expiration time.Duration) error
+	// TakeJSON reads and deletes key in one step. It reports false for a
+	// miss, including when a concurrent caller took key first.
+	TakeJSON(key string, v any) (bool, error)
 }
 
 type RedisCache struct {
@@ -117,6 +120,20 @@ func (r *RedisCache) SetJSON(key string, v interface{}, expiration time.Duration
 	return r.Set(key, string(val), expiration)
 }
 
+func (r *RedisCache) TakeJSON(key string, v interface{}) (bool, error) {
+	val, err := r.client.GetDel(r.ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %v", ErrCacheOperation, err)
+	}
+	if err := json.Unmarshal([]byte(val), v); err != nil {
+		return true, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return true, nil
+}
+
 func (r *RedisCache) Close() error {
 	if err := r.client.Close(); err != nil {
 		return fmt.Errorf("%w: %v", ErrCacheOperation, err)
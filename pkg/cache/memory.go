@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Set drops expired entries of a MemoryCache.
+const sweepInterval = time.Minute
+
+// MemoryCache is a CacheItf kept in process memory, for a single server
+// without Redis and for tests. Values are stored as strings the way Redis
+// stores them, so it behaves like RedisCache, misses included.
+type MemoryCache struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time // zero for no expiry
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (c *MemoryCache) Get(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+func (c *MemoryCache) Set(key string, value interface{}, expiration time.Duration) error {
+	var s string
+	switch value := value.(type) {
+	case string:
+		s = value
+	case []byte:
+		s = string(value)
+	default:
+		s = fmt.Sprint(value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry := memoryEntry{value: s}
+	if expiration > 0 {
+		entry.expires = now.Add(expiration)
+	}
+	c.entries[key] = entry
+
+	if now.Sub(c.lastSweep) >= sweepInterval {
+		c.lastSweep = now
+		for k, e := range c.entries {
+			if !e.expires.IsZero() && !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *MemoryCache) GetJSON(key string, v any) error {
+	val, err := c.Get(key)
+	if err != nil || val == nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(val.(string)), v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return nil
+}
+
+func (c *MemoryCache) SetJSON(key string, v any, expiration time.Duration) error {
+	val, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return c.Set(key, string(val), expiration)
+}
+
+func (c *MemoryCache) TakeJSON(key string, v any) (bool, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	if !ok || (!entry.expires.IsZero() && !c.now().Before(entry.expires)) {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(entry.value), v); err != nil {
+		return true, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return true, nil
+}
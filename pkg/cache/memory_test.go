@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	// A miss reads as nil without an error, like RedisCache
+	val, err := c.Get("missing")
+	require.NoError(t, err)
+	assert.Nil(t, val)
+
+	require.NoError(t, c.Set("n", 42, 0))
+	val, err = c.Get("n")
+	require.NoError(t, err)
+	assert.Equal(t, "42", val)
+
+	require.NoError(t, c.SetJSON("user", TestUser{Name: "John Doe"}, time.Minute))
+	var user TestUser
+	require.NoError(t, c.GetJSON("user", &user))
+	assert.Equal(t, "John Doe", user.Name)
+
+	now = now.Add(time.Minute)
+	user = TestUser{}
+	require.NoError(t, c.GetJSON("user", &user))
+	assert.Empty(t, user.Name, "expired entries read as missing")
+
+	require.NoError(t, c.Delete("n"))
+	val, err = c.Get("n")
+	require.NoError(t, err)
+	assert.Nil(t, val)
+
+	// Only the first take of a key gets it
+	require.NoError(t, c.SetJSON("user", TestUser{Name: "John Doe"}, time.Minute))
+	user = TestUser{}
+	took, err := c.TakeJSON("user", &user)
+	require.NoError(t, err)
+	assert.True(t, took)
+	assert.Equal(t, "John Doe", user.Name)
+	took, err = c.TakeJSON("user", &user)
+	require.NoError(t, err)
+	assert.False(t, took)
+
+	require.NoError(t, c.Set("bad", "{", 0))
+	assert.ErrorIs(t, c.GetJSON("bad", &user), ErrInvalidJSON)
+}
@@ -115,6 +115,34 @@ func (_m *CacheItf) SetJSON(key string, v interface{}, expiration time.Duration)
 	return r0
 }
 
+// TakeJSON provides a mock function with given fields: key, v
+func (_m *CacheItf) TakeJSON(key string, v interface{}) (bool, error) {
+	ret := _m.Called(key, v)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TakeJSON")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, interface{}) (bool, error)); ok {
+		return rf(key, v)
+	}
+	if rf, ok := ret.Get(0).(func(string, interface{}) bool); ok {
+		r0 = rf(key, v)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, interface{}) error); ok {
+		r1 = rf(key, v)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewCacheItf creates a new instance of CacheItf. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCacheItf(t interface {
@@ -0,0 +1,35 @@
+package cache
+
+import "time"
+
+// NullCache is a CacheItf that stores nothing, for running without Redis.
+// Every key reads as missing, the same way RedisCache reports a miss.
+type NullCache struct{}
+
+func NewNullCache() *NullCache {
+	return &NullCache{}
+}
+
+func (NullCache) Get(key string) (interface{}, error) {
+	return nil, nil
+}
+
+func (NullCache) Set(key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+func (NullCache) Delete(key string) error {
+	return nil
+}
+
+func (NullCache) GetJSON(key string, v any) error {
+	return nil
+}
+
+func (NullCache) SetJSON(key string, v any, expiration time.Duration) error {
+	return nil
+}
+
+func (NullCache) TakeJSON(key string, v any) (bool, error) {
+	return false, nil
+}
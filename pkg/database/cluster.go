@@ -0,0 +1,311 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solecode/pkg/config"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	healthCheckTimeout         = 3 * time.Second
+)
+
+var errReplicationStopped = errors.New("replication is not running")
+
+// Cluster routes queries between a primary database and its read replicas.
+// Writes always go to the primary; reads are spread across healthy replicas
+// and fall back to the primary when none are available.
+type Cluster struct {
+	dialect  Dialect
+	primary  *sql.DB
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint32
+	txm      *TxManager
+
+	// lag measures how far a replica is behind the primary
+	lag func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+	checked bool // only touched by the health check goroutine
+}
+
+// NewCluster connects to the primary and every configured replica and starts
+// health-checking the replicas in the background. Replicas that cannot be
+// reached are kept out of rotation until they recover.
+func NewCluster(cfg *config.DatabaseConfig) (*Cluster, error) {
+	dialect, err := DialectOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialect == SQLite && len(cfg.Replicas) > 0 {
+		return nil, fmt.Errorf("read replicas are not supported with sqlite")
+	}
+
+	primary, err := NewDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRetries := cfg.TxMaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultTxMaxRetries
+	}
+
+	c := newCluster(primary, dialect, maxRetries)
+	c.maxLag = cfg.MaxReplicaLag
+
+	for _, rc := range cfg.Replicas {
+		username, password := rc.Username, rc.Password
+		if username == "" {
+			username, password = cfg.Username, cfg.Password
+		}
+		port := rc.Port
+		if port == 0 {
+			port = cfg.Port
+		}
+
+		db, err := open(dialect, cfg, rc.Host, port, username, password)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to open replica %s: %w", rc.Host, err)
+		}
+		c.replicas = append(c.replicas, &replica{
+			name: fmt.Sprintf("%s:%d", rc.Host, port),
+			db:   db,
+		})
+	}
+
+	if len(c.replicas) > 0 {
+		c.checkReplicas()
+
+		interval := cfg.HealthCheckInterval
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		c.wg.Add(1)
+		go c.healthLoop(interval)
+	}
+
+	return c, nil
+}
+
+// WrapDB returns a Cluster without replicas around an already open pool.
+func WrapDB(db *sql.DB, dialect Dialect) *Cluster {
+	return newCluster(db, dialect, defaultTxMaxRetries)
+}
+
+func newCluster(primary *sql.DB, dialect Dialect, maxRetries int) *Cluster {
+	return &Cluster{
+		dialect: dialect,
+		primary: primary,
+		txm:     NewTxManager(primary, dialect, maxRetries),
+		lag:     replicationLagOf(dialect),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Dialect returns the SQL dialect of the cluster.
+func (c *Cluster) Dialect() Dialect {
+	return c.dialect
+}
+
+// Primary returns the primary connection pool.
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary
+}
+
+// Reader returns the pool a read should use: a healthy replica chosen
+// round-robin, or the primary when the context asks for it or no replica is
+// usable.
+func (c *Cluster) Reader(ctx context.Context) DBTX {
+	if usePrimary(ctx) || len(c.replicas) == 0 {
+		return c.primary
+	}
+
+	n := uint32(len(c.replicas))
+	for i := uint32(0); i < n; i++ {
+		r := c.replicas[c.next.Add(1)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return c.primary
+}
+
+// Writer returns the primary and records the write on the context so that
+// later reads honour read-your-writes.
+func (c *Cluster) Writer(ctx context.Context) DBTX {
+	markWrite(ctx)
+	return c.primary
+}
+
+// Transact runs fn in a transaction on the primary; see TxManager.Transact.
+func (c *Cluster) Transact(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	return c.txm.Transact(ctx, fn)
+}
+
+// Close stops health checks and closes every connection pool.
+func (c *Cluster) Close() error {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	c.wg.Wait()
+
+	var firstErr error
+	for _, r := range c.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := c.primary.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+func (c *Cluster) healthLoop(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas()
+		}
+	}
+}
+
+func (c *Cluster) checkReplicas() {
+	for _, r := range c.replicas {
+		err := c.checkReplica(r)
+		healthy := err == nil
+		wasHealthy := r.healthy.Swap(healthy)
+
+		switch {
+		case healthy && !wasHealthy:
+			log.Printf("Replica %s added to read rotation", r.name)
+		case !healthy && (wasHealthy || !r.checked):
+			log.Printf("Replica %s out of read rotation: %v", r.name, err)
+		}
+		r.checked = true
+	}
+}
+
+func (c *Cluster) checkReplica(r *replica) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	if c.maxLag <= 0 {
+		return nil
+	}
+
+	lag, err := c.lag(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	if lag > c.maxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, c.maxLag)
+	}
+	return nil
+}
+
+func replicationLagOf(dialect Dialect) func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	if dialect == Postgres {
+		return postgresReplicationLag
+	}
+	return mysqlReplicationLag
+}
+
+// mysqlReplicationLag reads Seconds_Behind_Source (or Seconds_Behind_Master
+// on servers older than MySQL 8.0.22) from the replica status.
+func mysqlReplicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, fmt.Errorf("failed to read replica status: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	if !rows.Next() {
+		// Not configured as a replica; nothing to lag behind.
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, fmt.Errorf("failed to read replica status: %w", err)
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errReplicationStopped
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse replication lag: %w", err)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, nil
+}
+
+// postgresReplicationLag measures how long ago the standby replayed its last
+// transaction. A primary reports no lag.
+func postgresReplicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	query := `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+		END
+	`
+
+	var seconds sql.NullFloat64
+	if err := db.QueryRowContext(ctx, query).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	if !seconds.Valid {
+		return 0, errReplicationStopped
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"solecode/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyConnector opens SQLite connections until it is told to fail, like
+// a replica that goes away and comes back.
+type flakyConnector struct {
+	driver driver.Driver
+	dsn    string
+	down   atomic.Bool
+}
+
+func (c *flakyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return c.driver.Open(c.dsn)
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// newTestReplicaCluster returns a cluster over a SQLite primary and two
+// SQLite files standing in for replicas, with their connectors.
+func newTestReplicaCluster(t *testing.T) (*Cluster, []*flakyConnector) {
+	c := newTestCluster(t)
+
+	var connectors []*flakyConnector
+	for _, name := range []string{"replica-1", "replica-2"} {
+		connector := &flakyConnector{
+			driver: c.Primary().Driver(),
+			dsn:    "file:" + filepath.Join(t.TempDir(), name+".db"),
+		}
+		db := sql.OpenDB(connector)
+		// Every check opens a connection, so going down shows at once
+		db.SetMaxIdleConns(0)
+		t.Cleanup(func() { db.Close() })
+		c.replicas = append(c.replicas, &replica{name: name, db: db})
+		connectors = append(connectors, connector)
+	}
+	c.checkReplicas()
+	return c, connectors
+}
+
+func TestClusterReader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("spreads reads across healthy replicas", func(t *testing.T) {
+		c, _ := newTestReplicaCluster(t)
+
+		first, second := c.Reader(ctx), c.Reader(ctx)
+		assert.NotSame(t, c.Primary(), first)
+		assert.NotSame(t, c.Primary(), second)
+		assert.NotSame(t, first, second)
+		assert.Same(t, first, c.Reader(ctx))
+	})
+
+	t.Run("skips failed replicas and falls back to the primary", func(t *testing.T) {
+		c, connectors := newTestReplicaCluster(t)
+
+		connectors[0].down.Store(true)
+		c.checkReplicas()
+		for i := 0; i < 3; i++ {
+			assert.Same(t, c.replicas[1].db, c.Reader(ctx))
+		}
+
+		connectors[1].down.Store(true)
+		c.checkReplicas()
+		assert.Same(t, c.Primary(), c.Reader(ctx))
+
+		// Recovered replicas rejoin the rotation on the next check
+		connectors[0].down.Store(false)
+		c.checkReplicas()
+		assert.Same(t, c.replicas[0].db, c.Reader(ctx))
+	})
+
+	t.Run("keeps lagging replicas out of rotation", func(t *testing.T) {
+		c, _ := newTestReplicaCluster(t)
+		c.maxLag = 5 * time.Second
+		lag := map[*sql.DB]time.Duration{c.replicas[0].db: time.Minute}
+		c.lag = func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+			return lag[db], nil
+		}
+
+		c.checkReplicas()
+		for i := 0; i < 3; i++ {
+			assert.Same(t, c.replicas[1].db, c.Reader(ctx))
+		}
+
+		c.lag = func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+			return 0, errReplicationStopped
+		}
+		c.checkReplicas()
+		assert.Same(t, c.Primary(), c.Reader(ctx))
+	})
+
+	t.Run("reads go to the primary after a write", func(t *testing.T) {
+		c, _ := newTestReplicaCluster(t)
+
+		request := WithReadYourWrites(ctx)
+		assert.NotSame(t, c.Primary(), c.Reader(request))
+		assert.Same(t, c.Primary(), c.Writer(request))
+		assert.Same(t, c.Primary(), c.Reader(request))
+		assert.Same(t, c.Primary(), c.Reader(WithReadYourWrites(request)))
+
+		// Other requests still read replicas, unless they ask for the primary
+		assert.NotSame(t, c.Primary(), c.Reader(WithReadYourWrites(ctx)))
+		assert.Same(t, c.Primary(), c.Reader(WithPrimary(ctx)))
+	})
+}
+
+func TestNewClusterRefusesSQLiteReplicas(t *testing.T) {
+	_, err := NewCluster(&config.DatabaseConfig{
+		Driver:   "sqlite",
+		Name:     filepath.Join(t.TempDir(), "primary.db"),
+		Replicas: []config.ReplicaConfig{{Host: "replica-1"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type ctxKey int
+
+const (
+	primaryKey ctxKey = iota
+	sessionKey
+)
+
+// session tracks whether a write has been issued within a request.
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithPrimary forces every read made with the returned context to go to
+// the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// WithReadYourWrites pins reads made with the returned context to the
+// primary once a write has gone through it, so a request never reads a
+// replica that has not caught up with its own changes yet.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(sessionKey).(*session); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey, &session{})
+}
+
+func markWrite(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
+
+func usePrimary(ctx context.Context) bool {
+	if forced, _ := ctx.Value(primaryKey).(bool); forced {
+		return true
+	}
+	s, ok := ctx.Value(sessionKey).(*session)
+	return ok && s.wrote.Load()
+}
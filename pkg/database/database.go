@@ -0,0 +1,83 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solecode/pkg/config"
+)
+
+// Dialect identifies the SQL flavour spoken by a database.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Dialects lists every supported dialect.
+var Dialects = []Dialect{MySQL, Postgres, SQLite}
+
+// DialectOf returns the dialect selected by cfg.Driver, defaulting to MySQL.
+func DialectOf(cfg *config.DatabaseConfig) (Dialect, error) {
+	switch Dialect(strings.ToLower(cfg.Driver)) {
+	case "", MySQL:
+		return MySQL, nil
+	case Postgres, "postgresql":
+		return Postgres, nil
+	case SQLite, "sqlite3":
+		return SQLite, nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// Rebind rewrites ? placeholders into the dialect's native form, so that
+// repositories can write each query once, with ?, and serve every dialect.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NewDB opens and pings the primary database using the driver selected in
+// cfg.
+func NewDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	dialect, err := DialectOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch dialect {
+	case Postgres:
+		return NewPostgresDB(cfg)
+	case SQLite:
+		return NewSQLiteDB(cfg)
+	default:
+		return NewMySQLDB(cfg)
+	}
+}
+
+func open(dialect Dialect, cfg *config.DatabaseConfig, host string, port int, username, password string) (*sql.DB, error) {
+	switch dialect {
+	case Postgres:
+		return openPostgres(cfg, host, port, username, password)
+	default:
+		return openMySQL(cfg, host, port, username, password)
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	mysqlErrDuplicateEntry = 1062
+	pqErrUniqueViolation   = "23505"
+)
+
+// sqliteError is implemented by the errors of modernc.org/sqlite.
+type sqliteError interface {
+	error
+	Code() int
+}
+
+// IsUniqueViolation reports whether err was caused by a unique constraint.
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDuplicateEntry
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pqErrUniqueViolation
+	}
+
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
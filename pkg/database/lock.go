@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"time"
+)
+
+const lockPollInterval = 500 * time.Millisecond
+
+// LockError is returned when an advisory lock could not be acquired in time.
+type LockError struct {
+	Name   string
+	Holder string // empty when the holder could not be determined
+}
+
+func (e *LockError) Error() string {
+	if e.Holder == "" {
+		return fmt.Sprintf("timed out waiting for lock %q", e.Name)
+	}
+	return fmt.Sprintf("timed out waiting for lock %q held by %s", e.Name, e.Holder)
+}
+
+// Lock is a held advisory lock.
+type Lock struct {
+	release func() error
+}
+
+// Release gives the lock back.
+func (l *Lock) Release() error {
+	return l.release()
+}
+
+// AcquireLock takes a database-wide advisory lock, waiting up to timeout for
+// another holder to release it. MySQL uses GET_LOCK and Postgres
+// pg_advisory_lock, both tied to a dedicated connection so the lock goes away
+// if the process dies. SQLite has no advisory locks and uses a lock table
+// instead.
+func AcquireLock(ctx context.Context, db *sql.DB, dialect Dialect, name string, timeout time.Duration) (*Lock, error) {
+	switch dialect {
+	case Postgres:
+		return acquirePostgresLock(ctx, db, name, timeout)
+	case SQLite:
+		return acquireSQLiteLock(ctx, db, name, timeout)
+	default:
+		return acquireMySQLLock(ctx, db, name, timeout)
+	}
+}
+
+func acquireMySQLLock(ctx context.Context, db *sql.DB, name string, timeout time.Duration) (*Lock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	var acquired sql.NullInt64
+	seconds := int(math.Ceil(timeout.Seconds()))
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, seconds).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	if acquired.Int64 != 1 {
+		holder := mysqlLockHolder(ctx, conn, name)
+		conn.Close()
+		return nil, &LockError{Name: name, Holder: holder}
+	}
+
+	return &Lock{release: func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}}, nil
+}
+
+func mysqlLockHolder(ctx context.Context, conn *sql.Conn, name string) string {
+	var id sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", name).Scan(&id); err != nil || !id.Valid {
+		return ""
+	}
+
+	var user, host string
+	query := `SELECT USER, HOST FROM information_schema.PROCESSLIST WHERE ID = ?`
+	if err := conn.QueryRowContext(ctx, query, id.Int64).Scan(&user, &host); err != nil {
+		return fmt.Sprintf("connection %d", id.Int64)
+	}
+	return fmt.Sprintf("connection %d (%s@%s)", id.Int64, user, host)
+}
+
+func acquirePostgresLock(ctx context.Context, db *sql.DB, name string, timeout time.Duration) (*Lock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	key := lockKey(name)
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			holder := postgresLockHolder(ctx, conn, key)
+			conn.Close()
+			return nil, &LockError{Name: name, Holder: holder}
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &Lock{release: func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}}, nil
+}
+
+func postgresLockHolder(ctx context.Context, conn *sql.Conn, key int64) string {
+	query := `
+		SELECT a.pid, COALESCE(a.usename, ''), COALESCE(host(a.client_addr), 'local'), COALESCE(a.application_name, '')
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+			AND ((l.classid::bigint << 32) | l.objid::bigint) = $1
+		LIMIT 1
+	`
+
+	var pid int64
+	var user, addr, app string
+	if err := conn.QueryRowContext(ctx, query, key).Scan(&pid, &user, &addr, &app); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("pid %d (%s@%s %s)", pid, user, addr, app)
+}
+
+func acquireSQLiteLock(ctx context.Context, db *sql.DB, name string, timeout time.Duration) (*Lock, error) {
+	createTableSQL := `
+		CREATE TABLE IF NOT EXISTS advisory_lock (
+			name VARCHAR(255) PRIMARY KEY,
+			holder VARCHAR(255) NOT NULL,
+			acquired_at DATETIME NOT NULL
+		)
+	`
+	if _, err := db.ExecContext(ctx, createTableSQL); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s pid %d", hostname, os.Getpid())
+
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := db.ExecContext(ctx,
+			`INSERT INTO advisory_lock (name, holder, acquired_at) VALUES (?, ?, ?)`,
+			name, holder, time.Now())
+		if err == nil {
+			break
+		}
+		if !IsUniqueViolation(err) {
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			var current string
+			var acquiredAt time.Time
+			row := db.QueryRowContext(ctx, `SELECT holder, acquired_at FROM advisory_lock WHERE name = ?`, name)
+			if err := row.Scan(&current, &acquiredAt); err == nil {
+				current = fmt.Sprintf("%s since %s (delete it from advisory_lock if that process is gone)",
+					current, acquiredAt.Format(time.RFC3339))
+			}
+			return nil, &LockError{Name: name, Holder: current}
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Lock{release: func() error {
+		_, err := db.Exec(`DELETE FROM advisory_lock WHERE name = ? AND holder = ?`, name, holder)
+		return err
+	}}, nil
+}
+
+// lockKey maps a lock name onto Postgres' 64-bit advisory lock key space.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// IsLockTimeout reports whether err means a lock is held by someone else.
+func IsLockTimeout(err error) bool {
+	var lockErr *LockError
+	return errors.As(err, &lockErr)
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLockSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newTestCluster(t).Primary()
+
+	lock, err := AcquireLock(ctx, db, SQLite, "migrations", time.Second)
+	require.NoError(t, err)
+
+	_, err = AcquireLock(ctx, db, SQLite, "migrations", 0)
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
+	assert.Contains(t, err.Error(), "held by")
+
+	other, err := AcquireLock(ctx, db, SQLite, "other", 0)
+	require.NoError(t, err)
+	require.NoError(t, other.Release())
+
+	require.NoError(t, lock.Release())
+
+	lock, err = AcquireLock(ctx, db, SQLite, "migrations", 0)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
@@ -11,8 +11,22 @@ import (
 )
 
 func NewMySQLDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	db, err := openMySQL(cfg, cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+func openMySQL(cfg *config.DatabaseConfig, host string, port int, username, password string) (*sql.DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+		username, password, host, port, cfg.Name)
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -23,9 +37,5 @@ func NewMySQLDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
 	db.SetMaxIdleConns(cfg.MaxIdleConnections)
 	db.SetConnMaxLifetime(time.Hour)
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
 	return db, nil
 }
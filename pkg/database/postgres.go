@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"solecode/pkg/config"
+
+	_ "github.com/lib/pq"
+)
+
+func NewPostgresDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	db, err := openPostgres(cfg, cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+func openPostgres(cfg *config.DatabaseConfig, host string, port int, username, password string) (*sql.DB, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		Path:     cfg.Name,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetMaxIdleConns(cfg.MaxIdleConnections)
+	db.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	"solecode/pkg/config"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLiteDB opens the SQLite database file named by cfg.Name, creating it
+// if needed.
+func NewSQLiteDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("failed to open database: sqlite needs a file path")
+	}
+
+	params := url.Values{"_pragma": {
+		"foreign_keys(1)",
+		"busy_timeout(5000)",
+		"journal_mode(WAL)",
+	}}
+	db, err := sql.Open("sqlite", "file:"+cfg.Name+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetMaxIdleConns(cfg.MaxIdleConnections)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	defaultTxMaxRetries = 3
+	txRetryBaseDelay    = 20 * time.Millisecond
+
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+
+	pqErrSerializationFailure = "40001"
+	pqErrDeadlockDetected     = "40P01"
+	pqErrLockNotAvailable     = "55P03"
+)
+
+// DBTX is the part of *sql.DB and *sql.Tx that repositories use.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Conn hands out the connection a repository should run a read or a write
+// on. *Cluster and *Tx both implement it.
+type Conn interface {
+	Reader(ctx context.Context) DBTX
+	Writer(ctx context.Context) DBTX
+	Dialect() Dialect
+}
+
+type txKey struct{}
+
+// Tx is a transaction-scoped Conn; reads and writes both go through the
+// underlying *sql.Tx so they see each other's changes.
+type Tx struct {
+	tx        *sql.Tx
+	dialect   Dialect
+	savepoint int
+}
+
+func (t *Tx) Reader(ctx context.Context) DBTX {
+	return t.tx
+}
+
+func (t *Tx) Writer(ctx context.Context) DBTX {
+	return t.tx
+}
+
+func (t *Tx) Dialect() Dialect {
+	return t.dialect
+}
+
+// TxManager runs callbacks inside database transactions.
+type TxManager struct {
+	db         *sql.DB
+	dialect    Dialect
+	maxRetries int
+}
+
+func NewTxManager(db *sql.DB, dialect Dialect, maxRetries int) *TxManager {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &TxManager{db: db, dialect: dialect, maxRetries: maxRetries}
+}
+
+// Transact runs fn inside a transaction that is committed when fn returns
+// nil and rolled back otherwise. When ctx already carries a transaction, fn
+// runs inside a savepoint of it instead, so only fn's own changes are undone
+// on error. Outermost transactions that fail on a deadlock or lock wait
+// timeout (or a serialization failure on Postgres, or a busy database on
+// SQLite) are retried from the start with exponential backoff.
+func (m *TxManager) Transact(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return tx.nested(ctx, fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt >= m.maxRetries {
+			return err
+		}
+
+		delay := txRetryBaseDelay << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	sqlTx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	tx := &Tx{tx: sqlTx, dialect: m.dialect}
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx), tx); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (t *Tx) nested(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	t.savepoint++
+	name := fmt.Sprintf("sp_%d", t.savepoint)
+	defer func() { t.savepoint-- }()
+
+	if _, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := fn(ctx, t); err != nil {
+		if _, rbErr := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if _, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// isRetryable reports whether err means the whole transaction was aborted
+// and can safely be run again.
+func isRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqErrSerializationFailure, pqErrDeadlockDetected, pqErrLockNotAvailable:
+			return true
+		}
+	}
+
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		// Extended result codes keep the primary code in the low byte
+		switch liteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solecode/pkg/config"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCluster(t *testing.T) *Cluster {
+	db, err := NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "tx.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE items (name TEXT NOT NULL)`)
+	require.NoError(t, err)
+
+	return WrapDB(db, SQLite)
+}
+
+func countItems(t *testing.T, c *Cluster) int {
+	var n int
+	require.NoError(t, c.Primary().QueryRow(`SELECT COUNT(*) FROM items`).Scan(&n))
+	return n
+}
+
+func insertItem(ctx context.Context, tx *Tx, name string) error {
+	_, err := tx.Writer(ctx).ExecContext(ctx, `INSERT INTO items (name) VALUES (?)`, name)
+	return err
+}
+
+func TestTransact(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commits when the callback succeeds", func(t *testing.T) {
+		c := newTestCluster(t)
+
+		err := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+			return insertItem(ctx, tx, "a")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, countItems(t, c))
+	})
+
+	t.Run("rolls back when the callback fails", func(t *testing.T) {
+		c := newTestCluster(t)
+		boom := errors.New("boom")
+
+		err := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+			require.NoError(t, insertItem(ctx, tx, "a"))
+			return boom
+		})
+		assert.ErrorIs(t, err, boom)
+		assert.Equal(t, 0, countItems(t, c))
+	})
+
+	t.Run("nested calls roll back to their savepoint", func(t *testing.T) {
+		c := newTestCluster(t)
+
+		err := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+			require.NoError(t, insertItem(ctx, tx, "outer"))
+
+			inner := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+				require.NoError(t, insertItem(ctx, tx, "inner"))
+				return errors.New("inner failed")
+			})
+			assert.Error(t, inner)
+
+			return c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+				return insertItem(ctx, tx, "second inner")
+			})
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, countItems(t, c))
+	})
+}
+
+func TestTransactRetries(t *testing.T) {
+	ctx := context.Background()
+
+	// failing returns a callback that inserts a row and fails with err on
+	// its first n calls, counting the calls in calls
+	failing := func(n int, err error, calls *int) func(ctx context.Context, tx *Tx) error {
+		return func(ctx context.Context, tx *Tx) error {
+			*calls++
+			if insertErr := insertItem(ctx, tx, "a"); insertErr != nil {
+				return insertErr
+			}
+			if *calls <= n {
+				return fmt.Errorf("insert failed: %w", err)
+			}
+			return nil
+		}
+	}
+
+	for name, err := range map[string]error{
+		"mysql deadlock":            &mysql.MySQLError{Number: mysqlErrDeadlock},
+		"mysql lock wait timeout":   &mysql.MySQLError{Number: mysqlErrLockWaitTimeout},
+		"postgres serialization":    &pq.Error{Code: pqErrSerializationFailure},
+		"postgres deadlock":         &pq.Error{Code: pqErrDeadlockDetected},
+		"postgres lock unavailable": &pq.Error{Code: pqErrLockNotAvailable},
+	} {
+		t.Run("retries on "+name, func(t *testing.T) {
+			c := newTestCluster(t)
+			var calls int
+
+			start := time.Now()
+			require.NoError(t, c.Transact(ctx, failing(2, err, &calls)))
+			assert.Equal(t, 3, calls)
+			// Failed attempts rolled back; backoff waited at least 20ms
+			// and then 40ms
+			assert.Equal(t, 1, countItems(t, c))
+			assert.GreaterOrEqual(t, time.Since(start), 3*txRetryBaseDelay)
+		})
+	}
+
+	t.Run("gives up after the configured retries", func(t *testing.T) {
+		c := newTestCluster(t)
+		deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock}
+		var calls int
+
+		err := c.Transact(ctx, failing(10, deadlock, &calls))
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, defaultTxMaxRetries+1, calls)
+		assert.Equal(t, 0, countItems(t, c))
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		c := newTestCluster(t)
+		var calls int
+
+		duplicate := &mysql.MySQLError{Number: mysqlErrDuplicateEntry}
+		assert.ErrorIs(t, c.Transact(ctx, failing(1, duplicate, &calls)), duplicate)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("retries the whole transaction rather than a savepoint", func(t *testing.T) {
+		c := newTestCluster(t)
+		deadlock := &pq.Error{Code: pqErrDeadlockDetected}
+		var outer, inner int
+
+		err := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+			outer++
+			return c.Transact(ctx, failing(1, deadlock, &inner))
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, outer)
+		assert.Equal(t, 2, inner)
+		assert.Equal(t, 1, countItems(t, c))
+	})
+
+	t.Run("stops retrying when the context ends", func(t *testing.T) {
+		c := newTestCluster(t)
+		ctx, cancel := context.WithCancel(ctx)
+		deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock}
+		var calls int
+
+		err := c.Transact(ctx, func(ctx context.Context, tx *Tx) error {
+			calls++
+			cancel()
+			return deadlock
+		})
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var data = LinkData{
+	Name:           "John <Doe>",
+	Email:          "john@example.com",
+	Link:           "https://app.example.com/verify-email?token=abc",
+	Token:          "abc",
+	ExpiresInHours: 24,
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	templates, err := NewTemplates("", "")
+	require.NoError(t, err)
+
+	msg, err := templates.Render(VerifyEmail, "", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Confirm your email address", msg.Subject)
+	assert.Contains(t, msg.Text, "Hello John <Doe>,")
+	assert.Contains(t, msg.Text, data.Link)
+	assert.Contains(t, msg.HTML, "Hello John &lt;Doe&gt;,")
+	assert.Contains(t, msg.HTML, `href="https://app.example.com/verify-email?token=abc"`)
+
+	msg, err = templates.Render(PasswordReset, "de-AT,en;q=0.5", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Setzen Sie Ihr Passwort zurück", msg.Subject)
+
+	// Unknown locales fall back to the default
+	msg, err = templates.Render(PasswordReset, "fr-FR, ../etc", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your password", msg.Subject)
+
+	_, err = templates.Render("welcome", "en", data)
+	assert.Error(t, err)
+}
+
+func TestRenderOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(p, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, p), []byte(content), 0o644))
+	}
+	write("en/verify_email.tmpl", `{{define "subject"}}Welcome aboard{{end}}{{define "text"}}Go to {{.Link}}{{end}}`)
+	write("fr/verify_email.tmpl", `{{define "subject"}}Confirmez votre adresse{{end}}{{define "text"}}{{.Link}}{{end}}`)
+
+	templates, err := NewTemplates(dir, "fr")
+	require.NoError(t, err)
+
+	msg, err := templates.Render(VerifyEmail, "en-GB", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome aboard", msg.Subject)
+	assert.Equal(t, "Go to "+data.Link+"\n", msg.Text)
+	assert.Empty(t, msg.HTML)
+
+	msg, err = templates.Render(VerifyEmail, "es", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Confirmez votre adresse", msg.Subject)
+
+	// Templates the directory does not override stay built in
+	msg, err = templates.Render(PasswordReset, "fr", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your password", msg.Subject)
+
+	write("en/broken.tmpl", `{{define "subject"}}No body{{end}}`)
+	_, err = NewTemplates(dir, "")
+	assert.ErrorContains(t, err, `does not define "text"`)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer("noreply@example.com")
+	require.NoError(t, mailer.Send(context.Background(), &Message{To: "john@example.com", Subject: "Hi", Text: "Hello"}))
+
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "noreply@example.com", messages[0].From)
+	assert.Equal(t, "john@example.com", messages[0].To)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := NewFileMailer(dir, "Example <noreply@example.com>")
+
+	err := mailer.Send(context.Background(), &Message{
+		To:      "john@example.com",
+		Subject: "Bestätigen Sie Ihre E-Mail-Adresse",
+		Text:    "Hallo John,\nbitte bestätigen.",
+		HTML:    "<p>Hallo John,</p>",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	file, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer file.Close()
+
+	parsed, err := mail.ReadMessage(file)
+	require.NoError(t, err)
+	assert.Equal(t, "Example <noreply@example.com>", parsed.Header.Get("From"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Bestätigen Sie Ihre E-Mail-Adresse", subject)
+	date, err := parsed.Header.Date()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), date, time.Minute)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+	require.Len(t, bodies, 2)
+	assert.Equal(t, "Hallo John,\r\nbitte bestätigen.", bodies[0])
+	assert.True(t, strings.HasPrefix(bodies[1], "<p>Hallo"))
+}
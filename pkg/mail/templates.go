@@ -0,0 +1,183 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	textTemplate "text/template"
+)
+
+// Templates shipped with the application.
+const (
+	VerifyEmail   = "verify_email"
+	PasswordReset = "password_reset"
+)
+
+// LinkData is what VerifyEmail and PasswordReset are rendered with.
+type LinkData struct {
+	Name  string
+	Email string
+	// Link opens the page that completes the action; Token is the token
+	// in it, for clients that call the API themselves.
+	Link           string
+	Token          string
+	ExpiresInHours int
+}
+
+// DefaultLocale is the locale used when neither the caller nor the
+// configuration names one that has the template.
+const DefaultLocale = "en"
+
+//go:embed templates
+var builtin embed.FS
+
+// Templates renders messages from files named <locale>/<name>.tmpl. Each
+// file defines a "subject" and a "text" template and may define an "html"
+// one, which is rendered with HTML escaping. Files in the override
+// directory replace the built-in ones of the same path, and may add
+// locales.
+type Templates struct {
+	sources       []fs.FS
+	defaultLocale string
+}
+
+// NewTemplates returns templates read from dir before the built-in ones;
+// empty dir uses only the built-in ones. Every template is parsed once, so
+// mistakes show up at start-up rather than when a message is sent.
+func NewTemplates(dir, defaultLocale string) (*Templates, error) {
+	builtinFS, err := fs.Sub(builtin, "templates")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{sources: []fs.FS{builtinFS}, defaultLocale: normalizeLocale(defaultLocale)}
+	if dir != "" {
+		t.sources = append([]fs.FS{os.DirFS(dir)}, t.sources...)
+	}
+	if t.defaultLocale == "" {
+		t.defaultLocale = DefaultLocale
+	}
+
+	for _, source := range t.sources {
+		err := fs.WalkDir(source, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+				return err
+			}
+			_, err = parse(source, p)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mail templates: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// Render renders template name for the first of locales that has it,
+// falling back to the default locale. locales is a language tag such as
+// "de-AT" or an Accept-Language value listing several in order of
+// preference; a regional tag also tries its language, so "de-AT" finds
+// "de".
+func (t *Templates) Render(name, locales string, data any) (*Message, error) {
+	for _, locale := range candidates(locales, t.defaultLocale) {
+		for _, source := range t.sources {
+			p := locale + "/" + name + ".tmpl"
+			if _, err := fs.Stat(source, p); errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			tmpl, err := parse(source, p)
+			if err != nil {
+				return nil, err
+			}
+			return tmpl.execute(data)
+		}
+	}
+	return nil, fmt.Errorf("no mail template %q", name)
+}
+
+// parsed holds one template file parsed twice: as text for the subject and
+// body, and as HTML for the optional HTML body.
+type parsed struct {
+	name string
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+func parse(source fs.FS, p string) (*parsed, error) {
+	raw, err := fs.ReadFile(source, p)
+	if err != nil {
+		return nil, err
+	}
+	text, err := textTemplate.New(p).Option("missingkey=error").Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	for _, required := range []string{"subject", "text"} {
+		if text.Lookup(required) == nil {
+			return nil, fmt.Errorf("mail template %s does not define %q", p, required)
+		}
+	}
+	html, err := htmlTemplate.New(p).Option("missingkey=error").Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	return &parsed{name: p, text: text, html: html}, nil
+}
+
+func (p *parsed) execute(data any) (*Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := p.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", p.name, err)
+	}
+	if err := p.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", p.name, err)
+	}
+	if p.html.Lookup("html") != nil {
+		if err := p.html.ExecuteTemplate(&html, "html", data); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", p.name, err)
+		}
+	}
+	return &Message{
+		// Subjects are one line however the template is laid out
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()),
+	}, nil
+}
+
+// candidates lists the locales to try for an Accept-Language style value,
+// most preferred first, ending with fallback.
+func candidates(locales, fallback string) []string {
+	var out []string
+	add := func(locale string) {
+		for _, seen := range out {
+			if seen == locale {
+				return
+			}
+		}
+		out = append(out, locale)
+	}
+	for _, part := range strings.Split(locales, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		locale := normalizeLocale(tag)
+		if locale == "" || locale == "*" || strings.ContainsAny(locale, "/.\\") {
+			continue
+		}
+		add(locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			add(language)
+		}
+	}
+	add(fallback)
+	add(DefaultLocale)
+	return out
+}
+
+func normalizeLocale(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
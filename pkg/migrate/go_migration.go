@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"solecode/pkg/database"
+)
+
+// GoMigration is a migration written in Go, for data backfills and other
+// changes that are awkward in SQL. It shares the version sequence with the
+// SQL files and runs on every dialect.
+type GoMigration struct {
+	Version string
+	Name    string
+	Up      func(ctx context.Context, db database.DBTX, dialect database.Dialect) error
+	Down    func(ctx context.Context, db database.DBTX, dialect database.Dialect) error
+	// NoTransaction runs the function against the pool instead of a
+	// transaction, e.g. to backfill a large table in batches.
+	NoTransaction bool
+}
+
+// mergeGoMigrations adds the Go migrations to the SQL ones, keeping version
+// order.
+func mergeGoMigrations(migrations []Migration, goMigrations []GoMigration) ([]Migration, error) {
+	for i := range goMigrations {
+		g := &goMigrations[i]
+		if findMigration(migrations, g.Version) != nil {
+			return nil, fmt.Errorf("migration version %s is defined both in SQL and in Go", g.Version)
+		}
+		migrations = append(migrations, Migration{
+			Version: g.Version,
+			Name:    g.Name,
+			HasUp:   g.Up != nil,
+			HasDown: g.Down != nil,
+			// The code cannot be fingerprinted; the checksum pins the name
+			Checksum: checksum("go:" + g.Version + "_" + g.Name),
+			Go:       g,
+		})
+	}
+
+	sortMigrations(migrations)
+	return migrations, nil
+}
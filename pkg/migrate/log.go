@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+
+	"solecode/pkg/database"
+)
+
+// directionPartial marks a migration_log row for a migration that failed
+// part way.
+const directionPartial = "partial"
+
+func createLogTable(ctx context.Context, db *sql.DB, dialect database.Dialect) error {
+	var createTableSQL string
+	switch dialect {
+	case database.Postgres:
+		createTableSQL = `
+			CREATE TABLE IF NOT EXISTS migration_log (
+				id BIGSERIAL PRIMARY KEY,
+				version VARCHAR(255) NOT NULL UNIQUE,
+				name VARCHAR(255) NOT NULL,
+				applied_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				direction VARCHAR(10) NOT NULL,
+				checksum VARCHAR(64) NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_migration_log_applied_at ON migration_log (applied_at);
+		`
+	case database.SQLite:
+		createTableSQL = `
+			CREATE TABLE IF NOT EXISTS migration_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				version VARCHAR(255) NOT NULL UNIQUE,
+				name VARCHAR(255) NOT NULL,
+				applied_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				direction VARCHAR(10) NOT NULL,
+				checksum VARCHAR(64) NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_migration_log_applied_at ON migration_log (applied_at);
+		`
+	default:
+		createTableSQL = `
+			CREATE TABLE IF NOT EXISTS migration_log (
+				id BIGINT AUTO_INCREMENT PRIMARY KEY,
+				version VARCHAR(255) NOT NULL UNIQUE,
+				name VARCHAR(255) NOT NULL,
+				applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				direction VARCHAR(10) NOT NULL,
+				checksum VARCHAR(64) NULL,
+				INDEX idx_version (version),
+				INDEX idx_applied_at (applied_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+		`
+	}
+
+	if _, err := db.ExecContext(ctx, createTableSQL); err != nil {
+		return err
+	}
+	return addChecksumColumn(ctx, db)
+}
+
+// addChecksumColumn upgrades migration_log tables created before checksums
+// were recorded.
+func addChecksumColumn(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `SELECT checksum FROM migration_log WHERE 1 = 0`); err == nil {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `ALTER TABLE migration_log ADD COLUMN checksum VARCHAR(64) NULL`)
+	return err
+}
+
+func recordMigration(ctx context.Context, db database.DBTX, dialect database.Dialect, migration Migration) error {
+	query := `
+		INSERT INTO migration_log (version, name, direction, checksum) 
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := db.ExecContext(ctx, dialect.Rebind(query), migration.Version, migration.Name, string(Up), migration.Checksum)
+	return err
+}
+
+func updateChecksum(ctx context.Context, db *sql.DB, dialect database.Dialect, version, checksum string) error {
+	query := `UPDATE migration_log SET checksum = ? WHERE version = ?`
+	_, err := db.ExecContext(ctx, dialect.Rebind(query), checksum, version)
+	return err
+}
+
+func removeMigration(ctx context.Context, db database.DBTX, dialect database.Dialect, version string) error {
+	query := `DELETE FROM migration_log WHERE version = ?`
+	_, err := db.ExecContext(ctx, dialect.Rebind(query), version)
+	return err
+}
+
+// markPartial records that migration failed part way, replacing any
+// existing row for its version.
+func markPartial(ctx context.Context, db *sql.DB, dialect database.Dialect, migration Migration) error {
+	if err := removeMigration(ctx, db, dialect, migration.Version); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO migration_log (version, name, direction, checksum) 
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := db.ExecContext(ctx, dialect.Rebind(query), migration.Version, migration.Name, directionPartial, migration.Checksum)
+	return err
+}
+
+// markApplied turns a partial row into an applied one.
+func markApplied(ctx context.Context, db *sql.DB, dialect database.Dialect, migration Migration) error {
+	query := `UPDATE migration_log SET direction = ?, checksum = ? WHERE version = ?`
+	_, err := db.ExecContext(ctx, dialect.Rebind(query), string(Up), migration.Checksum, migration.Version)
+	return err
+}
+
+func getApplied(ctx context.Context, db *sql.DB) ([]Applied, error) {
+	var migrations []Applied
+
+	query := `
+		SELECT version, name, checksum, applied_at, direction FROM migration_log 
+		WHERE direction IN ('up', 'partial') 
+		ORDER BY applied_at ASC
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Applied
+		var checksum sql.NullString
+		var direction string
+		if err := rows.Scan(&m.Version, &m.Name, &checksum, &m.AppliedAt, &direction); err != nil {
+			return nil, err
+		}
+		m.Checksum = checksum.String
+		m.Partial = direction == directionPartial
+		migrations = append(migrations, m)
+	}
+
+	return migrations, rows.Err()
+}
+
+// loadApplied is getApplied for a database that may not have a
+// migration_log table yet, which lets dry runs and status checks work
+// against a fresh database without creating it.
+func loadApplied(ctx context.Context, db *sql.DB) ([]Applied, error) {
+	if _, err := db.ExecContext(ctx, `SELECT 1 FROM migration_log WHERE 1 = 0`); err != nil {
+		return nil, nil
+	}
+	return getApplied(ctx, db)
+}
@@ -0,0 +1,500 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"solecode/pkg/database"
+)
+
+const (
+	lockName           = "userapi_migrations"
+	defaultLockTimeout = 60 * time.Second
+)
+
+// Direction is the way a migration is run.
+type Direction string
+
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Migration is a pair of up and down SQL files sharing a version, or a
+// GoMigration.
+type Migration struct {
+	Version  string
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	HasUp    bool
+	HasDown  bool
+	Checksum string
+	Go       *GoMigration // nil for SQL migrations
+}
+
+// Applied is a row of migration_log.
+type Applied struct {
+	Version   string
+	Name      string
+	Checksum  string // empty for rows recorded before checksums existed
+	AppliedAt time.Time
+	Partial   bool // failed part way; see PartialError
+}
+
+// PartialError reports a migration that failed after some of its
+// statements had already been committed, leaving the schema somewhere
+// between two versions. The migration is recorded as partial and every
+// later run refuses to start until it is resolved by hand.
+type PartialError struct {
+	Version   string
+	Direction Direction
+	Executed  int // statements that ran before the failure
+	Total     int
+	Statement string // the statement that failed
+	Err       error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("migration %s %s partially applied: %d of %d statements ran before %q failed: %v; "+
+		"fix the schema by hand, then run \"migrate resolve %s --applied\" or \"migrate resolve %s --rolled-back\"",
+		e.Version, e.Direction, e.Executed, e.Total, e.Statement, e.Err, e.Version, e.Version)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// Step is a single migration to run in one direction.
+type Step struct {
+	Migration Migration
+	Direction Direction
+}
+
+// SQL returns the statements the step runs.
+func (s Step) SQL() string {
+	if s.Direction == Down {
+		return s.Migration.DownSQL
+	}
+	return s.Migration.UpSQL
+}
+
+// Result describes a migration run. On failure Done holds the steps that
+// completed before the error.
+type Result struct {
+	Plan   []Step
+	Done   []Step
+	DryRun bool
+}
+
+// Options tunes a Migrator.
+type Options struct {
+	// LockTimeout is how long to wait for another run to release the
+	// migration lock. Defaults to a minute.
+	LockTimeout time.Duration
+	// DryRun plans without taking the lock or changing the database.
+	DryRun bool
+	// Logf receives progress messages. Nil discards them.
+	Logf func(format string, args ...any)
+	// GoMigrations are run alongside the SQL files.
+	GoMigrations []GoMigration
+}
+
+// Migrator applies the migrations found in a filesystem to a database.
+// Migrations are read from the directory named after the dialect, e.g.
+// mysql/20251028115523_solecode_user.up.sql.
+type Migrator struct {
+	db      *sql.DB
+	dialect database.Dialect
+	fsys    fs.FS
+	opts    Options
+}
+
+// New returns a Migrator for db reading migrations from fsys, which can be
+// an embed.FS or os.DirFS.
+func New(db *sql.DB, dialect database.Dialect, fsys fs.FS, opts Options) *Migrator {
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = defaultLockTimeout
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...any) {}
+	}
+	return &Migrator{db: db, dialect: dialect, fsys: fsys, opts: opts}
+}
+
+// Load returns the SQL migrations for the Migrator's dialect and the Go
+// migrations in version order.
+func (m *Migrator) Load() ([]Migration, error) {
+	migrations, err := Load(m.fsys, string(m.dialect))
+	if err != nil {
+		return nil, err
+	}
+	return mergeGoMigrations(migrations, m.opts.GoMigrations)
+}
+
+// Up applies every pending migration, stopping after version to when it is
+// not empty.
+func (m *Migrator) Up(ctx context.Context, to string) (*Result, error) {
+	return m.run(ctx, func(migrations []Migration, applied map[string]bool) ([]Step, error) {
+		return planUp(migrations, applied, to)
+	})
+}
+
+// Down rolls back the steps most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) (*Result, error) {
+	return m.run(ctx, func(migrations []Migration, applied map[string]bool) ([]Step, error) {
+		return planDown(migrations, applied, steps)
+	})
+}
+
+// Redo rolls back the most recent migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) (*Result, error) {
+	return m.run(ctx, planRedo)
+}
+
+// Goto migrates up or down to version. A version of "0" rolls back every
+// migration.
+func (m *Migrator) Goto(ctx context.Context, version string) (*Result, error) {
+	return m.run(ctx, func(migrations []Migration, applied map[string]bool) ([]Step, error) {
+		return planGoto(migrations, applied, version)
+	})
+}
+
+// Status compares the migrations with migration_log without changing the
+// database.
+func (m *Migrator) Status(ctx context.Context) (*StatusReport, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	report := buildStatusReport(migrations, applied)
+	return &report, nil
+}
+
+// Repair records the current checksum of every applied migration whose
+// file differs from, or predates, its log entry, and returns those
+// migrations.
+func (m *Migrator) Repair(ctx context.Context) ([]Migration, error) {
+	var repaired []Migration
+
+	err := m.withLock(ctx, func() error {
+		if err := createLogTable(ctx, m.db, m.dialect); err != nil {
+			return fmt.Errorf("failed to create migration log table: %w", err)
+		}
+
+		migrations, err := m.Load()
+		if err != nil {
+			return err
+		}
+
+		applied, err := getApplied(ctx, m.db)
+		if err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+
+		for _, a := range applied {
+			migration := findMigration(migrations, a.Version)
+			if migration == nil || migration.Checksum == a.Checksum {
+				continue
+			}
+			if err := updateChecksum(ctx, m.db, m.dialect, a.Version, migration.Checksum); err != nil {
+				return fmt.Errorf("failed to update checksum of %s: %w", a.Version, err)
+			}
+			repaired = append(repaired, *migration)
+		}
+		return nil
+	})
+
+	return repaired, err
+}
+
+// Resolve clears the partial state a PartialError left behind once the
+// schema has been fixed by hand: applied records the migration as applied,
+// otherwise it is recorded as not applied.
+func (m *Migrator) Resolve(ctx context.Context, version string, applied bool) error {
+	return m.withLock(ctx, func() error {
+		rows, err := loadApplied(ctx, m.db)
+		if err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+
+		var partial *Applied
+		for i := range rows {
+			if rows[i].Version == version && rows[i].Partial {
+				partial = &rows[i]
+			}
+		}
+		if partial == nil {
+			return fmt.Errorf("migration %s is not partially applied", version)
+		}
+
+		if !applied {
+			return removeMigration(ctx, m.db, m.dialect, version)
+		}
+
+		migrations, err := m.Load()
+		if err != nil {
+			return err
+		}
+		migration := findMigration(migrations, version)
+		if migration == nil {
+			return fmt.Errorf("unknown migration version %s", version)
+		}
+		return markApplied(ctx, m.db, m.dialect, *migration)
+	})
+}
+
+type planFunc func(migrations []Migration, applied map[string]bool) ([]Step, error)
+
+// run plans with build and executes the plan under the migration lock, or
+// only plans it in dry-run mode.
+func (m *Migrator) run(ctx context.Context, build planFunc) (*Result, error) {
+	result := &Result{DryRun: m.opts.DryRun}
+
+	if m.opts.DryRun {
+		plan, err := m.plan(ctx, build)
+		result.Plan = plan
+		return result, err
+	}
+
+	err := m.withLock(ctx, func() error {
+		// Ensure migration log table exists
+		if err := createLogTable(ctx, m.db, m.dialect); err != nil {
+			return fmt.Errorf("failed to create migration log table: %w", err)
+		}
+
+		plan, err := m.plan(ctx, build)
+		if err != nil {
+			return err
+		}
+		result.Plan = plan
+
+		for _, step := range plan {
+			if err := m.execute(ctx, step); err != nil {
+				return err
+			}
+			result.Done = append(result.Done, step)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+func (m *Migrator) plan(ctx context.Context, build planFunc) ([]Step, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	for _, a := range applied {
+		if a.Partial {
+			return nil, fmt.Errorf("refusing to migrate: migration %s was partially applied; fix the schema by hand, "+
+				"then run \"migrate resolve %s --applied\" or \"migrate resolve %s --rolled-back\"", a.Version, a.Version, a.Version)
+		}
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, fmt.Errorf("refusing to migrate: %w", err)
+	}
+
+	plan, err := build(migrations, appliedSet(applied))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan migrations: %w", err)
+	}
+	return plan, nil
+}
+
+// execute runs one step and updates migration_log, in the same transaction
+// unless the step opts out of one.
+func (m *Migrator) execute(ctx context.Context, step Step) error {
+	migration := step.Migration
+	if step.Direction == Down {
+		m.opts.Logf("→ Rolling back migration %s (%s)...", migration.Version, migration.Name)
+	} else {
+		m.opts.Logf("→ Applying migration %s (%s)...", migration.Version, migration.Name)
+	}
+
+	var err error
+	if migration.Go != nil {
+		err = m.executeGo(ctx, step)
+	} else {
+		err = m.executeSQL(ctx, step)
+	}
+	if err != nil {
+		var partial *PartialError
+		if errors.As(err, &partial) {
+			if markErr := markPartial(ctx, m.db, m.dialect, migration); markErr != nil {
+				return fmt.Errorf("%w (and failed to record it: %v)", err, markErr)
+			}
+		}
+		return err
+	}
+
+	if step.Direction == Down {
+		m.opts.Logf("✅ Rolled back migration %s (%s)", migration.Version, migration.Name)
+	} else {
+		m.opts.Logf("✅ Applied migration %s (%s)", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) executeSQL(ctx context.Context, step Step) error {
+	migration := step.Migration
+
+	annotations, err := ParseAnnotations(step.SQL())
+	if err != nil {
+		return fmt.Errorf("migration %s %s: %w", migration.Version, step.Direction, err)
+	}
+	statements, err := Split(step.SQL(), m.dialect)
+	if err != nil {
+		return fmt.Errorf("migration %s %s: %w", migration.Version, step.Direction, err)
+	}
+
+	if annotations.NoTransaction {
+		for i, statement := range statements {
+			if _, err := m.db.ExecContext(ctx, statement); err != nil {
+				return m.statementError(step, statements, i, err, i > 0)
+			}
+		}
+		return m.updateLog(ctx, m.db, step)
+	}
+
+	if !transactionalDDL(m.dialect) && len(statements) > 1 && containsDDL(statements) {
+		m.opts.Logf("⚠️  %s commits schema changes implicitly, so migration %s is not atomic", m.dialect, migration.Version)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for i, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			tx.Rollback()
+			// Whatever ran before a DDL statement was committed with it
+			committed := !transactionalDDL(m.dialect) && containsDDL(statements[:i])
+			return m.statementError(step, statements, i, err, committed)
+		}
+	}
+
+	if err := m.updateLog(ctx, tx, step); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) executeGo(ctx context.Context, step Step) error {
+	fn := step.Migration.Go.Up
+	if step.Direction == Down {
+		fn = step.Migration.Go.Down
+	}
+	if fn == nil {
+		return fmt.Errorf("migration %s has no %s function", step.Migration.Version, step.Direction)
+	}
+
+	if step.Migration.Go.NoTransaction {
+		if err := fn(ctx, m.db, m.dialect); err != nil {
+			return fmt.Errorf("failed to run migration %s %s: %w", step.Migration.Version, step.Direction, err)
+		}
+		return m.updateLog(ctx, m.db, step)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(ctx, tx, m.dialect); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to run migration %s %s: %w", step.Migration.Version, step.Direction, err)
+	}
+
+	if err := m.updateLog(ctx, tx, step); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// statementError describes the failure of statements[i]. When earlier
+// statements were committed it is a PartialError.
+func (m *Migrator) statementError(step Step, statements []string, i int, err error, committed bool) error {
+	if committed {
+		return &PartialError{
+			Version:   step.Migration.Version,
+			Direction: step.Direction,
+			Executed:  i,
+			Total:     len(statements),
+			Statement: statements[i],
+			Err:       err,
+		}
+	}
+	return fmt.Errorf("failed to run migration %s %s (statement %d of %d): %w",
+		step.Migration.Version, step.Direction, i+1, len(statements), err)
+}
+
+func (m *Migrator) updateLog(ctx context.Context, db database.DBTX, step Step) error {
+	var err error
+	if step.Direction == Down {
+		err = removeMigration(ctx, db, m.dialect, step.Migration.Version)
+	} else {
+		err = recordMigration(ctx, db, m.dialect, step.Migration)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update migration log for %s: %w", step.Migration.Version, err)
+	}
+	return nil
+}
+
+func containsDDL(statements []string) bool {
+	for _, statement := range statements {
+		if isDDL(statement) {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock runs fn while holding the migration lock, so that two runs
+// against the same database never interleave.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	m.opts.Logf("🔒 Waiting up to %s for the migration lock", m.opts.LockTimeout)
+	lock, err := database.AcquireLock(ctx, m.db, m.dialect, lockName, m.opts.LockTimeout)
+	if database.IsLockTimeout(err) {
+		return fmt.Errorf("another migration is in progress: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn()
+	if err := lock.Release(); err != nil && fnErr == nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return fnErr
+}
@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS, opts Options) (*Migrator, *sql.DB) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "migrate.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return New(db, database.SQLite, fsys, opts), db
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"sqlite/001_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"sqlite/001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"sqlite/002_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		"sqlite/002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"mysql/001_a.up.sql":    {Data: []byte("not for sqlite")},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n))
+	return n == 1
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testFS(), Options{})
+
+	result, err := m.Up(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, result.Done, 2)
+	assert.True(t, tableExists(t, db, "b"))
+
+	report, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Applied)
+	assert.Zero(t, report.Errors)
+
+	result, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 002"}, planSummary(result.Done))
+	assert.False(t, tableExists(t, db, "b"))
+
+	result, err = m.Redo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 001", "up 001"}, planSummary(result.Done))
+
+	result, err = m.Goto(ctx, "002")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 002"}, planSummary(result.Done))
+
+	result, err = m.Up(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, result.Plan)
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	m, db := newTestMigrator(t, testFS(), Options{DryRun: true})
+
+	result, err := m.Up(context.Background(), "")
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"up 001", "up 002"}, planSummary(result.Plan))
+	assert.Empty(t, result.Done)
+	assert.False(t, tableExists(t, db, "migration_log"))
+}
+
+func TestMigratorReportsPartialRun(t *testing.T) {
+	fsys := testFS()
+	fsys["sqlite/002_b.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE broken (")}
+	m, db := newTestMigrator(t, fsys, Options{})
+
+	result, err := m.Up(context.Background(), "")
+	require.Error(t, err)
+	assert.Equal(t, []string{"up 001"}, planSummary(result.Done))
+	assert.True(t, tableExists(t, db, "a"))
+}
+
+func TestMigratorPartialNoTransaction(t *testing.T) {
+	ctx := context.Background()
+	fsys := testFS()
+	fsys["sqlite/002_b.up.sql"] = &fstest.MapFile{Data: []byte(
+		"-- +notransaction\nCREATE TABLE b (id INTEGER);\nCREATE TABLE broken (;\n")}
+	m, db := newTestMigrator(t, fsys, Options{})
+
+	_, err := m.Up(ctx, "")
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, "002", partial.Version)
+	assert.Equal(t, 1, partial.Executed)
+	assert.Equal(t, 2, partial.Total)
+	assert.True(t, tableExists(t, db, "b"))
+
+	report, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, StatePartial, report.Migrations[1].State)
+
+	_, err = m.Up(ctx, "")
+	assert.ErrorContains(t, err, "partially applied")
+
+	require.NoError(t, m.Resolve(ctx, "002", true))
+	report, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Applied)
+	assert.Zero(t, report.Errors)
+
+	assert.Error(t, m.Resolve(ctx, "002", false), "002 is no longer partial")
+}
+
+func TestMigratorTransactionalFailureIsNotPartial(t *testing.T) {
+	fsys := testFS()
+	fsys["sqlite/002_b.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER);\nCREATE TABLE broken (;\n")}
+	m, db := newTestMigrator(t, fsys, Options{})
+
+	_, err := m.Up(context.Background(), "")
+	require.Error(t, err)
+	var partial *PartialError
+	assert.False(t, errors.As(err, &partial))
+	assert.False(t, tableExists(t, db, "b"), "sqlite rolls DDL back with the transaction")
+}
+
+func TestMigratorGoMigrations(t *testing.T) {
+	ctx := context.Background()
+	backfill := GoMigration{
+		Version: "003",
+		Name:    "backfill_b",
+		Up: func(ctx context.Context, db database.DBTX, dialect database.Dialect) error {
+			_, err := db.ExecContext(ctx, dialect.Rebind(`INSERT INTO b (id) VALUES (?)`), 42)
+			return err
+		},
+		Down: func(ctx context.Context, db database.DBTX, dialect database.Dialect) error {
+			_, err := db.ExecContext(ctx, `DELETE FROM b`)
+			return err
+		},
+	}
+	m, db := newTestMigrator(t, testFS(), Options{GoMigrations: []GoMigration{backfill}})
+
+	result, err := m.Up(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 001", "up 002", "up 003"}, planSummary(result.Done))
+
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM b`).Scan(&n))
+	assert.Equal(t, 1, n)
+
+	_, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM b`).Scan(&n))
+	assert.Zero(t, n)
+
+	clash := New(db, database.SQLite, testFS(), Options{GoMigrations: []GoMigration{{Version: "001", Name: "clash"}}})
+	_, err = clash.Load()
+	assert.ErrorContains(t, err, "both in SQL and in Go")
+}
@@ -0,0 +1,141 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// planUp applies every pending migration in version order, stopping after
+// target when it is set.
+func planUp(migrations []Migration, applied map[string]bool, target string) ([]Step, error) {
+	if target != "" && findMigration(migrations, target) == nil {
+		return nil, fmt.Errorf("unknown migration version %s", target)
+	}
+
+	var plan []Step
+	for _, migration := range migrations {
+		if target != "" && migration.Version > target {
+			break
+		}
+		if !applied[migration.Version] {
+			plan = append(plan, Step{Migration: migration, Direction: Up})
+		}
+	}
+	return plan, nil
+}
+
+// planDown rolls back the steps most recent applied migrations.
+func planDown(migrations []Migration, applied map[string]bool, steps int) ([]Step, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+
+	var plan []Step
+	for _, version := range sortedVersions(applied, true) {
+		if len(plan) == steps {
+			break
+		}
+		migration := findMigration(migrations, version)
+		if migration == nil {
+			return nil, fmt.Errorf("applied migration %s has no migration file to roll back with", version)
+		}
+		plan = append(plan, Step{Migration: *migration, Direction: Down})
+	}
+	return plan, nil
+}
+
+// planRedo rolls back the most recent migration and applies it again.
+func planRedo(migrations []Migration, applied map[string]bool) ([]Step, error) {
+	plan, err := planDown(migrations, applied, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("no applied migration to redo")
+	}
+	return append(plan, Step{Migration: plan[0].Migration, Direction: Up}), nil
+}
+
+// planGoto rolls back every applied migration newer than target and applies
+// every pending one up to and including it. A target of "0" rolls back
+// everything.
+func planGoto(migrations []Migration, applied map[string]bool, target string) ([]Step, error) {
+	if target != "0" && findMigration(migrations, target) == nil {
+		return nil, fmt.Errorf("unknown migration version %s", target)
+	}
+
+	var plan []Step
+	for _, version := range sortedVersions(applied, true) {
+		if target != "0" && version <= target {
+			break
+		}
+		migration := findMigration(migrations, version)
+		if migration == nil {
+			return nil, fmt.Errorf("applied migration %s has no migration file to roll back with", version)
+		}
+		plan = append(plan, Step{Migration: *migration, Direction: Down})
+	}
+
+	if target == "0" {
+		return plan, nil
+	}
+
+	up, err := planUp(migrations, applied, target)
+	if err != nil {
+		return nil, err
+	}
+	return append(plan, up...), nil
+}
+
+// verifyChecksums fails when an applied migration's file has been edited
+// since it ran. Rows without a checksum predate checksums and are skipped.
+func verifyChecksums(migrations []Migration, applied []Applied) error {
+	var changed []string
+	for _, a := range applied {
+		migration := findMigration(migrations, a.Version)
+		if migration == nil || a.Checksum == "" || a.Partial {
+			continue
+		}
+		if migration.Checksum != a.Checksum {
+			changed = append(changed, a.Version)
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("applied migrations changed on disk: %s (run \"migrate repair\" to accept the new contents)",
+			strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func appliedSet(applied []Applied) map[string]bool {
+	appliedMap := make(map[string]bool)
+	for _, m := range applied {
+		appliedMap[m.Version] = true
+	}
+	return appliedMap
+}
+
+func findMigration(migrations []Migration, version string) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+func sortedVersions(versions map[string]bool, descending bool) []string {
+	sorted := make([]string, 0, len(versions))
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i] > sorted[j]
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []Migration{
+	{Version: "001", Name: "one"},
+	{Version: "002", Name: "two"},
+	{Version: "003", Name: "three"},
+}
+
+func planSummary(plan []Step) []string {
+	var summary []string
+	for _, step := range plan {
+		summary = append(summary, string(step.Direction)+" "+step.Migration.Version)
+	}
+	return summary
+}
+
+func TestPlanUp(t *testing.T) {
+	plan, err := planUp(testMigrations, map[string]bool{"001": true}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 002", "up 003"}, planSummary(plan))
+
+	plan, err = planUp(testMigrations, map[string]bool{}, "002")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 001", "up 002"}, planSummary(plan))
+
+	_, err = planUp(testMigrations, map[string]bool{}, "999")
+	assert.Error(t, err)
+}
+
+func TestPlanDown(t *testing.T) {
+	applied := map[string]bool{"001": true, "002": true, "003": true}
+
+	plan, err := planDown(testMigrations, applied, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 003", "down 002"}, planSummary(plan))
+
+	_, err = planDown(testMigrations, map[string]bool{"004": true}, 1)
+	assert.Error(t, err, "applied version without a file cannot be rolled back")
+}
+
+func TestPlanRedo(t *testing.T) {
+	plan, err := planRedo(testMigrations, map[string]bool{"001": true, "002": true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 002", "up 002"}, planSummary(plan))
+
+	_, err = planRedo(testMigrations, map[string]bool{})
+	assert.Error(t, err)
+}
+
+func TestPlanGoto(t *testing.T) {
+	plan, err := planGoto(testMigrations, map[string]bool{"001": true, "002": true, "003": true}, "001")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 003", "down 002"}, planSummary(plan))
+
+	plan, err = planGoto(testMigrations, map[string]bool{"001": true}, "003")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 002", "up 003"}, planSummary(plan))
+
+	plan, err = planGoto(testMigrations, map[string]bool{"001": true, "002": true}, "0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 002", "down 001"}, planSummary(plan))
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	migrations := []Migration{
+		{Version: "001", Checksum: checksum("CREATE TABLE a (id INT);")},
+		{Version: "002", Checksum: checksum("CREATE TABLE b (id INT);")},
+	}
+
+	assert.NoError(t, verifyChecksums(migrations, []Applied{
+		{Version: "001", Checksum: checksum("CREATE TABLE a (id INT);")},
+		{Version: "002"}, // recorded before checksums existed
+	}))
+
+	err := verifyChecksums(migrations, []Applied{
+		{Version: "001", Checksum: checksum("CREATE TABLE a (id BIGINT);")},
+	})
+	assert.ErrorContains(t, err, "001")
+}
+
+func TestBuildStatusReport(t *testing.T) {
+	migrations := []Migration{
+		{Version: "001", Name: "one", HasUp: true, HasDown: true, Checksum: "aaa"},
+		{Version: "002", Name: "two", HasUp: true, Checksum: "bbb"},
+		{Version: "003", Name: "three", HasUp: true, HasDown: true, Checksum: "ccc"},
+	}
+	applied := []Applied{
+		{Version: "001", Name: "one", Checksum: "changed"},
+		{Version: "003", Name: "three", Checksum: "ccc"},
+		{Version: "000", Name: "gone", Checksum: "ddd"},
+	}
+
+	report := buildStatusReport(migrations, applied)
+
+	require.Len(t, report.Migrations, 4)
+	assert.Equal(t, 2, report.Applied)
+	assert.Equal(t, 1, report.Pending)
+
+	assert.Equal(t, []string{"file changed after it was applied"}, report.Migrations[0].Errors)
+	assert.Equal(t, []string{"missing .down.sql file"}, report.Migrations[1].Errors)
+	assert.Len(t, report.Migrations[1].Warnings, 1, "002 is pending behind applied 003")
+	assert.Empty(t, report.Migrations[2].Errors)
+	assert.Equal(t, "orphaned", report.Migrations[3].State)
+	assert.Equal(t, 3, report.Errors)
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Load reads the migrations in dir of fsys. Files are named
+// {version}_{name}.{up|down}.sql; anything else is ignored.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := make(map[string]*Migration)
+	for _, file := range files {
+		// Parse filename format: {version}_{name}.{up|down}.sql
+		parts := strings.Split(strings.TrimSuffix(path.Base(file), ".sql"), ".")
+		if len(parts) != 2 {
+			continue
+		}
+
+		baseParts := strings.SplitN(parts[0], "_", 2)
+		if len(baseParts) != 2 {
+			continue
+		}
+		version, name, direction := baseParts[0], baseParts[1], parts[1]
+		if direction != string(Up) && direction != string(Down) {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", file, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if direction == string(Up) {
+			migration.UpSQL = string(content)
+			migration.HasUp = true
+		} else {
+			migration.DownSQL = string(content)
+			migration.HasDown = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migration.Checksum = checksum(migration.UpSQL)
+		migrations = append(migrations, *migration)
+	}
+
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+func sortMigrations(migrations []Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// checksum fingerprints the contents of an up migration.
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
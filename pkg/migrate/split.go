@@ -0,0 +1,204 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"solecode/pkg/database"
+)
+
+var (
+	delimiterPattern = regexp.MustCompile(`(?i)^[ \t]*DELIMITER[ \t]+(\S+)[ \t]*(\r?\n|$)`)
+	dollarTagPattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+)
+
+// Split breaks a migration script into statements. It understands line and
+// block comments, quoted strings and identifiers, Postgres dollar quoting
+// and the mysql client's DELIMITER command, so semicolons inside any of
+// those do not end a statement. Statements consisting only of comments are
+// dropped.
+func Split(script string, dialect database.Dialect) ([]string, error) {
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+	delimiter := ";"
+
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	line := 1
+	for i := 0; i < len(script); {
+		rest := script[i:]
+
+		// DELIMITER is a client command, only valid at the start of a line
+		if i == 0 || script[i-1] == '\n' {
+			if m := delimiterPattern.FindStringSubmatch(rest); m != nil {
+				flush()
+				delimiter = m[1]
+				i += len(m[0])
+				line++
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(rest, delimiter):
+			flush()
+			i += len(delimiter)
+			continue
+
+		case strings.HasPrefix(rest, "--") || (dialect == database.MySQL && rest[0] == '#'):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			current.WriteString(rest[:end])
+			i += end
+			continue
+
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated block comment", line)
+			}
+			n := end + 4
+			// MySQL executes /*! ... */ and optimizer hints, so they count as code
+			if strings.HasPrefix(rest, "/*!") || strings.HasPrefix(rest, "/*+") {
+				hasCode = true
+			}
+			current.WriteString(rest[:n])
+			line += strings.Count(rest[:n], "\n")
+			i += n
+			continue
+
+		case rest[0] == '\'' || rest[0] == '"' || (dialect == database.MySQL && rest[0] == '`'):
+			n, err := quotedLength(rest, dialect)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			current.WriteString(rest[:n])
+			line += strings.Count(rest[:n], "\n")
+			hasCode = true
+			i += n
+			continue
+
+		case dialect == database.Postgres && rest[0] == '$':
+			if m := dollarTagPattern.FindString(rest); m != "" {
+				end := strings.Index(rest[len(m):], m)
+				if end < 0 {
+					return nil, fmt.Errorf("line %d: unterminated dollar-quoted string %s", line, m)
+				}
+				n := len(m) + end + len(m)
+				current.WriteString(rest[:n])
+				line += strings.Count(rest[:n], "\n")
+				hasCode = true
+				i += n
+				continue
+			}
+		}
+
+		c := script[i]
+		if c == '\n' {
+			line++
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			hasCode = true
+		}
+		current.WriteByte(c)
+		i++
+	}
+	flush()
+
+	return statements, nil
+}
+
+// quotedLength returns the length of the quoted string or identifier at the
+// start of s, including its quotes. A doubled quote is an escaped quote, and
+// MySQL also accepts backslash escapes in strings.
+func quotedLength(s string, dialect database.Dialect) (int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && dialect == database.MySQL && quote != '`':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c quote", quote)
+}
+
+// Annotations are directives given in a migration file as "-- +name"
+// comment lines.
+type Annotations struct {
+	// NoTransaction runs the statements one by one outside a transaction,
+	// for statements that cannot run in one such as CREATE INDEX
+	// CONCURRENTLY.
+	NoTransaction bool
+}
+
+// ParseAnnotations reads the annotations of a migration file. Unknown
+// annotations are an error so that typos are not silently ignored.
+func ParseAnnotations(script string) (Annotations, error) {
+	var annotations Annotations
+	for n, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-- +") {
+			continue
+		}
+
+		switch name := strings.TrimSpace(strings.TrimPrefix(line, "-- +")); name {
+		case "notransaction":
+			annotations.NoTransaction = true
+		default:
+			return annotations, fmt.Errorf("line %d: unknown annotation %q", n+1, name)
+		}
+	}
+	return annotations, nil
+}
+
+// ddlKeywords start statements that MySQL commits implicitly.
+var ddlKeywords = map[string]bool{
+	"CREATE": true, "ALTER": true, "DROP": true, "RENAME": true, "TRUNCATE": true,
+}
+
+// isDDL reports whether statement is a schema change.
+func isDDL(statement string) bool {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--"), strings.HasPrefix(statement, "#"):
+			end := strings.IndexByte(statement, '\n')
+			if end < 0 {
+				return false
+			}
+			statement = statement[end:]
+		case strings.HasPrefix(statement, "/*") && !strings.HasPrefix(statement, "/*!"):
+			end := strings.Index(statement, "*/")
+			if end < 0 {
+				return false
+			}
+			statement = statement[end+2:]
+		default:
+			fields := strings.Fields(statement)
+			return len(fields) > 0 && ddlKeywords[strings.ToUpper(fields[0])]
+		}
+	}
+}
+
+// transactionalDDL reports whether schema changes roll back with the
+// transaction they run in. MySQL commits before and after every DDL
+// statement.
+func transactionalDDL(dialect database.Dialect) bool {
+	return dialect != database.MySQL
+}
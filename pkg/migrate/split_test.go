@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"testing"
+
+	"solecode/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect database.Dialect
+		script  string
+		want    []string
+	}{
+		{
+			name:    "statements and comments",
+			dialect: database.MySQL,
+			script:  "-- header; not a statement\nCREATE TABLE a (id INT);\n/* block; comment */\nINSERT INTO a VALUES (1);\n# trailing;\n",
+			want:    []string{"-- header; not a statement\nCREATE TABLE a (id INT)", "/* block; comment */\nINSERT INTO a VALUES (1)"},
+		},
+		{
+			name:    "semicolons in strings and identifiers",
+			dialect: database.MySQL,
+			script:  "INSERT INTO a VALUES ('x;y', 'it''s;', 'back\\';slash');\nSELECT `odd;name` FROM a",
+			want:    []string{"INSERT INTO a VALUES ('x;y', 'it''s;', 'back\\';slash')", "SELECT `odd;name` FROM a"},
+		},
+		{
+			name:    "delimiter command",
+			dialect: database.MySQL,
+			script:  "DELIMITER //\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.id = 1; END//\nDELIMITER ;\nSELECT 1;",
+			want:    []string{"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.id = 1; END", "SELECT 1"},
+		},
+		{
+			name:    "dollar quoting",
+			dialect: database.Postgres,
+			script:  "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;\nSELECT $$a;b$$, $1;",
+			want:    []string{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $$a;b$$, $1"},
+		},
+		{
+			name:    "hash is not a comment outside mysql",
+			dialect: database.Postgres,
+			script:  "SELECT '{}'::jsonb #> '{a}';",
+			want:    []string{"SELECT '{}'::jsonb #> '{a}'"},
+		},
+		{
+			name:    "comment only",
+			dialect: database.SQLite,
+			script:  "-- nothing to do\n",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.script, tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitUnterminated(t *testing.T) {
+	_, err := Split("SELECT 1;\nSELECT 'oops;", database.MySQL)
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = Split("SELECT 1 /* never closed", database.SQLite)
+	assert.Error(t, err)
+}
+
+func TestParseAnnotations(t *testing.T) {
+	annotations, err := ParseAnnotations("-- +notransaction\nCREATE INDEX CONCURRENTLY i ON a (id);")
+	require.NoError(t, err)
+	assert.True(t, annotations.NoTransaction)
+
+	annotations, err = ParseAnnotations("CREATE TABLE a (id INT);")
+	require.NoError(t, err)
+	assert.False(t, annotations.NoTransaction)
+
+	_, err = ParseAnnotations("-- +notransacton\n")
+	assert.ErrorContains(t, err, "notransacton")
+}
+
+func TestIsDDL(t *testing.T) {
+	assert.True(t, isDDL("-- comment\nCREATE TABLE a (id INT)"))
+	assert.True(t, isDDL("/* c */ alter table a add b int"))
+	assert.False(t, isDDL("INSERT INTO a VALUES (1)"))
+}
@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// Migration states reported by Status.
+const (
+	StateApplied  = "applied"
+	StatePending  = "pending"
+	StateOrphaned = "orphaned"
+	StatePartial  = "partial"
+)
+
+// MigrationStatus describes one migration, or one orphaned log entry.
+type MigrationStatus struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"` // applied, pending, partial or orphaned
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Errors    []string   `json:"errors,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// StatusReport compares the migration files with migration_log and flags
+// drift: edited or missing files, out-of-order versions and log entries
+// without a file.
+type StatusReport struct {
+	Migrations []MigrationStatus `json:"migrations"`
+	Total      int               `json:"total"`
+	Applied    int               `json:"applied"`
+	Pending    int               `json:"pending"`
+	Errors     int               `json:"errors"`
+	Warnings   int               `json:"warnings"`
+}
+
+func buildStatusReport(migrations []Migration, applied []Applied) StatusReport {
+	var report StatusReport
+
+	appliedByVersion := make(map[string]Applied)
+	latestApplied := ""
+	for _, a := range applied {
+		appliedByVersion[a.Version] = a
+		if a.Version > latestApplied {
+			latestApplied = a.Version
+		}
+	}
+
+	for _, migration := range migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			State:   StatePending,
+		}
+
+		if !migration.HasUp {
+			status.Errors = append(status.Errors, "missing .up.sql file")
+		}
+		if !migration.HasDown {
+			status.Errors = append(status.Errors, "missing .down.sql file")
+		}
+
+		if a, ok := appliedByVersion[migration.Version]; ok && a.Partial {
+			status.State = StatePartial
+			appliedAt := a.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Errors = append(status.Errors, fmt.Sprintf(
+				`failed part way; fix the schema, then run "migrate resolve %s --applied" or "--rolled-back"`, migration.Version))
+		} else if ok {
+			status.State = StateApplied
+			appliedAt := a.AppliedAt
+			status.AppliedAt = &appliedAt
+			report.Applied++
+
+			switch {
+			case a.Checksum == "":
+				status.Warnings = append(status.Warnings, `no checksum recorded (run "migrate repair")`)
+			case a.Checksum != migration.Checksum:
+				status.Errors = append(status.Errors, "file changed after it was applied")
+			}
+		} else {
+			report.Pending++
+			if migration.Version < latestApplied {
+				status.Warnings = append(status.Warnings, fmt.Sprintf("out of order: older than applied version %s", latestApplied))
+			}
+		}
+
+		report.Migrations = append(report.Migrations, status)
+	}
+
+	for _, a := range applied {
+		if findMigration(migrations, a.Version) != nil {
+			continue
+		}
+		appliedAt := a.AppliedAt
+		report.Migrations = append(report.Migrations, MigrationStatus{
+			Version:   a.Version,
+			Name:      a.Name,
+			State:     StateOrphaned,
+			AppliedAt: &appliedAt,
+			Errors:    []string{"applied but no migration file exists"},
+		})
+	}
+
+	report.Total = len(migrations)
+	for _, status := range report.Migrations {
+		report.Errors += len(status.Errors)
+		report.Warnings += len(status.Warnings)
+	}
+
+	return report
+}
@@ -0,0 +1,196 @@
+// Package password hashes and verifies user passwords with argon2id or
+// bcrypt. Hashes are self-describing strings (PHC format for argon2id, the
+// usual $2a$ form for bcrypt), so a Hasher verifies hashes made with any
+// algorithm or parameters and reports the ones that need rehashing.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"solecode/pkg/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithms.
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// Defaults for zero config values, following the OWASP recommendations.
+const (
+	DefaultArgon2Memory      = 64 * 1024 // KiB
+	DefaultArgon2Iterations  = 3
+	DefaultArgon2Parallelism = 2
+	DefaultBcryptCost        = 12
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Hasher hashes new passwords with the configured algorithm and
+// parameters.
+type Hasher struct {
+	algorithm   string
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	bcryptCost  int
+}
+
+// New returns a Hasher for cfg, filling zero values with the defaults.
+func New(cfg config.PasswordConfig) (*Hasher, error) {
+	h := &Hasher{
+		algorithm:   strings.ToLower(cfg.Algorithm),
+		memory:      cfg.Argon2Memory,
+		iterations:  cfg.Argon2Iterations,
+		parallelism: cfg.Argon2Parallelism,
+		bcryptCost:  cfg.BcryptCost,
+	}
+	if h.algorithm == "" {
+		h.algorithm = Argon2id
+	}
+	if h.memory == 0 {
+		h.memory = DefaultArgon2Memory
+	}
+	if h.iterations == 0 {
+		h.iterations = DefaultArgon2Iterations
+	}
+	if h.parallelism == 0 {
+		h.parallelism = DefaultArgon2Parallelism
+	}
+	if h.bcryptCost == 0 {
+		h.bcryptCost = DefaultBcryptCost
+	}
+
+	if h.algorithm != Argon2id && h.algorithm != Bcrypt {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, cfg.Algorithm)
+	}
+	if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return h, nil
+}
+
+// Hash returns the encoded hash of password with a fresh random salt.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches encoded, whichever algorithm
+// made it. The error is for hashes it cannot read.
+func (h *Hasher) Verify(password, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+		}
+		return true, nil
+	}
+
+	params, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash reports whether encoded was made with another algorithm or
+// other parameters than h uses, so that it should be replaced after the
+// next successful Verify.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		if h.algorithm != Bcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.bcryptCost
+	}
+
+	if h.algorithm != Argon2id {
+		return true
+	}
+	params, salt, key, err := decodeArgon2(encoded)
+	return err != nil ||
+		params.memory != h.memory || params.iterations != h.iterations || params.parallelism != h.parallelism ||
+		len(salt) != argon2SaltLen || len(key) != argon2KeyLen
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// decodeArgon2 parses $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func decodeArgon2(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if parts[1] != Argon2id {
+		return params, nil, nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, parts[1])
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: argon2 version %d", ErrMalformedHash, version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+	return params, salt, key, nil
+}
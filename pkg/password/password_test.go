@@ -0,0 +1,118 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"solecode/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cheap keeps the tests fast; the parameters are far below production
+// strength.
+var cheap = config.PasswordConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: 4}
+
+func newHasher(t *testing.T, algorithm string) *Hasher {
+	cfg := cheap
+	cfg.Algorithm = algorithm
+	h, err := New(cfg)
+	require.NoError(t, err)
+	return h
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := newHasher(t, algorithm)
+
+			hash, err := h.Hash("Secret123!")
+			require.NoError(t, err)
+			assert.NotContains(t, hash, "Secret123!")
+
+			ok, err := h.Verify("Secret123!", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = h.Verify("Secret123?", hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.False(t, h.NeedsRehash(hash))
+
+			other, err := h.Hash("Secret123!")
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, other, "salts must differ")
+		})
+	}
+}
+
+func TestArgon2Format(t *testing.T) {
+	h := newHasher(t, "")
+	hash, err := h.Hash("Secret123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon := newHasher(t, Argon2id)
+	bcryptHasher := newHasher(t, Bcrypt)
+
+	argonHash, err := argon.Hash("Secret123!")
+	require.NoError(t, err)
+	bcryptHash, err := bcryptHasher.Hash("Secret123!")
+	require.NoError(t, err)
+
+	// Either hasher verifies the other's hashes but wants them replaced
+	ok, err := argon.Verify("Secret123!", bcryptHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, argon.NeedsRehash(bcryptHash))
+	ok, err = bcryptHasher.Verify("Secret123!", argonHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, bcryptHasher.NeedsRehash(argonHash))
+
+	stronger := cheap
+	stronger.Argon2Iterations = 2
+	stronger.BcryptCost = 5
+	strongerArgon, err := New(stronger)
+	require.NoError(t, err)
+	assert.True(t, strongerArgon.NeedsRehash(argonHash))
+	stronger.Algorithm = Bcrypt
+	strongerBcrypt, err := New(stronger)
+	require.NoError(t, err)
+	assert.True(t, strongerBcrypt.NeedsRehash(bcryptHash))
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	h := newHasher(t, Argon2id)
+	for _, encoded := range []string{
+		"",
+		"plain",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$2a$04$short",
+	} {
+		ok, err := h.Verify("Secret123!", encoded)
+		assert.Error(t, err, encoded)
+		assert.False(t, ok)
+		assert.True(t, h.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestNew(t *testing.T) {
+	h, err := New(config.PasswordConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, Argon2id, h.algorithm)
+	assert.Equal(t, uint32(DefaultArgon2Memory), h.memory)
+	assert.Equal(t, DefaultBcryptCost, h.bcryptCost)
+
+	_, err = New(config.PasswordConfig{Algorithm: "md5"})
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = New(config.PasswordConfig{Algorithm: Bcrypt, BcryptCost: 40})
+	assert.Error(t, err)
+}
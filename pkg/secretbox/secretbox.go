@@ -0,0 +1,85 @@
+// Package secretbox encrypts small secrets, such as TOTP secrets, before
+// they are stored, so that a leaked database or backup does not leak them.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of keys: AES-256.
+const KeySize = 32
+
+// version prefixes sealed values so the format can change later.
+const version = "v1."
+
+// ErrDecrypt is returned for values that were sealed with another key or
+// other associated data, or were tampered with.
+var ErrDecrypt = errors.New("failed to decrypt secret")
+
+// Box seals and opens secrets with AES-256-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box using key, which must be KeySize bytes.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// ParseKey decodes a base64 key, as generated by "openssl rand -base64 32".
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(s); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("encryption key is not base64")
+}
+
+// Seal encrypts plaintext. additionalData is not stored but has to be
+// passed to Open again; binding a secret to its owner this way stops it
+// from being copied to another row.
+func (b *Box) Seal(plaintext, additionalData []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, plaintext, additionalData)
+	return version + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value from Seal.
+func (b *Box) Open(sealed string, additionalData []byte) ([]byte, error) {
+	raw, ok := strings.CutPrefix(sealed, version)
+	if !ok {
+		return nil, ErrDecrypt
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
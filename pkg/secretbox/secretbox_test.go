@@ -0,0 +1,50 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte("secret"), []byte("user:1"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "secret")
+	again, err := box.Seal([]byte("secret"), []byte("user:1"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces must differ")
+
+	plaintext, err := box.Open(sealed, []byte("user:1"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	// Other associated data, another key or a flipped byte all fail
+	_, err = box.Open(sealed, []byte("user:2"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	other, err := New(bytes.Repeat([]byte{8}, KeySize))
+	require.NoError(t, err)
+	_, err = other.Open(sealed, []byte("user:1"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+	_, err = box.Open(string(tampered), []byte("user:1"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = box.Open("plain", nil)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestKeys(t *testing.T) {
+	_, err := New([]byte("short"))
+	assert.Error(t, err)
+
+	key, err := ParseKey("BwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwc=\n")
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{7}, KeySize), key)
+	_, err = ParseKey("not base64!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,360 @@
+// Package token signs and verifies JWT access tokens with a set of keys,
+// one of which signs while all of them verify, and publishes the public
+// keys as a JWK Set.
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"solecode/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// DefaultIssuer is the iss claim when the config leaves it empty.
+const DefaultIssuer = "userapi"
+
+// minSecretLen is the shortest HS256 secret accepted, the size of the
+// hash output as RFC 7518 requires.
+const minSecretLen = 32
+
+// leeway tolerates clock skew between the issuer and other verifiers.
+const leeway = 30 * time.Second
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoSigningKey = errors.New("no signing key")
+)
+
+// Claims are the claims of an access token. The subject is the user ID.
+// Tokens issued to OAuth clients name the client as their audience and
+// carry the granted scopes; impersonation tokens name the admin as their
+// actor.
+type Claims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	Actor *Actor `json:"act,omitempty"`
+}
+
+// Actor is the act claim of RFC 8693: who acts for the subject.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+type key struct {
+	id     string
+	method jwt.SigningMethod
+	sign   crypto.PrivateKey // nil for verify-only keys
+	verify crypto.PublicKey
+}
+
+// KeySet signs with its signing key and verifies with any of its keys.
+type KeySet struct {
+	issuer  string
+	signing *key
+	keys    map[string]*key
+}
+
+// NewKeySet loads the keys of cfg. The key named by cfg.SigningKey, or the
+// only key when there is one, signs new tokens.
+func NewKeySet(cfg config.AuthConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("no keys configured")
+	}
+
+	set := &KeySet{issuer: issuerOrDefault(cfg.Issuer), keys: make(map[string]*key)}
+	for _, kc := range cfg.Keys {
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kc.ID, err)
+		}
+		if _, ok := set.keys[k.id]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", k.id)
+		}
+		set.keys[k.id] = k
+	}
+
+	signingKey := cfg.SigningKey
+	if signingKey == "" && len(cfg.Keys) == 1 {
+		signingKey = cfg.Keys[0].ID
+	}
+	set.signing = set.keys[signingKey]
+	switch {
+	case set.signing == nil:
+		return nil, fmt.Errorf("%w: signing_key %q is not among the keys", ErrNoSigningKey, signingKey)
+	case set.signing.sign == nil:
+		return nil, fmt.Errorf("%w: key %q has no private key", ErrNoSigningKey, signingKey)
+	}
+	return set, nil
+}
+
+// NewEphemeralKeySet returns a set with a freshly generated Ed25519 key.
+// Its tokens stop verifying when the process exits, so it only suits
+// development and tests.
+func NewEphemeralKeySet(issuer string) (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	k := &key{
+		id:     "ephemeral-" + base64.RawURLEncoding.EncodeToString(public[:6]),
+		method: jwt.SigningMethodEdDSA,
+		sign:   private,
+		verify: public,
+	}
+	return &KeySet{issuer: issuerOrDefault(issuer), signing: k, keys: map[string]*key{k.id: k}}, nil
+}
+
+func issuerOrDefault(issuer string) string {
+	if issuer == "" {
+		return DefaultIssuer
+	}
+	return issuer
+}
+
+// Issuer returns the iss claim of the tokens the set signs.
+func (s *KeySet) Issuer() string {
+	return s.issuer
+}
+
+// Algorithm returns the algorithm of the signing key.
+func (s *KeySet) Algorithm() string {
+	return s.signing.method.Alg()
+}
+
+// Sign sets the issuer of claims and signs them with the signing key.
+func (s *KeySet) Sign(claims *Claims) (string, error) {
+	claims.Issuer = s.issuer
+	return s.SignClaims(claims)
+}
+
+// SignClaims signs claims of any shape, such as ID tokens, with the
+// signing key. Callers set the issuer.
+func (s *KeySet) SignClaims(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(s.signing.method, claims)
+	t.Header["kid"] = s.signing.id
+	signed, err := t.SignedString(s.signing.sign)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// Verify checks the signature and issuer of raw and that it is valid at
+// now, and returns its claims. Every error wraps ErrInvalidToken.
+func (s *KeySet) Verify(raw string, now time.Time) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// The key decides the algorithm, never the token
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, fmt.Errorf("algorithm %s does not match key %q", t.Method.Alg(), kid)
+		}
+		return k.verify, nil
+	},
+		jwt.WithValidMethods([]string{HS256, RS256, EdDSA}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// JWK is a public key in JSON Web Key form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, signing key first. HS256 keys
+// are secret and left out.
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	add := func(k *key) {
+		switch public := k.verify.(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "OKP", Use: "sig", Alg: EdDSA, Kid: k.id, Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(public)})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "RSA", Use: "sig", Alg: RS256, Kid: k.id,
+				N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())})
+		}
+	}
+
+	add(s.signing)
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		if id != s.signing.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		add(s.keys[id])
+	}
+	return set
+}
+
+func loadKey(kc config.SigningKeyConfig) (*key, error) {
+	if kc.ID == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+	k := &key{id: kc.ID}
+
+	switch kc.Algorithm {
+	case HS256:
+		if len(kc.Secret) < minSecretLen {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minSecretLen)
+		}
+		k.method = jwt.SigningMethodHS256
+		k.sign, k.verify = []byte(kc.Secret), []byte(kc.Secret)
+		return k, nil
+	case RS256:
+		k.method = jwt.SigningMethodRS256
+	case EdDSA:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q, use HS256, RS256 or EdDSA", kc.Algorithm)
+	}
+
+	switch {
+	case kc.PrivateKeyFile != "":
+		private, err := readPrivateKey(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", private)
+		}
+		k.sign, k.verify = private, signer.Public()
+	case kc.PublicKeyFile != "":
+		public, err := readPublicKey(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.verify = public
+	default:
+		return nil, fmt.Errorf("%s needs private_key_file or public_key_file", kc.Algorithm)
+	}
+
+	_, isRSA := k.verify.(*rsa.PublicKey)
+	_, isEd := k.verify.(ed25519.PublicKey)
+	if (kc.Algorithm == RS256 && !isRSA) || (kc.Algorithm == EdDSA && !isEd) {
+		return nil, fmt.Errorf("key type %T does not match algorithm %s", k.verify, kc.Algorithm)
+	}
+	return k, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM block", path)
+	}
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if private, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return private, nil
+	}
+	private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key in %s: not PKCS#8 or PKCS#1", path)
+	}
+	return private, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if public, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return public, nil
+	}
+	public, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key in %s: not PKIX or PKCS#1", path)
+	}
+	return public, nil
+}
+
+// GenerateKey returns a new key for algorithm: a PKCS#8 PEM private key
+// for RS256 and EdDSA, and a random base64 secret for HS256.
+func GenerateKey(algorithm string) ([]byte, error) {
+	var private crypto.PrivateKey
+	var err error
+	switch strings.ToUpper(algorithm) {
+	case strings.ToUpper(HS256):
+		secret := make([]byte, 48)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return []byte(base64.RawStdEncoding.EncodeToString(secret) + "\n"), nil
+	case strings.ToUpper(RS256):
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	case strings.ToUpper(EdDSA):
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q, use HS256, RS256 or EdDSA", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
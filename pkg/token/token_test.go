@@ -0,0 +1,253 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"solecode/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeKey generates a key for algorithm and returns its config.
+func writeKey(t *testing.T, id, algorithm string) config.SigningKeyConfig {
+	data, err := GenerateKey(algorithm)
+	require.NoError(t, err)
+	if algorithm == HS256 {
+		return config.SigningKeyConfig{ID: id, Algorithm: algorithm, Secret: strings.TrimSpace(string(data))}
+	}
+	path := filepath.Join(t.TempDir(), id+".pem")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return config.SigningKeyConfig{ID: id, Algorithm: algorithm, PrivateKeyFile: path}
+}
+
+func newClaims(ttl time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Email: "john@example.com",
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{HS256, RS256, EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			keys, err := NewKeySet(config.AuthConfig{Issuer: "test", Keys: []config.SigningKeyConfig{writeKey(t, "k1", algorithm)}})
+			require.NoError(t, err)
+
+			signed, err := keys.Sign(newClaims(time.Minute))
+			require.NoError(t, err)
+
+			claims, err := keys.Verify(signed, time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, "42", claims.Subject)
+			assert.Equal(t, "john@example.com", claims.Email)
+			assert.Equal(t, "test", claims.Issuer)
+			assert.Equal(t, algorithm, keys.Algorithm())
+
+			_, err = keys.Verify(signed[:len(signed)-4]+"AAAA", time.Now())
+			assert.ErrorIs(t, err, ErrInvalidToken)
+
+			expired, err := keys.Sign(newClaims(-time.Minute))
+			require.NoError(t, err)
+			_, err = keys.Verify(expired, time.Now())
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old := writeKey(t, "old", RS256)
+	next := writeKey(t, "next", EdDSA)
+
+	before, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{old}})
+	require.NoError(t, err)
+	oldToken, err := before.Sign(newClaims(time.Minute))
+	require.NoError(t, err)
+
+	after, err := NewKeySet(config.AuthConfig{SigningKey: "next", Keys: []config.SigningKeyConfig{old, next}})
+	require.NoError(t, err)
+	newToken, err := after.Sign(newClaims(time.Minute))
+	require.NoError(t, err)
+
+	// The retired key still verifies the tokens it signed
+	_, err = after.Verify(oldToken, time.Now())
+	assert.NoError(t, err)
+	_, err = after.Verify(newToken, time.Now())
+	assert.NoError(t, err)
+	_, err = before.Verify(newToken, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	header, _, _ := strings.Cut(newToken, ".")
+	decoded, err := base64.RawURLEncoding.DecodeString(header)
+	require.NoError(t, err)
+	assert.Contains(t, string(decoded), `"kid":"next"`)
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "next", jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "old", jwks.Keys[1].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+}
+
+func TestVerifyOnlyKey(t *testing.T) {
+	signer := writeKey(t, "k1", EdDSA)
+	keys, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{signer}})
+	require.NoError(t, err)
+	signed, err := keys.Sign(newClaims(time.Minute))
+	require.NoError(t, err)
+
+	block, err := readPEM(signer.PrivateKeyFile)
+	require.NoError(t, err)
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(private.(ed25519.PrivateKey).Public())
+	require.NoError(t, err)
+	publicPath := filepath.Join(t.TempDir(), "k1.pub")
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	retired := config.SigningKeyConfig{ID: "k1", Algorithm: EdDSA, PublicKeyFile: publicPath}
+	verifier, err := NewKeySet(config.AuthConfig{SigningKey: "k2", Keys: []config.SigningKeyConfig{retired, writeKey(t, "k2", HS256)}})
+	require.NoError(t, err)
+	_, err = verifier.Verify(signed, time.Now())
+	assert.NoError(t, err)
+
+	_, err = NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{retired}})
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestVerifyRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey := writeKey(t, "rsa", RS256)
+	keys, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{rsaKey}})
+	require.NoError(t, err)
+
+	// An HS256 token keyed with the published public key must not verify
+	jwk := keys.JWKS().Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	t1 := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(time.Minute))
+	t1.Header["kid"] = "rsa"
+	forged, err := t1.SignedString(x509.MarshalPKCS1PublicKey(public))
+	require.NoError(t, err)
+	_, err = keys.Verify(forged, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, newClaims(time.Minute))
+	unsigned.Header["kid"] = "rsa"
+	none, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = keys.Verify(none, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifyChecksIssuer(t *testing.T) {
+	key := config.SigningKeyConfig{ID: "k1", Algorithm: HS256, Secret: testSecret}
+	a, err := NewKeySet(config.AuthConfig{Issuer: "a", Keys: []config.SigningKeyConfig{key}})
+	require.NoError(t, err)
+	b, err := NewKeySet(config.AuthConfig{Issuer: "b", Keys: []config.SigningKeyConfig{key}})
+	require.NoError(t, err)
+
+	signed, err := a.Sign(newClaims(time.Minute))
+	require.NoError(t, err)
+	_, err = b.Verify(signed, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	for name, cfg := range map[string]config.AuthConfig{
+		"no keys":         {},
+		"short secret":    {Keys: []config.SigningKeyConfig{{ID: "k", Algorithm: HS256, Secret: "short"}}},
+		"unknown alg":     {Keys: []config.SigningKeyConfig{{ID: "k", Algorithm: "none", Secret: testSecret}}},
+		"missing file":    {Keys: []config.SigningKeyConfig{{ID: "k", Algorithm: EdDSA}}},
+		"unknown signing": {SigningKey: "x", Keys: []config.SigningKeyConfig{{ID: "k", Algorithm: HS256, Secret: testSecret}}},
+		"duplicate id": {Keys: []config.SigningKeyConfig{
+			{ID: "k", Algorithm: HS256, Secret: testSecret},
+			{ID: "k", Algorithm: HS256, Secret: testSecret},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewKeySet(cfg)
+			assert.Error(t, err)
+		})
+	}
+
+	rsaKey := writeKey(t, "k", RS256)
+	rsaKey.Algorithm = EdDSA
+	_, err := NewKeySet(config.AuthConfig{Keys: []config.SigningKeyConfig{rsaKey}})
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestEphemeralKeySet(t *testing.T) {
+	keys, err := NewEphemeralKeySet("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultIssuer, keys.Issuer())
+	assert.Equal(t, EdDSA, keys.Algorithm())
+
+	signed, err := keys.Sign(newClaims(time.Minute))
+	require.NoError(t, err)
+	_, err = keys.Verify(signed, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, keys.JWKS().Keys, 1)
+}
+
+func TestActionSigner(t *testing.T) {
+	_, err := NewActionSigner([]byte("short"))
+	assert.Error(t, err)
+
+	signer, err := NewActionSigner([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+
+	raw := signer.Sign("verify_email", 42, "john@example.com", now.Add(time.Hour))
+	subject, err := signer.Subject(raw)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), subject)
+
+	subject, err = signer.Verify(raw, "verify_email", "john@example.com", now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), subject)
+
+	// Another purpose, a changed state or another key do not verify
+	_, err = signer.Verify(raw, "reset_password", "john@example.com", now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = signer.Verify(raw, "verify_email", "john@example.com|verified", now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	other, err := NewEphemeralActionSigner()
+	require.NoError(t, err)
+	_, err = other.Verify(raw, "verify_email", "john@example.com", now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = signer.Verify(raw, "verify_email", "john@example.com", now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrExpiredToken)
+
+	// A token for another subject cannot borrow this signature
+	_, sig, _ := strings.Cut(raw, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("1."+strconv.FormatInt(now.Add(time.Hour).Unix(), 10))) + "." + sig
+	_, err = signer.Verify(forged, "verify_email", "john@example.com", now)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	for _, bad := range []string{"", "abc", "abc.def", raw + "x"} {
+		_, err := signer.Verify(bad, "verify_email", "john@example.com", now)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+}
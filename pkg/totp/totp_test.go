@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 secret of the RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// The RFC lists eight digits; apps show the last six
+	for unix, code := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		assert.Equal(t, code, Code(rfcSecret, Step(time.Unix(unix, 0))), "at %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	step, ok := Validate(rfcSecret, "005924", now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// Codes of the neighbouring steps pass within the skew only
+	previous := Code(rfcSecret, Step(now)-1)
+	step, ok = Validate(rfcSecret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+	_, ok = Validate(rfcSecret, previous, now, 0)
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, Code(rfcSecret, Step(now)+2), now, 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "005 924", now, 0)
+	assert.True(t, ok)
+	for _, bad := range []string{"", "00592", "0059245", "005925"} {
+		_, ok = Validate(rfcSecret, bad, now, 1)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("User API", "john@example.com", rfcSecret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/User API:john@example.com", uri.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+	assert.Equal(t, "User API", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	require.NoError(t, err)
+	b, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, a, SecretSize)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, EncodeSecret(a), 32)
+}
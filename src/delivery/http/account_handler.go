@@ -0,0 +1,191 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"solecode/pkg/validator"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+	accountUC "solecode/src/usecase/account"
+	lockoutUC "solecode/src/usecase/lockout"
+
+	"github.com/gorilla/mux"
+)
+
+// AccountHandler handles email verification and password resets
+type AccountHandler struct {
+	useCases  uc.UseCases
+	validator *validator.Validator
+}
+
+func NewAccountHandler(useCases uc.UseCases) *AccountHandler {
+	return &AccountHandler{useCases: useCases, validator: validator.New()}
+}
+
+// TokenRequest carries a token from a verification email
+// @Description Token from the link in the email
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+// PasswordResetRequest asks for a password reset email
+// @Description Email of the account
+type PasswordResetRequest struct {
+	Email string `json:"email" example:"john@example.com" validate:"required,email"`
+}
+
+// ConfirmPasswordResetRequest sets a new password with a reset token
+// @Description Token from the link in the reset email and the new password
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"NewSecret123!"`
+}
+
+// SendVerification godoc
+// @Summary Email a user a verification link
+// @Description Users may ask for their own. The email is written in the first language of Accept-Language that has templates.
+// @Tags account
+// @Produce json
+// @Param id path int true "User ID"
+// @Param Accept-Language header string false "Preferred languages, e.g. de-DE,de;q=0.9"
+// @Success 202
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/verification [post]
+func (h *AccountHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = h.useCases.Account.SendVerification(r.Context(), id, r.Header.Get("Accept-Language"))
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, accountUC.ErrAlreadyVerified):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// VerifyEmail godoc
+// @Summary Confirm an email address
+// @Description Redeem the token of a verification email. Each token works once. Invalid tokens count towards a lockout of the user they name and of the client IP.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param token body TokenRequest true "Verification token"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request body: token is required")
+		return
+	}
+
+	user, err := h.useCases.Account.VerifyEmail(r.Context(), req.Token)
+	var throttled *lockoutUC.ThrottledError
+	if errors.Is(err, accountUC.ErrInvalidToken) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.As(err, &throttled) {
+		writeThrottled(w, throttled)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// RequestPasswordReset godoc
+// @Summary Email a password reset link
+// @Description Answers 202 at once, whether or not an account has the email; the mail is sent in the background. Every request counts towards a lockout of the email and of the client IP, so repeated requests get 429, for unknown emails too.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetRequest true "Email"
+// @Param Accept-Language header string false "Preferred languages, e.g. de-DE,de;q=0.9"
+// @Success 202
+// @Failure 400 {object} ValidationErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password-reset [post]
+func (h *AccountHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		writeValidationErrors(w, err)
+		return
+	}
+
+	err := h.useCases.Account.QueuePasswordReset(r.Context(), req.Email, r.Header.Get("Accept-Language"))
+	var throttled *lockoutUC.ThrottledError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Set a new password with a reset token
+// @Description The token works once and also confirms the email it was sent to. Every session and refresh token of the user ends. Invalid tokens count towards a lockout of the user they name and of the client IP.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body ConfirmPasswordResetRequest true "Token and new password"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password-reset/confirm [post]
+func (h *AccountHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request body: token is required")
+		return
+	}
+
+	err := h.useCases.Account.ResetPassword(r.Context(), req.Token, req.Password)
+	var validationErrors validator.ValidationErrors
+	var throttled *lockoutUC.ThrottledError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.As(err, &validationErrors):
+		writeValidationErrors(w, validationErrors)
+	case errors.Is(err, accountUC.ErrInvalidToken):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
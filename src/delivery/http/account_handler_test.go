@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	lockoutUC "solecode/src/usecase/lockout"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lastToken returns the token in the link to path of the last mail sent,
+// waiting for mail sent in the background.
+func (s *testServer) lastToken(t *testing.T, path string) string {
+	require.Eventually(t, func() bool { return len(s.mailer.Messages()) > 0 }, time.Second, 10*time.Millisecond)
+	messages := s.mailer.Messages()
+	msg := messages[len(messages)-1]
+	for _, field := range strings.Fields(msg.Text) {
+		if strings.Contains(field, path+"?token=") {
+			link, err := url.Parse(field)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no %s link in %q", path, msg.Text)
+	return ""
+}
+
+func TestPasswordResetEndsSignIns(t *testing.T) {
+	server := newTestServer(t)
+	anonymous := &bearer{t: t, base: server.URL}
+
+	var tokens TokenResponse
+	resp := anonymous.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "john@example.com", Password: "Secret123!"}, &tokens)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	john := newBrowser(t, server.URL)
+	resp = john.do(http.MethodPost, "/api/v1/auth/session", LoginRequest{Email: "john@example.com", Password: "Secret123!"}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp = john.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = anonymous.do(http.MethodPost, "/api/v1/auth/password-reset", PasswordResetRequest{Email: "john@example.com"}, nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	reset := ConfirmPasswordResetRequest{Token: server.lastToken(t, "/reset-password"), Password: "NewSecret123!"}
+	resp = anonymous.do(http.MethodPost, "/api/v1/auth/password-reset/confirm", reset, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Whoever had the old password loses what it got them
+	resp = anonymous.do(http.MethodPost, "/api/v1/auth/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken}, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = john.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = anonymous.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "john@example.com", Password: "NewSecret123!"}, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestPasswordResetRequestsAreThrottled(t *testing.T) {
+	server := newTestServer(t)
+	anonymous := &bearer{t: t, base: server.URL}
+
+	for _, email := range []string{"john@example.com", "nobody@example.com"} {
+		for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+			resp := anonymous.do(http.MethodPost, "/api/v1/auth/password-reset", PasswordResetRequest{Email: email}, nil)
+			require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		}
+		resp := anonymous.do(http.MethodPost, "/api/v1/auth/password-reset", PasswordResetRequest{Email: email}, nil)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	}
+}
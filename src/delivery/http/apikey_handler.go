@@ -0,0 +1,204 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	apiKeyRepository "solecode/src/repository/apikey"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+	apiKeyUC "solecode/src/usecase/apikey"
+	authzUC "solecode/src/usecase/authz"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler handles HTTP requests for API keys
+type APIKeyHandler struct {
+	useCases uc.UseCases
+}
+
+func NewAPIKeyHandler(useCases uc.UseCases) *APIKeyHandler {
+	return &APIKeyHandler{useCases: useCases}
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+// @Description The key acts as user_id, limited to permissions, until expires_at if set
+type CreateAPIKeyRequest struct {
+	UserID      int64      `json:"user_id" example:"1"`
+	Name        string     `json:"name" example:"deploy"`
+	Permissions []string   `json:"permissions" example:"users:read"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse represents an API key without its secret
+// @Description An API key; the key itself is only returned on creation
+type APIKeyResponse struct {
+	ID          int64    `json:"id" example:"1"`
+	UserID      int64    `json:"user_id" example:"1"`
+	Name        string   `json:"name" example:"deploy"`
+	Prefix      string   `json:"prefix" example:"uapi_3f9a1c07"`
+	Permissions []string `json:"permissions" example:"users:read"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	RevokedAt   string   `json:"revoked_at,omitempty"`
+	LastUsedAt  string   `json:"last_used_at,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is a new API key
+// @Description key goes in the Authorization header as "ApiKey <key>" or "Bearer <key>". It cannot be shown again.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"uapi_3f9a1c07_4Jx0..."`
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Issue a key for a user. Its permissions are those of the user, narrowed to the listed ones.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body CreateAPIKeyRequest true "API key"
+// @Success 201 {object} CreatedAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid request body: user_id is required")
+		return
+	}
+	permissions := make([]entities.Permission, len(req.Permissions))
+	for i, permission := range req.Permissions {
+		permissions[i] = entities.Permission(permission)
+	}
+
+	key, raw, err := h.useCases.APIKey.Create(r.Context(), req.UserID, req.Name, permissions, req.ExpiresAt)
+	var validationErrors validator.ValidationErrors
+	switch {
+	case err == nil:
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusCreated, CreatedAPIKeyResponse{APIKeyResponse: toAPIKeyResponse(key), Key: raw})
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.As(err, &validationErrors):
+		writeValidationErrors(w, validationErrors)
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, authzUC.ErrUnknownPermission), errors.Is(err, apiKeyUC.ErrNoPermissions), errors.Is(err, apiKeyUC.ErrExpiryInPast):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Revoked and expired keys are included.
+// @Tags api-keys
+// @Produce json
+// @Param user_id query int false "Only the keys of this user"
+// @Success 200 {array} APIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var userID int64
+	if s := r.URL.Query().Get("user_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		userID = id
+	}
+
+	keys, err := h.useCases.APIKey.List(r.Context(), userID)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		resp[i] = toAPIKeyResponse(key)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoking a revoked key succeeds and keeps the first revocation time.
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	err = h.useCases.APIKey.Revoke(r.Context(), id)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, apiKeyRepository.ErrAPIKeyNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func toAPIKeyResponse(key *entities.APIKey) APIKeyResponse {
+	permissions := make([]string, len(key.Permissions))
+	for i, permission := range key.Permissions {
+		permissions[i] = string(permission)
+	}
+	return APIKeyResponse{
+		ID:          key.ID,
+		UserID:      key.UserID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: permissions,
+		ExpiresAt:   formatOptionalTime(key.ExpiresAt),
+		RevokedAt:   formatOptionalTime(key.RevokedAt),
+		LastUsedAt:  formatOptionalTime(key.LastUsedAt),
+		CreatedAt:   key.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// formatOptionalTime formats t, leaving unset times empty.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,314 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+	apiKeyUC "solecode/src/usecase/apikey"
+	authUC "solecode/src/usecase/auth"
+	lockoutUC "solecode/src/usecase/lockout"
+	twoFactorUC "solecode/src/usecase/twofactor"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultSessionCookie names the session cookie when AuthHandlerOptions
+// leaves it unset.
+const defaultSessionCookie = "session"
+
+// AuthHandler handles logins, token refreshes, cookie sessions and the
+// authentication of requests
+type AuthHandler struct {
+	useCases uc.UseCases
+	cookies  AuthHandlerOptions
+}
+
+// AuthHandlerOptions sets the attributes of session cookies.
+type AuthHandlerOptions struct {
+	// SessionCookie names the cookie holding the session token; the
+	// cookie holding the CSRF token gets a "_csrf" suffix. Empty means
+	// "session".
+	SessionCookie string
+	CookieDomain  string
+	// InsecureCookies drops the Secure attribute, for plain HTTP during
+	// development.
+	InsecureCookies bool
+	// SameSite defaults to Lax.
+	SameSite http.SameSite
+}
+
+func NewAuthHandler(useCases uc.UseCases, opts AuthHandlerOptions) *AuthHandler {
+	if opts.SessionCookie == "" {
+		opts.SessionCookie = defaultSessionCookie
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	return &AuthHandler{useCases: useCases, cookies: opts}
+}
+
+// LoginRequest is the body of a login
+// @Description Email and password of the user
+type LoginRequest struct {
+	Email    string `json:"email" example:"john@example.com"`
+	Password string `json:"password" example:"Secret123!"`
+}
+
+// RefreshRequest carries a refresh token
+// @Description Refresh token from a login or an earlier refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is an issued token pair
+// @Description access_token goes in the Authorization header as "Bearer <token>"; refresh_token renews it once
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type" example:"Bearer"`
+	ExpiresIn        int    `json:"expires_in" example:"900"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in" example:"2592000"`
+}
+
+// Login godoc
+// @Summary Log in with email and password
+// @Description Issue a short-lived access token and a refresh token. Unknown emails and wrong passwords get the same 401.
+// @Description Repeated failures for an email or from a client IP get 429 with a Retry-After header, whether or not the email exists.
+// @Description Users with two-factor authentication get 401 with a challenge instead, to complete at /auth/login/totp.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Credentials"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} SecondFactorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pair, err := h.useCases.Auth.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+	writeTokens(w, pair)
+}
+
+// Refresh godoc
+// @Summary Exchange a refresh token for a new token pair
+// @Description The refresh token is single use; the new one expires when the old one would have.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pair, err := h.useCases.Auth.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, authUC.ErrInvalidToken) {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeTokens(w, pair)
+}
+
+// Logout godoc
+// @Summary Revoke a refresh token
+// @Description Access tokens stay valid until they expire.
+// @Tags auth
+// @Accept json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.useCases.Auth.Logout(r.Context(), req.RefreshToken); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me godoc
+// @Summary Show the authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} UserResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal, _ := entities.PrincipalFrom(r.Context())
+	user, err := h.useCases.User.GetUser(r.Context(), principal.UserID)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// JWKS serves the public keys that verify access tokens at
+// /.well-known/jwks.json.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, h.useCases.Auth.JWKS())
+}
+
+// RequireAuth rejects requests without a valid bearer access token, API
+// key or session cookie and puts the caller's principal into the request
+// context. API keys come as "ApiKey <key>" or, for clients that only speak
+// bearer tokens, as "Bearer <key>". The cookie only counts for requests
+// without an Authorization header.
+func (h *AuthHandler) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(h.cookies.SessionCookie); err == nil && r.Header.Get("Authorization") == "" {
+			h.serveSession(w, r, cookie.Value, next)
+			return
+		}
+
+		scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		credentials = strings.TrimSpace(credentials)
+		isBearer, isAPIKey := strings.EqualFold(scheme, "Bearer"), strings.EqualFold(scheme, "ApiKey")
+		if !(isBearer || isAPIKey) || credentials == "" {
+			writeUnauthorized(w, "missing bearer token")
+			return
+		}
+
+		var principal *entities.Principal
+		var err error
+		if isAPIKey || apiKeyUC.IsKey(credentials) {
+			principal, err = h.useCases.APIKey.Authenticate(r.Context(), credentials)
+		} else {
+			principal, err = h.useCases.Auth.Authenticate(r.Context(), credentials)
+		}
+		switch {
+		case errors.Is(err, authUC.ErrInvalidToken):
+			writeUnauthorized(w, authUC.ErrInvalidToken.Error())
+			return
+		case errors.Is(err, apiKeyUC.ErrInvalidAPIKey):
+			writeUnauthorized(w, apiKeyUC.ErrInvalidAPIKey.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if principal.Impersonated() {
+			h.serveImpersonated(w, r, principal, next)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(entities.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Require lets requests reach handler only when their principal holds one
+// of permissions. It runs after RequireAuth; the use cases check again, so
+// it is the first line of defence rather than the only one.
+func (h *AuthHandler) Require(handler http.HandlerFunc, permissions ...entities.Permission) http.Handler {
+	return h.RequireSelfOr("", handler, permissions...)
+}
+
+// RequireSelfOr is Require for routes that also let users act on their own
+// account: ownerVar names the route variable holding the target user ID.
+func (h *AuthHandler) RequireSelfOr(ownerVar string, handler http.HandlerFunc, permissions ...entities.Permission) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ownerID int64
+		if ownerVar != "" {
+			// A malformed ID matches nobody; the handler rejects it if
+			// the request gets that far
+			ownerID, _ = strconv.ParseInt(mux.Vars(r)[ownerVar], 10, 64)
+		}
+		if err := h.useCases.Authz.Authorize(r.Context(), ownerID, permissions...); err != nil {
+			writeForbidden(w, r, err)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// writeLoginError answers the ways a login or its second factor fails.
+func writeLoginError(w http.ResponseWriter, err error) {
+	var required *authUC.SecondFactorRequiredError
+	var throttled *lockoutUC.ThrottledError
+	switch {
+	case errors.Is(err, userUC.ErrInvalidCredentials), errors.Is(err, authUC.ErrInvalidToken),
+		errors.Is(err, twoFactorUC.ErrInvalidCode):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.As(err, &required):
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusUnauthorized, SecondFactorResponse{
+			Error:     required.Error(),
+			Challenge: required.Challenge,
+			ExpiresIn: int(time.Until(required.ExpiresAt).Round(time.Second).Seconds()),
+		})
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeThrottled tells a client how many whole seconds to wait.
+func writeThrottled(w http.ResponseWriter, err *lockoutUC.ThrottledError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, lockoutUC.ErrThrottled.Error())
+}
+
+func writeTokens(w http.ResponseWriter, pair *authUC.TokenPair) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken:      pair.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(time.Until(pair.AccessExpiresAt).Round(time.Second).Seconds()),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: int(time.Until(pair.RefreshExpiresAt).Round(time.Second).Seconds()),
+	})
+}
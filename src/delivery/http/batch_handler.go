@@ -0,0 +1,145 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"solecode/pkg/validator"
+	userRepository "solecode/src/repository/user"
+	userUC "solecode/src/usecase/user"
+)
+
+// maxBatchBodyBytes caps the body of a batch request.
+const maxBatchBodyBytes = 32 << 20
+
+// BatchRequest is the body of a batch request
+// @Description Operations run in order. With atomic set they all succeed or none is applied.
+type BatchRequest struct {
+	Atomic     bool                    `json:"atomic" example:"false"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest is one operation of a batch
+// @Description create needs name and email, update needs id, name and email, delete needs id
+type BatchOperationRequest struct {
+	Op    string `json:"op" example:"create" enums:"create,update,delete"`
+	ID    int64  `json:"id,omitempty" example:"1"`
+	Name  string `json:"name,omitempty" example:"John Doe"`
+	Email string `json:"email,omitempty" example:"john@example.com"`
+}
+
+// BatchResponse carries one result per operation, in request order
+// @Description Per-operation results
+type BatchResponse struct {
+	Atomic    bool                  `json:"atomic"`
+	Succeeded int                   `json:"succeeded" example:"2"`
+	Failed    int                   `json:"failed" example:"1"`
+	Results   []BatchResultResponse `json:"results"`
+}
+
+// BatchResultResponse is the outcome of one operation
+// @Description status is the HTTP status the operation would have had on its own; 424 marks operations undone because an atomic batch failed
+type BatchResultResponse struct {
+	Index   int                         `json:"index" example:"0"`
+	Op      string                      `json:"op" example:"create"`
+	Status  int                         `json:"status" example:"201"`
+	User    *UserResponse               `json:"user,omitempty"`
+	Error   string                      `json:"error,omitempty"`
+	Details []validator.ValidationError `json:"details,omitempty"`
+}
+
+// BatchUsers godoc
+// @Summary Create, update and delete users in bulk
+// @Description Run up to the configured maximum of operations (default 1000) in one request. Each operation gets its own result.
+// @Description The response is 200 when every operation succeeded and 207 otherwise.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param batch body BatchRequest true "Operations"
+// @Success 200 {object} BatchResponse
+// @Success 207 {object} BatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
+// @Router /users/batch [post]
+func (h *UserHandler) BatchUsers(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, "operations must not be empty")
+		return
+	}
+	if len(req.Operations) > h.batchMax {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d operations per batch", h.batchMax))
+		return
+	}
+
+	ops := make([]userUC.BatchOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = userUC.BatchOperation{Op: op.Op, ID: op.ID, Name: op.Name, Email: op.Email}
+	}
+
+	results, err := h.userUseCase.User.BatchUsers(r.Context(), ops, req.Atomic)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := BatchResponse{Atomic: req.Atomic, Results: make([]BatchResultResponse, len(results))}
+	for i, result := range results {
+		item := BatchResultResponse{Index: i, Op: ops[i].Op}
+		if result.Err != nil {
+			resp.Failed++
+			item.Status = batchErrorStatus(result.Err)
+			item.Error = result.Err.Error()
+			var validationErrors validator.ValidationErrors
+			if errors.As(result.Err, &validationErrors) {
+				item.Details = validationErrors
+			}
+		} else {
+			resp.Succeeded++
+			item.Status = map[string]int{
+				userUC.BatchCreate: http.StatusCreated,
+				userUC.BatchUpdate: http.StatusOK,
+				userUC.BatchDelete: http.StatusNoContent,
+			}[ops[i].Op]
+			if result.User != nil {
+				user := toUserResponse(result.User)
+				item.User = &user
+			}
+		}
+		resp.Results[i] = item
+	}
+
+	status := http.StatusOK
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeJSON(w, status, resp)
+}
+
+func batchErrorStatus(err error) int {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, userUC.ErrBatchRolledBack):
+		return http.StatusFailedDependency
+	case isForbidden(err):
+		return http.StatusForbidden
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, userRepository.ErrEmailExists):
+		return http.StatusConflict
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest
+	default:
+		// Everything else the use case reports per operation is bad input
+		return http.StatusBadRequest
+	}
+}
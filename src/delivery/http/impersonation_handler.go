@@ -0,0 +1,278 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	impersonationRepository "solecode/src/repository/impersonation"
+	userRepository "solecode/src/repository/user"
+	impersonationUC "solecode/src/usecase/impersonation"
+
+	"github.com/gorilla/mux"
+)
+
+// impersonatedHeader names the admin behind an impersonated request in its
+// response.
+const impersonatedHeader = "X-Impersonated-By"
+
+// StartImpersonationRequest is the body of an impersonation
+// @Description The user to act as and why, such as a support ticket
+type StartImpersonationRequest struct {
+	UserID int64  `json:"user_id" example:"2"`
+	Reason string `json:"reason" example:"ticket 1234: cannot see invoices"`
+}
+
+// ImpersonationResponse is an impersonation without its token
+// @Description admin_id acts as user_id from started_at until ended_at or expires_at, whichever comes first
+type ImpersonationResponse struct {
+	ID        string `json:"id"`
+	AdminID   int64  `json:"admin_id" example:"1"`
+	UserID    int64  `json:"user_id" example:"2"`
+	Reason    string `json:"reason" example:"ticket 1234: cannot see invoices"`
+	StartedAt string `json:"started_at" example:"2026-10-18T13:00:00Z"`
+	ExpiresAt string `json:"expires_at" example:"2026-10-18T13:30:00Z"`
+	EndedAt   string `json:"ended_at,omitempty"`
+}
+
+// StartedImpersonationResponse is a started impersonation
+// @Description access_token acts as the user until it expires or the impersonation is stopped; there is no refresh token
+type StartedImpersonationResponse struct {
+	ImpersonationResponse
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"1800"`
+}
+
+// ImpersonationEventResponse is one entry of the impersonation log
+// @Description action is start, stop or request; requests carry their method, path and response status
+type ImpersonationEventResponse struct {
+	ID        int64  `json:"id" example:"1"`
+	Action    string `json:"action" example:"request"`
+	Method    string `json:"method,omitempty" example:"PUT"`
+	Path      string `json:"path,omitempty" example:"/api/v1/users/2"`
+	Status    int    `json:"status,omitempty" example:"200"`
+	CreatedAt string `json:"created_at" example:"2026-10-18T13:05:00Z"`
+}
+
+// serveImpersonated serves a request made with an impersonation token. Its
+// response and the server log name the admin behind it, its audit events
+// carry the admin, and every request that may change state is recorded in
+// the impersonation log before it runs; one that cannot be recorded does
+// not run.
+func (h *AuthHandler) serveImpersonated(w http.ResponseWriter, r *http.Request, principal *entities.Principal, next http.Handler) {
+	ctx := audit.WithImpersonator(entities.WithPrincipal(r.Context(), principal), principal.ImpersonatorID)
+	r = r.WithContext(ctx)
+	w.Header().Set(impersonatedHeader, strconv.FormatInt(principal.ImpersonatorID, 10))
+	log.Printf("impersonated request: admin %d as user %d: %s %s", principal.ImpersonatorID, principal.UserID, r.Method, r.URL.Path)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	event, err := h.useCases.Impersonation.RecordRequest(ctx, r.Method, r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(sw, r)
+	if err := h.useCases.Impersonation.RecordResponse(ctx, event, sw.status); err != nil {
+		log.Printf("recording impersonated response failed: %v", err)
+	}
+}
+
+// statusWriter remembers the status of the response it writes.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// StartImpersonation godoc
+// @Summary Impersonate a user
+// @Description Issue an access token that acts as the user on behalf of the caller, to reproduce what the user sees. Responses to it carry an X-Impersonated-By header naming the admin.
+// @Description It cannot delete the user, change their password, email or two-factor settings, or manage roles, API keys and clients. Users holding permissions the admin lacks cannot be impersonated.
+// @Tags impersonations
+// @Accept json
+// @Produce json
+// @Param impersonation body StartImpersonationRequest true "Impersonation"
+// @Success 201 {object} StartedImpersonationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /impersonations [post]
+func (h *AuthHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	var req StartImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid request body: user_id is required")
+		return
+	}
+
+	impersonation, err := h.useCases.Impersonation.Start(r.Context(), req.UserID, req.Reason)
+	var validationErrors validator.ValidationErrors
+	switch {
+	case err == nil:
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusCreated, StartedImpersonationResponse{
+			ImpersonationResponse: toImpersonationResponse(impersonation),
+			AccessToken:           impersonation.Token,
+			TokenType:             "Bearer",
+			ExpiresIn:             int(time.Until(impersonation.ExpiresAt).Round(time.Second).Seconds()),
+		})
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, impersonationUC.ErrSelfImpersonation), errors.Is(err, impersonationUC.ErrPrivilegedTarget):
+		writeProblem(w, r, http.StatusForbidden, err.Error())
+	case errors.As(err, &validationErrors):
+		writeValidationErrors(w, validationErrors)
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListImpersonations godoc
+// @Summary List impersonations
+// @Description Newest first, including stopped and expired ones.
+// @Tags impersonations
+// @Produce json
+// @Param admin_id query int false "Only the impersonations by this admin"
+// @Param user_id query int false "Only the impersonations of this user"
+// @Success 200 {array} ImpersonationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /impersonations [get]
+func (h *AuthHandler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	var ids [2]int64
+	for i, name := range []string{"admin_id", "user_id"} {
+		if s := r.URL.Query().Get(name); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || id <= 0 {
+				writeError(w, http.StatusBadRequest, "Invalid user ID")
+				return
+			}
+			ids[i] = id
+		}
+	}
+
+	impersonations, err := h.useCases.Impersonation.List(r.Context(), ids[0], ids[1])
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]ImpersonationResponse, len(impersonations))
+	for i, impersonation := range impersonations {
+		resp[i] = toImpersonationResponse(impersonation)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetImpersonationEvents godoc
+// @Summary Show the log of an impersonation
+// @Description Its start, stop and every request but GET, HEAD and OPTIONS made with it, oldest first.
+// @Tags impersonations
+// @Produce json
+// @Param id path string true "Impersonation ID"
+// @Success 200 {array} ImpersonationEventResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /impersonations/{id}/events [get]
+func (h *AuthHandler) GetImpersonationEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.useCases.Impersonation.Events(r.Context(), mux.Vars(r)["id"])
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if errors.Is(err, impersonationRepository.ErrImpersonationNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]ImpersonationEventResponse, len(events))
+	for i, event := range events {
+		resp[i] = ImpersonationEventResponse{
+			ID:        event.ID,
+			Action:    event.Action,
+			Method:    event.Method,
+			Path:      event.Path,
+			Status:    event.Status,
+			CreatedAt: event.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// StopImpersonation godoc
+// @Summary Stop an impersonation
+// @Description Its token stops working at once. The impersonation token itself may stop it; others need users:impersonate. Stopping a stopped or expired impersonation succeeds.
+// @Tags impersonations
+// @Param id path string true "Impersonation ID"
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /impersonations/{id} [delete]
+func (h *AuthHandler) StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	err := h.useCases.Impersonation.Stop(r.Context(), mux.Vars(r)["id"])
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, impersonationRepository.ErrImpersonationNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func toImpersonationResponse(impersonation *entities.Impersonation) ImpersonationResponse {
+	return ImpersonationResponse{
+		ID:        impersonation.ID,
+		AdminID:   impersonation.AdminID,
+		UserID:    impersonation.UserID,
+		Reason:    impersonation.Reason,
+		StartedAt: impersonation.StartedAt.UTC().Format(time.RFC3339),
+		ExpiresAt: impersonation.ExpiresAt.UTC().Format(time.RFC3339),
+		EndedAt:   formatOptionalTime(impersonation.EndedAt),
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bearer is an API client with an access token.
+type bearer struct {
+	t     *testing.T
+	base  string
+	token string
+}
+
+func (b *bearer) do(method, path string, body interface{}, out interface{}) *http.Response {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(b.t, err)
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, b.base+path, reader)
+	require.NoError(b.t, err)
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(b.t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(b.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func TestImpersonation(t *testing.T) {
+	server := newTestServer(t)
+
+	var tokens TokenResponse
+	anonymous := &bearer{t: t, base: server.URL}
+	resp := anonymous.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "john@example.com", Password: "Secret123!"}, &tokens)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	admin := &bearer{t: t, base: server.URL, token: tokens.AccessToken}
+
+	var jane UserResponse
+	resp = admin.do(http.MethodPost, "/api/v1/users", CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com"}, &jane)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = admin.do(http.MethodPost, "/api/v1/impersonations", StartImpersonationRequest{UserID: jane.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var started StartedImpersonationResponse
+	resp = admin.do(http.MethodPost, "/api/v1/impersonations", StartImpersonationRequest{UserID: jane.ID, Reason: "ticket 1234"}, &started)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int64(1), started.AdminID)
+	assert.Equal(t, "Bearer", started.TokenType)
+
+	// The token acts as Jane, and its responses name the admin
+	impersonating := &bearer{t: t, base: server.URL, token: started.AccessToken}
+	var me UserResponse
+	resp = impersonating.do(http.MethodGet, "/api/v1/auth/me", nil, &me)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "jane@example.com", me.Email)
+	assert.Equal(t, "1", resp.Header.Get(impersonatedHeader))
+	resp = admin.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	assert.Empty(t, resp.Header.Get(impersonatedHeader))
+
+	// Dangerous actions are refused, everything that may change state is
+	// logged with its outcome
+	resp = impersonating.do(http.MethodPut, "/api/v1/users/2", CreateUserRequest{Name: "Jane Smith", Email: "jane@example.com"}, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = impersonating.do(http.MethodPut, "/api/v1/users/2/password", ChangePasswordRequest{NewPassword: "Secret123!"}, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = impersonating.do(http.MethodDelete, "/api/v1/users/2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = impersonating.do(http.MethodGet, "/api/v1/impersonations", nil, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// The impersonation stops itself, and its token with it
+	resp = impersonating.do(http.MethodDelete, "/api/v1/impersonations/"+started.ID, nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = impersonating.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var events []ImpersonationEventResponse
+	resp = admin.do(http.MethodGet, "/api/v1/impersonations/"+started.ID+"/events", nil, &events)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var got []ImpersonationEventResponse
+	for _, event := range events {
+		got = append(got, ImpersonationEventResponse{Action: event.Action, Method: event.Method, Path: event.Path, Status: event.Status})
+	}
+	assert.Equal(t, []ImpersonationEventResponse{
+		{Action: "start"},
+		{Action: "request", Method: "PUT", Path: "/api/v1/users/2", Status: http.StatusOK},
+		{Action: "request", Method: "PUT", Path: "/api/v1/users/2/password", Status: http.StatusForbidden},
+		{Action: "request", Method: "DELETE", Path: "/api/v1/users/2", Status: http.StatusForbidden},
+		{Action: "request", Method: "DELETE", Path: "/api/v1/impersonations/" + started.ID, Status: http.StatusNoContent},
+		{Action: "stop"},
+	}, got)
+
+	var impersonations []ImpersonationResponse
+	resp = admin.do(http.MethodGet, "/api/v1/impersonations?user_id=2", nil, &impersonations)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, impersonations, 1)
+	assert.Equal(t, "ticket 1234", impersonations[0].Reason)
+	assert.NotEmpty(t, impersonations[0].EndedAt)
+	resp = admin.do(http.MethodGet, "/api/v1/impersonations/unknown/events", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	userRepository "solecode/src/repository/user"
+
+	"github.com/gorilla/mux"
+)
+
+// LockoutResponse is the login lockout state of a user
+// @Description failures counts the failed logins within the window; retry_after is how many seconds the next login has to wait
+type LockoutResponse struct {
+	Failures    int    `json:"failures" example:"3"`
+	Locked      bool   `json:"locked" example:"false"`
+	LockedUntil string `json:"locked_until,omitempty"`
+	RetryAfter  int    `json:"retry_after" example:"1"`
+}
+
+// GetLockout godoc
+// @Summary Show a user's login lockout
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} LockoutResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/lockout [get]
+func (h *AuthHandler) GetLockout(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	status, err := h.useCases.Lockout.Status(r.Context(), id)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := LockoutResponse{
+		Failures:   status.Failures,
+		Locked:     !status.LockedUntil.IsZero(),
+		RetryAfter: int(math.Ceil(status.RetryAfter.Seconds())),
+	}
+	if resp.Locked {
+		resp.LockedUntil = status.LockedUntil.UTC().Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UnlockUser godoc
+// @Summary Lift a user's login lockout
+// @Description Clear the failed logins and any lockout of the user's email. Lockouts of client IPs stay; lift those with the CLI.
+// @Tags users
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/lockout [delete]
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = h.useCases.Lockout.Unlock(r.Context(), id)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
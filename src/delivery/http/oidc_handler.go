@@ -0,0 +1,634 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	oauthRepository "solecode/src/repository/oauth"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+	oidcUC "solecode/src/usecase/oidc"
+
+	"github.com/gorilla/mux"
+)
+
+// OIDCHandler serves the OpenID Connect provider: discovery, the
+// authorisation, token and userinfo endpoints, and the management of
+// clients and consents.
+type OIDCHandler struct {
+	useCases       uc.UseCases
+	auth           *AuthHandler
+	interactionURL string
+}
+
+// OIDCHandlerOptions sets where users are sent to sign in and consent.
+type OIDCHandlerOptions struct {
+	// InteractionURL is the page that signs users in with a cookie
+	// session and asks for consent; authorisation requests that need the
+	// user are redirected there with their query. Without it they get 401
+	// or a JSON description of the consent to ask for.
+	InteractionURL string
+}
+
+func NewOIDCHandler(useCases uc.UseCases, auth *AuthHandler, opts OIDCHandlerOptions) *OIDCHandler {
+	return &OIDCHandler{useCases: useCases, auth: auth, interactionURL: opts.InteractionURL}
+}
+
+// DiscoveryResponse is the OpenID Provider metadata
+// @Description Served at /.well-known/openid-configuration
+type DiscoveryResponse struct {
+	Issuer                            string   `json:"issuer" example:"https://id.example.com"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint" example:"https://id.example.com/api/v1/oauth/authorize"`
+	TokenEndpoint                     string   `json:"token_endpoint" example:"https://id.example.com/api/v1/oauth/token"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint" example:"https://id.example.com/api/v1/oauth/userinfo"`
+	JWKSURI                           string   `json:"jwks_uri" example:"https://id.example.com/.well-known/jwks.json"`
+	ResponseTypesSupported            []string `json:"response_types_supported" example:"code"`
+	GrantTypesSupported               []string `json:"grant_types_supported" example:"authorization_code"`
+	SubjectTypesSupported             []string `json:"subject_types_supported" example:"public"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported" example:"EdDSA"`
+	ScopesSupported                   []string `json:"scopes_supported" example:"openid"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported" example:"client_secret_basic"`
+	ClaimsSupported                   []string `json:"claims_supported" example:"sub"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported" example:"S256"`
+}
+
+// AuthorizeRequest is an authorisation request answered by the sign-in page
+// @Description The query of the authorisation request; approve answers the consent prompt, and leaving it out only checks the request
+type AuthorizeRequest struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri" example:"https://app.example.com/callback"`
+	ResponseType        string `json:"response_type" example:"code"`
+	Scope               string `json:"scope" example:"openid profile email"`
+	State               string `json:"state,omitempty"`
+	Nonce               string `json:"nonce,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" example:"S256"`
+	Approve             *bool  `json:"approve,omitempty" example:"true"`
+}
+
+// AuthorizationResponse is the outcome of an authorisation request
+// @Description Either consent_required with the scopes to ask about, or redirect_to, the client's redirect URI with a code or an error
+type AuthorizationResponse struct {
+	ClientID        string   `json:"client_id"`
+	ClientName      string   `json:"client_name" example:"Wiki"`
+	Scopes          []string `json:"scopes" example:"openid,email"`
+	ConsentRequired bool     `json:"consent_required" example:"true"`
+	RedirectTo      string   `json:"redirect_to,omitempty" example:"https://app.example.com/callback?code=...&state=xyz"`
+}
+
+// OAuthTokenResponse is what a code is redeemed for
+// @Description id_token is a JWT about the user for the client; access_token opens the userinfo endpoint
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"900"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope" example:"openid email"`
+}
+
+// OAuthErrorResponse is an error of the token endpoint
+// @Description As RFC 6749 section 5.2 defines it
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty" example:"invalid or expired code"`
+}
+
+// UserInfoResponse holds the claims of the user a token was issued for
+// @Description The scopes of the token decide which claims are present
+type UserInfoResponse struct {
+	Subject       string `json:"sub" example:"1"`
+	Name          string `json:"name,omitempty" example:"John Doe"`
+	UpdatedAt     int64  `json:"updated_at,omitempty" example:"1792328400"`
+	Email         string `json:"email,omitempty" example:"john@example.com"`
+	EmailVerified *bool  `json:"email_verified,omitempty" example:"true"`
+}
+
+// CreateClientRequest registers an OAuth client
+// @Description Public clients, such as single-page and native apps, get no secret and rely on PKCE alone
+type CreateClientRequest struct {
+	Name         string   `json:"name" example:"Wiki"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://wiki.example.com/callback"`
+	Public       bool     `json:"public" example:"false"`
+}
+
+// ClientResponse is an OAuth client
+// @Description client_secret is only returned on registration of confidential clients
+type ClientResponse struct {
+	ID           string   `json:"client_id"`
+	Name         string   `json:"name" example:"Wiki"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://wiki.example.com/callback"`
+	Public       bool     `json:"public" example:"false"`
+	CreatedAt    string   `json:"created_at" example:"2026-10-18T13:00:00Z"`
+	Secret       string   `json:"client_secret,omitempty"`
+}
+
+// ConsentResponse is the consent of a user to a client
+// @Description The client receives the claims of scopes without asking again
+type ConsentResponse struct {
+	ClientID  string   `json:"client_id"`
+	Scopes    []string `json:"scopes" example:"openid,email"`
+	GrantedAt string   `json:"granted_at" example:"2026-10-18T13:00:00Z"`
+}
+
+// Discovery godoc
+// @Summary OpenID Provider metadata
+// @Description Needs auth.issuer to be the external URL of the server and an RS256 or EdDSA signing key, else 503.
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} DiscoveryResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.useCases.OIDC.Configuration()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	base := strings.TrimSuffix(cfg.Issuer, "/")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, DiscoveryResponse{
+		Issuer:                            cfg.Issuer,
+		AuthorizationEndpoint:             base + "/api/v1/oauth/authorize",
+		TokenEndpoint:                     base + "/api/v1/oauth/token",
+		UserinfoEndpoint:                  base + "/api/v1/oauth/userinfo",
+		JWKSURI:                           base + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{cfg.SigningAlgorithm},
+		ScopesSupported:                   cfg.Scopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		ClaimsSupported:                   cfg.Claims,
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	})
+}
+
+// Authorize godoc
+// @Summary Start an OpenID Connect sign-in
+// @Description The authorisation endpoint of the code flow; PKCE with S256 is required. Signed-in users who consented before are redirected to redirect_uri with a code.
+// @Description Requests without a signed-in user, or needing consent, are redirected to the configured interaction page with their query, or get 401 or 200 with the consent to ask for.
+// @Description Invalid requests are redirected back with an error, except for an unknown client or redirect URI.
+// @Tags oauth
+// @Produce json
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "A registered redirect URI"
+// @Param response_type query string true "code"
+// @Param scope query string true "Must include openid"
+// @Param state query string false "Returned to the client"
+// @Param nonce query string false "Put into the ID token"
+// @Param code_challenge query string true "base64url SHA-256 of the code verifier"
+// @Param code_challenge_method query string true "S256"
+// @Success 200 {object} AuthorizationResponse
+// @Success 302
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /oauth/authorize [get]
+func (h *OIDCHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	_, sessionErr := r.Cookie(h.auth.cookies.SessionCookie)
+	if sessionErr != nil && r.Header.Get("Authorization") == "" {
+		if h.interactionURL != "" {
+			http.Redirect(w, r, h.interaction(r.URL.RawQuery), http.StatusFound)
+			return
+		}
+		writeUnauthorized(w, "sign in to continue")
+		return
+	}
+
+	h.auth.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := oidcUC.AuthorizationRequest{
+			ClientID:            query.Get("client_id"),
+			RedirectURI:         query.Get("redirect_uri"),
+			ResponseType:        query.Get("response_type"),
+			Scope:               query.Get("scope"),
+			State:               query.Get("state"),
+			Nonce:               query.Get("nonce"),
+			CodeChallenge:       query.Get("code_challenge"),
+			CodeChallengeMethod: query.Get("code_challenge_method"),
+		}
+
+		authorization, err := h.useCases.OIDC.Authorize(r.Context(), req)
+		var oauthErr *oidcUC.Error
+		switch {
+		case errors.As(err, &oauthErr):
+			http.Redirect(w, r, errorRedirect(req, oauthErr), http.StatusFound)
+		case err != nil:
+			writeOIDCError(w, r, err)
+		case authorization.Code != "":
+			http.Redirect(w, r, codeRedirect(req, authorization.Code), http.StatusFound)
+		case h.interactionURL != "":
+			http.Redirect(w, r, h.interaction(r.URL.RawQuery), http.StatusFound)
+		default:
+			writeJSON(w, http.StatusOK, toAuthorizationResponse(authorization, ""))
+		}
+	})).ServeHTTP(w, r)
+}
+
+// AnswerAuthorization godoc
+// @Summary Answer an OpenID Connect sign-in
+// @Description For the interaction page: checks the authorisation request of the signed-in user or, with approve, records the answer to the consent prompt.
+// @Description The page then sends the browser to redirect_to, carrying a code or an error for the client.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param request body AuthorizeRequest true "Authorisation request"
+// @Success 200 {object} AuthorizationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /oauth/authorize [post]
+func (h *OIDCHandler) AnswerAuthorization(w http.ResponseWriter, r *http.Request) {
+	var body AuthorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req := oidcUC.AuthorizationRequest{
+		ClientID:            body.ClientID,
+		RedirectURI:         body.RedirectURI,
+		ResponseType:        body.ResponseType,
+		Scope:               body.Scope,
+		State:               body.State,
+		Nonce:               body.Nonce,
+		CodeChallenge:       body.CodeChallenge,
+		CodeChallengeMethod: body.CodeChallengeMethod,
+	}
+
+	var authorization *oidcUC.Authorization
+	var err error
+	if body.Approve == nil {
+		authorization, err = h.useCases.OIDC.Authorize(r.Context(), req)
+	} else {
+		authorization, err = h.useCases.OIDC.Consent(r.Context(), req, *body.Approve)
+	}
+	var oauthErr *oidcUC.Error
+	switch {
+	case errors.As(err, &oauthErr):
+		writeJSON(w, http.StatusOK, AuthorizationResponse{ClientID: req.ClientID, Scopes: []string{}, RedirectTo: errorRedirect(req, oauthErr)})
+	case err != nil:
+		writeOIDCError(w, r, err)
+	case authorization.Code != "":
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, toAuthorizationResponse(authorization, codeRedirect(req, authorization.Code)))
+	default:
+		writeJSON(w, http.StatusOK, toAuthorizationResponse(authorization, ""))
+	}
+}
+
+// Token godoc
+// @Summary Redeem an authorisation code
+// @Description The token endpoint of the code flow. Confidential clients authenticate with HTTP Basic or client_secret in the body; public clients send only client_id.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code"
+// @Param code formData string true "Authorisation code"
+// @Param redirect_uri formData string true "The redirect URI of the authorisation request"
+// @Param code_verifier formData string true "PKCE code verifier"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200 {object} OAuthTokenResponse
+// @Failure 400 {object} OAuthErrorResponse
+// @Failure 401 {object} OAuthErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /oauth/token [post]
+func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: oidcUC.CodeInvalidRequest, ErrorDescription: "malformed form body"})
+		return
+	}
+
+	req := oidcUC.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+	// Basic credentials are form-encoded before they are joined, so that
+	// IDs and secrets may contain colons
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		var idErr, secretErr error
+		req.ClientID, idErr = url.QueryUnescape(id)
+		req.ClientSecret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			basic = false
+			req.ClientID = ""
+		}
+	}
+
+	tokens, err := h.useCases.OIDC.Exchange(r.Context(), req)
+	var oauthErr *oidcUC.Error
+	switch {
+	case errors.As(err, &oauthErr):
+		status := http.StatusBadRequest
+		if oauthErr.Code == oidcUC.CodeInvalidClient {
+			status = http.StatusUnauthorized
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+		}
+		writeJSON(w, status, OAuthErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+	case errors.Is(err, oidcUC.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, OAuthTokenResponse{
+			AccessToken: tokens.AccessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(time.Until(tokens.ExpiresAt).Round(time.Second).Seconds()),
+			IDToken:     tokens.IDToken,
+			Scope:       strings.Join(tokens.Scopes, " "),
+		})
+	}
+}
+
+// UserInfo godoc
+// @Summary Show the claims of the signed-in user to a client
+// @Description Takes an access token from the token endpoint, whose scopes decide the claims returned.
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} UserInfoResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /oauth/userinfo [get]
+func (h *OIDCHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	scheme, accessToken, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	accessToken = strings.TrimSpace(accessToken)
+	if !strings.EqualFold(scheme, "Bearer") || accessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+		writeError(w, http.StatusUnauthorized, "missing bearer token")
+		return
+	}
+
+	info, err := h.useCases.OIDC.UserInfo(r.Context(), accessToken)
+	switch {
+	case errors.Is(err, oidcUC.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth", error="invalid_token"`)
+		writeError(w, http.StatusUnauthorized, oidcUC.ErrInvalidToken.Error())
+	case errors.Is(err, oidcUC.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, UserInfoResponse{
+			Subject:       info.Subject,
+			Name:          info.Name,
+			UpdatedAt:     info.UpdatedAt,
+			Email:         info.Email,
+			EmailVerified: info.EmailVerified,
+		})
+	}
+}
+
+// CreateClient godoc
+// @Summary Register an OAuth client
+// @Description Redirect URIs must be absolute https URLs, or http on a loopback host, without a fragment. The secret of a confidential client cannot be shown again.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param client body CreateClientRequest true "Client"
+// @Success 201 {object} ClientResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /oauth/clients [post]
+func (h *OIDCHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req CreateClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	client, secret, err := h.useCases.OIDC.RegisterClient(r.Context(), req.Name, req.RedirectURIs, req.Public)
+	var validationErrors validator.ValidationErrors
+	switch {
+	case err == nil:
+		resp := toClientResponse(client)
+		resp.Secret = secret
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusCreated, resp)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.As(err, &validationErrors):
+		writeValidationErrors(w, validationErrors)
+	case errors.Is(err, oidcUC.ErrInvalidRedirectURI), errors.Is(err, oidcUC.ErrNoRedirectURIs):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListClients godoc
+// @Summary List OAuth clients
+// @Tags oauth
+// @Produce json
+// @Success 200 {array} ClientResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /oauth/clients [get]
+func (h *OIDCHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.useCases.OIDC.ListClients(r.Context())
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]ClientResponse, len(clients))
+	for i, client := range clients {
+		resp[i] = toClientResponse(client)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteClient godoc
+// @Summary Delete an OAuth client
+// @Description Consents to the client go with it, and its access tokens stop working at the userinfo endpoint.
+// @Tags oauth
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /oauth/clients/{id} [delete]
+func (h *OIDCHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	err := h.useCases.OIDC.DeleteClient(r.Context(), mux.Vars(r)["id"])
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, oauthRepository.ErrClientNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListConsents godoc
+// @Summary List the OAuth consents of a user
+// @Description Users may list their own.
+// @Tags oauth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} ConsentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/consents [get]
+func (h *OIDCHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := h.useCases.OIDC.ListConsents(r.Context(), id)
+	if err != nil {
+		writeOIDCError(w, r, err)
+		return
+	}
+	resp := make([]ConsentResponse, len(consents))
+	for i, consent := range consents {
+		resp[i] = ConsentResponse{
+			ClientID:  consent.ClientID,
+			Scopes:    consent.Scopes,
+			GrantedAt: consent.GrantedAt.UTC().Format(time.RFC3339),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeConsent godoc
+// @Summary Revoke the consent of a user to an OAuth client
+// @Description The next sign-in asks again, and the client's access tokens stop working at the userinfo endpoint. Users may revoke their own.
+// @Tags oauth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param client path string true "Client ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/consents/{client} [delete]
+func (h *OIDCHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.useCases.OIDC.RevokeConsent(r.Context(), id, mux.Vars(r)["client"]); err != nil {
+		writeOIDCError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// interaction returns the interaction page for an authorisation request
+// with the given query.
+func (h *OIDCHandler) interaction(query string) string {
+	separator := "?"
+	if strings.Contains(h.interactionURL, "?") {
+		separator = "&"
+	}
+	return h.interactionURL + separator + query
+}
+
+// codeRedirect returns the redirect URI of req carrying code.
+func codeRedirect(req oidcUC.AuthorizationRequest, code string) string {
+	return redirectWith(req.RedirectURI, url.Values{"code": {code}}, req.State)
+}
+
+// errorRedirect returns the redirect URI of req carrying err.
+func errorRedirect(req oidcUC.AuthorizationRequest, err *oidcUC.Error) string {
+	return redirectWith(req.RedirectURI, url.Values{"error": {err.Code}, "error_description": {err.Description}}, req.State)
+}
+
+// redirectWith adds params and state to the query of a redirect URI, which
+// the use case has matched against the registered ones.
+func redirectWith(redirectURI string, params url.Values, state string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func toAuthorizationResponse(authorization *oidcUC.Authorization, redirectTo string) AuthorizationResponse {
+	return AuthorizationResponse{
+		ClientID:        authorization.Client.ID,
+		ClientName:      authorization.Client.Name,
+		Scopes:          authorization.Scopes,
+		ConsentRequired: authorization.ConsentRequired,
+		RedirectTo:      redirectTo,
+	}
+}
+
+func toClientResponse(client *entities.OAuthClient) ClientResponse {
+	return ClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.Public(),
+		CreatedAt:    client.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// writeOIDCError answers the errors of authorisation requests that cannot
+// go back to the client, and of managing consents.
+func writeOIDCError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, oidcUC.ErrUnknownClient), errors.Is(err, oidcUC.ErrRedirectURIMismatch):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, userRepository.ErrUserNotFound), errors.Is(err, oauthRepository.ErrConsentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, oidcUC.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
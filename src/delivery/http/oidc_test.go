@@ -0,0 +1,336 @@
+package http
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/cache"
+	"solecode/pkg/config"
+	"solecode/pkg/mail"
+	"solecode/pkg/password"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	uc "solecode/src/usecase"
+	oidcUC "solecode/src/usecase/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves the whole API, with the admin it was seeded with and
+// the mail it sent.
+type testServer struct {
+	*httptest.Server
+	user   *entities.User
+	mailer *mail.MemoryMailer
+}
+
+// newTestServer serves the whole API over a memory repository holding an
+// admin, john@example.com with password Secret123!.
+func newTestServer(t *testing.T) *testServer {
+	// The issuer is the server's own URL, known once it listens
+	server := httptest.NewUnstartedServer(nil)
+	t.Cleanup(server.Close)
+	keys, err := token.NewEphemeralKeySet("http://" + server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	hasher, err := password.New(config.PasswordConfig{Algorithm: password.Bcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+	signer, err := token.NewEphemeralActionSigner()
+	require.NoError(t, err)
+	templates, err := mail.NewTemplates("", "en")
+	require.NoError(t, err)
+
+	mailer := mail.NewMemoryMailer("noreply@example.com")
+	repo := repository.NewMemoryRepository()
+	useCases := uc.InitUsecase(uc.Dependencies{
+		Repo:      *repo,
+		Cache:     cache.NewMemoryCache(),
+		Hasher:    hasher,
+		Keys:      keys,
+		AuditLog:  audit.NewNopLogger(),
+		Mailer:    mailer,
+		Templates: templates,
+		Signer:    signer,
+	})
+
+	sys := entities.WithPrincipal(context.Background(), entities.SystemPrincipal())
+	hash, err := hasher.Hash("Secret123!")
+	require.NoError(t, err)
+	user := &entities.User{Name: "John Doe", Email: "john@example.com", PasswordHash: hash}
+	require.NoError(t, repo.User.Create(sys, user))
+	_, err = useCases.Authz.GrantRole(sys, user.ID, entities.RoleAdmin)
+	require.NoError(t, err)
+
+	authHandler := NewAuthHandler(*useCases, AuthHandlerOptions{InsecureCookies: true})
+	router := NewRouter(NewUserHandler(*useCases, UserHandlerOptions{}), authHandler, NewRoleHandler(*useCases),
+		NewAPIKeyHandler(*useCases), NewAccountHandler(*useCases), NewOIDCHandler(*useCases, authHandler, OIDCHandlerOptions{}),
+		RouterOptions{})
+	server.Config.Handler = router.GetHandler()
+	server.Start()
+	return &testServer{Server: server, user: user, mailer: mailer}
+}
+
+// browser is a user agent with a cookie session.
+type browser struct {
+	t      *testing.T
+	base   string
+	client *http.Client
+	csrf   string
+}
+
+func newBrowser(t *testing.T, base string) *browser {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &browser{t: t, base: base, client: &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (b *browser) do(method, path string, body interface{}, out interface{}) *http.Response {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(b.t, err)
+		reader = strings.NewReader(string(encoded))
+	}
+	req, err := http.NewRequest(method, b.base+path, reader)
+	require.NoError(b.t, err)
+	if b.csrf != "" {
+		req.Header.Set(csrfHeader, b.csrf)
+	}
+	resp, err := b.client.Do(req)
+	require.NoError(b.t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(b.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+// fakeClient is a confidential app signing users in with the code flow.
+type fakeClient struct {
+	t         *testing.T
+	id        string
+	secret    string
+	redirect  string
+	discovery DiscoveryResponse
+	verifier  string
+	state     string
+	nonce     string
+}
+
+// authorizeURL starts a sign-in with fresh PKCE, state and nonce values.
+func (c *fakeClient) authorizeURL(scope string) string {
+	c.verifier, c.state, c.nonce = randomString(c.t), randomString(c.t), randomString(c.t)
+	sum := sha256.Sum256([]byte(c.verifier))
+	query := url.Values{
+		"client_id":             {c.id},
+		"redirect_uri":          {c.redirect},
+		"response_type":         {"code"},
+		"scope":                 {scope},
+		"state":                 {c.state},
+		"nonce":                 {c.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return c.discovery.AuthorizationEndpoint + "?" + query.Encode()
+}
+
+// callback checks the redirect back to the app and returns its code.
+func (c *fakeClient) callback(location string) string {
+	u, err := url.Parse(location)
+	require.NoError(c.t, err)
+	require.True(c.t, strings.HasPrefix(location, c.redirect+"?"), location)
+	require.Equal(c.t, c.state, u.Query().Get("state"))
+	require.Empty(c.t, u.Query().Get("error"), u.Query().Get("error_description"))
+	return u.Query().Get("code")
+}
+
+// tokenResult is an answer of the token endpoint, tokens or an error.
+type tokenResult struct {
+	OAuthTokenResponse
+	Error string `json:"error"`
+}
+
+func (c *fakeClient) exchange(code string) (*http.Response, tokenResult) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.redirect},
+		"code_verifier": {c.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, c.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.id), url.QueryEscape(c.secret))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	var result tokenResult
+	require.NoError(c.t, json.NewDecoder(resp.Body).Decode(&result))
+	return resp, result
+}
+
+// verifyIDToken checks an ID token against the published keys.
+func (c *fakeClient) verifyIDToken(raw string) jwt.MapClaims {
+	resp, err := http.Get(c.discovery.JWKSURI)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	var set token.JWKS
+	require.NoError(c.t, json.NewDecoder(resp.Body).Decode(&set))
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		for _, key := range set.Keys {
+			if key.Kid == t.Header["kid"] {
+				public, err := base64.RawURLEncoding.DecodeString(key.X)
+				return ed25519.PublicKey(public), err
+			}
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}, jwt.WithValidMethods(c.discovery.IDTokenSigningAlgValuesSupported),
+		jwt.WithIssuer(c.discovery.Issuer), jwt.WithAudience(c.id), jwt.WithExpirationRequired())
+	require.NoError(c.t, err)
+	return claims
+}
+
+func (c *fakeClient) userInfo(accessToken string) (*http.Response, UserInfoResponse) {
+	req, err := http.NewRequest(http.MethodGet, c.discovery.UserinfoEndpoint, nil)
+	require.NoError(c.t, err)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	var info UserInfoResponse
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(c.t, json.NewDecoder(resp.Body).Decode(&info))
+	}
+	return resp, info
+}
+
+func randomString(t *testing.T) string {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestOIDCCodeFlow(t *testing.T) {
+	server := newTestServer(t)
+	john := newBrowser(t, server.URL)
+
+	// Anonymous browsers cannot start a sign-in
+	app := &fakeClient{t: t, redirect: "https://wiki.example.com/callback"}
+	resp := john.do(http.MethodGet, "/.well-known/openid-configuration", nil, &app.discovery)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, server.URL, app.discovery.Issuer)
+	assert.Equal(t, []string{token.EdDSA}, app.discovery.IDTokenSigningAlgValuesSupported)
+	assert.Equal(t, []string{"S256"}, app.discovery.CodeChallengeMethodsSupported)
+	resp = john.do(http.MethodGet, strings.TrimPrefix(app.authorizeURL("openid"), server.URL), nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// John signs in with a cookie session and, as an admin, registers the app
+	var session SessionResponse
+	resp = john.do(http.MethodPost, "/api/v1/auth/session", LoginRequest{Email: "john@example.com", Password: "Secret123!"}, &session)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	john.csrf = session.CSRFToken
+	var registered ClientResponse
+	resp = john.do(http.MethodPost, "/api/v1/oauth/clients", CreateClientRequest{Name: "Wiki", RedirectURIs: []string{app.redirect}}, &registered)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NotEmpty(t, registered.Secret)
+	app.id, app.secret = registered.ID, registered.Secret
+
+	// The first sign-in asks for consent, which the sign-in page records
+	authorizeURL := app.authorizeURL("openid profile email")
+	var authorization AuthorizationResponse
+	resp = john.do(http.MethodGet, strings.TrimPrefix(authorizeURL, server.URL), nil, &authorization)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, authorization.ConsentRequired)
+	assert.Equal(t, "Wiki", authorization.ClientName)
+	assert.Equal(t, []string{"openid", "profile", "email"}, authorization.Scopes)
+
+	query, err := url.Parse(authorizeURL)
+	require.NoError(t, err)
+	approve := true
+	answer := AuthorizeRequest{
+		ClientID:            app.id,
+		RedirectURI:         app.redirect,
+		ResponseType:        "code",
+		Scope:               query.Query().Get("scope"),
+		State:               app.state,
+		Nonce:               app.nonce,
+		CodeChallenge:       query.Query().Get("code_challenge"),
+		CodeChallengeMethod: "S256",
+		Approve:             &approve,
+	}
+	resp = john.do(http.MethodPost, "/api/v1/oauth/authorize", answer, &authorization)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	code := app.callback(authorization.RedirectTo)
+
+	// The app redeems the code and checks the ID token
+	resp, tokens := app.exchange(code)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, "openid profile email", tokens.Scope)
+	claims := app.verifyIDToken(tokens.IDToken)
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, app.nonce, claims["nonce"])
+	assert.Equal(t, "John Doe", claims["name"])
+	assert.Equal(t, "john@example.com", claims["email"])
+	assert.Equal(t, false, claims["email_verified"])
+
+	resp, info := app.userInfo(tokens.AccessToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", info.Subject)
+	assert.Equal(t, "john@example.com", info.Email)
+	assert.Equal(t, server.user.UpdatedAt.Unix(), info.UpdatedAt)
+
+	// Codes work once, and client tokens do not open the API
+	resp, replay := app.exchange(code)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, oidcUC.CodeInvalidGrant, replay.Error)
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/auth/me", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	me, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	me.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, me.StatusCode)
+
+	// With consent on record, the next sign-in goes straight back to the app
+	resp = john.do(http.MethodGet, strings.TrimPrefix(app.authorizeURL("openid email"), server.URL), nil, nil)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	resp, _ = app.exchange(app.callback(resp.Header.Get("Location")))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Revoking consent cuts the app off
+	var consents []ConsentResponse
+	resp = john.do(http.MethodGet, "/api/v1/users/1/consents", nil, &consents)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, consents, 1)
+	assert.Equal(t, app.id, consents[0].ClientID)
+	resp = john.do(http.MethodDelete, "/api/v1/users/1/consents/"+app.id, nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = app.userInfo(tokens.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+}
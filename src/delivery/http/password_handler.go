@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"solecode/pkg/validator"
+	userRepository "solecode/src/repository/user"
+	lockoutUC "solecode/src/usecase/lockout"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/gorilla/mux"
+)
+
+// ChangePasswordRequest is the body of a password change
+// @Description current_password must match the stored password; leave it empty to set the first password of a user who has none
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"OldSecret1!"`
+	NewPassword     string `json:"new_password" example:"NewSecret2@" validate:"required,max=72,password"`
+}
+
+// ChangePassword godoc
+// @Summary Change a user's password
+// @Description Replace the password after checking the current one. The password is stored hashed and never returned. Every session and refresh token of the user ends, but for the session the user changes their own password from. Wrong current passwords count towards the lockout of the user like failed logins.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param password body ChangePasswordRequest true "Current and new password"
+// @Success 204
+// @Failure 400 {object} ValidationErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/password [put]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		writeValidationErrors(w, err)
+		return
+	}
+
+	err = h.userUseCase.User.ChangePassword(r.Context(), id, req.CurrentPassword, req.NewPassword)
+	var validationErrors validator.ValidationErrors
+	var throttled *lockoutUC.ThrottledError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.As(err, &validationErrors):
+		writeValidationErrors(w, validationErrors)
+	case errors.Is(err, userUC.ErrInvalidCredentials):
+		writeError(w, http.StatusForbidden, "current password is incorrect")
+	case errors.Is(err, userUC.ErrPasswordNotSet):
+		writeError(w, http.StatusConflict, "user has no password; leave current_password empty to set one")
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,9 +2,11 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"solecode/pkg/validator"
 	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
 )
 
 // / ErrorResponse represents an error response
@@ -18,6 +20,16 @@ type ValidationErrorResponse struct {
 	Details []validator.ValidationError `json:"details"`
 }
 
+// ProblemResponse is an RFC 7807 problem detail
+// @Description Sent as application/problem+json
+type ProblemResponse struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Forbidden"`
+	Status   int    `json:"status" example:"403"`
+	Detail   string `json:"detail,omitempty" example:"forbidden: requires users:delete"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/users/42"`
+}
+
 // notFoundHandler handles 404 errors
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusNotFound, ErrorResponse{
@@ -71,13 +83,38 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
+// writeProblem writes an RFC 7807 problem response for r.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ProblemResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+// writeForbidden answers a refusal by the policy; the policy has already
+// audited it.
+func writeForbidden(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusForbidden, err.Error())
+}
+
+// isForbidden reports whether err is a refusal by the policy.
+func isForbidden(err error) bool {
+	return errors.Is(err, authzUC.ErrForbidden)
+}
+
 // toUserResponse converts User entity to UserResponse
 func toUserResponse(user *entities.User) UserResponse {
 	return UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:         user.ID,
+		Name:       user.Name,
+		Email:      user.Email,
+		CreatedAt:  user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:  user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		VerifiedAt: formatOptionalTime(user.VerifiedAt),
 	}
 }
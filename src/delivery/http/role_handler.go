@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"solecode/src/entities"
+	roleRepository "solecode/src/repository/role"
+	userRepository "solecode/src/repository/user"
+	uc "solecode/src/usecase"
+
+	"github.com/gorilla/mux"
+)
+
+// RoleHandler handles HTTP requests for roles and their assignment
+type RoleHandler struct {
+	useCases uc.UseCases
+}
+
+func NewRoleHandler(useCases uc.UseCases) *RoleHandler {
+	return &RoleHandler{useCases: useCases}
+}
+
+// RoleResponse represents a role
+// @Description A named set of permissions
+type RoleResponse struct {
+	Name        string   `json:"name" example:"admin"`
+	Description string   `json:"description" example:"Manages every user and role"`
+	Permissions []string `json:"permissions" example:"users:read,users:delete"`
+}
+
+// SetRolesRequest replaces the roles of a user
+// @Description Names of every role the user should hold; an empty list removes them all
+type SetRolesRequest struct {
+	Roles []string `json:"roles" example:"admin"`
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Tags roles
+// @Produce json
+// @Success 200 {array} RoleResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /roles [get]
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.useCases.Authz.ListRoles(r.Context())
+	h.writeRoles(w, r, roles, err)
+}
+
+// GetUserRoles godoc
+// @Summary List the roles of a user
+// @Description Users may list their own roles.
+// @Tags roles
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/roles [get]
+func (h *RoleHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	roles, err := h.useCases.Authz.UserRoles(r.Context(), id)
+	h.writeRoles(w, r, roles, err)
+}
+
+// SetUserRoles godoc
+// @Summary Replace the roles of a user
+// @Description Takes effect on the user's next request.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param roles body SetRolesRequest true "Role names"
+// @Success 200 {array} RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/roles [put]
+func (h *RoleHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req SetRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Roles == nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: roles is required")
+		return
+	}
+
+	roles, err := h.useCases.Authz.SetUserRoles(r.Context(), id, req.Roles)
+	h.writeRoles(w, r, roles, err)
+}
+
+// writeRoles answers with roles, or with the status matching err.
+func (h *RoleHandler) writeRoles(w http.ResponseWriter, r *http.Request, roles []*entities.Role, err error) {
+	switch {
+	case err == nil:
+		resp := make([]RoleResponse, len(roles))
+		for i, role := range roles {
+			resp[i] = toRoleResponse(role)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, roleRepository.ErrRoleNotFound):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func toRoleResponse(role *entities.Role) RoleResponse {
+	permissions := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = string(permission)
+	}
+	return RoleResponse{Name: role.Name, Description: role.Description, Permissions: permissions}
+}
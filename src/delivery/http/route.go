@@ -2,8 +2,15 @@ package http
 
 import (
 	"net/http"
+	"net/netip"
+	"strings"
 	"time"
 
+	"solecode/pkg/audit"
+	"solecode/pkg/database"
+	"solecode/src/entities"
+	lockoutUC "solecode/src/usecase/lockout"
+
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
@@ -13,32 +20,129 @@ type Router struct {
 	router *mux.Router
 }
 
+// RouterOptions switches optional routes on and off.
+type RouterOptions struct {
+	Swagger bool
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// names the client.
+	TrustedProxies []netip.Prefix
+}
+
 // NewRouter creates a new router with all routes configured
-func NewRouter(userHandler *UserHandler) *Router {
+func NewRouter(userHandler *UserHandler, authHandler *AuthHandler, roleHandler *RoleHandler, apiKeyHandler *APIKeyHandler, accountHandler *AccountHandler, oidcHandler *OIDCHandler, opts RouterOptions) *Router {
 	r := mux.NewRouter()
+	r.Use(consistencyMiddleware)
+	r.Use(auditMiddleware)
+	r.Use(clientIPMiddleware(opts.TrustedProxies))
 
 	// API routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 
-	// User routes
-	api.HandleFunc("/users", userHandler.CreateUser).Methods("POST")
-	api.HandleFunc("/users/{id}", userHandler.GetUser).Methods("GET")
-	api.HandleFunc("/users/{id}", userHandler.UpdateUser).Methods("PUT")
-	api.HandleFunc("/users/{id}", userHandler.DeleteUser).Methods("DELETE")
-	swaggerHandler := httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"), // The url pointing to API definition
-	)
+	// Public auth routes
+	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/login/totp", authHandler.LoginSecondFactor).Methods("POST")
+	api.HandleFunc("/auth/session", authHandler.CreateSession).Methods("POST")
+	api.HandleFunc("/auth/session/totp", authHandler.CreateSessionSecondFactor).Methods("POST")
+	api.HandleFunc("/auth/refresh", authHandler.Refresh).Methods("POST")
+	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	api.HandleFunc("/auth/verify-email", accountHandler.VerifyEmail).Methods("POST")
+	api.HandleFunc("/auth/password-reset", accountHandler.RequestPasswordReset).Methods("POST")
+	api.HandleFunc("/auth/password-reset/confirm", accountHandler.ConfirmPasswordReset).Methods("POST")
+
+	// OpenID Connect endpoints for clients. The authorisation endpoint
+	// authenticates the user itself, to send anonymous browsers to the
+	// sign-in page; userinfo takes the access tokens of clients, which
+	// RequireAuth refuses
+	api.HandleFunc("/oauth/authorize", oidcHandler.Authorize).Methods("GET")
+	api.HandleFunc("/oauth/token", oidcHandler.Token).Methods("POST")
+	api.HandleFunc("/oauth/userinfo", oidcHandler.UserInfo).Methods("GET", "POST")
+
+	// Everything else needs an access token, API key or session cookie, and
+	// each route declares the permissions that admit it; RequireSelfOr also
+	// admits users acting on their own {id}
+	protected := api.NewRoute().Subrouter()
+	protected.Use(authHandler.RequireAuth)
+	protected.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+	protected.HandleFunc("/auth/session", authHandler.GetSession).Methods("GET")
+	protected.HandleFunc("/auth/session", authHandler.DeleteSession).Methods("DELETE")
+
+	// User routes; the fixed paths come before /users/{id}. A batch may
+	// mix operations, which the use case authorises one by one
+	protected.Handle("/users/batch", authHandler.Require(userHandler.BatchUsers,
+		entities.PermUsersCreate, entities.PermUsersUpdate, entities.PermUsersDelete)).Methods("POST")
+	protected.Handle("/users/import", authHandler.Require(userHandler.ImportUsers, entities.PermUsersCreate)).Methods("POST")
+	protected.Handle("/users/import/{id}", authHandler.Require(userHandler.GetImportJob, entities.PermUsersCreate)).Methods("GET")
+	protected.Handle("/users/export", authHandler.Require(userHandler.ExportUsers, entities.PermUsersRead)).Methods("GET")
+	protected.Handle("/users", authHandler.Require(userHandler.CreateUser, entities.PermUsersCreate)).Methods("POST")
+	protected.Handle("/users/{id}", authHandler.RequireSelfOr("id", userHandler.GetUser, entities.PermUsersRead)).Methods("GET")
+	protected.Handle("/users/{id}", authHandler.RequireSelfOr("id", userHandler.UpdateUser, entities.PermUsersUpdate)).Methods("PUT")
+	protected.Handle("/users/{id}", authHandler.Require(userHandler.DeleteUser, entities.PermUsersDelete)).Methods("DELETE")
+	protected.Handle("/users/{id}/password", authHandler.RequireSelfOr("id", userHandler.ChangePassword, entities.PermUsersPassword)).Methods("PUT")
+	protected.Handle("/users/{id}/lockout", authHandler.Require(authHandler.GetLockout, entities.PermUsersUpdate)).Methods("GET")
+	protected.Handle("/users/{id}/lockout", authHandler.Require(authHandler.UnlockUser, entities.PermUsersUpdate)).Methods("DELETE")
+	protected.Handle("/users/{id}/sessions", authHandler.RequireSelfOr("id", authHandler.ListSessions, entities.PermUsersUpdate)).Methods("GET")
+	protected.Handle("/users/{id}/sessions", authHandler.RequireSelfOr("id", authHandler.RevokeSessions, entities.PermUsersUpdate)).Methods("DELETE")
+	protected.Handle("/users/{id}/sessions/{session}", authHandler.RequireSelfOr("id", authHandler.RevokeSession, entities.PermUsersUpdate)).Methods("DELETE")
+	protected.Handle("/users/{id}/consents", authHandler.RequireSelfOr("id", oidcHandler.ListConsents, entities.PermUsersUpdate)).Methods("GET")
+	protected.Handle("/users/{id}/consents/{client}", authHandler.RequireSelfOr("id", oidcHandler.RevokeConsent, entities.PermUsersUpdate)).Methods("DELETE")
+	protected.Handle("/users/{id}/verification", authHandler.RequireSelfOr("id", accountHandler.SendVerification, entities.PermUsersUpdate)).Methods("POST")
+
+	// Two-factor routes; only users themselves enrol and disable, which
+	// the use case enforces on top of these
+	protected.Handle("/users/{id}/totp", authHandler.RequireSelfOr("id", authHandler.GetTOTPStatus,
+		entities.PermUsersUpdate, entities.PermUsersTwoFactor)).Methods("GET")
+	protected.Handle("/users/{id}/totp", authHandler.RequireSelfOr("id", authHandler.EnrollTOTP, entities.PermUsersUpdate)).Methods("POST")
+	protected.Handle("/users/{id}/totp", authHandler.Require(authHandler.ResetTOTP, entities.PermUsersTwoFactor)).Methods("DELETE")
+	protected.Handle("/users/{id}/totp/confirm", authHandler.RequireSelfOr("id", authHandler.ConfirmTOTP, entities.PermUsersUpdate)).Methods("POST")
+	protected.Handle("/users/{id}/totp/disable", authHandler.RequireSelfOr("id", authHandler.DisableTOTP, entities.PermUsersUpdate)).Methods("POST")
+	protected.Handle("/users/{id}/totp/recovery-codes", authHandler.RequireSelfOr("id", authHandler.RegenerateRecoveryCodes, entities.PermUsersUpdate)).Methods("POST")
+
+	// Role routes
+	protected.Handle("/roles", authHandler.Require(roleHandler.ListRoles, entities.PermRolesRead)).Methods("GET")
+	protected.Handle("/users/{id}/roles", authHandler.RequireSelfOr("id", roleHandler.GetUserRoles, entities.PermRolesRead)).Methods("GET")
+	protected.Handle("/users/{id}/roles", authHandler.Require(roleHandler.SetUserRoles, entities.PermRolesAssign)).Methods("PUT")
+
+	// API key routes
+	protected.Handle("/api-keys", authHandler.Require(apiKeyHandler.CreateAPIKey, entities.PermAPIKeysManage)).Methods("POST")
+	protected.Handle("/api-keys", authHandler.Require(apiKeyHandler.ListAPIKeys, entities.PermAPIKeysManage)).Methods("GET")
+	protected.Handle("/api-keys/{id}", authHandler.Require(apiKeyHandler.RevokeAPIKey, entities.PermAPIKeysManage)).Methods("DELETE")
+
+	// Impersonation routes; an impersonation token may stop itself, which
+	// the use case allows without the permission
+	protected.Handle("/impersonations", authHandler.Require(authHandler.StartImpersonation, entities.PermUsersImpersonate)).Methods("POST")
+	protected.Handle("/impersonations", authHandler.Require(authHandler.ListImpersonations, entities.PermUsersImpersonate)).Methods("GET")
+	protected.Handle("/impersonations/{id}/events", authHandler.Require(authHandler.GetImpersonationEvents, entities.PermUsersImpersonate)).Methods("GET")
+	protected.HandleFunc("/impersonations/{id}", authHandler.StopImpersonation).Methods("DELETE")
+
+	// OAuth client routes, and the interaction page's answer to an
+	// authorisation request, which users give for themselves
+	protected.Handle("/oauth/clients", authHandler.Require(oidcHandler.CreateClient, entities.PermClientsManage)).Methods("POST")
+	protected.Handle("/oauth/clients", authHandler.Require(oidcHandler.ListClients, entities.PermClientsManage)).Methods("GET")
+	protected.Handle("/oauth/clients/{id}", authHandler.Require(oidcHandler.DeleteClient, entities.PermClientsManage)).Methods("DELETE")
+	protected.HandleFunc("/oauth/authorize", oidcHandler.AnswerAuthorization).Methods("POST")
+
+	// Public keys for verifying access and ID tokens, and the provider
+	// metadata of OpenID Connect
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+	r.HandleFunc("/.well-known/openid-configuration", oidcHandler.Discovery).Methods("GET")
+
 	// Health check
 	r.HandleFunc("/health", healthCheck).Methods("GET")
 
-	// Serve Swagger UI
-	r.PathPrefix("/swagger/").Handler(swaggerHandler)
+	if opts.Swagger {
+		swaggerHandler := httpSwagger.Handler(
+			httpSwagger.URL("/swagger/doc.json"), // The url pointing to API definition
+		)
 
-	// Serve Swagger JSON
-	r.HandleFunc("/swagger/doc.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		http.ServeFile(w, r, "./docs/swagger.json")
-	})
+		// Serve Swagger UI
+		r.PathPrefix("/swagger/").Handler(swaggerHandler)
+
+		// Serve Swagger JSON
+		r.HandleFunc("/swagger/doc.json", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			http.ServeFile(w, r, "./docs/swagger.json")
+		})
+	}
 
 	// NotFound handler
 	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
@@ -56,6 +160,78 @@ func (r *Router) GetHandler() http.Handler {
 	return r.router
 }
 
+// consistencyMiddleware pins reads to the primary after a write within the
+// same request. Clients that just wrote through another request can send
+// "X-Consistency: strong" to read from the primary as well.
+func consistencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := database.WithReadYourWrites(r.Context())
+		if strings.EqualFold(r.Header.Get("X-Consistency"), "strong") {
+			ctx = database.WithPrimary(ctx)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// auditMiddleware lets audit events logged while serving a request name
+// it.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.WithRequest(r.Context(), audit.RequestInfo{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIPMiddleware tells the lockout use case which client a request
+// comes from, for its per-IP limits.
+func clientIPMiddleware(trusted []netip.Prefix) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := lockoutUC.WithClientIP(r.Context(), clientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP returns the address a request comes from. Behind trusted
+// proxies that is the last X-Forwarded-For entry no trusted proxy added;
+// entries before it are whatever the client chose to send.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	ip := addrPort.Addr().Unmap()
+	if !isTrusted(ip, trusted) {
+		return ip.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // healthCheck handles health check requests
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
@@ -0,0 +1,346 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
+
+	"github.com/gorilla/mux"
+)
+
+// csrfHeader carries the CSRF token of cookie-authenticated requests that
+// change state.
+const csrfHeader = "X-CSRF-Token"
+
+// SessionResponse is a started cookie session
+// @Description The session token travels in an HttpOnly cookie; send csrf_token in the X-CSRF-Token header of every request but GET, HEAD and OPTIONS
+type SessionResponse struct {
+	ID        string `json:"id"`
+	CSRFToken string `json:"csrf_token"`
+	ExpiresAt string `json:"expires_at" example:"2026-10-19T01:00:00Z"`
+}
+
+// SessionInfoResponse is one session of a user
+// @Description current marks the session the request came with
+type SessionInfoResponse struct {
+	ID         string `json:"id"`
+	IP         string `json:"ip,omitempty" example:"192.0.2.1"`
+	UserAgent  string `json:"user_agent,omitempty" example:"Mozilla/5.0"`
+	CreatedAt  string `json:"created_at" example:"2026-10-18T13:00:00Z"`
+	LastSeenAt string `json:"last_seen_at" example:"2026-10-18T13:20:00Z"`
+	ExpiresAt  string `json:"expires_at" example:"2026-10-19T01:00:00Z"`
+	Current    bool   `json:"current" example:"true"`
+}
+
+// RevokedSessionsResponse counts revoked sessions
+// @Description How many sessions were ended
+type RevokedSessionsResponse struct {
+	Revoked int `json:"revoked" example:"2"`
+}
+
+type sessionKey struct{}
+
+// sessionFrom returns the session a request was authenticated with, if
+// it came with a session cookie.
+func sessionFrom(ctx context.Context) (*entities.Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*entities.Session)
+	return session, ok
+}
+
+// serveSession authenticates a request by its session cookie. Requests
+// that may change state must repeat the session's CSRF token in a header,
+// which other sites cannot read to send.
+func (h *AuthHandler) serveSession(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	principal, session, err := h.useCases.Session.Authenticate(r.Context(), token)
+	if errors.Is(err, sessionUC.ErrInvalidSession) {
+		h.clearSessionCookies(w)
+		writeUnauthorized(w, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeader)), []byte(session.CSRFToken)) != 1 {
+			writeProblem(w, r, http.StatusForbidden, "missing or invalid CSRF token")
+			return
+		}
+	}
+	// Rotated for changed privileges
+	if session.Token != "" {
+		h.setSessionCookies(w, session)
+	}
+
+	ctx := context.WithValue(entities.WithPrincipal(r.Context(), principal), sessionKey{}, session)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// CreateSession godoc
+// @Summary Log in with a session cookie
+// @Description For browsers: sets an HttpOnly session cookie and a readable CSRF token cookie instead of answering tokens.
+// @Description Failures are those of /auth/login, including the second factor challenge, which completes at /auth/session/totp.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Credentials"
+// @Success 201 {object} SessionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} SecondFactorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/session [post]
+func (h *AuthHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.useCases.Auth.CheckLogin(r.Context(), req.Email, req.Password)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+	h.startSession(w, r, user)
+}
+
+// CreateSessionSecondFactor godoc
+// @Summary Complete a session login with a second factor
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body SecondFactorRequest true "Challenge and code"
+// @Success 201 {object} SessionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/session/totp [post]
+func (h *AuthHandler) CreateSessionSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.useCases.Auth.CheckSecondFactor(r.Context(), req.Challenge, req.Code)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+	h.startSession(w, r, user)
+}
+
+// GetSession godoc
+// @Summary Show the current cookie session
+// @Description Answers the CSRF token again, for pages that lost it.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} SessionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /auth/session [get]
+func (h *AuthHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionFrom(r.Context())
+	if !ok {
+		writeError(w, http.StatusNotFound, "request not authenticated with a session cookie")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, toSessionResponse(session))
+}
+
+// DeleteSession godoc
+// @Summary Log out of the current cookie session
+// @Tags auth
+// @Param X-CSRF-Token header string true "CSRF token of the session"
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/session [delete]
+func (h *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(h.cookies.SessionCookie); err == nil {
+		if err := h.useCases.Session.Revoke(r.Context(), cookie.Value); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	h.clearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSessions godoc
+// @Summary List a user's cookie sessions
+// @Description Most recently active first.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} SessionInfoResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/sessions [get]
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.useCases.Session.List(r.Context(), id)
+	if err != nil {
+		writeSessionError(w, r, err)
+		return
+	}
+	current, _ := sessionFrom(r.Context())
+	resp := make([]SessionInfoResponse, len(sessions))
+	for i, session := range sessions {
+		resp[i] = SessionInfoResponse{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.UTC().Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.UTC().Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.UTC().Format(time.RFC3339),
+			Current:    current != nil && current.ID == session.ID,
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeSession godoc
+// @Summary End one of a user's cookie sessions
+// @Tags users
+// @Param id path int true "User ID"
+// @Param session path string true "Session ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/sessions/{session} [delete]
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID := mux.Vars(r)["session"]
+	if err := h.useCases.Session.RevokeSession(r.Context(), id, sessionID); err != nil {
+		writeSessionError(w, r, err)
+		return
+	}
+	if current, ok := sessionFrom(r.Context()); ok && current.ID == sessionID {
+		h.clearSessionCookies(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions godoc
+// @Summary End all of a user's cookie sessions
+// @Description Access and refresh tokens stay valid; API keys are revoked separately.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} RevokedSessionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/sessions [delete]
+func (h *AuthHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.useCases.Session.RevokeAll(r.Context(), id)
+	if err != nil {
+		writeSessionError(w, r, err)
+		return
+	}
+	if current, ok := sessionFrom(r.Context()); ok && current.UserID == id {
+		h.clearSessionCookies(w)
+	}
+	writeJSON(w, http.StatusOK, RevokedSessionsResponse{Revoked: count})
+}
+
+// startSession starts a session for user, who just logged in, and sets
+// its cookies.
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, user *entities.User) {
+	session, err := h.useCases.Session.Create(r.Context(), user.ID, sessionUC.Client{
+		IP:        lockoutUC.ClientIP(r.Context()),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.setSessionCookies(w, session)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, toSessionResponse(session))
+}
+
+// setSessionCookies hands the client the token of session, unreadable to
+// scripts, and its CSRF token, readable so that pages can send it back.
+func (h *AuthHandler) setSessionCookies(w http.ResponseWriter, session *entities.Session) {
+	http.SetCookie(w, h.cookie(h.cookies.SessionCookie, session.Token, session.ExpiresAt, true))
+	http.SetCookie(w, h.cookie(h.cookies.SessionCookie+"_csrf", session.CSRFToken, session.ExpiresAt, false))
+}
+
+func (h *AuthHandler) clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, h.cookie(h.cookies.SessionCookie, "", time.Unix(0, 0), true))
+	http.SetCookie(w, h.cookie(h.cookies.SessionCookie+"_csrf", "", time.Unix(0, 0), false))
+}
+
+func (h *AuthHandler) cookie(name, value string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   h.cookies.CookieDomain,
+		Expires:  expires,
+		Secure:   !h.cookies.InsecureCookies,
+		HttpOnly: httpOnly,
+		SameSite: h.cookies.SameSite,
+	}
+}
+
+func toSessionResponse(session *entities.Session) SessionResponse {
+	return SessionResponse{
+		ID:        session.ID,
+		CSRFToken: session.CSRFToken,
+		ExpiresAt: session.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// writeSessionError answers the errors of managing a user's sessions.
+func writeSessionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, userRepository.ErrUserNotFound), errors.Is(err, sessionUC.ErrSessionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeletingUserEndsSessions(t *testing.T) {
+	server := newTestServer(t)
+
+	var tokens TokenResponse
+	anonymous := &bearer{t: t, base: server.URL}
+	resp := anonymous.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "john@example.com", Password: "Secret123!"}, &tokens)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	admin := &bearer{t: t, base: server.URL, token: tokens.AccessToken}
+
+	var jane UserResponse
+	resp = admin.do(http.MethodPost, "/api/v1/users", CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com"}, &jane)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp = admin.do(http.MethodPut, "/api/v1/users/2/password", ChangePasswordRequest{NewPassword: "Secret123!"}, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	browser := newBrowser(t, server.URL)
+	resp = browser.do(http.MethodPost, "/api/v1/auth/session", LoginRequest{Email: "jane@example.com", Password: "Secret123!"}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp = browser.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = admin.do(http.MethodDelete, "/api/v1/users/2", nil, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = browser.do(http.MethodGet, "/api/v1/auth/me", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,453 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxImportBytes caps the size of an uploaded import file.
+	maxImportBytes = 512 << 20
+	// maxRunningImports bounds how many imports write to the database at
+	// once; later jobs wait in the queued state.
+	maxRunningImports = 2
+	// maxPendingImports bounds the jobs spooled to disk and not finished
+	// yet, so uploads cannot fill the temporary directory; beyond it
+	// uploads are refused with 503.
+	maxPendingImports = 8
+	// importJobRetention is how long finished jobs can still be polled.
+	importJobRetention = 24 * time.Hour
+)
+
+// Import job states.
+const (
+	ImportQueued    = "queued"
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+)
+
+// ImportJobResponse describes an import job
+// @Description Import job status. The per-row error report is included once the job has finished.
+type ImportJobResponse struct {
+	ID         string  `json:"id" example:"5f2b9c1e8a7d4e01"`
+	Status     string  `json:"status" example:"running" enums:"queued,running,succeeded,failed"`
+	Format     string  `json:"format" example:"csv"`
+	BytesTotal int64   `json:"bytes_total" example:"1048576"`
+	BytesRead  int64   `json:"bytes_read" example:"524288"`
+	Percent    float64 `json:"percent" example:"50"`
+	userUC.ImportCounts
+	Errors     []userUC.ImportRowError `json:"errors,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	CreatedAt  string                  `json:"created_at"`
+	FinishedAt string                  `json:"finished_at,omitempty"`
+}
+
+// importJob is an import running in the background on a spooled copy of
+// the uploaded file.
+type importJob struct {
+	id        string
+	format    string
+	size      int64
+	read      atomic.Int64
+	createdAt time.Time
+	// principal started the job, which runs with its permissions
+	principal *entities.Principal
+
+	mu         sync.Mutex
+	status     string
+	counts     userUC.ImportCounts
+	report     *userUC.ImportReport
+	err        error
+	finishedAt time.Time
+}
+
+func (j *importJob) response() ImportJobResponse {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	resp := ImportJobResponse{
+		ID:           j.id,
+		Status:       j.status,
+		Format:       j.format,
+		BytesTotal:   j.size,
+		BytesRead:    j.read.Load(),
+		ImportCounts: j.counts,
+		CreatedAt:    j.createdAt.UTC().Format(time.RFC3339),
+	}
+	if j.size > 0 {
+		resp.Percent = float64(resp.BytesRead) * 100 / float64(j.size)
+	}
+	if j.report != nil {
+		resp.Errors = j.report.Errors
+	}
+	if j.err != nil {
+		resp.Error = j.err.Error()
+	}
+	if !j.finishedAt.IsZero() {
+		resp.FinishedAt = j.finishedAt.UTC().Format(time.RFC3339)
+		if j.status == ImportSucceeded {
+			resp.Percent = 100
+		}
+	}
+	return resp
+}
+
+// importJobs keeps the jobs of this process. Jobs are not shared between
+// instances and do not survive a restart.
+type importJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*importJob
+	running chan struct{}
+	pending chan struct{}
+}
+
+func newImportJobs() *importJobs {
+	return &importJobs{
+		jobs:    make(map[string]*importJob),
+		running: make(chan struct{}, maxRunningImports),
+		pending: make(chan struct{}, maxPendingImports),
+	}
+}
+
+// reserve takes a pending slot for an upload, reporting false when every
+// slot is taken. The slot is given back with release once the job ends or
+// the upload fails.
+func (s *importJobs) reserve() bool {
+	select {
+	case s.pending <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *importJobs) release() {
+	<-s.pending
+}
+
+func (s *importJobs) add(job *importJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, old := range s.jobs {
+		old.mu.Lock()
+		expired := !old.finishedAt.IsZero() && time.Since(old.finishedAt) > importJobRetention
+		old.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.id] = job
+}
+
+func (s *importJobs) get(id string) *importJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// run imports the spooled file and removes it afterwards, releasing the
+// job's pending slot.
+func (s *importJobs) run(useCase userUC.UserUseCaseItf, job *importJob, file *os.File) {
+	defer s.release()
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	s.running <- struct{}{}
+	defer func() { <-s.running }()
+
+	job.mu.Lock()
+	job.status = ImportRunning
+	job.mu.Unlock()
+
+	ctx := entities.WithPrincipal(context.Background(), job.principal)
+	report, err := useCase.ImportUsers(ctx, &countingReader{r: file, n: &job.read}, userUC.ImportOptions{
+		Format: job.format,
+		Progress: func(counts userUC.ImportCounts) {
+			job.mu.Lock()
+			job.counts = counts
+			job.mu.Unlock()
+		},
+	})
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.report, job.err = report, err
+	if report != nil {
+		job.counts = report.ImportCounts
+	}
+	job.status = ImportSucceeded
+	if err != nil {
+		job.status = ImportFailed
+	}
+	job.finishedAt = time.Now()
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// ImportUsers godoc
+// @Summary Import users from CSV or JSON Lines
+// @Description Upload CSV with a header row naming the name and email columns, or JSON Lines with one {"name", "email"} object per line.
+// @Description Rows are validated like single creates and deduplicated on the normalised email. The import runs in the background; poll the job URL from the Location header for progress and the per-row error report.
+// @Description While too many imports are waiting or running, uploads are refused with 503 and a Retry-After header.
+// @Tags users
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv or jsonl, default from Content-Type" Enums(csv, jsonl)
+// @Param file body string true "CSV or JSON Lines"
+// @Success 202 {object} ImportJobResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
+// @Router /users/import [post]
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	format := importFormat(r)
+	if format == "" {
+		writeError(w, http.StatusBadRequest, "Unknown import format: use ?format=csv or ?format=jsonl, or a text/csv or application/x-ndjson body")
+		return
+	}
+
+	if !h.imports.reserve() {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, "Too many imports in progress, try again later")
+		return
+	}
+
+	// Large uploads outlive the server's read timeout
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	// Spool the body so the job can outlive the request
+	file, err := os.CreateTemp("", "user-import-*")
+	if err != nil {
+		h.imports.release()
+		writeError(w, http.StatusInternalServerError, "Failed to store upload")
+		return
+	}
+	size, err := io.Copy(file, http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		h.imports.release()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import larger than %d bytes", int64(maxImportBytes)))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "Failed to read upload")
+		return
+	}
+
+	job := &importJob{
+		id:        newJobID(),
+		format:    format,
+		size:      size,
+		status:    ImportQueued,
+		createdAt: time.Now(),
+	}
+	job.principal, _ = entities.PrincipalFrom(r.Context())
+	h.imports.add(job)
+	go h.imports.run(h.userUseCase.User, job, file)
+
+	w.Header().Set("Location", "/api/v1/users/import/"+job.id)
+	writeJSON(w, http.StatusAccepted, job.response())
+}
+
+// GetImportJob godoc
+// @Summary Get an import job
+// @Description Progress of an import job, with the per-row error report once it has finished
+// @Tags users
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} ImportJobResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
+// @Router /users/import/{id} [get]
+func (h *UserHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	job := h.imports.get(mux.Vars(r)["id"])
+	if job == nil {
+		writeError(w, http.StatusNotFound, "import job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job.response())
+}
+
+// ExportUsers godoc
+// @Summary Export users as CSV or JSON Lines
+// @Description Stream the users matching the filters, ordered by ID. Use after_id with the last exported ID to resume.
+// @Tags users
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or jsonl" Enums(csv, jsonl)
+// @Param include_deleted query bool false "Include soft deleted users"
+// @Param created_from query string false "Created at or after, RFC 3339 or YYYY-MM-DD"
+// @Param created_to query string false "Created before, RFC 3339 or YYYY-MM-DD"
+// @Param domain query string false "Email domain, e.g. example.com"
+// @Param after_id query int false "Only users with a greater ID"
+// @Param limit query int false "Maximum number of users"
+// @Success 200 {string} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
+// @Router /users/export [get]
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = userUC.FormatCSV
+	}
+	contentType := map[string]string{
+		userUC.FormatCSV:   "text/csv; charset=utf-8",
+		userUC.FormatJSONL: "application/x-ndjson",
+	}[format]
+	if contentType == "" {
+		writeError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+
+	filter, err := exportFilter(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A large export outlives the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent, so a failure can only cut the stream short
+	if _, err := h.userUseCase.User.ExportUsers(r.Context(), &flushWriter{w: w}, format, filter); err != nil {
+		log.Printf("user export failed: %v", err)
+	}
+}
+
+func exportFilter(query url.Values) (userRepository.ListFilter, error) {
+	get := func(key string) string {
+		return strings.TrimSpace(query.Get(key))
+	}
+
+	var filter userRepository.ListFilter
+	var err error
+	if v := get("include_deleted"); v != "" {
+		if filter.IncludeDeleted, err = strconv.ParseBool(v); err != nil {
+			return filter, fmt.Errorf("include_deleted must be true or false")
+		}
+	}
+	if v := get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("limit must be a non-negative number")
+		}
+	}
+	if v := get("after_id"); v != "" {
+		if filter.AfterID, err = strconv.ParseInt(v, 10, 64); err != nil || filter.AfterID < 0 {
+			return filter, fmt.Errorf("after_id must be a non-negative number")
+		}
+	}
+	if filter.CreatedFrom, err = parseFilterTime(get("created_from")); err != nil {
+		return filter, fmt.Errorf("created_from: %w", err)
+	}
+	if filter.CreatedTo, err = parseFilterTime(get("created_to")); err != nil {
+		return filter, fmt.Errorf("created_to: %w", err)
+	}
+	filter.EmailDomain = strings.TrimPrefix(get("domain"), "@")
+	return filter, nil
+}
+
+// parseFilterTime accepts RFC 3339 timestamps and plain dates, which mean
+// midnight UTC. An empty string is the zero time.
+func parseFilterTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be RFC 3339 or YYYY-MM-DD")
+	}
+	return t, nil
+}
+
+// importFormat picks the import format from ?format or the Content-Type.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format == userUC.FormatCSV || format == userUC.FormatJSONL {
+			return format
+		}
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return userUC.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return userUC.FormatJSONL
+	}
+	return ""
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// flushWriter sends every write to the client straight away so a streamed
+// export is not held in the server's buffers.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
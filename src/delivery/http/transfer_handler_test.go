@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	uc "solecode/src/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportUsersRefusesBeyondPendingJobs(t *testing.T) {
+	h := NewUserHandler(uc.UseCases{}, UserHandlerOptions{})
+	for i := 0; i < maxPendingImports; i++ {
+		assert.True(t, h.imports.reserve())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/import?format=csv", strings.NewReader("name,email\n"))
+	rec := httptest.NewRecorder()
+	h.ImportUsers(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// A finished job frees its slot
+	h.imports.release()
+	assert.True(t, h.imports.reserve())
+	assert.False(t, h.imports.reserve())
+}
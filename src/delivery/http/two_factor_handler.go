@@ -0,0 +1,312 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	userRepository "solecode/src/repository/user"
+	lockoutUC "solecode/src/usecase/lockout"
+	twoFactorUC "solecode/src/usecase/twofactor"
+
+	"github.com/gorilla/mux"
+)
+
+// SecondFactorResponse asks for the second factor of a login
+// @Description Post the challenge with a code from the authenticator app, or a recovery code, to /auth/login/totp within expires_in seconds
+type SecondFactorResponse struct {
+	Error     string `json:"error" example:"second factor required"`
+	Challenge string `json:"challenge"`
+	ExpiresIn int    `json:"expires_in" example:"300"`
+}
+
+// SecondFactorRequest completes a login challenge
+// @Description Challenge from the login and a six digit code or a recovery code
+type SecondFactorRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code" example:"123456"`
+}
+
+// TOTPCodeRequest carries a code from the authenticator app
+// @Description A six digit code, or for disabling and new recovery codes also a recovery code
+type TOTPCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// TOTPEnrollmentResponse is what an authenticator app is set up with
+// @Description Show uri as a QR code, or secret for typing in; confirm with a code to enable
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/userapi:john@example.com?algorithm=SHA1&digits=6&issuer=userapi&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+// RecoveryCodesResponse lists new recovery codes
+// @Description Each code replaces a six digit code once; they are shown only this time
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k3pxp-jbswy"`
+}
+
+// TOTPStatusResponse is the two-factor state of a user
+// @Description pending is set between enrolment and confirmation
+type TOTPStatusResponse struct {
+	Enabled           bool `json:"enabled" example:"true"`
+	Pending           bool `json:"pending" example:"false"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left" example:"10"`
+}
+
+// LoginSecondFactor godoc
+// @Summary Complete a login with a second factor
+// @Description Wrong codes count towards the login lockout of the account. A challenge stays usable until it expires or succeeds.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body SecondFactorRequest true "Challenge and code"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login/totp [post]
+func (h *AuthHandler) LoginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pair, err := h.useCases.Auth.LoginSecondFactor(r.Context(), req.Challenge, req.Code)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+	writeTokens(w, pair)
+}
+
+// EnrollTOTP godoc
+// @Summary Start setting up an authenticator app
+// @Description Only users themselves may enrol. Enrolling again before confirming replaces the secret.
+// @Tags two-factor
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 201 {object} TOTPEnrollmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp [post]
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.useCases.TwoFactor.Enroll(r.Context(), id)
+	if err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, TOTPEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// ConfirmTOTP godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm the enrolment with a code from the app. Answers the recovery codes, which are not shown again.
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body TOTPCodeRequest true "Code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := totpCodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.useCases.TwoFactor.Confirm(r.Context(), id, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Only users themselves may disable it, with a current code or a recovery code. Wrong codes count towards the lockout of the account like failed logins.
+// @Tags two-factor
+// @Accept json
+// @Param id path int true "User ID"
+// @Param request body TOTPCodeRequest true "Code"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp/disable [post]
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := totpCodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.useCases.TwoFactor.Disable(r.Context(), id, req.Code); err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace the recovery codes
+// @Description Takes a current code or an unused recovery code; the old recovery codes stop working. Wrong codes count towards the lockout of the account like failed logins.
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body TOTPCodeRequest true "Code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := totpCodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.useCases.TwoFactor.RegenerateRecoveryCodes(r.Context(), id, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// GetTOTPStatus godoc
+// @Summary Show a user's two-factor state
+// @Tags two-factor
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} TOTPStatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp [get]
+func (h *AuthHandler) GetTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.useCases.TwoFactor.Status(r.Context(), id)
+	if err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, TOTPStatusResponse{
+		Enabled:           status.Enabled,
+		Pending:           status.Pending,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// ResetTOTP godoc
+// @Summary Reset a user's two-factor authentication
+// @Description For users who lost their app and their recovery codes. They log in with the password alone afterwards.
+// @Tags two-factor
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/totp [delete]
+func (h *AuthHandler) ResetTOTP(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.useCases.TwoFactor.Reset(r.Context(), id); err != nil {
+		writeTwoFactorError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userIDParam parses the id path variable, answering 400 when it is not
+// a user ID.
+func userIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// totpCodeRequest parses the user ID and the body of requests that take a
+// code.
+func totpCodeRequest(w http.ResponseWriter, r *http.Request) (int64, *TOTPCodeRequest, bool) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return 0, nil, false
+	}
+	var req TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request body: code is required")
+		return 0, nil, false
+	}
+	return id, &req, true
+}
+
+// writeTwoFactorError answers the errors of the two-factor use case.
+func writeTwoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+	var throttled *lockoutUC.ThrottledError
+	switch {
+	case isForbidden(err):
+		writeForbidden(w, r, err)
+	case errors.Is(err, userRepository.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, twoFactorUC.ErrInvalidCode):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, twoFactorUC.ErrAlreadyEnabled), errors.Is(err, twoFactorUC.ErrNotEnrolled):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, twoFactorUC.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
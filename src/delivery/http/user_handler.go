@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -11,16 +12,34 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// defaultBatchMaxOperations caps batch requests when UserHandlerOptions
+// leaves it unset.
+const defaultBatchMaxOperations = 1000
+
 // UserHandler handles HTTP requests for users
 type UserHandler struct {
 	userUseCase uc.UseCases
 	validator   *validator.Validator
+	imports     *importJobs
+	batchMax    int
+}
+
+// UserHandlerOptions tunes a UserHandler.
+type UserHandlerOptions struct {
+	// BatchMaxOperations caps the operations of one batch request; 0 means
+	// the default of 1000.
+	BatchMaxOperations int
 }
 
-func NewUserHandler(userUseCase uc.UseCases) *UserHandler {
+func NewUserHandler(userUseCase uc.UseCases, opts UserHandlerOptions) *UserHandler {
+	if opts.BatchMaxOperations <= 0 {
+		opts.BatchMaxOperations = defaultBatchMaxOperations
+	}
 	return &UserHandler{
 		userUseCase: userUseCase,
 		validator:   validator.New(),
+		imports:     newImportJobs(),
+		batchMax:    opts.BatchMaxOperations,
 	}
 }
 
@@ -39,6 +58,8 @@ type UserResponse struct {
 	Email     string `json:"email" example:"john@example.com"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	// VerifiedAt is set once the user has confirmed their email
+	VerifiedAt string `json:"verified_at,omitempty"`
 }
 
 // CreateUser godoc
@@ -52,6 +73,9 @@ type UserResponse struct {
 // @Failure 400 {object} ValidationErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
 // @Router /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req CreateUserRequest
@@ -64,7 +88,16 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		writeValidationErrors(w, err)
 		return
 	}
-	user, err := h.userUseCase.User.CreateUser(req.Name, req.Email)
+	user, err := h.userUseCase.User.CreateUser(r.Context(), req.Name, req.Email)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		writeValidationErrors(w, validationErrors)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -84,6 +117,9 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -95,7 +131,11 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userUseCase.User.GetUser(id)
+	user, err := h.userUseCase.User.GetUser(r.Context(), id)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "user not found" {
@@ -121,6 +161,9 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -144,7 +187,16 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userUseCase.User.UpdateUser(id, req.Name, req.Email)
+	user, err := h.userUseCase.User.UpdateUser(r.Context(), id, req.Name, req.Email)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		writeValidationErrors(w, validationErrors)
+		return
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		switch err.Error() {
@@ -162,7 +214,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Soft delete a user by ID
+// @Description Soft delete a user by ID. Their sessions and refresh tokens end.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -171,6 +223,9 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ProblemResponse
+// @Security BearerAuth
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -182,7 +237,12 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userUseCase.User.DeleteUser(id); err != nil {
+	err = h.userUseCase.User.DeleteUser(r.Context(), id)
+	if isForbidden(err) {
+		writeForbidden(w, r, err)
+		return
+	}
+	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "user not found" {
 			status = http.StatusNotFound
@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// APIKey lets a program act as a user without their password. Only the
+// hash of the key is stored; the key itself is shown once, when created.
+type APIKey struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the start of the key, kept in clear so keys can be told
+	// apart and looked up.
+	Prefix string `json:"prefix"`
+	// Permissions scope the key; it never holds more than its user.
+	Permissions []Permission `json:"permissions"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time   `json:"revoked_at,omitempty"`
+	// LastUsedAt is updated at most every few minutes, so it can lag.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	Hash string `json:"-"`
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
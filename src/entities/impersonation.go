@@ -0,0 +1,50 @@
+package entities
+
+import "time"
+
+// Impersonation is a stretch of time in which an admin acts as another
+// user, through an access token carrying both. Its ID is the jti of that
+// token.
+type Impersonation struct {
+	ID      string `json:"id"`
+	AdminID int64  `json:"admin_id"`
+	UserID  int64  `json:"user_id"`
+	// Reason is what the admin gave for starting, such as a ticket.
+	Reason    string     `json:"reason"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+
+	// Token is the access token, set only when the impersonation starts.
+	Token string `json:"-"`
+}
+
+// Active reports whether the impersonation has neither ended nor expired
+// at now.
+func (i *Impersonation) Active(now time.Time) bool {
+	return i.EndedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// Actions of the impersonation log.
+const (
+	ImpersonationStart   = "start"
+	ImpersonationStop    = "stop"
+	ImpersonationRequest = "request"
+)
+
+// ImpersonationEvent is one entry of the impersonation log: the start or
+// stop of an impersonation, or a request that may have changed state.
+type ImpersonationEvent struct {
+	ID              int64  `json:"id"`
+	ImpersonationID string `json:"impersonation_id"`
+	AdminID         int64  `json:"admin_id"`
+	UserID          int64  `json:"user_id"`
+	Action          string `json:"action"`
+	// Method and Path name the request of request events. Status is its
+	// response status, or 0 while it is being served or when the answer
+	// could not be recorded.
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+// OAuthClient is an application that signs its users in through this
+// service with OpenID Connect.
+type OAuthClient struct {
+	// ID is the client_id the application presents.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// RedirectURIs are the only places codes are sent to; a request
+	// must name one of them exactly.
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// SecretHash is the hash of the client secret, empty for public
+	// clients such as single-page apps, which cannot keep a secret and
+	// rely on PKCE alone.
+	SecretHash string `json:"-"`
+}
+
+// Public reports whether the client has no secret.
+func (c *OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// OAuthConsent records that a user let a client receive the claims of
+// Scopes. Clients asking for no more than that skip the consent prompt.
+type OAuthConsent struct {
+	UserID    int64     `json:"user_id"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+}
@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID int64
+	Email  string
+	// TokenID identifies the credential presented, such as the jti of an
+	// access token.
+	TokenID   string
+	ExpiresAt time.Time
+
+	// Roles and Permissions are what the caller's roles grant, loaded when
+	// the request is authenticated.
+	Roles       []string
+	Permissions []Permission
+
+	// APIKeyID is set when the caller presented an API key, whose Scopes
+	// then limit what the caller's permissions and self rules allow. Nil
+	// Scopes mean no limit.
+	APIKeyID int64
+	Scopes   []Permission
+
+	// ImpersonatorID is set when an admin acts as the user through an
+	// impersonation token; TokenID then names the impersonation. The
+	// permissions of ImpersonationRestricted are out of scope.
+	ImpersonatorID int64
+
+	// System marks operators acting through the CLI, who hold every
+	// permission and are not a user.
+	System bool
+}
+
+// ImpersonationRestricted are the permissions nobody holds while
+// impersonating, whatever the roles of the user: they would let the admin
+// take over the account or act beyond it.
+var ImpersonationRestricted = []Permission{
+	PermUsersDelete, PermUsersPassword, PermUsersTwoFactor,
+	PermRolesAssign, PermRolesManage, PermAPIKeysManage,
+	PermClientsManage, PermUsersImpersonate,
+}
+
+// SystemPrincipal returns the principal of CLI commands, which run with
+// direct database access and so are trusted with everything.
+func SystemPrincipal() *Principal {
+	return &Principal{System: true}
+}
+
+// Has reports whether p holds permission.
+func (p *Principal) Has(permission Permission) bool {
+	return p.System || (p.InScope(permission) && slices.Contains(p.Permissions, permission))
+}
+
+// InScope reports whether the credential p presented may be used for
+// permission at all.
+func (p *Principal) InScope(permission Permission) bool {
+	if p.Impersonated() && slices.Contains(ImpersonationRestricted, permission) {
+		return false
+	}
+	return p.Scopes == nil || slices.Contains(p.Scopes, permission)
+}
+
+// Impersonated reports whether an admin is acting as p's user.
+func (p *Principal) Impersonated() bool {
+	return p.ImpersonatorID != 0
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal of ctx, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,40 @@
+package entities
+
+import "time"
+
+// Permission names an action on a kind of resource, e.g. "users:delete".
+type Permission string
+
+const (
+	PermUsersRead        Permission = "users:read"
+	PermUsersCreate      Permission = "users:create"
+	PermUsersUpdate      Permission = "users:update"
+	PermUsersDelete      Permission = "users:delete"
+	PermUsersPassword    Permission = "users:password"
+	PermRolesRead        Permission = "roles:read"
+	PermRolesAssign      Permission = "roles:assign"
+	PermRolesManage      Permission = "roles:manage"
+	PermAPIKeysManage    Permission = "apikeys:manage"
+	PermUsersTwoFactor   Permission = "users:two_factor"
+	PermClientsManage    Permission = "clients:manage"
+	PermUsersImpersonate Permission = "users:impersonate"
+)
+
+// AllPermissions lists every permission the application checks.
+var AllPermissions = []Permission{
+	PermUsersRead, PermUsersCreate, PermUsersUpdate, PermUsersDelete,
+	PermUsersPassword, PermRolesRead, PermRolesAssign, PermRolesManage,
+	PermAPIKeysManage, PermUsersTwoFactor, PermClientsManage, PermUsersImpersonate,
+}
+
+// RoleAdmin is the role the initial migration grants every permission.
+const RoleAdmin = "admin"
+
+// Role is a named set of permissions assigned to users.
+type Role struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
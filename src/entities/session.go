@@ -0,0 +1,31 @@
+package entities
+
+import "time"
+
+// Session is a cookie login kept on the server. The client holds only
+// Token; ID names the session in listings without being usable to log in.
+type Session struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	// CSRFToken must accompany state-changing requests; it stays the same
+	// for the life of the session.
+	CSRFToken string    `json:"csrf_token"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastSeenAt is updated at most every minute, so it can lag.
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// ExpiresAt ends the session however active it is.
+	ExpiresAt time.Time `json:"expires_at"`
+	// Privileges fingerprints the roles and permissions the session was
+	// issued with; when they change, the session is rotated.
+	Privileges string `json:"privileges"`
+	// Generation is the sign-in generation of the user the session was
+	// issued under; the session ends when it changes.
+	Generation string `json:"generation,omitempty"`
+
+	// Token is the secret the cookie carries, set only when the session
+	// is created or rotated. Only its hash is stored.
+	Token string `json:"-"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import "time"
+
+// TOTP is a user's enrolment in an authenticator app. It only guards
+// logins once confirmed, which proves the app produces the right codes.
+type TOTP struct {
+	UserID      int64      `json:"user_id"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	// LastStep is the time step of the last accepted code; a code is only
+	// accepted for a later step, so each works once.
+	LastStep  int64     `json:"last_step"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Secret is the shared secret, encrypted with the user ID as
+	// associated data.
+	Secret string `json:"-"`
+}
+
+// Enabled reports whether logins need a code.
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
@@ -12,4 +12,22 @@ type User struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// VerifiedAt is when the user proved they own Email. Changing the
+	// email clears it.
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+
+	// PasswordHash is the encoded password hash, empty when no password is
+	// set. It is never serialised, so users read back from the cache or an
+	// export never carry it.
+	PasswordHash string `json:"-"`
+}
+
+// Verified reports whether the user has confirmed their email.
+func (u *User) Verified() bool {
+	return u.VerifiedAt != nil
+}
+
+// HasPassword reports whether the user has a password set.
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
 }
@@ -0,0 +1,56 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+//go:generate mockery --name APIKeyRepositoryItf --output mocks --filename apikeyrepository_mock.go --outpkg mocks
+type APIKeyRepositoryItf interface {
+	// Create stores a key and fills in its ID and creation time.
+	Create(ctx context.Context, key *entities.APIKey) error
+	GetByID(ctx context.Context, id int64) (*entities.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error)
+	// List returns the keys of a user, or of every user when userID is 0,
+	// ordered by ID. Revoked and expired keys are included.
+	List(ctx context.Context, userID int64) ([]*entities.APIKey, error)
+	// Revoke marks a key revoked at at; revoking it again keeps the first
+	// time.
+	Revoke(ctx context.Context, id int64, at time.Time) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db database.Conn
+}
+
+func NewAPIKeyRepository(db database.Conn) APIKeyRepositoryItf {
+	return &apiKeyRepository{db: db}
+}
+
+// joinPermissions and splitPermissions store scopes as one comma-separated
+// column.
+func joinPermissions(permissions []entities.Permission) string {
+	names := make([]string, len(permissions))
+	for i, permission := range permissions {
+		names[i] = string(permission)
+	}
+	return strings.Join(names, ",")
+}
+
+func splitPermissions(s string) []entities.Permission {
+	permissions := []entities.Permission{}
+	for _, name := range strings.Split(s, ",") {
+		if name != "" {
+			permissions = append(permissions, entities.Permission(name))
+		}
+	}
+	return permissions
+}
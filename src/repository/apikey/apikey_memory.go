@@ -0,0 +1,104 @@
+package apikey
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"solecode/src/entities"
+)
+
+// memoryAPIKeyRepository keeps keys in process memory for tests and local
+// experiments.
+type memoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[int64]*entities.APIKey
+	nextID int64
+}
+
+func NewMemoryAPIKeyRepository() APIKeyRepositoryItf {
+	return &memoryAPIKeyRepository{keys: make(map[int64]*entities.APIKey)}
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *entities.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	key.ID = r.nextID
+	key.CreatedAt = time.Now()
+	r.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) GetByID(ctx context.Context, id int64) (*entities.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return copyAPIKey(key), nil
+}
+
+func (r *memoryAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (r *memoryAPIKeyRepository) List(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []*entities.APIKey{}
+	for _, key := range r.keys {
+		if userID == 0 || key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+	}
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+func copyAPIKey(key *entities.APIKey) *entities.APIKey {
+	c := *key
+	c.Permissions = slices.Clone(key.Permissions)
+	if c.Permissions == nil {
+		c.Permissions = []entities.Permission{}
+	}
+	return &c
+}
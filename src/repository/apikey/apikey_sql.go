@@ -0,0 +1,126 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, permissions, expires_at, revoked_at, last_used_at, created_at"
+
+// scanAPIKey scans a row selected with apiKeyColumns.
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*entities.APIKey, error) {
+	key := &entities.APIKey{}
+	var permissions string
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &permissions,
+		&key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Permissions = splitPermissions(permissions)
+	return key, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *entities.APIKey) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+	now := time.Now()
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, permissions, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{key.UserID, key.Name, key.Prefix, key.Hash, joinPermissions(key.Permissions), key.ExpiresAt, now}
+	var err error
+	if dialect == database.Postgres {
+		err = db.QueryRowContext(ctx, dialect.Rebind(query+" RETURNING id"), args...).Scan(&key.ID)
+	} else {
+		var result sql.Result
+		result, err = db.ExecContext(ctx, query, args...)
+		if err == nil {
+			key.ID, err = result.LastInsertId()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	key.CreatedAt = now
+	return nil
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id int64) (*entities.APIKey, error) {
+	return r.get(ctx, "id = ?", id)
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*entities.APIKey, error) {
+	return r.get(ctx, "prefix = ?", prefix)
+}
+
+func (r *apiKeyRepository) get(ctx context.Context, where string, arg interface{}) (*entities.APIKey, error) {
+	query := r.db.Dialect().Rebind(`SELECT ` + apiKeyColumns + ` FROM api_keys WHERE ` + where)
+	key, err := scanAPIKey(r.db.Reader(ctx).QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	var args []interface{}
+	if userID > 0 {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, r.db.Dialect().Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*entities.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	query := r.db.Dialect().Rebind(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`)
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		// Already revoked, or missing
+		_, err := r.GetByID(ctx, id)
+		return err
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	query := r.db.Dialect().Rebind(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`)
+	if _, err := r.db.Writer(ctx).ExecContext(ctx, query, at, id); err != nil {
+		return fmt.Errorf("failed to record api key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package apikey_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/src/entities"
+	apiKeyRepo "solecode/src/repository/apikey"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	runAPIKeyTests(t, func(t *testing.T) (apiKeyRepo.APIKeyRepositoryItf, []int64) {
+		return apiKeyRepo.NewMemoryAPIKeyRepository(), []int64{1, 2}
+	})
+}
+
+func TestSQLiteAPIKeyRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "apikeys.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	runSQLAPIKeyTests(t, db, database.SQLite)
+}
+
+func TestMySQLAPIKeyRepository(t *testing.T) {
+	runSQLAPIKeyTests(t, openTestDB(t, database.MySQL, "TEST_MYSQL_DSN"), database.MySQL)
+}
+
+func TestPostgresAPIKeyRepository(t *testing.T) {
+	runSQLAPIKeyTests(t, openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN"), database.Postgres)
+}
+
+// runSQLAPIKeyTests migrates db and runs the suite, emptying the keys and
+// users and creating two users before each test.
+func runSQLAPIKeyTests(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	runAPIKeyTests(t, func(t *testing.T) (apiKeyRepo.APIKeyRepositoryItf, []int64) {
+		for _, query := range []string{"DELETE FROM api_keys", "DELETE FROM users"} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+
+		var ids []int64
+		for _, email := range []string{"john@example.com", "jane@example.com"} {
+			ids = append(ids, insertUser(t, db, dialect, email))
+		}
+		return apiKeyRepo.NewAPIKeyRepository(database.WrapDB(db, dialect)), ids
+	})
+}
+
+func insertUser(t *testing.T, db *sql.DB, dialect database.Dialect, email string) int64 {
+	query := "INSERT INTO users (name, email) VALUES (?, ?)"
+	var id int64
+	if dialect == database.Postgres {
+		require.NoError(t, db.QueryRow(dialect.Rebind(query+" RETURNING id"), "Test User", email).Scan(&id))
+		return id
+	}
+	result, err := db.Exec(query, "Test User", email)
+	require.NoError(t, err)
+	id, err = result.LastInsertId()
+	require.NoError(t, err)
+	return id
+}
+
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runAPIKeyTests checks the behaviour every APIKeyRepositoryItf
+// implementation shares. newRepo returns an empty repository and the IDs of
+// two existing users.
+func runAPIKeyTests(t *testing.T, newRepo func(t *testing.T) (apiKeyRepo.APIKeyRepositoryItf, []int64)) {
+	ctx := context.Background()
+
+	newKey := func(userID int64, prefix string) *entities.APIKey {
+		return &entities.APIKey{
+			UserID:      userID,
+			Name:        "deploy",
+			Prefix:      prefix,
+			Hash:        "5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef",
+			Permissions: []entities.Permission{entities.PermUsersRead, entities.PermUsersCreate},
+		}
+	}
+
+	t.Run("Create and get", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+		key := newKey(users[0], "uapi_0a1b2c3d")
+		key.ExpiresAt = &expires
+		require.NoError(t, repo.Create(ctx, key))
+		assert.NotZero(t, key.ID)
+		assert.False(t, key.CreatedAt.IsZero())
+
+		got, err := repo.GetByPrefix(ctx, "uapi_0a1b2c3d")
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, got.ID)
+		assert.Equal(t, users[0], got.UserID)
+		assert.Equal(t, "deploy", got.Name)
+		assert.Equal(t, key.Hash, got.Hash)
+		assert.Equal(t, key.Permissions, got.Permissions)
+		require.NotNil(t, got.ExpiresAt)
+		assert.True(t, expires.Equal(*got.ExpiresAt))
+		assert.Nil(t, got.RevokedAt)
+		assert.Nil(t, got.LastUsedAt)
+
+		got, err = repo.GetByID(ctx, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "uapi_0a1b2c3d", got.Prefix)
+	})
+
+	t.Run("unknown keys are not found", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		_, err := repo.GetByPrefix(ctx, "uapi_ffffffff")
+		assert.ErrorIs(t, err, apiKeyRepo.ErrAPIKeyNotFound)
+		_, err = repo.GetByID(ctx, 42)
+		assert.ErrorIs(t, err, apiKeyRepo.ErrAPIKeyNotFound)
+		assert.ErrorIs(t, repo.Revoke(ctx, 42, time.Now()), apiKeyRepo.ErrAPIKeyNotFound)
+	})
+
+	t.Run("List filters by user", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, newKey(users[0], "uapi_00000001")))
+		require.NoError(t, repo.Create(ctx, newKey(users[1], "uapi_00000002")))
+		require.NoError(t, repo.Create(ctx, newKey(users[0], "uapi_00000003")))
+
+		keys, err := repo.List(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, keys, 3)
+		assert.Equal(t, "uapi_00000001", keys[0].Prefix)
+
+		keys, err = repo.List(ctx, users[0])
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, []string{"uapi_00000001", "uapi_00000003"}, []string{keys[0].Prefix, keys[1].Prefix})
+	})
+
+	t.Run("Revoke keeps the first time", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		key := newKey(users[0], "uapi_0a1b2c3d")
+		require.NoError(t, repo.Create(ctx, key))
+
+		first := time.Now().Truncate(time.Second)
+		require.NoError(t, repo.Revoke(ctx, key.ID, first))
+		require.NoError(t, repo.Revoke(ctx, key.ID, first.Add(time.Hour)))
+
+		got, err := repo.GetByID(ctx, key.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.RevokedAt)
+		assert.True(t, first.Equal(*got.RevokedAt))
+		assert.False(t, got.Active(time.Now()))
+	})
+
+	t.Run("TouchLastUsed", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		key := newKey(users[0], "uapi_0a1b2c3d")
+		require.NoError(t, repo.Create(ctx, key))
+
+		at := time.Now().Truncate(time.Second)
+		require.NoError(t, repo.TouchLastUsed(ctx, key.ID, at))
+		got, err := repo.GetByID(ctx, key.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.LastUsedAt)
+		assert.True(t, at.Equal(*got.LastUsedAt))
+	})
+}
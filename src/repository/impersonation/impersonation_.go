@@ -0,0 +1,42 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+var ErrImpersonationNotFound = errors.New("impersonation not found")
+
+//go:generate mockery --name ImpersonationRepositoryItf --output mocks --filename impersonationrepository_mock.go --outpkg mocks
+type ImpersonationRepositoryItf interface {
+	// Create stores an impersonation as given.
+	Create(ctx context.Context, impersonation *entities.Impersonation) error
+	Get(ctx context.Context, id string) (*entities.Impersonation, error)
+	// List returns the impersonations by adminID of userID, newest first;
+	// either may be 0 to match anyone.
+	List(ctx context.Context, adminID, userID int64) ([]*entities.Impersonation, error)
+	// End marks an impersonation ended at at; ending it again keeps the
+	// first time.
+	End(ctx context.Context, id string, at time.Time) error
+
+	// LogEvent appends an event to the log and fills in its ID and
+	// creation time.
+	LogEvent(ctx context.Context, event *entities.ImpersonationEvent) error
+	// SetEventStatus records the response status of a request event.
+	SetEventStatus(ctx context.Context, id int64, status int) error
+	// ListEvents returns the events of an impersonation in the order they
+	// were logged.
+	ListEvents(ctx context.Context, impersonationID string) ([]*entities.ImpersonationEvent, error)
+}
+
+type impersonationRepository struct {
+	db database.Conn
+}
+
+func NewImpersonationRepository(db database.Conn) ImpersonationRepositoryItf {
+	return &impersonationRepository{db: db}
+}
@@ -0,0 +1,120 @@
+package impersonation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"solecode/src/entities"
+)
+
+// memoryImpersonationRepository keeps the log in process memory for tests
+// and local experiments.
+type memoryImpersonationRepository struct {
+	mu             sync.RWMutex
+	impersonations map[string]*entities.Impersonation
+	events         []*entities.ImpersonationEvent
+}
+
+func NewMemoryImpersonationRepository() ImpersonationRepositoryItf {
+	return &memoryImpersonationRepository{impersonations: make(map[string]*entities.Impersonation)}
+}
+
+func (r *memoryImpersonationRepository) Create(ctx context.Context, impersonation *entities.Impersonation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.impersonations[impersonation.ID] = copyImpersonation(impersonation)
+	return nil
+}
+
+func (r *memoryImpersonationRepository) Get(ctx context.Context, id string) (*entities.Impersonation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	impersonation, ok := r.impersonations[id]
+	if !ok {
+		return nil, ErrImpersonationNotFound
+	}
+	return copyImpersonation(impersonation), nil
+}
+
+func (r *memoryImpersonationRepository) List(ctx context.Context, adminID, userID int64) ([]*entities.Impersonation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	impersonations := []*entities.Impersonation{}
+	for _, impersonation := range r.impersonations {
+		if (adminID == 0 || impersonation.AdminID == adminID) && (userID == 0 || impersonation.UserID == userID) {
+			impersonations = append(impersonations, copyImpersonation(impersonation))
+		}
+	}
+	sort.Slice(impersonations, func(i, j int) bool {
+		a, b := impersonations[i], impersonations[j]
+		if !a.StartedAt.Equal(b.StartedAt) {
+			return a.StartedAt.After(b.StartedAt)
+		}
+		return a.ID < b.ID
+	})
+	return impersonations, nil
+}
+
+func (r *memoryImpersonationRepository) End(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	impersonation, ok := r.impersonations[id]
+	if !ok {
+		return ErrImpersonationNotFound
+	}
+	if impersonation.EndedAt == nil {
+		impersonation.EndedAt = &at
+	}
+	return nil
+}
+
+func (r *memoryImpersonationRepository) LogEvent(ctx context.Context, event *entities.ImpersonationEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.events)) + 1
+	event.CreatedAt = time.Now()
+	c := *event
+	r.events = append(r.events, &c)
+	return nil
+}
+
+func (r *memoryImpersonationRepository) SetEventStatus(ctx context.Context, id int64, status int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id > 0 && id <= int64(len(r.events)) {
+		r.events[id-1].Status = status
+	}
+	return nil
+}
+
+func (r *memoryImpersonationRepository) ListEvents(ctx context.Context, impersonationID string) ([]*entities.ImpersonationEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []*entities.ImpersonationEvent{}
+	for _, event := range r.events {
+		if event.ImpersonationID == impersonationID {
+			c := *event
+			events = append(events, &c)
+		}
+	}
+	return events, nil
+}
+
+func copyImpersonation(impersonation *entities.Impersonation) *entities.Impersonation {
+	c := *impersonation
+	c.Token = ""
+	if impersonation.EndedAt != nil {
+		ended := *impersonation.EndedAt
+		c.EndedAt = &ended
+	}
+	return &c
+}
@@ -0,0 +1,166 @@
+package impersonation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+const (
+	impersonationColumns = "id, admin_id, user_id, reason, started_at, expires_at, ended_at"
+	eventColumns         = "id, impersonation_id, admin_id, user_id, action, method, path, status, created_at"
+)
+
+// scanImpersonation scans a row selected with impersonationColumns.
+func scanImpersonation(row interface{ Scan(...interface{}) error }) (*entities.Impersonation, error) {
+	impersonation := &entities.Impersonation{}
+	err := row.Scan(&impersonation.ID, &impersonation.AdminID, &impersonation.UserID, &impersonation.Reason,
+		&impersonation.StartedAt, &impersonation.ExpiresAt, &impersonation.EndedAt)
+	if err != nil {
+		return nil, err
+	}
+	return impersonation, nil
+}
+
+// scanEvent scans a row selected with eventColumns.
+func scanEvent(row interface{ Scan(...interface{}) error }) (*entities.ImpersonationEvent, error) {
+	event := &entities.ImpersonationEvent{}
+	err := row.Scan(&event.ID, &event.ImpersonationID, &event.AdminID, &event.UserID, &event.Action,
+		&event.Method, &event.Path, &event.Status, &event.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *impersonationRepository) Create(ctx context.Context, impersonation *entities.Impersonation) error {
+	query := r.db.Dialect().Rebind(`INSERT INTO impersonations (` + impersonationColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	_, err := r.db.Writer(ctx).ExecContext(ctx, query, impersonation.ID, impersonation.AdminID, impersonation.UserID,
+		impersonation.Reason, impersonation.StartedAt, impersonation.ExpiresAt, impersonation.EndedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create impersonation: %w", err)
+	}
+	return nil
+}
+
+func (r *impersonationRepository) Get(ctx context.Context, id string) (*entities.Impersonation, error) {
+	query := r.db.Dialect().Rebind(`SELECT ` + impersonationColumns + ` FROM impersonations WHERE id = ?`)
+	impersonation, err := scanImpersonation(r.db.Reader(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrImpersonationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonation: %w", err)
+	}
+	return impersonation, nil
+}
+
+func (r *impersonationRepository) List(ctx context.Context, adminID, userID int64) ([]*entities.Impersonation, error) {
+	query := `SELECT ` + impersonationColumns + ` FROM impersonations WHERE 1 = 1`
+	var args []interface{}
+	if adminID > 0 {
+		query += ` AND admin_id = ?`
+		args = append(args, adminID)
+	}
+	if userID > 0 {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY started_at DESC, id`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, r.db.Dialect().Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+	defer rows.Close()
+
+	impersonations := []*entities.Impersonation{}
+	for rows.Next() {
+		impersonation, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation: %w", err)
+		}
+		impersonations = append(impersonations, impersonation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+	return impersonations, nil
+}
+
+func (r *impersonationRepository) End(ctx context.Context, id string, at time.Time) error {
+	query := r.db.Dialect().Rebind(`UPDATE impersonations SET ended_at = ? WHERE id = ? AND ended_at IS NULL`)
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to end impersonation: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		// Already ended, or missing
+		_, err := r.Get(ctx, id)
+		return err
+	}
+	return nil
+}
+
+func (r *impersonationRepository) LogEvent(ctx context.Context, event *entities.ImpersonationEvent) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+	now := time.Now()
+
+	query := `INSERT INTO impersonation_events (impersonation_id, admin_id, user_id, action, method, path, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{event.ImpersonationID, event.AdminID, event.UserID, event.Action, event.Method, event.Path, event.Status, now}
+	var err error
+	if dialect == database.Postgres {
+		err = db.QueryRowContext(ctx, dialect.Rebind(query+" RETURNING id"), args...).Scan(&event.ID)
+	} else {
+		var result sql.Result
+		result, err = db.ExecContext(ctx, query, args...)
+		if err == nil {
+			event.ID, err = result.LastInsertId()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to log impersonation event: %w", err)
+	}
+
+	event.CreatedAt = now
+	return nil
+}
+
+func (r *impersonationRepository) SetEventStatus(ctx context.Context, id int64, status int) error {
+	query := r.db.Dialect().Rebind(`UPDATE impersonation_events SET status = ? WHERE id = ?`)
+	if _, err := r.db.Writer(ctx).ExecContext(ctx, query, status, id); err != nil {
+		return fmt.Errorf("failed to record impersonation event status: %w", err)
+	}
+	return nil
+}
+
+func (r *impersonationRepository) ListEvents(ctx context.Context, impersonationID string) ([]*entities.ImpersonationEvent, error) {
+	query := r.db.Dialect().Rebind(`SELECT ` + eventColumns + ` FROM impersonation_events WHERE impersonation_id = ? ORDER BY id`)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, impersonationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonation events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*entities.ImpersonationEvent{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list impersonation events: %w", err)
+	}
+	return events, nil
+}
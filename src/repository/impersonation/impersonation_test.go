@@ -0,0 +1,189 @@
+package impersonation_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/src/entities"
+	impersonationRepo "solecode/src/repository/impersonation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryImpersonationRepository(t *testing.T) {
+	runImpersonationTests(t, func(t *testing.T) impersonationRepo.ImpersonationRepositoryItf {
+		return impersonationRepo.NewMemoryImpersonationRepository()
+	})
+}
+
+func TestSQLiteImpersonationRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "impersonations.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	runSQLImpersonationTests(t, db, database.SQLite)
+}
+
+func TestMySQLImpersonationRepository(t *testing.T) {
+	runSQLImpersonationTests(t, openTestDB(t, database.MySQL, "TEST_MYSQL_DSN"), database.MySQL)
+}
+
+func TestPostgresImpersonationRepository(t *testing.T) {
+	runSQLImpersonationTests(t, openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN"), database.Postgres)
+}
+
+// runSQLImpersonationTests migrates db and runs the suite, emptying the
+// log before each test.
+func runSQLImpersonationTests(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	runImpersonationTests(t, func(t *testing.T) impersonationRepo.ImpersonationRepositoryItf {
+		for _, query := range []string{"DELETE FROM impersonation_events", "DELETE FROM impersonations"} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+		return impersonationRepo.NewImpersonationRepository(database.WrapDB(db, dialect))
+	})
+}
+
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runImpersonationTests checks the behaviour every
+// ImpersonationRepositoryItf implementation shares. newRepo returns an
+// empty repository.
+func runImpersonationTests(t *testing.T, newRepo func(t *testing.T) impersonationRepo.ImpersonationRepositoryItf) {
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Second)
+
+	newImpersonation := func(id string, adminID, userID int64, startedAt time.Time) *entities.Impersonation {
+		return &entities.Impersonation{
+			ID:        id,
+			AdminID:   adminID,
+			UserID:    userID,
+			Reason:    "ticket 1234",
+			StartedAt: startedAt,
+			ExpiresAt: startedAt.Add(30 * time.Minute),
+		}
+	}
+
+	t.Run("Create and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, newImpersonation("imp1", 1, 2, start)))
+
+		got, err := repo.Get(ctx, "imp1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.AdminID)
+		assert.Equal(t, int64(2), got.UserID)
+		assert.Equal(t, "ticket 1234", got.Reason)
+		assert.True(t, start.Equal(got.StartedAt))
+		assert.True(t, start.Add(30*time.Minute).Equal(got.ExpiresAt))
+		assert.Nil(t, got.EndedAt)
+		assert.True(t, got.Active(start))
+	})
+
+	t.Run("unknown impersonations are not found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Get(ctx, "nope")
+		assert.ErrorIs(t, err, impersonationRepo.ErrImpersonationNotFound)
+		assert.ErrorIs(t, repo.End(ctx, "nope", start), impersonationRepo.ErrImpersonationNotFound)
+	})
+
+	t.Run("List filters by admin and user, newest first", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, newImpersonation("imp1", 1, 2, start)))
+		require.NoError(t, repo.Create(ctx, newImpersonation("imp2", 1, 3, start.Add(time.Minute))))
+		require.NoError(t, repo.Create(ctx, newImpersonation("imp3", 4, 2, start.Add(2*time.Minute))))
+
+		ids := func(impersonations []*entities.Impersonation) []string {
+			ids := []string{}
+			for _, impersonation := range impersonations {
+				ids = append(ids, impersonation.ID)
+			}
+			return ids
+		}
+
+		all, err := repo.List(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"imp3", "imp2", "imp1"}, ids(all))
+
+		byAdmin, err := repo.List(ctx, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"imp2", "imp1"}, ids(byAdmin))
+
+		ofUser, err := repo.List(ctx, 0, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"imp3", "imp1"}, ids(ofUser))
+
+		both, err := repo.List(ctx, 4, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"imp3"}, ids(both))
+	})
+
+	t.Run("End keeps the first time", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, newImpersonation("imp1", 1, 2, start)))
+		first := start.Add(time.Minute)
+		require.NoError(t, repo.End(ctx, "imp1", first))
+		require.NoError(t, repo.End(ctx, "imp1", first.Add(time.Minute)))
+
+		got, err := repo.Get(ctx, "imp1")
+		require.NoError(t, err)
+		require.NotNil(t, got.EndedAt)
+		assert.True(t, first.Equal(*got.EndedAt))
+		assert.False(t, got.Active(first))
+	})
+
+	t.Run("events", func(t *testing.T) {
+		repo := newRepo(t)
+
+		started := &entities.ImpersonationEvent{ImpersonationID: "imp1", AdminID: 1, UserID: 2, Action: entities.ImpersonationStart}
+		require.NoError(t, repo.LogEvent(ctx, started))
+		assert.NotZero(t, started.ID)
+		assert.False(t, started.CreatedAt.IsZero())
+
+		request := &entities.ImpersonationEvent{ImpersonationID: "imp1", AdminID: 1, UserID: 2,
+			Action: entities.ImpersonationRequest, Method: "PUT", Path: "/api/v1/users/2"}
+		require.NoError(t, repo.LogEvent(ctx, request))
+		require.NoError(t, repo.LogEvent(ctx, &entities.ImpersonationEvent{ImpersonationID: "imp2", AdminID: 1, UserID: 3,
+			Action: entities.ImpersonationStart}))
+		require.NoError(t, repo.SetEventStatus(ctx, request.ID, 200))
+
+		events, err := repo.ListEvents(ctx, "imp1")
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, entities.ImpersonationStart, events[0].Action)
+		assert.Equal(t, entities.ImpersonationRequest, events[1].Action)
+		assert.Equal(t, "PUT", events[1].Method)
+		assert.Equal(t, "/api/v1/users/2", events[1].Path)
+		assert.Equal(t, 200, events[1].Status)
+
+		events, err = repo.ListEvents(ctx, "nope")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
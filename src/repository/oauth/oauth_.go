@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+var (
+	ErrClientNotFound  = errors.New("oauth client not found")
+	ErrConsentNotFound = errors.New("oauth consent not found")
+)
+
+//go:generate mockery --name OAuthRepositoryItf --output mocks --filename oauthrepository_mock.go --outpkg mocks
+type OAuthRepositoryItf interface {
+	// CreateClient stores a client and fills in its creation time.
+	CreateClient(ctx context.Context, client *entities.OAuthClient) error
+	GetClient(ctx context.Context, id string) (*entities.OAuthClient, error)
+	// ListClients returns every client, ordered by ID.
+	ListClients(ctx context.Context) ([]*entities.OAuthClient, error)
+	// DeleteClient removes a client and the consents given to it.
+	DeleteClient(ctx context.Context, id string) error
+
+	GetConsent(ctx context.Context, userID int64, clientID string) (*entities.OAuthConsent, error)
+	// SaveConsent stores the consent of a user to a client, replacing an
+	// earlier one.
+	SaveConsent(ctx context.Context, consent *entities.OAuthConsent) error
+	// ListConsents returns the consents of a user, ordered by client ID.
+	ListConsents(ctx context.Context, userID int64) ([]*entities.OAuthConsent, error)
+	DeleteConsent(ctx context.Context, userID int64, clientID string) error
+}
+
+type oauthRepository struct {
+	db database.Conn
+}
+
+func NewOAuthRepository(db database.Conn) OAuthRepositoryItf {
+	return &oauthRepository{db: db}
+}
+
+// Redirect URIs are stored one per line, scopes separated by spaces as
+// OAuth writes them; neither can contain its separator.
+func joinRedirectURIs(uris []string) string {
+	return strings.Join(uris, "\n")
+}
+
+func splitRedirectURIs(s string) []string {
+	return strings.Fields(s)
+}
+
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func splitScopes(s string) []string {
+	return strings.Fields(s)
+}
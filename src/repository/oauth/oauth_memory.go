@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"solecode/src/entities"
+)
+
+type consentKey struct {
+	userID   int64
+	clientID string
+}
+
+// memoryOAuthRepository keeps clients and consents in process memory for
+// tests and local experiments.
+type memoryOAuthRepository struct {
+	mu       sync.RWMutex
+	clients  map[string]*entities.OAuthClient
+	consents map[consentKey]*entities.OAuthConsent
+}
+
+func NewMemoryOAuthRepository() OAuthRepositoryItf {
+	return &memoryOAuthRepository{
+		clients:  make(map[string]*entities.OAuthClient),
+		consents: make(map[consentKey]*entities.OAuthConsent),
+	}
+}
+
+func (r *memoryOAuthRepository) CreateClient(ctx context.Context, client *entities.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[client.ID]; ok {
+		return fmt.Errorf("failed to create oauth client: %q exists", client.ID)
+	}
+	client.CreatedAt = time.Now()
+	r.clients[client.ID] = copyClient(client)
+	return nil
+}
+
+func (r *memoryOAuthRepository) GetClient(ctx context.Context, id string) (*entities.OAuthClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return copyClient(client), nil
+}
+
+func (r *memoryOAuthRepository) ListClients(ctx context.Context) ([]*entities.OAuthClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*entities.OAuthClient, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, copyClient(client))
+	}
+	slices.SortFunc(clients, func(a, b *entities.OAuthClient) int { return strings.Compare(a.ID, b.ID) })
+	return clients, nil
+}
+
+func (r *memoryOAuthRepository) DeleteClient(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[id]; !ok {
+		return ErrClientNotFound
+	}
+	delete(r.clients, id)
+	for key := range r.consents {
+		if key.clientID == id {
+			delete(r.consents, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryOAuthRepository) GetConsent(ctx context.Context, userID int64, clientID string) (*entities.OAuthConsent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	consent, ok := r.consents[consentKey{userID, clientID}]
+	if !ok {
+		return nil, ErrConsentNotFound
+	}
+	return copyConsent(consent), nil
+}
+
+func (r *memoryOAuthRepository) SaveConsent(ctx context.Context, consent *entities.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[consent.ClientID]; !ok {
+		return fmt.Errorf("failed to save oauth consent: %w", ErrClientNotFound)
+	}
+	r.consents[consentKey{consent.UserID, consent.ClientID}] = copyConsent(consent)
+	return nil
+}
+
+func (r *memoryOAuthRepository) ListConsents(ctx context.Context, userID int64) ([]*entities.OAuthConsent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	consents := []*entities.OAuthConsent{}
+	for key, consent := range r.consents {
+		if key.userID == userID {
+			consents = append(consents, copyConsent(consent))
+		}
+	}
+	slices.SortFunc(consents, func(a, b *entities.OAuthConsent) int { return strings.Compare(a.ClientID, b.ClientID) })
+	return consents, nil
+}
+
+func (r *memoryOAuthRepository) DeleteConsent(ctx context.Context, userID int64, clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := consentKey{userID, clientID}
+	if _, ok := r.consents[key]; !ok {
+		return ErrConsentNotFound
+	}
+	delete(r.consents, key)
+	return nil
+}
+
+func copyClient(client *entities.OAuthClient) *entities.OAuthClient {
+	c := *client
+	c.RedirectURIs = slices.Clone(client.RedirectURIs)
+	return &c
+}
+
+func copyConsent(consent *entities.OAuthConsent) *entities.OAuthConsent {
+	c := *consent
+	c.Scopes = slices.Clone(consent.Scopes)
+	return &c
+}
@@ -0,0 +1,172 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"solecode/src/entities"
+)
+
+const clientColumns = "id, name, secret_hash, redirect_uris, created_at"
+
+// scanClient scans a row selected with clientColumns.
+func scanClient(row interface{ Scan(...interface{}) error }) (*entities.OAuthClient, error) {
+	client := &entities.OAuthClient{}
+	var redirectURIs string
+	if err := row.Scan(&client.ID, &client.Name, &client.SecretHash, &redirectURIs, &client.CreatedAt); err != nil {
+		return nil, err
+	}
+	client.RedirectURIs = splitRedirectURIs(redirectURIs)
+	return client, nil
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *entities.OAuthClient) error {
+	now := time.Now()
+	query := r.db.Dialect().Rebind(`INSERT INTO oauth_clients (` + clientColumns + `) VALUES (?, ?, ?, ?, ?)`)
+	_, err := r.db.Writer(ctx).ExecContext(ctx, query, client.ID, client.Name, client.SecretHash,
+		joinRedirectURIs(client.RedirectURIs), now)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	client.CreatedAt = now
+	return nil
+}
+
+func (r *oauthRepository) GetClient(ctx context.Context, id string) (*entities.OAuthClient, error) {
+	query := r.db.Dialect().Rebind(`SELECT ` + clientColumns + ` FROM oauth_clients WHERE id = ?`)
+	client, err := scanClient(r.db.Reader(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return client, nil
+}
+
+func (r *oauthRepository) ListClients(ctx context.Context) ([]*entities.OAuthClient, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `SELECT `+clientColumns+` FROM oauth_clients ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []*entities.OAuthClient{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+		}
+		clients = append(clients, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *oauthRepository) DeleteClient(ctx context.Context, id string) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+	if _, err := db.ExecContext(ctx, dialect.Rebind(`DELETE FROM oauth_consents WHERE client_id = ?`), id); err != nil {
+		return fmt.Errorf("failed to delete oauth consents: %w", err)
+	}
+	ok, err := r.exec(ctx, dialect.Rebind(`DELETE FROM oauth_clients WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+	if !ok {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+func (r *oauthRepository) GetConsent(ctx context.Context, userID int64, clientID string) (*entities.OAuthConsent, error) {
+	query := r.db.Dialect().Rebind(`SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE user_id = ? AND client_id = ?`)
+	consent, err := scanConsent(r.db.Reader(ctx).QueryRowContext(ctx, query, userID, clientID))
+	if err == sql.ErrNoRows {
+		return nil, ErrConsentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth consent: %w", err)
+	}
+	return consent, nil
+}
+
+func (r *oauthRepository) SaveConsent(ctx context.Context, consent *entities.OAuthConsent) error {
+	dialect := r.db.Dialect()
+	scopes := joinScopes(consent.Scopes)
+	update := dialect.Rebind(`UPDATE oauth_consents SET scopes = ?, granted_at = ? WHERE user_id = ? AND client_id = ?`)
+	ok, err := r.exec(ctx, update, scopes, consent.GrantedAt, consent.UserID, consent.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+	if ok {
+		return nil
+	}
+
+	insert := dialect.Rebind(`INSERT INTO oauth_consents (user_id, client_id, scopes, granted_at) VALUES (?, ?, ?, ?)`)
+	if _, err := r.db.Writer(ctx).ExecContext(ctx, insert, consent.UserID, consent.ClientID, scopes, consent.GrantedAt); err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+	return nil
+}
+
+func (r *oauthRepository) ListConsents(ctx context.Context, userID int64) ([]*entities.OAuthConsent, error) {
+	query := r.db.Dialect().Rebind(`SELECT user_id, client_id, scopes, granted_at FROM oauth_consents WHERE user_id = ? ORDER BY client_id`)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []*entities.OAuthConsent{}
+	for rows.Next() {
+		consent, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list oauth consents: %w", err)
+	}
+	return consents, nil
+}
+
+func (r *oauthRepository) DeleteConsent(ctx context.Context, userID int64, clientID string) error {
+	query := r.db.Dialect().Rebind(`DELETE FROM oauth_consents WHERE user_id = ? AND client_id = ?`)
+	ok, err := r.exec(ctx, query, userID, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth consent: %w", err)
+	}
+	if !ok {
+		return ErrConsentNotFound
+	}
+	return nil
+}
+
+func scanConsent(row interface{ Scan(...interface{}) error }) (*entities.OAuthConsent, error) {
+	consent := &entities.OAuthConsent{}
+	var scopes string
+	if err := row.Scan(&consent.UserID, &consent.ClientID, &scopes, &consent.GrantedAt); err != nil {
+		return nil, err
+	}
+	consent.Scopes = splitScopes(scopes)
+	return consent, nil
+}
+
+// exec runs a statement and reports whether it changed a row.
+func (r *oauthRepository) exec(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,163 @@
+package oauth_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/src/entities"
+	oauthRepo "solecode/src/repository/oauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryOAuthRepository(t *testing.T) {
+	runOAuthTests(t, func(t *testing.T) (oauthRepo.OAuthRepositoryItf, []int64) {
+		return oauthRepo.NewMemoryOAuthRepository(), []int64{1, 2}
+	})
+}
+
+func TestSQLiteOAuthRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "oauth.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	runSQLOAuthTests(t, db, database.SQLite)
+}
+
+func TestMySQLOAuthRepository(t *testing.T) {
+	runSQLOAuthTests(t, openTestDB(t, database.MySQL, "TEST_MYSQL_DSN"), database.MySQL)
+}
+
+func TestPostgresOAuthRepository(t *testing.T) {
+	runSQLOAuthTests(t, openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN"), database.Postgres)
+}
+
+// runSQLOAuthTests migrates db and runs the suite, emptying the clients,
+// consents and users and creating two users before each test.
+func runSQLOAuthTests(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	runOAuthTests(t, func(t *testing.T) (oauthRepo.OAuthRepositoryItf, []int64) {
+		for _, query := range []string{"DELETE FROM oauth_consents", "DELETE FROM oauth_clients", "DELETE FROM user_recovery_codes",
+			"DELETE FROM user_totp", "DELETE FROM api_keys", "DELETE FROM users"} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+
+		var ids []int64
+		for _, email := range []string{"john@example.com", "jane@example.com"} {
+			ids = append(ids, insertUser(t, db, dialect, email))
+		}
+		return oauthRepo.NewOAuthRepository(database.WrapDB(db, dialect)), ids
+	})
+}
+
+func insertUser(t *testing.T, db *sql.DB, dialect database.Dialect, email string) int64 {
+	query := "INSERT INTO users (name, email) VALUES (?, ?)"
+	var id int64
+	if dialect == database.Postgres {
+		require.NoError(t, db.QueryRow(dialect.Rebind(query+" RETURNING id"), "Test User", email).Scan(&id))
+		return id
+	}
+	result, err := db.Exec(query, "Test User", email)
+	require.NoError(t, err)
+	id, err = result.LastInsertId()
+	require.NoError(t, err)
+	return id
+}
+
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runOAuthTests checks the behaviour every OAuthRepositoryItf
+// implementation shares. newRepo returns an empty repository and the IDs
+// of two existing users.
+func runOAuthTests(t *testing.T, newRepo func(t *testing.T) (oauthRepo.OAuthRepositoryItf, []int64)) {
+	ctx := context.Background()
+
+	t.Run("clients", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		_, err := repo.GetClient(ctx, "web")
+		assert.ErrorIs(t, err, oauthRepo.ErrClientNotFound)
+
+		web := &entities.OAuthClient{ID: "web", Name: "Web", SecretHash: "hash",
+			RedirectURIs: []string{"https://app.example.com/callback", "http://localhost:3000/callback"}}
+		require.NoError(t, repo.CreateClient(ctx, web))
+		assert.False(t, web.CreatedAt.IsZero())
+		require.NoError(t, repo.CreateClient(ctx, &entities.OAuthClient{ID: "spa", Name: "SPA",
+			RedirectURIs: []string{"https://spa.example.com/"}}))
+
+		got, err := repo.GetClient(ctx, "web")
+		require.NoError(t, err)
+		assert.Equal(t, "Web", got.Name)
+		assert.Equal(t, web.RedirectURIs, got.RedirectURIs)
+		assert.False(t, got.Public())
+
+		clients, err := repo.ListClients(ctx)
+		require.NoError(t, err)
+		require.Len(t, clients, 2)
+		assert.Equal(t, "spa", clients[0].ID)
+		assert.True(t, clients[0].Public())
+
+		require.NoError(t, repo.DeleteClient(ctx, "spa"))
+		assert.ErrorIs(t, repo.DeleteClient(ctx, "spa"), oauthRepo.ErrClientNotFound)
+	})
+
+	t.Run("consents", func(t *testing.T) {
+		repo, users := newRepo(t)
+		for _, id := range []string{"web", "cli"} {
+			require.NoError(t, repo.CreateClient(ctx, &entities.OAuthClient{ID: id, Name: id, RedirectURIs: []string{"https://example.com/"}}))
+		}
+
+		_, err := repo.GetConsent(ctx, users[0], "web")
+		assert.ErrorIs(t, err, oauthRepo.ErrConsentNotFound)
+
+		at := time.Now().Truncate(time.Second)
+		require.NoError(t, repo.SaveConsent(ctx, &entities.OAuthConsent{UserID: users[0], ClientID: "web", Scopes: []string{"openid"}, GrantedAt: at}))
+		require.NoError(t, repo.SaveConsent(ctx, &entities.OAuthConsent{UserID: users[0], ClientID: "web", Scopes: []string{"openid", "email"}, GrantedAt: at}))
+		require.NoError(t, repo.SaveConsent(ctx, &entities.OAuthConsent{UserID: users[0], ClientID: "cli", Scopes: []string{"openid"}, GrantedAt: at}))
+		require.NoError(t, repo.SaveConsent(ctx, &entities.OAuthConsent{UserID: users[1], ClientID: "web", Scopes: []string{"openid"}, GrantedAt: at}))
+
+		got, err := repo.GetConsent(ctx, users[0], "web")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"openid", "email"}, got.Scopes, "saving again replaces")
+		assert.True(t, at.Equal(got.GrantedAt))
+
+		consents, err := repo.ListConsents(ctx, users[0])
+		require.NoError(t, err)
+		require.Len(t, consents, 2)
+		assert.Equal(t, "cli", consents[0].ClientID)
+
+		require.NoError(t, repo.DeleteConsent(ctx, users[0], "cli"))
+		assert.ErrorIs(t, repo.DeleteConsent(ctx, users[0], "cli"), oauthRepo.ErrConsentNotFound)
+
+		// Deleting a client takes its consents along
+		require.NoError(t, repo.DeleteClient(ctx, "web"))
+		consents, err = repo.ListConsents(ctx, users[0])
+		require.NoError(t, err)
+		assert.Empty(t, consents)
+		_, err = repo.GetConsent(ctx, users[1], "web")
+		assert.ErrorIs(t, err, oauthRepo.ErrConsentNotFound)
+	})
+}
@@ -1,17 +1,73 @@
 package repository
 
 import (
-	"database/sql"
+	"context"
 
+	"solecode/pkg/database"
+	apiKeyRepo "solecode/src/repository/apikey"
+	impersonationRepo "solecode/src/repository/impersonation"
+	oauthRepo "solecode/src/repository/oauth"
+	roleRepo "solecode/src/repository/role"
+	totpRepo "solecode/src/repository/totp"
 	userRepo "solecode/src/repository/user"
 )
 
 type Repository struct {
-	User userRepo.UserRepositoryItf
+	User          userRepo.UserRepositoryItf
+	Role          roleRepo.RoleRepositoryItf
+	APIKey        apiKeyRepo.APIKeyRepositoryItf
+	TOTP          totpRepo.TOTPRepositoryItf
+	OAuth         oauthRepo.OAuthRepositoryItf
+	Impersonation impersonationRepo.ImpersonationRepositoryItf
+
+	db *database.Cluster
+}
+
+func InitRepository(db *database.Cluster) *Repository {
+	return &Repository{
+		User:          userRepo.NewUserRepository(db),
+		Role:          roleRepo.NewRoleRepository(db),
+		APIKey:        apiKeyRepo.NewAPIKeyRepository(db),
+		TOTP:          totpRepo.NewTOTPRepository(db),
+		OAuth:         oauthRepo.NewOAuthRepository(db),
+		Impersonation: impersonationRepo.NewImpersonationRepository(db),
+		db:            db,
+	}
 }
 
-func InitRepository(db *sql.DB) *Repository {
+// NewMemoryRepository returns repositories that keep their data in process
+// memory, for tests and local experiments.
+func NewMemoryRepository() *Repository {
 	return &Repository{
-		User: userRepo.NewUserRepository(db),
+		User:          userRepo.NewMemoryUserRepository(),
+		Role:          roleRepo.NewMemoryRoleRepository(),
+		APIKey:        apiKeyRepo.NewMemoryAPIKeyRepository(),
+		TOTP:          totpRepo.NewMemoryTOTPRepository(),
+		OAuth:         oauthRepo.NewMemoryOAuthRepository(),
+		Impersonation: impersonationRepo.NewMemoryImpersonationRepository(),
 	}
 }
+
+// WithinTx runs fn in a transaction and hands it repositories bound to that
+// transaction. The transaction commits when fn returns nil and rolls back
+// otherwise; calling WithinTx again from inside fn opens a savepoint.
+// In-memory repositories have no transactions: fn runs directly, so writes
+// made before it fails stay applied. Callers relying on all-or-nothing
+// writes, such as atomic user batches, only get them from SQL databases.
+func (r *Repository) WithinTx(ctx context.Context, fn func(ctx context.Context, tx *Repository) error) error {
+	if r.db == nil {
+		return fn(ctx, r)
+	}
+
+	return r.db.Transact(ctx, func(ctx context.Context, tx *database.Tx) error {
+		return fn(ctx, &Repository{
+			User:          userRepo.NewUserRepository(tx),
+			Role:          roleRepo.NewRoleRepository(tx),
+			APIKey:        apiKeyRepo.NewAPIKeyRepository(tx),
+			TOTP:          totpRepo.NewTOTPRepository(tx),
+			OAuth:         oauthRepo.NewOAuthRepository(tx),
+			Impersonation: impersonationRepo.NewImpersonationRepository(tx),
+			db:            r.db,
+		})
+	})
+}
@@ -0,0 +1,39 @@
+package role
+
+import (
+	"context"
+	"errors"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+)
+
+//go:generate mockery --name RoleRepositoryItf --output mocks --filename rolerepository_mock.go --outpkg mocks
+type RoleRepositoryItf interface {
+	// Create stores a role with its permissions and fills in its ID and
+	// creation time. It writes several rows; run it in a transaction.
+	Create(ctx context.Context, role *entities.Role) error
+	// Delete removes a role and takes it away from every user holding it.
+	Delete(ctx context.Context, id int64) error
+	GetByName(ctx context.Context, name string) (*entities.Role, error)
+	// List returns every role ordered by name.
+	List(ctx context.Context) ([]*entities.Role, error)
+	// ListByUser returns the roles assigned to a user ordered by name.
+	ListByUser(ctx context.Context, userID int64) ([]*entities.Role, error)
+	// SetUserRoles replaces the roles of a user; run it in a transaction so
+	// readers never see the user without roles.
+	SetUserRoles(ctx context.Context, userID int64, roleIDs []int64) error
+}
+
+type roleRepository struct {
+	db database.Conn
+}
+
+func NewRoleRepository(db database.Conn) RoleRepositoryItf {
+	return &roleRepository{db: db}
+}
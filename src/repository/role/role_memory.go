@@ -0,0 +1,132 @@
+package role
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"solecode/src/entities"
+)
+
+// memoryRoleRepository keeps roles in process memory for tests and local
+// experiments. Like the migrations, it starts with the admin role.
+type memoryRoleRepository struct {
+	mu        sync.RWMutex
+	roles     map[int64]*entities.Role
+	userRoles map[int64][]int64
+	nextID    int64
+}
+
+func NewMemoryRoleRepository() RoleRepositoryItf {
+	r := &memoryRoleRepository{
+		roles:     make(map[int64]*entities.Role),
+		userRoles: make(map[int64][]int64),
+	}
+	r.Create(context.Background(), &entities.Role{
+		Name:        entities.RoleAdmin,
+		Description: "Manages every user and role",
+		Permissions: entities.AllPermissions,
+	})
+	return r
+}
+
+func (r *memoryRoleRepository) Create(ctx context.Context, role *entities.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.roles {
+		if existing.Name == role.Name {
+			return ErrRoleExists
+		}
+	}
+	r.nextID++
+	role.ID = r.nextID
+	role.CreatedAt = time.Now()
+	r.roles[role.ID] = copyRole(role)
+	return nil
+}
+
+func (r *memoryRoleRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[id]; !ok {
+		return ErrRoleNotFound
+	}
+	delete(r.roles, id)
+	for userID, roleIDs := range r.userRoles {
+		r.userRoles[userID] = slices.DeleteFunc(roleIDs, func(roleID int64) bool { return roleID == id })
+	}
+	return nil
+}
+
+func (r *memoryRoleRepository) GetByName(ctx context.Context, name string) (*entities.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, role := range r.roles {
+		if role.Name == name {
+			return copyRole(role), nil
+		}
+	}
+	return nil, ErrRoleNotFound
+}
+
+func (r *memoryRoleRepository) List(ctx context.Context) ([]*entities.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := []*entities.Role{}
+	for _, role := range r.roles {
+		roles = append(roles, copyRole(role))
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+func (r *memoryRoleRepository) ListByUser(ctx context.Context, userID int64) ([]*entities.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := []*entities.Role{}
+	for _, id := range r.userRoles[userID] {
+		if role, ok := r.roles[id]; ok {
+			roles = append(roles, copyRole(role))
+		}
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+func (r *memoryRoleRepository) SetUserRoles(ctx context.Context, userID int64, roleIDs []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := []int64{}
+	for _, id := range roleIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	r.userRoles[userID] = ids
+	return nil
+}
+
+// copyRole returns a role with sorted permissions that shares nothing with
+// role.
+func copyRole(role *entities.Role) *entities.Role {
+	c := *role
+	c.Permissions = slices.Clone(role.Permissions)
+	if c.Permissions == nil {
+		c.Permissions = []entities.Permission{}
+	}
+	slices.Sort(c.Permissions)
+	c.Permissions = slices.Compact(c.Permissions)
+	return &c
+}
+
+func sortRoles(roles []*entities.Role) {
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+}
@@ -0,0 +1,156 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+// roleSelect joins each role to its permissions, one row per permission;
+// roles without any come back once with a NULL permission.
+const roleSelect = `
+	SELECT r.id, r.name, r.description, r.created_at, rp.permission
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role_id = r.id
+`
+
+func (r *roleRepository) Create(ctx context.Context, role *entities.Role) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+	now := time.Now()
+
+	query := `INSERT INTO roles (name, description, created_at) VALUES (?, ?, ?)`
+	var err error
+	if dialect == database.Postgres {
+		err = db.QueryRowContext(ctx, dialect.Rebind(query+" RETURNING id"), role.Name, role.Description, now).Scan(&role.ID)
+	} else {
+		var result sql.Result
+		result, err = db.ExecContext(ctx, query, role.Name, role.Description, now)
+		if err == nil {
+			role.ID, err = result.LastInsertId()
+		}
+	}
+	if database.IsUniqueViolation(err) {
+		return ErrRoleExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	for _, permission := range role.Permissions {
+		_, err := db.ExecContext(ctx, dialect.Rebind(`INSERT INTO role_permissions (role_id, permission) VALUES (?, ?)`), role.ID, permission)
+		if database.IsUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to grant permission %s: %w", permission, err)
+		}
+	}
+
+	role.CreatedAt = now
+	return nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id int64) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+
+	// Foreign keys cascade, but not every SQLite connection enforces them
+	for _, query := range []string{
+		`DELETE FROM user_roles WHERE role_id = ?`,
+		`DELETE FROM role_permissions WHERE role_id = ?`,
+	} {
+		if _, err := db.ExecContext(ctx, dialect.Rebind(query), id); err != nil {
+			return fmt.Errorf("failed to delete role: %w", err)
+		}
+	}
+
+	result, err := db.ExecContext(ctx, dialect.Rebind(`DELETE FROM roles WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*entities.Role, error) {
+	roles, err := r.query(ctx, roleSelect+` WHERE r.name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, ErrRoleNotFound
+	}
+	return roles[0], nil
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]*entities.Role, error) {
+	return r.query(ctx, roleSelect)
+}
+
+func (r *roleRepository) ListByUser(ctx context.Context, userID int64) ([]*entities.Role, error) {
+	return r.query(ctx, roleSelect+` JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = ?`, userID)
+}
+
+func (r *roleRepository) SetUserRoles(ctx context.Context, userID int64, roleIDs []int64) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+
+	if _, err := db.ExecContext(ctx, dialect.Rebind(`DELETE FROM user_roles WHERE user_id = ?`), userID); err != nil {
+		return fmt.Errorf("failed to clear user roles: %w", err)
+	}
+	now := time.Now()
+	for _, roleID := range roleIDs {
+		_, err := db.ExecContext(ctx, dialect.Rebind(`INSERT INTO user_roles (user_id, role_id, created_at) VALUES (?, ?, ?)`), userID, roleID, now)
+		if database.IsUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to assign role: %w", err)
+		}
+	}
+	return nil
+}
+
+// query runs a roleSelect query and folds its rows into roles ordered by
+// name, each with its permissions sorted.
+func (r *roleRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entities.Role, error) {
+	query = r.db.Dialect().Rebind(query + ` ORDER BY r.name, rp.permission`)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []*entities.Role{}
+	var current *entities.Role
+	for rows.Next() {
+		var role entities.Role
+		var permission sql.NullString
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		if current == nil || current.ID != role.ID {
+			role.Permissions = []entities.Permission{}
+			current = &role
+			roles = append(roles, current)
+		}
+		if permission.Valid {
+			current.Permissions = append(current.Permissions, entities.Permission(permission.String))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
@@ -0,0 +1,187 @@
+package role_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/src/entities"
+	roleRepo "solecode/src/repository/role"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRoleRepository(t *testing.T) {
+	runRoleTests(t, func(t *testing.T) (roleRepo.RoleRepositoryItf, []int64) {
+		return roleRepo.NewMemoryRoleRepository(), []int64{1, 2}
+	})
+}
+
+func TestSQLiteRoleRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "roles.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	runSQLRoleTests(t, db, database.SQLite)
+}
+
+func TestMySQLRoleRepository(t *testing.T) {
+	runSQLRoleTests(t, openTestDB(t, database.MySQL, "TEST_MYSQL_DSN"), database.MySQL)
+}
+
+func TestPostgresRoleRepository(t *testing.T) {
+	runSQLRoleTests(t, openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN"), database.Postgres)
+}
+
+// runSQLRoleTests migrates db and runs the suite, resetting everything but
+// the seeded admin role and creating two users before each test.
+func runSQLRoleTests(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	runRoleTests(t, func(t *testing.T) (roleRepo.RoleRepositoryItf, []int64) {
+		for _, query := range []string{
+			"DELETE FROM user_roles",
+			"DELETE FROM roles WHERE name <> 'admin'",
+			"DELETE FROM users",
+		} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+
+		var ids []int64
+		for _, email := range []string{"john@example.com", "jane@example.com"} {
+			ids = append(ids, insertUser(t, db, dialect, email))
+		}
+		return roleRepo.NewRoleRepository(database.WrapDB(db, dialect)), ids
+	})
+}
+
+func insertUser(t *testing.T, db *sql.DB, dialect database.Dialect, email string) int64 {
+	query := "INSERT INTO users (name, email) VALUES (?, ?)"
+	var id int64
+	if dialect == database.Postgres {
+		require.NoError(t, db.QueryRow(dialect.Rebind(query+" RETURNING id"), "Test User", email).Scan(&id))
+		return id
+	}
+	result, err := db.Exec(query, "Test User", email)
+	require.NoError(t, err)
+	id, err = result.LastInsertId()
+	require.NoError(t, err)
+	return id
+}
+
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runRoleTests checks the behaviour every RoleRepositoryItf implementation
+// shares. newRepo returns a repository holding only the admin role, and the
+// IDs of two existing users.
+func runRoleTests(t *testing.T, newRepo func(t *testing.T) (roleRepo.RoleRepositoryItf, []int64)) {
+	ctx := context.Background()
+
+	t.Run("admin role is seeded with every permission", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		admin, err := repo.GetByName(ctx, entities.RoleAdmin)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, entities.AllPermissions, admin.Permissions)
+		assert.False(t, admin.CreatedAt.IsZero())
+	})
+
+	t.Run("Create and List", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		support := &entities.Role{
+			Name:        "support",
+			Description: "Reads users",
+			Permissions: []entities.Permission{entities.PermUsersRead, entities.PermUsersPassword},
+		}
+		require.NoError(t, repo.Create(ctx, support))
+		assert.NotZero(t, support.ID)
+		require.NoError(t, repo.Create(ctx, &entities.Role{Name: "empty"}))
+
+		err := repo.Create(ctx, &entities.Role{Name: "support"})
+		assert.ErrorIs(t, err, roleRepo.ErrRoleExists)
+
+		roles, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, roles, 3)
+		assert.Equal(t, []string{"admin", "empty", "support"}, []string{roles[0].Name, roles[1].Name, roles[2].Name})
+		assert.Empty(t, roles[1].Permissions)
+		assert.Equal(t, "Reads users", roles[2].Description)
+		assert.Equal(t, []entities.Permission{entities.PermUsersPassword, entities.PermUsersRead}, roles[2].Permissions)
+	})
+
+	t.Run("GetByName reports unknown roles", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		_, err := repo.GetByName(ctx, "nobody")
+		assert.ErrorIs(t, err, roleRepo.ErrRoleNotFound)
+	})
+
+	t.Run("SetUserRoles replaces assignments", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		admin, err := repo.GetByName(ctx, entities.RoleAdmin)
+		require.NoError(t, err)
+		support := &entities.Role{Name: "support", Permissions: []entities.Permission{entities.PermUsersRead}}
+		require.NoError(t, repo.Create(ctx, support))
+
+		require.NoError(t, repo.SetUserRoles(ctx, users[0], []int64{support.ID, admin.ID, admin.ID}))
+		roles, err := repo.ListByUser(ctx, users[0])
+		require.NoError(t, err)
+		require.Len(t, roles, 2)
+		assert.Equal(t, "admin", roles[0].Name)
+		assert.Equal(t, "support", roles[1].Name)
+
+		roles, err = repo.ListByUser(ctx, users[1])
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+
+		require.NoError(t, repo.SetUserRoles(ctx, users[0], []int64{support.ID}))
+		roles, err = repo.ListByUser(ctx, users[0])
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		assert.Equal(t, "support", roles[0].Name)
+
+		require.NoError(t, repo.SetUserRoles(ctx, users[0], nil))
+		roles, err = repo.ListByUser(ctx, users[0])
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("Delete removes assignments", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		support := &entities.Role{Name: "support", Permissions: []entities.Permission{entities.PermUsersRead}}
+		require.NoError(t, repo.Create(ctx, support))
+		require.NoError(t, repo.SetUserRoles(ctx, users[0], []int64{support.ID}))
+
+		require.NoError(t, repo.Delete(ctx, support.ID))
+		roles, err := repo.ListByUser(ctx, users[0])
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+		_, err = repo.GetByName(ctx, "support")
+		assert.ErrorIs(t, err, roleRepo.ErrRoleNotFound)
+
+		assert.ErrorIs(t, repo.Delete(ctx, support.ID), roleRepo.ErrRoleNotFound)
+	})
+}
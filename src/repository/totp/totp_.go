@@ -0,0 +1,46 @@
+package totp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/database"
+	"solecode/src/entities"
+)
+
+var ErrTOTPNotFound = errors.New("totp enrolment not found")
+
+//go:generate mockery --name TOTPRepositoryItf --output mocks --filename totprepository_mock.go --outpkg mocks
+type TOTPRepositoryItf interface {
+	// Get returns the enrolment of a user, confirmed or not.
+	Get(ctx context.Context, userID int64) (*entities.TOTP, error)
+	// Create stores a new, unconfirmed enrolment and fills in its
+	// creation time. Delete any earlier one first.
+	Create(ctx context.Context, totp *entities.TOTP) error
+	// Confirm enables an unconfirmed enrolment; step is the step of the
+	// code that confirmed it.
+	Confirm(ctx context.Context, userID int64, step int64, at time.Time) error
+	// AdvanceStep records step as the last accepted one. It reports false,
+	// changing nothing, unless step is later than the last accepted one,
+	// so that of two logins racing with one code only one wins.
+	AdvanceStep(ctx context.Context, userID int64, step int64) (bool, error)
+	// Delete removes the enrolment and the recovery codes of a user.
+	Delete(ctx context.Context, userID int64) error
+
+	// ReplaceRecoveryCodes swaps the recovery codes of a user for hashes.
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	// UseRecoveryCode marks the unused code with hash used. It reports
+	// false if the user has no such code or it was used before.
+	UseRecoveryCode(ctx context.Context, userID int64, hash string, at time.Time) (bool, error)
+	// CountRecoveryCodes returns how many unused codes a user has left.
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+}
+
+type totpRepository struct {
+	db database.Conn
+}
+
+func NewTOTPRepository(db database.Conn) TOTPRepositoryItf {
+	return &totpRepository{db: db}
+}
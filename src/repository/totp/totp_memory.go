@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"solecode/src/entities"
+)
+
+// memoryTOTPRepository keeps enrolments and recovery codes in process
+// memory for tests and local experiments.
+type memoryTOTPRepository struct {
+	mu    sync.RWMutex
+	totps map[int64]*entities.TOTP
+	// codes maps user IDs to their code hashes and whether each is used.
+	codes map[int64]map[string]bool
+}
+
+func NewMemoryTOTPRepository() TOTPRepositoryItf {
+	return &memoryTOTPRepository{
+		totps: make(map[int64]*entities.TOTP),
+		codes: make(map[int64]map[string]bool),
+	}
+}
+
+func (r *memoryTOTPRepository) Get(ctx context.Context, userID int64) (*entities.TOTP, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totp, ok := r.totps[userID]
+	if !ok {
+		return nil, ErrTOTPNotFound
+	}
+	c := *totp
+	return &c, nil
+}
+
+func (r *memoryTOTPRepository) Create(ctx context.Context, totp *entities.TOTP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.totps[totp.UserID]; ok {
+		return fmt.Errorf("failed to create totp enrolment: user %d already has one", totp.UserID)
+	}
+	totp.CreatedAt = time.Now()
+	c := *totp
+	c.ConfirmedAt = nil
+	r.totps[totp.UserID] = &c
+	return nil
+}
+
+func (r *memoryTOTPRepository) Confirm(ctx context.Context, userID int64, step int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totp, ok := r.totps[userID]
+	if !ok || totp.ConfirmedAt != nil {
+		return ErrTOTPNotFound
+	}
+	totp.ConfirmedAt, totp.LastStep = &at, step
+	return nil
+}
+
+func (r *memoryTOTPRepository) AdvanceStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totp, ok := r.totps[userID]
+	if !ok || totp.LastStep >= step {
+		return false, nil
+	}
+	totp.LastStep = step
+	return true, nil
+}
+
+func (r *memoryTOTPRepository) Delete(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.totps, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+func (r *memoryTOTPRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = false
+	}
+	r.codes[userID] = codes
+	return nil
+}
+
+func (r *memoryTOTPRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.codes[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	r.codes[userID][hash] = true
+	return true, nil
+}
+
+func (r *memoryTOTPRepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,115 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"solecode/src/entities"
+)
+
+func (r *totpRepository) Get(ctx context.Context, userID int64) (*entities.TOTP, error) {
+	query := r.db.Dialect().Rebind(`SELECT user_id, secret, confirmed_at, last_step, created_at FROM user_totp WHERE user_id = ?`)
+	totp := &entities.TOTP{}
+	err := r.db.Reader(ctx).QueryRowContext(ctx, query, userID).
+		Scan(&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.LastStep, &totp.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp enrolment: %w", err)
+	}
+	return totp, nil
+}
+
+func (r *totpRepository) Create(ctx context.Context, totp *entities.TOTP) error {
+	now := time.Now()
+	query := r.db.Dialect().Rebind(`INSERT INTO user_totp (user_id, secret, last_step, created_at) VALUES (?, ?, ?, ?)`)
+	if _, err := r.db.Writer(ctx).ExecContext(ctx, query, totp.UserID, totp.Secret, totp.LastStep, now); err != nil {
+		return fmt.Errorf("failed to create totp enrolment: %w", err)
+	}
+	totp.CreatedAt = now
+	return nil
+}
+
+func (r *totpRepository) Confirm(ctx context.Context, userID int64, step int64, at time.Time) error {
+	query := r.db.Dialect().Rebind(`UPDATE user_totp SET confirmed_at = ?, last_step = ? WHERE user_id = ? AND confirmed_at IS NULL`)
+	ok, err := r.exec(ctx, query, at, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to confirm totp enrolment: %w", err)
+	}
+	if !ok {
+		return ErrTOTPNotFound
+	}
+	return nil
+}
+
+func (r *totpRepository) AdvanceStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	query := r.db.Dialect().Rebind(`UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?`)
+	ok, err := r.exec(ctx, query, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *totpRepository) Delete(ctx context.Context, userID int64) error {
+	for _, query := range []string{
+		`DELETE FROM user_recovery_codes WHERE user_id = ?`,
+		`DELETE FROM user_totp WHERE user_id = ?`,
+	} {
+		if _, err := r.db.Writer(ctx).ExecContext(ctx, r.db.Dialect().Rebind(query), userID); err != nil {
+			return fmt.Errorf("failed to delete totp enrolment: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *totpRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	db := r.db.Writer(ctx)
+	dialect := r.db.Dialect()
+	if _, err := db.ExecContext(ctx, dialect.Rebind(`DELETE FROM user_recovery_codes WHERE user_id = ?`), userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := time.Now()
+	insert := dialect.Rebind(`INSERT INTO user_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)`)
+	for _, hash := range hashes {
+		if _, err := db.ExecContext(ctx, insert, userID, hash, now); err != nil {
+			return fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *totpRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string, at time.Time) (bool, error) {
+	query := r.db.Dialect().Rebind(`UPDATE user_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`)
+	ok, err := r.exec(ctx, query, at, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *totpRepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	query := r.db.Dialect().Rebind(`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL`)
+	var count int
+	if err := r.db.Reader(ctx).QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// exec runs an update and reports whether it changed a row.
+func (r *totpRepository) exec(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
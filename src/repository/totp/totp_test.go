@@ -0,0 +1,193 @@
+package totp_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/src/entities"
+	totpRepo "solecode/src/repository/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTOTPRepository(t *testing.T) {
+	runTOTPTests(t, func(t *testing.T) (totpRepo.TOTPRepositoryItf, []int64) {
+		return totpRepo.NewMemoryTOTPRepository(), []int64{1, 2}
+	})
+}
+
+func TestSQLiteTOTPRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "totp.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	runSQLTOTPTests(t, db, database.SQLite)
+}
+
+func TestMySQLTOTPRepository(t *testing.T) {
+	runSQLTOTPTests(t, openTestDB(t, database.MySQL, "TEST_MYSQL_DSN"), database.MySQL)
+}
+
+func TestPostgresTOTPRepository(t *testing.T) {
+	runSQLTOTPTests(t, openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN"), database.Postgres)
+}
+
+// runSQLTOTPTests migrates db and runs the suite, emptying the enrolments,
+// codes and users and creating two users before each test.
+func runSQLTOTPTests(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	runTOTPTests(t, func(t *testing.T) (totpRepo.TOTPRepositoryItf, []int64) {
+		for _, query := range []string{"DELETE FROM user_recovery_codes", "DELETE FROM user_totp", "DELETE FROM api_keys", "DELETE FROM users"} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+
+		var ids []int64
+		for _, email := range []string{"john@example.com", "jane@example.com"} {
+			ids = append(ids, insertUser(t, db, dialect, email))
+		}
+		return totpRepo.NewTOTPRepository(database.WrapDB(db, dialect)), ids
+	})
+}
+
+func insertUser(t *testing.T, db *sql.DB, dialect database.Dialect, email string) int64 {
+	query := "INSERT INTO users (name, email) VALUES (?, ?)"
+	var id int64
+	if dialect == database.Postgres {
+		require.NoError(t, db.QueryRow(dialect.Rebind(query+" RETURNING id"), "Test User", email).Scan(&id))
+		return id
+	}
+	result, err := db.Exec(query, "Test User", email)
+	require.NoError(t, err)
+	id, err = result.LastInsertId()
+	require.NoError(t, err)
+	return id
+}
+
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// runTOTPTests checks the behaviour every TOTPRepositoryItf implementation
+// shares. newRepo returns an empty repository and the IDs of two existing
+// users.
+func runTOTPTests(t *testing.T, newRepo func(t *testing.T) (totpRepo.TOTPRepositoryItf, []int64)) {
+	ctx := context.Background()
+
+	t.Run("enrol and confirm", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		_, err := repo.Get(ctx, users[0])
+		assert.ErrorIs(t, err, totpRepo.ErrTOTPNotFound)
+
+		totp := &entities.TOTP{UserID: users[0], Secret: "v1.sealed"}
+		require.NoError(t, repo.Create(ctx, totp))
+		assert.False(t, totp.CreatedAt.IsZero())
+
+		got, err := repo.Get(ctx, users[0])
+		require.NoError(t, err)
+		assert.Equal(t, "v1.sealed", got.Secret)
+		assert.False(t, got.Enabled())
+
+		at := time.Now().Truncate(time.Second)
+		require.NoError(t, repo.Confirm(ctx, users[0], 100, at))
+		got, err = repo.Get(ctx, users[0])
+		require.NoError(t, err)
+		require.True(t, got.Enabled())
+		assert.True(t, at.Equal(*got.ConfirmedAt))
+		assert.Equal(t, int64(100), got.LastStep)
+
+		// Confirming twice, or without an enrolment, fails
+		assert.ErrorIs(t, repo.Confirm(ctx, users[0], 101, at), totpRepo.ErrTOTPNotFound)
+		assert.ErrorIs(t, repo.Confirm(ctx, users[1], 101, at), totpRepo.ErrTOTPNotFound)
+	})
+
+	t.Run("steps only advance", func(t *testing.T) {
+		repo, users := newRepo(t)
+		require.NoError(t, repo.Create(ctx, &entities.TOTP{UserID: users[0], Secret: "v1.sealed"}))
+		require.NoError(t, repo.Confirm(ctx, users[0], 100, time.Now()))
+
+		ok, err := repo.AdvanceStep(ctx, users[0], 100)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = repo.AdvanceStep(ctx, users[0], 101)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = repo.AdvanceStep(ctx, users[0], 101)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = repo.AdvanceStep(ctx, users[1], 101)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("recovery codes", func(t *testing.T) {
+		repo, users := newRepo(t)
+
+		require.NoError(t, repo.ReplaceRecoveryCodes(ctx, users[0], []string{"hash-a", "hash-b"}))
+		require.NoError(t, repo.ReplaceRecoveryCodes(ctx, users[1], []string{"hash-a"}))
+		count, err := repo.CountRecoveryCodes(ctx, users[0])
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		ok, err := repo.UseRecoveryCode(ctx, users[0], "hash-a", time.Now())
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = repo.UseRecoveryCode(ctx, users[0], "hash-a", time.Now())
+		require.NoError(t, err)
+		assert.False(t, ok, "codes work once")
+		ok, err = repo.UseRecoveryCode(ctx, users[0], "hash-c", time.Now())
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		count, err = repo.CountRecoveryCodes(ctx, users[0])
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		count, err = repo.CountRecoveryCodes(ctx, users[1])
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "codes belong to one user")
+
+		// New codes replace used and unused ones alike
+		require.NoError(t, repo.ReplaceRecoveryCodes(ctx, users[0], []string{"hash-a", "hash-d", "hash-e"}))
+		count, err = repo.CountRecoveryCodes(ctx, users[0])
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("delete removes enrolment and codes", func(t *testing.T) {
+		repo, users := newRepo(t)
+		require.NoError(t, repo.Create(ctx, &entities.TOTP{UserID: users[0], Secret: "v1.sealed"}))
+		require.NoError(t, repo.ReplaceRecoveryCodes(ctx, users[0], []string{"hash-a"}))
+
+		require.NoError(t, repo.Delete(ctx, users[0]))
+		_, err := repo.Get(ctx, users[0])
+		assert.ErrorIs(t, err, totpRepo.ErrTOTPNotFound)
+		count, err := repo.CountRecoveryCodes(ctx, users[0])
+		require.NoError(t, err)
+		assert.Zero(t, count)
+
+		// A new enrolment can follow
+		require.NoError(t, repo.Create(ctx, &entities.TOTP{UserID: users[0], Secret: "v1.other"}))
+		require.NoError(t, repo.Delete(ctx, users[1]))
+	})
+}
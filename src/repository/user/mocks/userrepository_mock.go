@@ -3,9 +3,14 @@
 package mocks
 
 import (
+	context "context"
 	entities "solecode/src/entities"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	user "solecode/src/repository/user"
 )
 
 // UserRepositoryItf is an autogenerated mock type for the UserRepositoryItf type
@@ -13,17 +18,17 @@ type UserRepositoryItf struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: _a0
-func (_m *UserRepositoryItf) Create(_a0 *entities.User) error {
-	ret := _m.Called(_a0)
+// Create provides a mock function with given fields: ctx, _a1
+func (_m *UserRepositoryItf) Create(ctx context.Context, _a1 *entities.User) error {
+	ret := _m.Called(ctx, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*entities.User) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.User) error); ok {
+		r0 = rf(ctx, _a1)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -31,17 +36,35 @@ func (_m *UserRepositoryItf) Create(_a0 *entities.User) error {
 	return r0
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *UserRepositoryItf) Delete(id int64) error {
-	ret := _m.Called(id)
+// CreateBatch provides a mock function with given fields: ctx, users
+func (_m *UserRepositoryItf) CreateBatch(ctx context.Context, users []*entities.User) error {
+	ret := _m.Called(ctx, users)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*entities.User) error); ok {
+		r0 = rf(ctx, users)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *UserRepositoryItf) Delete(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -49,9 +72,27 @@ func (_m *UserRepositoryItf) Delete(id int64) error {
 	return r0
 }
 
-// GetByEmail provides a mock function with given fields: email
-func (_m *UserRepositoryItf) GetByEmail(email string) (*entities.User, error) {
-	ret := _m.Called(email)
+// DeleteBatch provides a mock function with given fields: ctx, ids
+func (_m *UserRepositoryItf) DeleteBatch(ctx context.Context, ids []int64) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByEmail provides a mock function with given fields: ctx, email
+func (_m *UserRepositoryItf) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetByEmail")
@@ -59,19 +100,19 @@ func (_m *UserRepositoryItf) GetByEmail(email string) (*entities.User, error) {
 
 	var r0 *entities.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (*entities.User, error)); ok {
-		return rf(email)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.User, error)); ok {
+		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(string) *entities.User); ok {
-		r0 = rf(email)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.User); ok {
+		r0 = rf(ctx, email)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(email)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -79,9 +120,9 @@ func (_m *UserRepositoryItf) GetByEmail(email string) (*entities.User, error) {
 	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: id
-func (_m *UserRepositoryItf) GetByID(id int64) (*entities.User, error) {
-	ret := _m.Called(id)
+// GetByID provides a mock function with given fields: ctx, id
+func (_m *UserRepositoryItf) GetByID(ctx context.Context, id int64) (*entities.User, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetByID")
@@ -89,19 +130,167 @@ func (_m *UserRepositoryItf) GetByID(id int64) (*entities.User, error) {
 
 	var r0 *entities.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (*entities.User, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*entities.User, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) *entities.User); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entities.User); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, filter
+func (_m *UserRepositoryItf) List(ctx context.Context, filter user.ListFilter) ([]*entities.User, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, user.ListFilter) ([]*entities.User, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, user.ListFilter) []*entities.User); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, user.ListFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkVerified provides a mock function with given fields: ctx, id, email, at
+func (_m *UserRepositoryItf) MarkVerified(ctx context.Context, id int64, email string, at time.Time) error {
+	ret := _m.Called(ctx, id, email, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkVerified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, id, email, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplacePasswordHash provides a mock function with given fields: ctx, id, old, hash
+func (_m *UserRepositoryItf) ReplacePasswordHash(ctx context.Context, id int64, old string, hash string) error {
+	ret := _m.Called(ctx, id, old, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplacePasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, id, old, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Restore provides a mock function with given fields: ctx, id
+func (_m *UserRepositoryItf) Restore(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPasswordHash provides a mock function with given fields: ctx, id, hash
+func (_m *UserRepositoryItf) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	ret := _m.Called(ctx, id, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSignInGeneration provides a mock function with given fields: ctx, id, generation
+func (_m *UserRepositoryItf) SetSignInGeneration(ctx context.Context, id int64, generation string) error {
+	ret := _m.Called(ctx, id, generation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSignInGeneration")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, generation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SignInGeneration provides a mock function with given fields: ctx, id
+func (_m *UserRepositoryItf) SignInGeneration(ctx context.Context, id int64) (string, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SignInGeneration")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (string, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) string); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -109,17 +298,35 @@ func (_m *UserRepositoryItf) GetByID(id int64) (*entities.User, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: _a0
-func (_m *UserRepositoryItf) Update(_a0 *entities.User) error {
-	ret := _m.Called(_a0)
+// Update provides a mock function with given fields: ctx, _a1
+func (_m *UserRepositoryItf) Update(ctx context.Context, _a1 *entities.User) error {
+	ret := _m.Called(ctx, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Update")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*entities.User) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.User) error); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateBatch provides a mock function with given fields: ctx, users
+func (_m *UserRepositoryItf) UpdateBatch(ctx context.Context, users []*entities.User) error {
+	ret := _m.Called(ctx, users)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*entities.User) error); ok {
+		r0 = rf(ctx, users)
 	} else {
 		r0 = ret.Error(0)
 	}
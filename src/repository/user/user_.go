@@ -1,23 +1,259 @@
 package user
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"solecode/pkg/database"
 	"solecode/src/entities"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailExists  = errors.New("email already exists")
+)
+
 //go:generate mockery --name UserRepositoryItf --output mocks --filename userrepository_mock.go --outpkg mocks
 type UserRepositoryItf interface {
-	Create(user *entities.User) error
-	GetByID(id int64) (*entities.User, error)
-	GetByEmail(email string) (*entities.User, error)
-	Update(user *entities.User) error
-	Delete(id int64) error
+	Create(ctx context.Context, user *entities.User) error
+	// CreateBatch inserts users with multi-row INSERTs and fills in their
+	// IDs and timestamps. A duplicate email fails with ErrEmailExists;
+	// chunks inserted before it stay unless the caller uses a transaction.
+	// On MySQL each chunk runs in a transaction of its own, which reads
+	// the new IDs back by email.
+	CreateBatch(ctx context.Context, users []*entities.User) error
+	// UpdateBatch and DeleteBatch change many users with one statement per
+	// chunk. They fail with ErrUserNotFound when any ID is missing or
+	// deleted, after changing the others; run them in a transaction to get
+	// all or nothing.
+	// Update, UpdateBatch and the others that change emails clear
+	// verified_at of users whose email changes.
+	UpdateBatch(ctx context.Context, users []*entities.User) error
+	DeleteBatch(ctx context.Context, ids []int64) error
+	GetByID(ctx context.Context, id int64) (*entities.User, error)
+	GetByEmail(ctx context.Context, email string) (*entities.User, error)
+	Update(ctx context.Context, user *entities.User) error
+	Delete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) error
+	// SetPasswordHash replaces the password hash of an active user; an
+	// empty hash removes the password.
+	SetPasswordHash(ctx context.Context, id int64, hash string) error
+	// ReplacePasswordHash is SetPasswordHash that fails with
+	// ErrUserNotFound when the user's hash is no longer old.
+	ReplacePasswordHash(ctx context.Context, id int64, old, hash string) error
+	// SignInGeneration returns the sign-in generation of a user, deleted
+	// ones included, or "" when none was ever set. It reads the primary,
+	// since a stale generation would let ended sign-ins back in.
+	SignInGeneration(ctx context.Context, id int64) (string, error)
+	// SetSignInGeneration replaces the sign-in generation of a user,
+	// deleted ones included.
+	SetSignInGeneration(ctx context.Context, id int64, generation string) error
+	// MarkVerified records that an active user confirmed email at at. It
+	// fails with ErrUserNotFound when the user's email is no longer email.
+	MarkVerified(ctx context.Context, id int64, email string, at time.Time) error
+	List(ctx context.Context, filter ListFilter) ([]*entities.User, error)
+}
+
+// ListFilter narrows List. The zero value lists every active user.
+type ListFilter struct {
+	Limit          int // 0 means no limit
+	Offset         int
+	IncludeDeleted bool
+
+	// IDs, when not empty, lists only these users.
+	IDs []int64
+	// AfterID lists users with a greater ID, for keyset pagination over
+	// large tables.
+	AfterID int64
+	// CreatedFrom and CreatedTo bound created_at, inclusive and exclusive;
+	// zero values leave that side open.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// EmailDomain keeps users whose email ends in "@" + EmailDomain.
+	EmailDomain string
+}
+
+// listWhere returns the WHERE clause for filter with ? placeholders, or an
+// empty string when nothing is filtered.
+func listWhere(filter ListFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if len(filter.IDs) > 0 {
+		conditions = append(conditions, "id IN ("+placeholders(len(filter.IDs))+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if filter.AfterID > 0 {
+		conditions = append(conditions, "id > ?")
+		args = append(args, filter.AfterID)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedTo)
+	}
+	if filter.EmailDomain != "" {
+		conditions = append(conditions, `email LIKE ? ESCAPE '!'`)
+		args = append(args, "%@"+likeEscaper.Replace(strings.ToLower(filter.EmailDomain)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// keepVerified is the SET item that clears verified_at when the email
+// changes. It must come before the email assignment: MySQL evaluates
+// assignments left to right and would otherwise compare the new email.
+const keepVerified = "verified_at = CASE WHEN email = ? THEN verified_at END"
+
+// updateBatchQuery builds an UPDATE that sets each user's name and email
+// by ID, with ? placeholders.
+func updateBatchQuery(users []*entities.User, now time.Time) (string, []interface{}) {
+	var verified, names, emails strings.Builder
+	var verifiedArgs, nameArgs, emailArgs, idArgs []interface{}
+	for _, user := range users {
+		verified.WriteString(" WHEN ? THEN CASE WHEN email = ? THEN verified_at END")
+		names.WriteString(" WHEN ? THEN ?")
+		emails.WriteString(" WHEN ? THEN ?")
+		verifiedArgs = append(verifiedArgs, user.ID, user.Email)
+		nameArgs = append(nameArgs, user.ID, user.Name)
+		emailArgs = append(emailArgs, user.ID, user.Email)
+		idArgs = append(idArgs, user.ID)
+	}
+
+	// verified_at comes first, as in keepVerified
+	query := "UPDATE users SET verified_at = CASE id" + verified.String() + " END, name = CASE id" + names.String() +
+		" END, email = CASE id" + emails.String() +
+		" END, updated_at = ? WHERE id IN (" + placeholders(len(users)) + ") AND deleted_at IS NULL"
+	args := append(append(append(append(verifiedArgs, nameArgs...), emailArgs...), now), idArgs...)
+	return query, args
+}
+
+// deleteBatchQuery builds a soft delete of ids with ? placeholders.
+func deleteBatchQuery(ids []int64, now time.Time) (string, []interface{}) {
+	args := []interface{}{now}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return "UPDATE users SET deleted_at = ? WHERE id IN (" + placeholders(len(ids)) + ") AND deleted_at IS NULL", args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
 
+// chunks calls fn with consecutive slices of at most maxBatchRows items.
+func chunks[T any](items []T, fn func([]T) error) error {
+	for len(items) > 0 {
+		n := min(len(items), maxBatchRows)
+		if err := fn(items[:n]); err != nil {
+			return err
+		}
+		items = items[n:]
+	}
+	return nil
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// maxBatchRows caps the rows of one multi-row INSERT so its placeholders
+// stay within every dialect's limit; CreateBatch splits larger batches.
+const maxBatchRows = 1000
+
 type userRepository struct {
-	db *sql.DB
+	db database.Conn
 }
 
-func NewUserRepository(db *sql.DB) UserRepositoryItf {
+type userPostgresRepository struct {
+	db database.Conn
+}
+
+// NewUserRepository returns the implementation matching the connection's
+// dialect. SQLite accepts the same statements and placeholders as MySQL, so
+// both share userRepository.
+func NewUserRepository(db database.Conn) UserRepositoryItf {
+	if db.Dialect() == database.Postgres {
+		return &userPostgresRepository{db: db}
+	}
 	return &userRepository{db: db}
 }
+
+// userColumns is the select list that userFields scans. Users without a
+// password have a NULL hash.
+const userColumns = "id, name, email, created_at, updated_at, deleted_at, COALESCE(password_hash, ''), verified_at"
+
+func userFields(user *entities.User) []interface{} {
+	return []interface{}{
+		&user.ID, &user.Name, &user.Email,
+		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.PasswordHash, &user.VerifiedAt,
+	}
+}
+
+// assignIDs closes rows of (id, email) after setting the IDs and timestamps
+// of the users they name. Rows are matched by email, which is unique, as no
+// dialect promises to return them in insertion order.
+func assignIDs(rows *sql.Rows, users []*entities.User, now time.Time) error {
+	defer rows.Close()
+
+	ids := make(map[string]int64, len(users))
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		ids[email] = id
+	}
+	if err := rows.Err(); database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create users: %w", err)
+	}
+
+	for _, user := range users {
+		id, ok := ids[user.Email]
+		if !ok {
+			return fmt.Errorf("failed to create users: no ID returned for %s", user.Email)
+		}
+		user.ID = id
+		user.CreatedAt = now
+		user.UpdatedAt = now
+	}
+	return nil
+}
+
+// nullIfEmpty stores empty strings as NULL.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func scanUsers(rows *sql.Rows) ([]*entities.User, error) {
+	users := []*entities.User{}
+	for rows.Next() {
+		user := &entities.User{}
+		if err := rows.Scan(userFields(user)...); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,348 @@
+package user
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	entities "solecode/src/entities"
+)
+
+// memoryUserRepository keeps users in process memory. It mirrors the SQL
+// implementations, including soft delete and emails staying reserved by
+// deleted users, and is meant for tests and local experiments.
+type memoryUserRepository struct {
+	mu          sync.RWMutex
+	users       map[int64]*entities.User
+	generations map[int64]string
+	nextID      int64
+}
+
+func NewMemoryUserRepository() UserRepositoryItf {
+	return &memoryUserRepository{
+		users:       make(map[int64]*entities.User),
+		generations: make(map[int64]string),
+	}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return ErrEmailExists
+	}
+
+	now := time.Now()
+	r.nextID++
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.DeletedAt = nil
+
+	r.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (r *memoryUserRepository) CreateBatch(ctx context.Context, users []*entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Like a single INSERT, a duplicate anywhere in the batch inserts nothing
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		if seen[user.Email] || r.emailTaken(user.Email, 0) {
+			return ErrEmailExists
+		}
+		seen[user.Email] = true
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		r.nextID++
+		user.ID = r.nextID
+		user.CreatedAt = now
+		user.UpdatedAt = now
+		user.DeletedAt = nil
+		r.users[user.ID] = copyUser(user)
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateBatch(ctx context.Context, users []*entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check everything first; there are no transactions to undo a
+	// half-applied batch
+	// A repeated ID matches one row, which the SQL implementations report
+	// as not found
+	ids := make(map[int64]bool, len(users))
+	emails := make(map[string]int64, len(users))
+	for _, user := range users {
+		stored, ok := r.users[user.ID]
+		if !ok || stored.DeletedAt != nil || ids[user.ID] {
+			return ErrUserNotFound
+		}
+		ids[user.ID] = true
+		if other, ok := emails[user.Email]; ok && other != user.ID {
+			return ErrEmailExists
+		}
+		emails[user.Email] = user.ID
+	}
+	for id, user := range r.users {
+		if owner, ok := emails[user.Email]; ok && owner != id && !updatedAway(users, id, user.Email) {
+			return ErrEmailExists
+		}
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		stored := r.users[user.ID]
+		if stored.Email != user.Email {
+			stored.VerifiedAt = nil
+		}
+		stored.Name = user.Name
+		stored.Email = user.Email
+		stored.UpdatedAt = now
+		user.UpdatedAt = now
+	}
+	return nil
+}
+
+// updatedAway reports whether the batch gives user id an email other than
+// email.
+func updatedAway(users []*entities.User, id int64, email string) bool {
+	for _, user := range users {
+		if user.ID == id {
+			return user.Email != email
+		}
+	}
+	return false
+}
+
+func (r *memoryUserRepository) DeleteBatch(ctx context.Context, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; !ok || user.DeletedAt != nil || seen[id] {
+			return ErrUserNotFound
+		}
+		seen[id] = true
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		deletedAt := now
+		r.users[id].DeletedAt = &deletedAt
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id int64) (*entities.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && user.DeletedAt == nil {
+			return copyUser(user), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return ErrEmailExists
+	}
+
+	user.UpdatedAt = time.Now()
+	if stored.Email != user.Email {
+		stored.VerifiedAt = nil
+	}
+	stored.Name = user.Name
+	stored.Email = user.Email
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+
+	now := time.Now()
+	user.DeletedAt = &now
+	return nil
+}
+
+func (r *memoryUserRepository) Restore(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil {
+		return ErrUserNotFound
+	}
+
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryUserRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+
+	user.PasswordHash = hash
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryUserRepository) ReplacePasswordHash(ctx context.Context, id int64, old, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil || user.PasswordHash != old {
+		return ErrUserNotFound
+	}
+
+	user.PasswordHash = hash
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryUserRepository) SignInGeneration(ctx context.Context, id int64) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.users[id]; !ok {
+		return "", ErrUserNotFound
+	}
+	return r.generations[id], nil
+}
+
+func (r *memoryUserRepository) SetSignInGeneration(ctx context.Context, id int64, generation string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrUserNotFound
+	}
+	r.generations[id] = generation
+	return nil
+}
+
+func (r *memoryUserRepository) MarkVerified(ctx context.Context, id int64, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil || user.Email != email {
+		return ErrUserNotFound
+	}
+
+	user.VerifiedAt = &at
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, filter ListFilter) ([]*entities.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*entities.User{}
+	for _, user := range r.users {
+		if matchesFilter(user, filter) {
+			users = append(users, copyUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	if filter.Offset >= len(users) {
+		return []*entities.User{}, nil
+	}
+	users = users[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(users) {
+		users = users[:filter.Limit]
+	}
+	return users, nil
+}
+
+// matchesFilter mirrors listWhere.
+func matchesFilter(user *entities.User, filter ListFilter) bool {
+	switch {
+	case user.DeletedAt != nil && !filter.IncludeDeleted:
+		return false
+	case len(filter.IDs) > 0 && !slices.Contains(filter.IDs, user.ID):
+		return false
+	case user.ID <= filter.AfterID:
+		return false
+	case !filter.CreatedFrom.IsZero() && user.CreatedAt.Before(filter.CreatedFrom):
+		return false
+	case !filter.CreatedTo.IsZero() && !user.CreatedAt.Before(filter.CreatedTo):
+		return false
+	case filter.EmailDomain != "" && !strings.HasSuffix(user.Email, "@"+strings.ToLower(filter.EmailDomain)):
+		return false
+	}
+	return true
+}
+
+// emailTaken reports whether any user other than exceptID, deleted or not,
+// holds email, matching the unique index of the SQL schemas.
+func (r *memoryUserRepository) emailTaken(email string, exceptID int64) bool {
+	for id, user := range r.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func copyUser(user *entities.User) *entities.User {
+	c := *user
+	if user.DeletedAt != nil {
+		deletedAt := *user.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	if user.VerifiedAt != nil {
+		verifiedAt := *user.VerifiedAt
+		c.VerifiedAt = &verifiedAt
+	}
+	return &c
+}
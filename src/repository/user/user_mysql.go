@@ -1,21 +1,28 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
+	"solecode/pkg/database"
 	entities "solecode/src/entities"
 )
 
-func (r *userRepository) Create(user *entities.User) error {
+func (r *userRepository) Create(ctx context.Context, user *entities.User) error {
 	query := `
-		INSERT INTO users (name, email, created_at, updated_at) 
-		VALUES (?, ?, ?, ?)
+		INSERT INTO users (name, email, password_hash, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?)
 	`
 
 	now := time.Now()
-	result, err := r.db.Exec(query, user.Name, user.Email, now, now)
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, user.Name, user.Email, nullIfEmpty(user.PasswordHash), now, now)
+	if database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -31,21 +38,126 @@ func (r *userRepository) Create(user *entities.User) error {
 	return nil
 }
 
-func (r *userRepository) GetByID(id int64) (*entities.User, error) {
+func (r *userRepository) CreateBatch(ctx context.Context, users []*entities.User) error {
+	return chunks(users, func(users []*entities.User) error {
+		return r.createChunk(ctx, users)
+	})
+}
+
+func (r *userRepository) createChunk(ctx context.Context, users []*entities.User) error {
+	now := time.Now()
+	query := "INSERT INTO users (name, email, password_hash, created_at, updated_at) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", len(users)), ", ")
+	args := make([]interface{}, 0, 5*len(users))
+	for _, user := range users {
+		args = append(args, user.Name, user.Email, nullIfEmpty(user.PasswordHash), now, now)
+	}
+
+	if r.db.Dialect() == database.SQLite {
+		rows, err := r.db.Writer(ctx).QueryContext(ctx, query+" RETURNING id, email", args...)
+		if database.IsUniqueViolation(err) {
+			return ErrEmailExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create users: %w", err)
+		}
+		return assignIDs(rows, users, now)
+	}
+
+	// MySQL has no RETURNING, and the IDs of a multi-row INSERT are not
+	// consecutive with auto_increment_increment above 1 or interleaved
+	// auto-increment locking, so they are read back by email in the same
+	// transaction
+	return r.transact(ctx, func(db database.DBTX) error {
+		_, err := db.ExecContext(ctx, query, args...)
+		if database.IsUniqueViolation(err) {
+			return ErrEmailExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create users: %w", err)
+		}
+
+		emails := make([]interface{}, len(users))
+		for i, user := range users {
+			emails[i] = user.Email
+		}
+		rows, err := db.QueryContext(ctx, "SELECT id, email FROM users WHERE email IN ("+placeholders(len(users))+")", emails...)
+		if err != nil {
+			return fmt.Errorf("failed to read user IDs: %w", err)
+		}
+		return assignIDs(rows, users, now)
+	})
+}
+
+// transact runs fn on a transaction: the one the repository is bound to, or
+// a new one on the cluster.
+func (r *userRepository) transact(ctx context.Context, fn func(db database.DBTX) error) error {
+	cluster, ok := r.db.(*database.Cluster)
+	if !ok {
+		return fn(r.db.Writer(ctx))
+	}
+	return cluster.Transact(ctx, func(ctx context.Context, tx *database.Tx) error {
+		return fn(tx.Writer(ctx))
+	})
+}
+
+func (r *userRepository) UpdateBatch(ctx context.Context, users []*entities.User) error {
+	return chunks(users, func(users []*entities.User) error {
+		now := time.Now()
+		query, args := updateBatchQuery(users, now)
+		result, err := r.db.Writer(ctx).ExecContext(ctx, query, args...)
+		if database.IsUniqueViolation(err) {
+			return ErrEmailExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update users: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows != int64(len(users)) {
+			return ErrUserNotFound
+		}
+		for _, user := range users {
+			user.UpdatedAt = now
+		}
+		return nil
+	})
+}
+
+func (r *userRepository) DeleteBatch(ctx context.Context, ids []int64) error {
+	return chunks(ids, func(ids []int64) error {
+		query, args := deleteBatchQuery(ids, time.Now())
+		result, err := r.db.Writer(ctx).ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete users: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows != int64(len(ids)) {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*entities.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at, deleted_at 
+		SELECT ` + userColumns + `
 		FROM users 
 		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	user := &entities.User{}
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Name, &user.Email,
-		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-	)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, query, id).Scan(userFields(user)...)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -54,18 +166,15 @@ func (r *userRepository) GetByID(id int64) (*entities.User, error) {
 	return user, nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*entities.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at, deleted_at 
+		SELECT ` + userColumns + `
 		FROM users 
 		WHERE email = ? AND deleted_at IS NULL
 	`
 
 	user := &entities.User{}
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Name, &user.Email,
-		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-	)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, query, email).Scan(userFields(user)...)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -77,15 +186,18 @@ func (r *userRepository) GetByEmail(email string) (*entities.User, error) {
 	return user, nil
 }
 
-func (r *userRepository) Update(user *entities.User) error {
+func (r *userRepository) Update(ctx context.Context, user *entities.User) error {
 	query := `
 		UPDATE users 
-		SET name = ?, email = ?, updated_at = ? 
+		SET ` + keepVerified + `, name = ?, email = ?, updated_at = ? 
 		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	user.UpdatedAt = time.Now()
-	result, err := r.db.Exec(query, user.Name, user.Email, user.UpdatedAt, user.ID)
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, user.Email, user.Name, user.Email, user.UpdatedAt, user.ID)
+	if database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
@@ -95,16 +207,16 @@ func (r *userRepository) Update(user *entities.User) error {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (r *userRepository) Delete(id int64) error {
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
 	query := `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
 
-	result, err := r.db.Exec(query, time.Now(), id)
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -114,8 +226,144 @@ func (r *userRepository) Delete(id int64) error {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	query := `UPDATE users SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(hash), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to set password hash: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) ReplacePasswordHash(ctx context.Context, id int64, old, hash string) error {
+	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ? AND COALESCE(password_hash, '') = ? AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(hash), time.Now(), id, old)
+	if err != nil {
+		return fmt.Errorf("failed to replace password hash: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) SignInGeneration(ctx context.Context, id int64) (string, error) {
+	query := `SELECT signin_generation FROM users WHERE id = ?`
+
+	var generation sql.NullString
+	err := r.db.Reader(database.WithPrimary(ctx)).QueryRowContext(ctx, query, id).Scan(&generation)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get sign-in generation: %w", err)
+	}
+
+	return generation.String, nil
+}
+
+func (r *userRepository) SetSignInGeneration(ctx context.Context, id int64, generation string) error {
+	query := `UPDATE users SET signin_generation = ? WHERE id = ?`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(generation), id)
+	if err != nil {
+		return fmt.Errorf("failed to set sign-in generation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
 	}
 
 	return nil
 }
+
+func (r *userRepository) MarkVerified(ctx context.Context, id int64, email string, at time.Time) error {
+	query := `UPDATE users SET verified_at = ?, updated_at = ? WHERE id = ? AND email = ? AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, at, time.Now(), id, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark user verified: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) List(ctx context.Context, filter ListFilter) ([]*entities.User, error) {
+	where, args := listWhere(filter)
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+	` + where + " ORDER BY id"
+
+	if filter.Limit > 0 || filter.Offset > 0 {
+		// MySQL has no OFFSET without LIMIT
+		limit := int64(filter.Limit)
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	return scanUsers(rows)
+}
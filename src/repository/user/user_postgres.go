@@ -0,0 +1,342 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"solecode/pkg/database"
+	entities "solecode/src/entities"
+)
+
+func (r *userPostgresRepository) Create(ctx context.Context, user *entities.User) error {
+	query := `
+		INSERT INTO users (name, email, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.Writer(ctx).QueryRowContext(ctx, query, user.Name, user.Email, nullIfEmpty(user.PasswordHash), now, now).Scan(&user.ID)
+	if database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+func (r *userPostgresRepository) CreateBatch(ctx context.Context, users []*entities.User) error {
+	return chunks(users, func(users []*entities.User) error {
+		return r.createChunk(ctx, users)
+	})
+}
+
+func (r *userPostgresRepository) createChunk(ctx context.Context, users []*entities.User) error {
+	now := time.Now()
+	values := make([]string, len(users))
+	args := make([]interface{}, 0, 5*len(users))
+	for i, user := range users {
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", 5*i+1, 5*i+2, 5*i+3, 5*i+4, 5*i+5)
+		args = append(args, user.Name, user.Email, nullIfEmpty(user.PasswordHash), now, now)
+	}
+	query := "INSERT INTO users (name, email, password_hash, created_at, updated_at) VALUES " +
+		strings.Join(values, ", ") + " RETURNING id"
+
+	rows, err := r.db.Writer(ctx).QueryContext(ctx, query, args...)
+	if database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create users: %w", err)
+	}
+	defer rows.Close()
+
+	// RETURNING yields the IDs in VALUES order
+	for _, user := range users {
+		if !rows.Next() {
+			break
+		}
+		if err := rows.Scan(&user.ID); err != nil {
+			return fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		user.CreatedAt = now
+		user.UpdatedAt = now
+	}
+	if err := rows.Err(); database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create users: %w", err)
+	}
+	return nil
+}
+
+func (r *userPostgresRepository) UpdateBatch(ctx context.Context, users []*entities.User) error {
+	return chunks(users, func(users []*entities.User) error {
+		now := time.Now()
+		query, args := updateBatchQuery(users, now)
+		result, err := r.db.Writer(ctx).ExecContext(ctx, database.Postgres.Rebind(query), args...)
+		if database.IsUniqueViolation(err) {
+			return ErrEmailExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update users: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows != int64(len(users)) {
+			return ErrUserNotFound
+		}
+		for _, user := range users {
+			user.UpdatedAt = now
+		}
+		return nil
+	})
+}
+
+func (r *userPostgresRepository) DeleteBatch(ctx context.Context, ids []int64) error {
+	return chunks(ids, func(ids []int64) error {
+		query, args := deleteBatchQuery(ids, time.Now())
+		result, err := r.db.Writer(ctx).ExecContext(ctx, database.Postgres.Rebind(query), args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete users: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows != int64(len(ids)) {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+func (r *userPostgresRepository) GetByID(ctx context.Context, id int64) (*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	user := &entities.User{}
+	err := r.db.Reader(ctx).QueryRowContext(ctx, query, id).Scan(userFields(user)...)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userPostgresRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email = $1 AND deleted_at IS NULL
+	`
+
+	user := &entities.User{}
+	err := r.db.Reader(ctx).QueryRowContext(ctx, query, email).Scan(userFields(user)...)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userPostgresRepository) Update(ctx context.Context, user *entities.User) error {
+	query := `
+		UPDATE users
+		SET verified_at = CASE WHEN email = $2 THEN verified_at END, name = $1, email = $2, updated_at = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`
+
+	user.UpdatedAt = time.Now()
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, user.Name, user.Email, user.UpdatedAt, user.ID)
+	if database.IsUniqueViolation(err) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) Delete(ctx context.Context, id int64) error {
+	query := `UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) Restore(ctx context.Context, id int64) error {
+	query := `UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(hash), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to set password hash: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) ReplacePasswordHash(ctx context.Context, id int64, old, hash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3 AND COALESCE(password_hash, '') = $4 AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(hash), time.Now(), id, old)
+	if err != nil {
+		return fmt.Errorf("failed to replace password hash: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) SignInGeneration(ctx context.Context, id int64) (string, error) {
+	query := `SELECT signin_generation FROM users WHERE id = $1`
+
+	var generation sql.NullString
+	err := r.db.Reader(database.WithPrimary(ctx)).QueryRowContext(ctx, query, id).Scan(&generation)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get sign-in generation: %w", err)
+	}
+
+	return generation.String, nil
+}
+
+func (r *userPostgresRepository) SetSignInGeneration(ctx context.Context, id int64, generation string) error {
+	query := `UPDATE users SET signin_generation = $1 WHERE id = $2`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, nullIfEmpty(generation), id)
+	if err != nil {
+		return fmt.Errorf("failed to set sign-in generation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) MarkVerified(ctx context.Context, id int64, email string, at time.Time) error {
+	query := `UPDATE users SET verified_at = $1, updated_at = $2 WHERE id = $3 AND email = $4 AND deleted_at IS NULL`
+
+	result, err := r.db.Writer(ctx).ExecContext(ctx, query, at, time.Now(), id, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark user verified: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userPostgresRepository) List(ctx context.Context, filter ListFilter) ([]*entities.User, error) {
+	where, args := listWhere(filter)
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+	` + where + " ORDER BY id LIMIT ? OFFSET ?"
+
+	// A NULL limit means no limit
+	var limit interface{}
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	args = append(args, limit, filter.Offset)
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, database.Postgres.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	return scanUsers(rows)
+}
@@ -0,0 +1,80 @@
+package user_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"solecode/docs/migrations"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	userRepo "solecode/src/repository/user"
+	"solecode/src/repository/user/usertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	usertest.RunConformance(t, func(t *testing.T) userRepo.UserRepositoryItf {
+		return userRepo.NewMemoryUserRepository()
+	})
+}
+
+func TestMySQLUserRepository(t *testing.T) {
+	db := openTestDB(t, database.MySQL, "TEST_MYSQL_DSN")
+
+	usertest.RunConformance(t, func(t *testing.T) userRepo.UserRepositoryItf {
+		_, err := db.Exec("DELETE FROM users")
+		require.NoError(t, err)
+		return userRepo.NewUserRepository(database.WrapDB(db, database.MySQL))
+	})
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	db := openTestDB(t, database.Postgres, "TEST_POSTGRES_DSN")
+
+	usertest.RunConformance(t, func(t *testing.T) userRepo.UserRepositoryItf {
+		_, err := db.Exec("DELETE FROM users")
+		require.NoError(t, err)
+		return userRepo.NewUserRepository(database.WrapDB(db, database.Postgres))
+	})
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.DatabaseConfig{
+		Name: filepath.Join(t.TempDir(), "users.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	applyTestMigrations(t, db, database.SQLite)
+
+	usertest.RunConformance(t, func(t *testing.T) userRepo.UserRepositoryItf {
+		_, err := db.Exec("DELETE FROM users")
+		require.NoError(t, err)
+		return userRepo.NewUserRepository(database.WrapDB(db, database.SQLite))
+	})
+}
+
+// openTestDB connects to the database named by the DSN in env and applies
+// the dialect's up migrations. The test is skipped when env is unset.
+func openTestDB(t *testing.T, dialect database.Dialect, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s not set", env)
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	applyTestMigrations(t, db, dialect)
+
+	return db
+}
+
+func applyTestMigrations(t *testing.T, db *sql.DB, dialect database.Dialect) {
+	_, err := migrate.New(db, dialect, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+}
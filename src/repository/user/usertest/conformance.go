@@ -0,0 +1,431 @@
+// Package usertest holds the conformance suite shared by every
+// UserRepositoryItf implementation.
+package usertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"solecode/src/entities"
+	userRepo "solecode/src/repository/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunConformance checks the behaviour every UserRepositoryItf
+// implementation must share. newRepo must return a repository backed by an
+// empty users table.
+func RunConformance(t *testing.T, newRepo func(t *testing.T) userRepo.UserRepositoryItf) {
+	ctx := context.Background()
+
+	t.Run("Create assigns ID and timestamps", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+		assert.NotZero(t, user.ID)
+		assert.False(t, user.CreatedAt.IsZero())
+		assert.False(t, user.UpdatedAt.IsZero())
+
+		got, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+		assert.Equal(t, "John Doe", got.Name)
+		assert.Equal(t, "john@example.com", got.Email)
+		assert.WithinDuration(t, user.CreatedAt, got.CreatedAt, time.Second)
+		assert.Nil(t, got.DeletedAt)
+	})
+
+	t.Run("Create rejects duplicate email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, &entities.User{Name: "John Doe", Email: "john@example.com"}))
+		err := repo.Create(ctx, &entities.User{Name: "Jane Doe", Email: "john@example.com"})
+		assert.ErrorIs(t, err, userRepo.ErrEmailExists)
+	})
+
+	t.Run("CreateBatch assigns IDs in order", func(t *testing.T) {
+		repo := newRepo(t)
+
+		users := []*entities.User{
+			{Name: "John Doe", Email: "john@example.com"},
+			{Name: "Jane Doe", Email: "jane@example.com"},
+			{Name: "Jim Doe", Email: "jim@example.com"},
+		}
+		require.NoError(t, repo.CreateBatch(ctx, users))
+
+		for _, user := range users {
+			assert.NotZero(t, user.ID)
+			assert.False(t, user.CreatedAt.IsZero())
+
+			got, err := repo.GetByID(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.Email, got.Email)
+		}
+		assert.Less(t, users[0].ID, users[1].ID)
+		assert.Less(t, users[1].ID, users[2].ID)
+	})
+
+	t.Run("CreateBatch rejects duplicate email", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Create(ctx, &entities.User{Name: "John Doe", Email: "john@example.com"}))
+		err := repo.CreateBatch(ctx, []*entities.User{
+			{Name: "Jane Doe", Email: "jane@example.com"},
+			{Name: "John Doe", Email: "john@example.com"},
+		})
+		assert.ErrorIs(t, err, userRepo.ErrEmailExists)
+
+		got, err := repo.GetByEmail(ctx, "jane@example.com")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("UpdateBatch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		john := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		jane := &entities.User{Name: "Jane Doe", Email: "jane@example.com"}
+		require.NoError(t, repo.CreateBatch(ctx, []*entities.User{john, jane}))
+
+		john.Name, jane.Email = "Johnny Doe", "jane.doe@example.com"
+		require.NoError(t, repo.UpdateBatch(ctx, []*entities.User{john, jane}))
+
+		got, err := repo.GetByID(ctx, john.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny Doe", got.Name)
+		assert.Equal(t, "john@example.com", got.Email)
+		got, err = repo.GetByID(ctx, jane.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "jane.doe@example.com", got.Email)
+
+		jane.Email = "john@example.com"
+		assert.ErrorIs(t, repo.UpdateBatch(ctx, []*entities.User{jane}), userRepo.ErrEmailExists)
+
+		require.NoError(t, repo.Delete(ctx, john.ID))
+		assert.ErrorIs(t, repo.UpdateBatch(ctx, []*entities.User{john}), userRepo.ErrUserNotFound)
+		assert.ErrorIs(t, repo.UpdateBatch(ctx, []*entities.User{{ID: 999999, Name: "Nobody", Email: "nobody@example.com"}}), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("DeleteBatch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		users := []*entities.User{
+			{Name: "John Doe", Email: "john@example.com"},
+			{Name: "Jane Doe", Email: "jane@example.com"},
+		}
+		require.NoError(t, repo.CreateBatch(ctx, users))
+
+		require.NoError(t, repo.DeleteBatch(ctx, []int64{users[0].ID, users[1].ID}))
+		for _, user := range users {
+			_, err := repo.GetByID(ctx, user.ID)
+			assert.ErrorIs(t, err, userRepo.ErrUserNotFound)
+		}
+
+		assert.ErrorIs(t, repo.DeleteBatch(ctx, []int64{users[0].ID}), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("GetByID returns userRepo.ErrUserNotFound for unknown ID", func(t *testing.T) {
+		repo := newRepo(t)
+
+		got, err := repo.GetByID(ctx, 999999)
+		assert.ErrorIs(t, err, userRepo.ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("GetByEmail", func(t *testing.T) {
+		repo := newRepo(t)
+
+		got, err := repo.GetByEmail(ctx, "nobody@example.com")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+
+		got, err = repo.GetByEmail(ctx, "john@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, user.ID, got.ID)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+		other := &entities.User{Name: "Jane Doe", Email: "jane@example.com"}
+		require.NoError(t, repo.Create(ctx, other))
+
+		createdAt := user.CreatedAt
+		user.Name = "Johnny Doe"
+		user.Email = "johnny@example.com"
+		require.NoError(t, repo.Update(ctx, user))
+		assert.False(t, user.UpdatedAt.Before(createdAt))
+
+		got, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny Doe", got.Name)
+		assert.Equal(t, "johnny@example.com", got.Email)
+		assert.WithinDuration(t, createdAt, got.CreatedAt, time.Second)
+
+		got, err = repo.GetByEmail(ctx, "john@example.com")
+		assert.NoError(t, err)
+		assert.Nil(t, got, "old email should no longer match")
+
+		user.Email = other.Email
+		assert.ErrorIs(t, repo.Update(ctx, user), userRepo.ErrEmailExists)
+
+		missing := &entities.User{ID: 999999, Name: "Nobody", Email: "nobody@example.com"}
+		assert.ErrorIs(t, repo.Update(ctx, missing), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("Delete is a soft delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+		require.NoError(t, repo.Delete(ctx, user.ID))
+
+		_, err := repo.GetByID(ctx, user.ID)
+		assert.ErrorIs(t, err, userRepo.ErrUserNotFound)
+
+		got, err := repo.GetByEmail(ctx, user.Email)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+
+		assert.ErrorIs(t, repo.Delete(ctx, user.ID), userRepo.ErrUserNotFound)
+		assert.ErrorIs(t, repo.Update(ctx, user), userRepo.ErrUserNotFound)
+
+		// The email stays reserved by the deleted row
+		err = repo.Create(ctx, &entities.User{Name: "John Doe", Email: "john@example.com"})
+		assert.ErrorIs(t, err, userRepo.ErrEmailExists)
+	})
+
+	t.Run("Delete returns userRepo.ErrUserNotFound for unknown ID", func(t *testing.T) {
+		repo := newRepo(t)
+
+		assert.ErrorIs(t, repo.Delete(ctx, 999999), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("Restore undoes a soft delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+
+		assert.ErrorIs(t, repo.Restore(ctx, user.ID), userRepo.ErrUserNotFound, "active users cannot be restored")
+
+		require.NoError(t, repo.Delete(ctx, user.ID))
+		require.NoError(t, repo.Restore(ctx, user.ID))
+
+		got, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got.DeletedAt)
+
+		assert.ErrorIs(t, repo.Restore(ctx, 999999), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("SetPasswordHash stores and clears the hash", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com", PasswordHash: "$2a$04$first"}
+		require.NoError(t, repo.Create(ctx, user))
+		got, err := repo.GetByEmail(ctx, "john@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "$2a$04$first", got.PasswordHash)
+
+		require.NoError(t, repo.SetPasswordHash(ctx, user.ID, "$2a$04$second"))
+		got, err = repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "$2a$04$second", got.PasswordHash)
+
+		// Updating other fields keeps the hash
+		got.Name = "Johnny Doe"
+		require.NoError(t, repo.Update(ctx, got))
+		users, err := repo.List(ctx, userRepo.ListFilter{})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "$2a$04$second", users[0].PasswordHash)
+
+		require.NoError(t, repo.SetPasswordHash(ctx, user.ID, ""))
+		got, err = repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, got.HasPassword())
+
+		require.NoError(t, repo.Delete(ctx, user.ID))
+		assert.ErrorIs(t, repo.SetPasswordHash(ctx, user.ID, "$2a$04$third"), userRepo.ErrUserNotFound)
+		assert.ErrorIs(t, repo.SetPasswordHash(ctx, 999999, "$2a$04$third"), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("ReplacePasswordHash only replaces the expected hash", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+
+		// No password counts as the empty hash
+		require.NoError(t, repo.ReplacePasswordHash(ctx, user.ID, "", "$2a$04$first"))
+		assert.ErrorIs(t, repo.ReplacePasswordHash(ctx, user.ID, "", "$2a$04$second"), userRepo.ErrUserNotFound)
+		require.NoError(t, repo.ReplacePasswordHash(ctx, user.ID, "$2a$04$first", "$2a$04$second"))
+		assert.ErrorIs(t, repo.ReplacePasswordHash(ctx, user.ID, "$2a$04$first", "$2a$04$third"), userRepo.ErrUserNotFound)
+		got, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "$2a$04$second", got.PasswordHash)
+
+		require.NoError(t, repo.Delete(ctx, user.ID))
+		assert.ErrorIs(t, repo.ReplacePasswordHash(ctx, user.ID, "$2a$04$second", "$2a$04$third"), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("SignInGeneration survives deletion", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, repo.Create(ctx, user))
+		generation, err := repo.SignInGeneration(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, generation)
+
+		require.NoError(t, repo.SetSignInGeneration(ctx, user.ID, "first"))
+		require.NoError(t, repo.Delete(ctx, user.ID))
+		generation, err = repo.SignInGeneration(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "first", generation)
+		require.NoError(t, repo.SetSignInGeneration(ctx, user.ID, "second"))
+		require.NoError(t, repo.Restore(ctx, user.ID))
+		generation, err = repo.SignInGeneration(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "second", generation)
+
+		_, err = repo.SignInGeneration(ctx, 999999)
+		assert.ErrorIs(t, err, userRepo.ErrUserNotFound)
+		assert.ErrorIs(t, repo.SetSignInGeneration(ctx, 999999, "third"), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("MarkVerified and email changes", func(t *testing.T) {
+		repo := newRepo(t)
+
+		john := &entities.User{Name: "John Doe", Email: "john@example.com"}
+		jane := &entities.User{Name: "Jane Doe", Email: "jane@example.com"}
+		require.NoError(t, repo.Create(ctx, john))
+		require.NoError(t, repo.Create(ctx, jane))
+
+		at := time.Now().Truncate(time.Second)
+		assert.ErrorIs(t, repo.MarkVerified(ctx, john.ID, "old@example.com", at), userRepo.ErrUserNotFound)
+		require.NoError(t, repo.MarkVerified(ctx, john.ID, "john@example.com", at))
+		require.NoError(t, repo.MarkVerified(ctx, jane.ID, "jane@example.com", at))
+		got, err := repo.GetByID(ctx, john.ID)
+		require.NoError(t, err)
+		require.True(t, got.Verified())
+		assert.True(t, at.Equal(*got.VerifiedAt))
+
+		// Renaming keeps the verification, a new email drops it
+		got.Name = "Johnny Doe"
+		require.NoError(t, repo.Update(ctx, got))
+		got, err = repo.GetByID(ctx, john.ID)
+		require.NoError(t, err)
+		assert.True(t, got.Verified())
+		got.Email = "johnny@example.com"
+		require.NoError(t, repo.Update(ctx, got))
+		got, err = repo.GetByID(ctx, john.ID)
+		require.NoError(t, err)
+		assert.False(t, got.Verified())
+
+		require.NoError(t, repo.MarkVerified(ctx, john.ID, "johnny@example.com", at))
+		require.NoError(t, repo.UpdateBatch(ctx, []*entities.User{
+			{ID: john.ID, Name: "John Doe", Email: "john@example.com"},
+			{ID: jane.ID, Name: "Janet Doe", Email: "jane@example.com"},
+		}))
+		users, err := repo.List(ctx, userRepo.ListFilter{})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.False(t, users[0].Verified())
+		assert.True(t, users[1].Verified())
+
+		require.NoError(t, repo.Delete(ctx, jane.ID))
+		assert.ErrorIs(t, repo.MarkVerified(ctx, jane.ID, "jane@example.com", at), userRepo.ErrUserNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+
+		users, err := repo.List(ctx, userRepo.ListFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		var ids []int64
+		for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			user := &entities.User{Name: "John Doe", Email: email}
+			require.NoError(t, repo.Create(ctx, user))
+			ids = append(ids, user.ID)
+		}
+		require.NoError(t, repo.Delete(ctx, ids[1]))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[0], ids[2]}, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{IncludeDeleted: true})
+		require.NoError(t, err)
+		assert.Equal(t, ids, userIDs(users))
+		assert.NotNil(t, users[1].DeletedAt)
+
+		users, err = repo.List(ctx, userRepo.ListFilter{IncludeDeleted: true, Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[1]}, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{Offset: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[2]}, userIDs(users))
+	})
+
+	t.Run("List filters", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var ids []int64
+		for _, email := range []string{"a@example.com", "b@example.org", "c@example.com", "d@ex_ample.com"} {
+			user := &entities.User{Name: "John Doe", Email: email}
+			require.NoError(t, repo.Create(ctx, user))
+			ids = append(ids, user.ID)
+		}
+
+		users, err := repo.List(ctx, userRepo.ListFilter{IDs: []int64{ids[3], ids[0], 999999}})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[0], ids[3]}, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{AfterID: ids[1], Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[2]}, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{EmailDomain: "Example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[0], ids[2]}, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{EmailDomain: "ex_ample.com"})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ids[3]}, userIDs(users))
+
+		now := time.Now()
+		users, err = repo.List(ctx, userRepo.ListFilter{CreatedFrom: now.Add(-time.Hour), CreatedTo: now.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, ids, userIDs(users))
+
+		users, err = repo.List(ctx, userRepo.ListFilter{CreatedFrom: now.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		users, err = repo.List(ctx, userRepo.ListFilter{CreatedTo: now.Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+}
+
+func userIDs(users []*entities.User) []int64 {
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
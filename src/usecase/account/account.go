@@ -0,0 +1,231 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/mail"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+)
+
+// Token purposes; a token only works for the flow it was issued for.
+const (
+	purposeVerify = "verify_email"
+	purposeReset  = "reset_password"
+)
+
+// verifyState and resetState describe what a token is bound to. Verifying
+// the email or setting the password changes the state, so each token works
+// once; changing the email voids both kinds.
+func verifyState(user *entities.User) string {
+	verifiedAt := ""
+	if user.VerifiedAt != nil {
+		verifiedAt = user.VerifiedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return user.Email + "\x00" + verifiedAt
+}
+
+func resetState(user *entities.User) string {
+	return user.Email + "\x00" + user.PasswordHash
+}
+
+func (uc *accountUseCase) SendVerification(ctx context.Context, userID int64, locale string) error {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Verified() {
+		return ErrAlreadyVerified
+	}
+
+	raw := uc.signer.Sign(purposeVerify, user.ID, verifyState(user), uc.now().Add(uc.opts.VerificationTTL))
+	return uc.send(ctx, user, mail.VerifyEmail, locale, "/verify-email", raw, uc.opts.VerificationTTL)
+}
+
+func (uc *accountUseCase) VerifyEmail(ctx context.Context, raw string) (*entities.User, error) {
+	user, err := uc.redeem(ctx, raw, purposeVerify, verifyState)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	if err := uc.repo.User.MarkVerified(ctx, user.ID, user.Email, now); err != nil {
+		return nil, err
+	}
+	user.VerifiedAt = &now
+	uc.cache.Delete(fmt.Sprintf("user:%d", user.ID))
+
+	uc.log(ctx, "account.verify_email", user.ID)
+	return user, nil
+}
+
+func (uc *accountUseCase) RequestPasswordReset(ctx context.Context, email, locale string) error {
+	user, err := uc.repo.User.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return fmt.Errorf("failed to get user by email: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	raw := uc.signer.Sign(purposeReset, user.ID, resetState(user), uc.now().Add(uc.opts.PasswordResetTTL))
+	return uc.send(ctx, user, mail.PasswordReset, locale, "/reset-password", raw, uc.opts.PasswordResetTTL)
+}
+
+func (uc *accountUseCase) QueuePasswordReset(ctx context.Context, email, locale string) error {
+	// Requests have their own counters, per email and per client IP, so
+	// that asking for reset mails locks nobody out of signing in
+	if err := uc.requests.Check(ctx, email); err != nil {
+		return err
+	}
+	if err := uc.requests.Failure(ctx, email); err != nil {
+		return err
+	}
+
+	select {
+	case uc.queue <- struct{}{}:
+	default:
+		log.Printf("password reset dropped: %d mails already queued", maxQueuedResets)
+		return nil
+	}
+
+	// The request may end before the mail is out
+	ctx = context.WithoutCancel(ctx)
+	uc.queued.Add(1)
+	go func() {
+		defer uc.queued.Done()
+		defer func() { <-uc.queue }()
+		if err := uc.RequestPasswordReset(ctx, email, locale); err != nil {
+			log.Printf("password reset failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (uc *accountUseCase) ResetPassword(ctx context.Context, raw, password string) error {
+	// Check the password first so a rejected one does not use up the
+	// token
+	if err := uc.validator.ValidateStruct(&passwordInput{Password: password}); err != nil {
+		return err
+	}
+	user, err := uc.redeem(ctx, raw, purposeReset, resetState)
+	if err != nil {
+		return err
+	}
+
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	// Only the first of concurrent redemptions of a token finds the hash
+	// it was issued against
+	err = uc.repo.User.ReplacePasswordHash(ctx, user.ID, user.PasswordHash, hash)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if !user.Verified() {
+		if err := uc.repo.User.MarkVerified(ctx, user.ID, user.Email, uc.now()); err != nil {
+			return err
+		}
+	}
+	uc.cache.Delete(fmt.Sprintf("user:%d", user.ID))
+	if err := uc.sessions.EndSignIns(ctx, user.ID, ""); err != nil {
+		return fmt.Errorf("failed to end sign-ins: %w", err)
+	}
+
+	uc.log(ctx, "account.password_reset", user.ID)
+	return nil
+}
+
+// redeem returns the user a token was issued to, after checking it against
+// the user's current state. Invalid tokens count towards the lockout of
+// the purpose and user they name; malformed ones, which name no user,
+// share one counter.
+func (uc *accountUseCase) redeem(ctx context.Context, raw, purpose string, state func(*entities.User) string) (*entities.User, error) {
+	account := purpose
+	if userID, err := uc.signer.Subject(raw); err == nil {
+		account = fmt.Sprintf("%s:%d", purpose, userID)
+	}
+	if err := uc.lockout.Check(ctx, account); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.verify(ctx, raw, purpose, state)
+	if errors.Is(err, ErrInvalidToken) {
+		if err := uc.lockout.Failure(ctx, account); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.lockout.Success(ctx, account); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verify returns the user a token was issued to, or ErrInvalidToken.
+func (uc *accountUseCase) verify(ctx context.Context, raw, purpose string, state func(*entities.User) string) (*entities.User, error) {
+	userID, err := uc.signer.Subject(raw)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.signer.Verify(raw, purpose, state(user), uc.now()); err != nil {
+		if errors.Is(err, token.ErrExpiredToken) || errors.Is(err, token.ErrInvalidToken) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// send renders template for user with a link to path carrying raw, and
+// mails it.
+func (uc *accountUseCase) send(ctx context.Context, user *entities.User, template, locale, path, raw string, ttl time.Duration) error {
+	msg, err := uc.templates.Render(template, locale, mail.LinkData{
+		Name:           user.Name,
+		Email:          user.Email,
+		Link:           strings.TrimSuffix(uc.opts.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(raw),
+		Token:          raw,
+		ExpiresInHours: int(math.Ceil(ttl.Hours())),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	if err := uc.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s mail: %w", template, err)
+	}
+	return nil
+}
+
+// log audits a completed flow. The actor is the user the token was issued
+// to, who proved they hold it.
+func (uc *accountUseCase) log(ctx context.Context, action string, userID int64) {
+	uc.audit.Log(ctx, audit.Event{Action: action, Outcome: audit.Success, ActorID: userID, TargetID: userID})
+}
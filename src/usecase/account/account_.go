@@ -0,0 +1,154 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/mail"
+	"solecode/pkg/password"
+	"solecode/pkg/token"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultVerificationTTL  = 48 * time.Hour
+	DefaultPasswordResetTTL = time.Hour
+	DefaultLinkBaseURL      = "http://localhost:8080"
+)
+
+// ResetRequestScope is the lockout scope password reset requests are
+// counted in.
+const ResetRequestScope = "password_reset"
+
+// maxQueuedResets bounds the reset mails QueuePasswordReset sends at once;
+// requests beyond it are dropped.
+const maxQueuedResets = 32
+
+var (
+	// ErrInvalidToken is returned for verification and reset tokens that
+	// are malformed, expired, already used or meant for the other flow.
+	ErrInvalidToken    = errors.New("invalid or expired token")
+	ErrAlreadyVerified = errors.New("email already verified")
+)
+
+//go:generate mockery --name AccountUseCaseItf --output mocks --filename accountusecase_mock.go --outpkg mocks
+type AccountUseCaseItf interface {
+	// SendVerification mails a user a link that confirms their email.
+	// Users may ask for their own. locale picks the template language and
+	// may be an Accept-Language value.
+	SendVerification(ctx context.Context, userID int64, locale string) error
+	// VerifyEmail marks the email of a verification token's user as
+	// verified and returns the user. Invalid tokens count towards a
+	// lockout of the user they name and of the client IP, and a throttled
+	// attempt fails with a *lockout.ThrottledError.
+	VerifyEmail(ctx context.Context, token string) (*entities.User, error)
+	// RequestPasswordReset mails a reset link to the active user with
+	// email. It succeeds whether or not there is one, so callers cannot
+	// learn which emails exist.
+	RequestPasswordReset(ctx context.Context, email, locale string) error
+	// QueuePasswordReset is RequestPasswordReset in the background, for
+	// anonymous callers: it returns at once, so neither its outcome nor
+	// its timing tell whether the email exists, and failures are logged.
+	// Every request counts towards a limit on email and on the client IP,
+	// so that nobody can flood an inbox; a throttled request fails with a
+	// *lockout.ThrottledError, alike for known and unknown emails. The
+	// limit is kept apart from the lockout of logins.
+	QueuePasswordReset(ctx context.Context, email, locale string) error
+	// ResetPassword sets the password of a reset token's user and ends
+	// their sessions and refresh tokens. It also verifies their email,
+	// which the link was sent to. Invalid tokens are throttled like in
+	// VerifyEmail.
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+// Options tunes token lifetimes and links; zero values use the defaults.
+type Options struct {
+	VerificationTTL  time.Duration
+	PasswordResetTTL time.Duration
+	// LinkBaseURL is where the pages that complete the flows are served:
+	// links go to <LinkBaseURL>/verify-email?token=... and
+	// <LinkBaseURL>/reset-password?token=...
+	LinkBaseURL string
+	// ResetRequests limits password reset requests like failed logins,
+	// with its own counters in the ResetRequestScope scope; zero values
+	// use the lockout defaults.
+	ResetRequests lockoutUC.Options
+}
+
+type accountUseCase struct {
+	repo      *repository.Repository
+	cache     cachePkg.CacheItf
+	hasher    *password.Hasher
+	policy    *authzUC.Policy
+	sessions  sessionUC.SessionUseCaseItf
+	lockout   lockoutUC.LockoutUseCaseItf
+	requests  lockoutUC.LockoutUseCaseItf
+	audit     audit.Logger
+	mailer    mail.Mailer
+	templates *mail.Templates
+	signer    *token.ActionSigner
+	validator *validator.Validator
+	opts      Options
+	now       func() time.Time
+
+	// queue holds a slot per reset mail being sent in the background
+	queue  chan struct{}
+	queued sync.WaitGroup
+}
+
+// passwordInput carries the same rules as the user use case applies.
+type passwordInput struct {
+	Password string `json:"password" validate:"required,max=72,password"`
+}
+
+func NewAccountUseCase(
+	repo *repository.Repository,
+	cache cachePkg.CacheItf,
+	hasher *password.Hasher,
+	policy *authzUC.Policy,
+	sessions sessionUC.SessionUseCaseItf,
+	lockout lockoutUC.LockoutUseCaseItf,
+	auditLog audit.Logger,
+	mailer mail.Mailer,
+	templates *mail.Templates,
+	signer *token.ActionSigner,
+	opts Options,
+) AccountUseCaseItf {
+	if opts.VerificationTTL <= 0 {
+		opts.VerificationTTL = DefaultVerificationTTL
+	}
+	if opts.PasswordResetTTL <= 0 {
+		opts.PasswordResetTTL = DefaultPasswordResetTTL
+	}
+	if opts.LinkBaseURL == "" {
+		opts.LinkBaseURL = DefaultLinkBaseURL
+	}
+	opts.ResetRequests.Scope = ResetRequestScope
+	return &accountUseCase{
+		repo:      repo,
+		cache:     cache,
+		hasher:    hasher,
+		policy:    policy,
+		sessions:  sessions,
+		lockout:   lockout,
+		requests:  lockoutUC.NewLockoutUseCase(repo, cache, policy, auditLog, opts.ResetRequests),
+		audit:     auditLog,
+		mailer:    mailer,
+		templates: templates,
+		signer:    signer,
+		validator: validator.New(),
+		opts:      opts,
+		now:       time.Now,
+		queue:     make(chan struct{}, maxQueuedResets),
+	}
+}
@@ -0,0 +1,291 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/config"
+	"solecode/pkg/mail"
+	"solecode/pkg/password"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	authzUC "solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc       *accountUseCase
+	sessions sessionUC.SessionUseCaseItf
+	mailer   *mail.MemoryMailer
+	hasher   *password.Hasher
+}
+
+// newTestEnv returns an account use case over the shared fixture, mailing
+// into memory.
+func newTestEnv(t *testing.T) *testEnv {
+	hasher, err := password.New(config.PasswordConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1})
+	require.NoError(t, err)
+	templates, err := mail.NewTemplates("", "")
+	require.NoError(t, err)
+	signer, err := token.NewEphemeralActionSigner()
+	require.NoError(t, err)
+
+	env := &testEnv{Env: usecasetest.New(t), mailer: mail.NewMemoryMailer("noreply@example.com"), hasher: hasher}
+	repo, memoryCache := env.Repo, env.Cache
+	policy := authzUC.NewPolicy(audit.NewNopLogger())
+	authz := authzUC.NewAuthzUseCase(repo, memoryCache, policy, audit.NewNopLogger())
+	env.sessions = sessionUC.NewSessionUseCase(repo, authz, policy, memoryCache, audit.NewNopLogger(), sessionUC.Options{})
+	lockout := lockoutUC.NewLockoutUseCase(repo, memoryCache, policy, audit.NewNopLogger(), lockoutUC.Options{})
+	env.uc = NewAccountUseCase(repo, memoryCache, hasher, policy, env.sessions, lockout,
+		audit.NewNopLogger(), env.mailer, templates, signer, Options{LinkBaseURL: "https://app.example.com/"}).(*accountUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+// lastToken returns the token in the link of the last mail sent.
+func (env *testEnv) lastToken(t *testing.T, path string) string {
+	messages := env.mailer.Messages()
+	require.NotEmpty(t, messages)
+	msg := messages[len(messages)-1]
+	for _, field := range strings.Fields(msg.Text) {
+		if strings.HasPrefix(field, "https://app.example.com"+path+"?token=") {
+			link, err := url.Parse(field)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no %s link in %q", path, msg.Text)
+	return ""
+}
+
+func TestVerifyEmail(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.uc.SendVerification(usecasetest.As(env.User.ID), env.User.ID, "de-DE,de;q=0.9"))
+	messages := env.mailer.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "john@example.com", messages[0].To)
+	assert.Equal(t, "Bestätigen Sie Ihre E-Mail-Adresse", messages[0].Subject)
+	assert.Contains(t, messages[0].Text, "48 Stunden")
+	raw := env.lastToken(t, "/verify-email")
+
+	// A verification token does not reset passwords
+	assert.ErrorIs(t, env.uc.ResetPassword(ctx, raw, "Secret123!"), ErrInvalidToken)
+
+	user, err := env.uc.VerifyEmail(ctx, raw)
+	require.NoError(t, err)
+	assert.True(t, user.Verified())
+	stored, err := env.Repo.User.GetByID(ctx, env.User.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Verified())
+
+	// Single use
+	_, err = env.uc.VerifyEmail(ctx, raw)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorIs(t, env.uc.SendVerification(usecasetest.As(env.User.ID), env.User.ID, ""), ErrAlreadyVerified)
+}
+
+func TestVerifyEmailExpiresAndFollowsEmail(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.uc.SendVerification(usecasetest.As(env.User.ID), env.User.ID, ""))
+	raw := env.lastToken(t, "/verify-email")
+
+	env.Clock.Advance(DefaultVerificationTTL)
+	_, err := env.uc.VerifyEmail(ctx, raw)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	env.Clock.Set(time.Now())
+	require.NoError(t, env.uc.SendVerification(usecasetest.As(env.User.ID), env.User.ID, ""))
+	raw = env.lastToken(t, "/verify-email")
+	env.User.Email = "johnny@example.com"
+	require.NoError(t, env.Repo.User.Update(ctx, env.User))
+	_, err = env.uc.VerifyEmail(ctx, raw)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	for _, bad := range []string{"", "garbage", raw + "x"} {
+		_, err := env.uc.VerifyEmail(ctx, bad)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+}
+
+func TestSendVerificationIsAuthorized(t *testing.T) {
+	env := newTestEnv(t)
+
+	err := env.uc.SendVerification(usecasetest.As(env.User.ID+1), env.User.ID, "")
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	assert.Empty(t, env.mailer.Messages())
+}
+
+func TestPasswordReset(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// Unknown emails succeed without mail
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, "nobody@example.com", ""))
+	assert.Empty(t, env.mailer.Messages())
+
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, " John@Example.com ", "en"))
+	messages := env.mailer.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Reset your password", messages[0].Subject)
+	assert.Contains(t, messages[0].Text, "expires in 1 hour.")
+	raw := env.lastToken(t, "/reset-password")
+
+	// A rejected password leaves the token usable
+	assert.Error(t, env.uc.ResetPassword(ctx, raw, "short"))
+	require.NoError(t, env.uc.ResetPassword(ctx, raw, "Secret123!"))
+
+	stored, err := env.Repo.User.GetByID(ctx, env.User.ID)
+	require.NoError(t, err)
+	ok, err := env.hasher.Verify("Secret123!", stored.PasswordHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, stored.Verified())
+
+	assert.ErrorIs(t, env.uc.ResetPassword(ctx, raw, "Other123!"), ErrInvalidToken)
+}
+
+func TestPasswordResetEndsSessions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	session, err := env.sessions.Create(ctx, env.User.ID, sessionUC.Client{})
+	require.NoError(t, err)
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, "john@example.com", ""))
+	require.NoError(t, env.uc.ResetPassword(ctx, env.lastToken(t, "/reset-password"), "Secret123!"))
+
+	_, _, err = env.sessions.Authenticate(ctx, session.Token)
+	assert.ErrorIs(t, err, sessionUC.ErrInvalidSession)
+}
+
+// slowUsers widens the window between reading a user and writing it.
+type slowUsers struct {
+	userRepository.UserRepositoryItf
+}
+
+func (r slowUsers) GetByID(ctx context.Context, id int64) (*entities.User, error) {
+	user, err := r.UserRepositoryItf.GetByID(ctx, id)
+	time.Sleep(10 * time.Millisecond)
+	return user, err
+}
+
+func TestPasswordResetConcurrently(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, "john@example.com", ""))
+	raw := env.lastToken(t, "/reset-password")
+	repo := *env.uc.repo
+	repo.User = slowUsers{repo.User}
+	env.uc.repo = &repo
+
+	// Racing redemptions of one token set one password
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if env.uc.ResetPassword(ctx, raw, fmt.Sprintf("Secret123!%d", i)) == nil {
+				succeeded.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+}
+
+func TestQueuePasswordReset(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.uc.QueuePasswordReset(ctx, "nobody@example.com", ""))
+	require.NoError(t, env.uc.QueuePasswordReset(ctx, "john@example.com", ""))
+	env.uc.queued.Wait()
+	require.Len(t, env.mailer.Messages(), 1)
+	assert.NotEmpty(t, env.lastToken(t, "/reset-password"))
+
+	// Requests beyond the queue are dropped rather than waited for
+	for i := 0; i < maxQueuedResets; i++ {
+		env.uc.queue <- struct{}{}
+	}
+	require.NoError(t, env.uc.QueuePasswordReset(ctx, "john@example.com", ""))
+	env.uc.queued.Wait()
+	assert.Len(t, env.mailer.Messages(), 1)
+}
+
+func TestQueuePasswordResetIsThrottled(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := lockoutUC.WithClientIP(context.Background(), "192.0.2.1")
+
+	// Every request counts, for unknown emails too, so an inbox cannot be
+	// flooded and throttling does not tell which emails exist
+	for _, email := range []string{"john@example.com", "nobody@example.com"} {
+		for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+			require.NoError(t, env.uc.QueuePasswordReset(ctx, email, ""))
+		}
+		var throttled *lockoutUC.ThrottledError
+		assert.ErrorAs(t, env.uc.QueuePasswordReset(ctx, email, ""), &throttled)
+	}
+	env.uc.queued.Wait()
+	assert.Len(t, env.mailer.Messages(), lockoutUC.DefaultDelayAfter)
+
+	// Logins from the same address are counted apart, even past the
+	// limit of the address
+	for i := 0; i < lockoutUC.DefaultMaxIPFailures; i++ {
+		env.uc.QueuePasswordReset(ctx, fmt.Sprintf("user%d@example.com", i), "")
+	}
+	env.uc.queued.Wait()
+	var throttled *lockoutUC.ThrottledError
+	assert.ErrorAs(t, env.uc.QueuePasswordReset(ctx, "jane@example.com", ""), &throttled)
+	require.NoError(t, env.uc.lockout.Check(ctx, "john@example.com"))
+}
+
+func TestInvalidTokensAreThrottled(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, "john@example.com", ""))
+	raw := env.lastToken(t, "/reset-password")
+
+	// Invalid tokens for a user lock the flow for that user, the genuine
+	// token included
+	forged := env.uc.signer.Sign(purposeReset, env.User.ID, "stale", env.Clock.Now().Add(time.Hour))
+	for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+		require.ErrorIs(t, env.uc.ResetPassword(ctx, forged, "Secret123!"), ErrInvalidToken)
+	}
+	var throttled *lockoutUC.ThrottledError
+	assert.ErrorAs(t, env.uc.ResetPassword(ctx, raw, "Secret123!"), &throttled)
+
+	// Verification is counted apart
+	require.NoError(t, env.uc.SendVerification(usecasetest.As(env.User.ID), env.User.ID, ""))
+	_, err := env.uc.VerifyEmail(ctx, env.lastToken(t, "/verify-email"))
+	assert.NoError(t, err)
+}
+
+func TestPasswordResetExpires(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.uc.RequestPasswordReset(ctx, "john@example.com", ""))
+	raw := env.lastToken(t, "/reset-password")
+
+	env.Clock.Advance(DefaultPasswordResetTTL)
+	assert.ErrorIs(t, env.uc.ResetPassword(ctx, raw, "Secret123!"), ErrInvalidToken)
+}
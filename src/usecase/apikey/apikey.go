@@ -0,0 +1,227 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	apiKeyRepository "solecode/src/repository/apikey"
+	userRepository "solecode/src/repository/user"
+	authzUC "solecode/src/usecase/authz"
+)
+
+// A key is KeyPrefix, 8 hex characters and an underscore, which together
+// form the stored prefix, followed by 32 random bytes in base64url.
+const (
+	prefixLength = len(KeyPrefix) + 8
+	secretLength = 43
+)
+
+// keyRecord is what the cache holds for a key, under its prefix. The hash
+// is of a random 32-byte secret, so it is of no use to anyone who reads it.
+type keyRecord struct {
+	ID          int64                 `json:"id"`
+	UserID      int64                 `json:"user_id"`
+	Email       string                `json:"email"`
+	Hash        string                `json:"hash"`
+	Permissions []entities.Permission `json:"permissions"`
+	ExpiresAt   *time.Time            `json:"expires_at"`
+	RevokedAt   *time.Time            `json:"revoked_at"`
+	LastUsedAt  *time.Time            `json:"last_used_at"`
+}
+
+func recordKey(prefix string) string {
+	return "api_key:" + prefix
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (uc *apiKeyUseCase) Create(ctx context.Context, userID int64, name string, permissions []entities.Permission, expiresAt *time.Time) (*entities.APIKey, string, error) {
+	if err := uc.authz.Authorize(ctx, 0, entities.PermAPIKeysManage); err != nil {
+		return nil, "", err
+	}
+
+	input := keyInput{Name: strings.TrimSpace(name)}
+	if err := uc.validator.ValidateStruct(&input); err != nil {
+		return nil, "", err
+	}
+	if len(permissions) == 0 {
+		return nil, "", ErrNoPermissions
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(entities.AllPermissions, permission) {
+			return nil, "", fmt.Errorf("%w: %s", authzUC.ErrUnknownPermission, permission)
+		}
+	}
+	permissions = slices.Clone(permissions)
+	slices.Sort(permissions)
+	permissions = slices.Compact(permissions)
+	if expiresAt != nil && !expiresAt.After(uc.now()) {
+		return nil, "", ErrExpiryInPast
+	}
+
+	raw, prefix, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &entities.APIKey{
+		UserID:      userID,
+		Name:        input.Name,
+		Prefix:      prefix,
+		Hash:        hashKey(raw),
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+	}
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if _, err := tx.User.GetByID(ctx, userID); err != nil {
+			return err
+		}
+		return tx.APIKey.Create(ctx, key)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	uc.log(ctx, "apikeys.create", userID, key.Prefix)
+	return key, raw, nil
+}
+
+func (uc *apiKeyUseCase) List(ctx context.Context, userID int64) ([]*entities.APIKey, error) {
+	if err := uc.authz.Authorize(ctx, 0, entities.PermAPIKeysManage); err != nil {
+		return nil, err
+	}
+	return uc.repo.APIKey.List(ctx, userID)
+}
+
+func (uc *apiKeyUseCase) Revoke(ctx context.Context, id int64) error {
+	if err := uc.authz.Authorize(ctx, 0, entities.PermAPIKeysManage); err != nil {
+		return err
+	}
+
+	key, err := uc.repo.APIKey.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := uc.repo.APIKey.Revoke(ctx, id, uc.now()); err != nil {
+		return err
+	}
+	if err := uc.cache.Delete(recordKey(key.Prefix)); err != nil {
+		return fmt.Errorf("failed to evict api key: %w", err)
+	}
+
+	uc.log(ctx, "apikeys.revoke", key.UserID, key.Prefix)
+	return nil
+}
+
+func (uc *apiKeyUseCase) Authenticate(ctx context.Context, key string) (*entities.Principal, error) {
+	if !IsKey(key) || len(key) != prefixLength+1+secretLength || key[prefixLength] != '_' {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix := key[:prefixLength]
+
+	record, err := uc.lookup(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(record.Hash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	now := uc.now()
+	active := entities.APIKey{ExpiresAt: record.ExpiresAt, RevokedAt: record.RevokedAt}
+	if !active.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	principal := &entities.Principal{
+		UserID:   record.UserID,
+		Email:    record.Email,
+		TokenID:  prefix,
+		APIKeyID: record.ID,
+		Scopes:   record.Permissions,
+	}
+	if record.ExpiresAt != nil {
+		principal.ExpiresAt = *record.ExpiresAt
+	}
+	if err := uc.authz.LoadPermissions(ctx, principal); err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedInterval {
+		// Best effort: a failed write only leaves LastUsedAt stale
+		if err := uc.repo.APIKey.TouchLastUsed(ctx, record.ID, now); err == nil {
+			record.LastUsedAt = &now
+			uc.cache.SetJSON(recordKey(prefix), record, recordTTL)
+		}
+	}
+	return principal, nil
+}
+
+// lookup returns the record of the key with prefix, from the cache when it
+// holds one.
+func (uc *apiKeyUseCase) lookup(ctx context.Context, prefix string) (*keyRecord, error) {
+	var record keyRecord
+	if err := uc.cache.GetJSON(recordKey(prefix), &record); err == nil && record.ID != 0 {
+		return &record, nil
+	}
+
+	key, err := uc.repo.APIKey.GetByPrefix(ctx, prefix)
+	if errors.Is(err, apiKeyRepository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.repo.User.GetByID(ctx, key.UserID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record = keyRecord{
+		ID:          key.ID,
+		UserID:      key.UserID,
+		Email:       user.Email,
+		Hash:        key.Hash,
+		Permissions: key.Permissions,
+		ExpiresAt:   key.ExpiresAt,
+		RevokedAt:   key.RevokedAt,
+		LastUsedAt:  key.LastUsedAt,
+	}
+	uc.cache.SetJSON(recordKey(prefix), record, recordTTL)
+	return &record, nil
+}
+
+// log audits a successful change made by the principal of ctx.
+func (uc *apiKeyUseCase) log(ctx context.Context, action string, targetID int64, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, TargetID: targetID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
+
+// generateKey returns a new key and its prefix.
+func generateKey() (key, prefix string, err error) {
+	b := make([]byte, 4+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix = KeyPrefix + hex.EncodeToString(b[:4])
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(b[4:]), prefix, nil
+}
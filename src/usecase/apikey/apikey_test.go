@@ -0,0 +1,179 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc  *apiKeyUseCase
+	log *audit.MemoryLogger
+	sys context.Context
+}
+
+// newTestEnv returns an API key use case over the shared fixture, with
+// john@example.com an admin.
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{Env: usecasetest.New(t), log: audit.NewMemoryLogger(), sys: usecasetest.System()}
+	authz := authzUC.NewAuthzUseCase(env.Repo, env.Cache, authzUC.NewPolicy(env.log), env.log)
+	_, err := authz.GrantRole(env.sys, env.User.ID, entities.RoleAdmin)
+	require.NoError(t, err)
+
+	env.uc = NewAPIKeyUseCase(env.Repo, authz, env.Cache, env.log).(*apiKeyUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	key, raw, err := env.uc.Create(env.sys, env.User.ID, " deploy ",
+		[]entities.Permission{entities.PermUsersRead, entities.PermUsersCreate, entities.PermUsersRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, IsKey(raw))
+	assert.Equal(t, key.Prefix, raw[:prefixLength])
+	assert.Equal(t, "deploy", key.Name)
+	assert.Equal(t, []entities.Permission{entities.PermUsersCreate, entities.PermUsersRead}, key.Permissions)
+	assert.NotContains(t, key.Hash, raw[prefixLength+1:])
+
+	stored, err := env.Repo.APIKey.GetByID(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, hashKey(raw), stored.Hash)
+
+	principal, err := env.uc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, env.User.ID, principal.UserID)
+	assert.Equal(t, "john@example.com", principal.Email)
+	assert.Equal(t, key.ID, principal.APIKeyID)
+	assert.Equal(t, []string{entities.RoleAdmin}, principal.Roles)
+	// The admin's permissions, narrowed to the key's scopes
+	assert.True(t, principal.Has(entities.PermUsersRead))
+	assert.False(t, principal.Has(entities.PermUsersDelete))
+
+	tampered := []byte(raw)
+	tampered[len(tampered)-1] ^= 1
+	for _, bad := range []string{"", "uapi_", raw[:len(raw)-1], string(tampered), "Bearer " + raw} {
+		_, err := env.uc.Authenticate(ctx, bad)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, bad)
+	}
+
+	require.Len(t, env.log.Events(), 2)
+	assert.Equal(t, "apikeys.create", env.log.Events()[1].Action)
+	assert.Equal(t, key.Prefix, env.log.Events()[1].Detail)
+}
+
+func TestCreateValidation(t *testing.T) {
+	env := newTestEnv(t)
+	read := []entities.Permission{entities.PermUsersRead}
+
+	_, _, err := env.uc.Create(env.sys, env.User.ID, "deploy", nil, nil)
+	assert.ErrorIs(t, err, ErrNoPermissions)
+	_, _, err = env.uc.Create(env.sys, env.User.ID, "deploy", []entities.Permission{"users:fly"}, nil)
+	assert.ErrorIs(t, err, authzUC.ErrUnknownPermission)
+	past := env.Clock.Now().Add(-time.Second)
+	_, _, err = env.uc.Create(env.sys, env.User.ID, "deploy", read, &past)
+	assert.ErrorIs(t, err, ErrExpiryInPast)
+	_, _, err = env.uc.Create(env.sys, env.User.ID, "", read, nil)
+	assert.Error(t, err)
+	_, _, err = env.uc.Create(env.sys, 42, "deploy", read, nil)
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestManagingKeysRequiresPermission(t *testing.T) {
+	env := newTestEnv(t)
+	john := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: env.User.ID})
+
+	_, _, err := env.uc.Create(john, env.User.ID, "deploy", []entities.Permission{entities.PermUsersRead}, nil)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.List(john, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	assert.ErrorIs(t, env.uc.Revoke(john, 1), authzUC.ErrForbidden)
+
+	_, err = env.uc.List(context.Background(), 0)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+}
+
+func TestRevokeAndExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	read := []entities.Permission{entities.PermUsersRead}
+
+	expires := env.Clock.Now().Add(time.Hour)
+	_, expiring, err := env.uc.Create(env.sys, env.User.ID, "short", read, &expires)
+	require.NoError(t, err)
+	revoked, raw, err := env.uc.Create(env.sys, env.User.ID, "revoked", read, nil)
+	require.NoError(t, err)
+
+	// Both keys are cached by their first use
+	_, err = env.uc.Authenticate(ctx, expiring)
+	require.NoError(t, err)
+	_, err = env.uc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+
+	require.NoError(t, env.uc.Revoke(env.sys, revoked.ID))
+	_, err = env.uc.Authenticate(ctx, raw)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	env.Clock.Set(expires)
+	_, err = env.uc.Authenticate(ctx, expiring)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	keys, err := env.uc.List(env.sys, env.User.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.NotNil(t, keys[1].RevokedAt)
+	actions := env.log.Actions()
+	assert.Equal(t, "apikeys.revoke", actions[len(actions)-1])
+}
+
+func TestLastUsedIsThrottled(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	key, raw, err := env.uc.Create(env.sys, env.User.ID, "deploy", []entities.Permission{entities.PermUsersRead}, nil)
+	require.NoError(t, err)
+	first := env.Clock.Now()
+
+	lastUsed := func() time.Time {
+		stored, err := env.Repo.APIKey.GetByID(ctx, key.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.LastUsedAt)
+		return *stored.LastUsedAt
+	}
+
+	_, err = env.uc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, first, lastUsed())
+
+	env.Clock.Set(first.Add(lastUsedInterval - time.Second))
+	_, err = env.uc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, first, lastUsed())
+
+	env.Clock.Set(first.Add(lastUsedInterval))
+	_, err = env.uc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, env.Clock.Now(), lastUsed())
+}
+
+func TestDeletedOwner(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, raw, err := env.uc.Create(env.sys, env.User.ID, "deploy", []entities.Permission{entities.PermUsersRead}, nil)
+	require.NoError(t, err)
+	require.NoError(t, env.Repo.User.Delete(env.sys, env.User.ID))
+
+	_, err = env.uc.Authenticate(context.Background(), raw)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	impersonationUC "solecode/src/usecase/impersonation"
+	twoFactorUC "solecode/src/usecase/twofactor"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// refreshRecord is what the cache holds for a refresh token, under the
+// token's hash so that a leaked cache does not leak usable tokens.
+type refreshRecord struct {
+	UserID    int64     `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Generation is the sign-in generation of the user the token was
+	// issued under, as for sessions.
+	Generation string `json:"generation,omitempty"`
+}
+
+func refreshKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return "refresh_token:" + hex.EncodeToString(sum[:])
+}
+
+// challengeRecord is what the cache holds for a login waiting for its
+// second factor, under the challenge's hash like refresh tokens.
+type challengeRecord struct {
+	UserID    int64     `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func challengeKey(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return "login_challenge:" + hex.EncodeToString(sum[:])
+}
+
+func (uc *authUseCase) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	user, err := uc.CheckLogin(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	return uc.issue(ctx, user, uc.now().Add(uc.refreshTTL))
+}
+
+func (uc *authUseCase) LoginSecondFactor(ctx context.Context, challenge, code string) (*TokenPair, error) {
+	user, err := uc.CheckSecondFactor(ctx, challenge, code)
+	if err != nil {
+		return nil, err
+	}
+	return uc.issue(ctx, user, uc.now().Add(uc.refreshTTL))
+}
+
+func (uc *authUseCase) CheckLogin(ctx context.Context, email, password string) (*entities.User, error) {
+	if err := uc.lockout.Check(ctx, email); err != nil {
+		return nil, err
+	}
+	user, err := uc.users.Authenticate(ctx, email, password)
+	if errors.Is(err, userUC.ErrInvalidCredentials) {
+		if err := uc.lockout.Failure(ctx, email); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := uc.twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		// Failures are kept until the second factor succeeds, so that
+		// knowing the password does not reset the count of wrong codes
+		return nil, uc.challenge(user)
+	}
+	if err := uc.lockout.Success(ctx, email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (uc *authUseCase) CheckSecondFactor(ctx context.Context, challenge, code string) (*entities.User, error) {
+	if challenge == "" {
+		return nil, ErrInvalidToken
+	}
+	var record challengeRecord
+	if err := uc.cache.GetJSON(challengeKey(challenge), &record); err != nil {
+		return nil, fmt.Errorf("failed to read login challenge: %w", err)
+	}
+	if record.UserID == 0 || !uc.now().Before(record.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := uc.users.GetUser(entities.WithPrincipal(ctx, &entities.Principal{UserID: record.UserID}), record.UserID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.lockout.Check(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	err = uc.twoFactor.Verify(ctx, user.ID, code)
+	if errors.Is(err, twoFactorUC.ErrInvalidCode) {
+		if err := uc.lockout.Failure(ctx, user.Email); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if errors.Is(err, twoFactorUC.ErrNotEnrolled) {
+		// Reset since the password was checked; start over
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.cache.Delete(challengeKey(challenge)); err != nil {
+		return nil, fmt.Errorf("failed to delete login challenge: %w", err)
+	}
+	if err := uc.lockout.Success(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (uc *authUseCase) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	// Single use: a stolen token stops working once its owner refreshes,
+	// and of two concurrent refreshes only the one that took it succeeds
+	record, err := uc.takeRefresh(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	generation, err := uc.sessions.Generation(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Generation != generation {
+		return nil, ErrInvalidToken
+	}
+
+	// The refresh token speaks for its user, who may read their own record
+	ctx = entities.WithPrincipal(ctx, &entities.Principal{UserID: record.UserID})
+	user, err := uc.users.GetUser(ctx, record.UserID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return uc.issue(ctx, user, record.ExpiresAt)
+}
+
+func (uc *authUseCase) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	if err := uc.cache.Delete(refreshKey(refreshToken)); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+func (uc *authUseCase) Authenticate(ctx context.Context, accessToken string) (*entities.Principal, error) {
+	claims, err := uc.keys.Verify(accessToken, uc.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return nil, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+	// Tokens issued to OAuth clients only open the userinfo endpoint
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("%w: issued to a client", ErrInvalidToken)
+	}
+
+	principal := &entities.Principal{UserID: userID, Email: claims.Email, TokenID: claims.ID}
+	if claims.ExpiresAt != nil {
+		principal.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.Actor != nil {
+		if principal.ImpersonatorID, err = uc.checkImpersonation(ctx, claims, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := uc.authz.LoadPermissions(ctx, principal); err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	return principal, nil
+}
+
+func (uc *authUseCase) JWKS() token.JWKS {
+	return uc.keys.JWKS()
+}
+
+// checkImpersonation returns the admin behind an impersonation token of
+// userID, which stops verifying once the impersonation is stopped.
+func (uc *authUseCase) checkImpersonation(ctx context.Context, claims *token.Claims, userID int64) (int64, error) {
+	adminID, err := strconv.ParseInt(claims.Actor.Subject, 10, 64)
+	if err != nil || adminID <= 0 {
+		return 0, fmt.Errorf("%w: bad actor", ErrInvalidToken)
+	}
+	impersonation, err := uc.impersonation.Check(ctx, claims.ID)
+	if errors.Is(err, impersonationUC.ErrInvalidImpersonation) {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if impersonation.AdminID != adminID || impersonation.UserID != userID {
+		return 0, fmt.Errorf("%w: impersonation does not match", ErrInvalidToken)
+	}
+	return adminID, nil
+}
+
+// takeRefresh revokes a refresh token and returns its record if it was
+// live.
+func (uc *authUseCase) takeRefresh(refreshToken string) (*refreshRecord, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidToken
+	}
+	var record refreshRecord
+	took, err := uc.cache.TakeJSON(refreshKey(refreshToken), &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !took || record.UserID == 0 || !uc.now().Before(record.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return &record, nil
+}
+
+// issue signs an access token for user and stores a new refresh token
+// that expires at refreshExpiresAt.
+func (uc *authUseCase) issue(ctx context.Context, user *entities.User, refreshExpiresAt time.Time) (*TokenPair, error) {
+	now := uc.now()
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	pair := &TokenPair{AccessExpiresAt: now.Add(uc.accessTTL), RefreshExpiresAt: refreshExpiresAt}
+	pair.AccessToken, err = uc.keys.Sign(&token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(pair.AccessExpiresAt),
+			ID:        jti,
+		},
+		Email: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pair.RefreshToken, err = randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	generation, err := uc.sessions.Generation(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	record := refreshRecord{UserID: user.ID, ExpiresAt: refreshExpiresAt, Generation: generation}
+	if err := uc.cache.SetJSON(refreshKey(pair.RefreshToken), record, refreshExpiresAt.Sub(now)); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return pair, nil
+}
+
+// challenge stores a login challenge for user and returns it as a
+// *SecondFactorRequiredError.
+func (uc *authUseCase) challenge(user *entities.User) error {
+	challenge, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	record := challengeRecord{UserID: user.ID, ExpiresAt: uc.now().Add(uc.factorTTL)}
+	if err := uc.cache.SetJSON(challengeKey(challenge), record, uc.factorTTL); err != nil {
+		return fmt.Errorf("failed to store login challenge: %w", err)
+	}
+	return &SecondFactorRequiredError{Challenge: challenge, ExpiresAt: record.ExpiresAt}
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
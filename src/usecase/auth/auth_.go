@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	impersonationUC "solecode/src/usecase/impersonation"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
+	twoFactorUC "solecode/src/usecase/twofactor"
+	userUC "solecode/src/usecase/user"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	DefaultSecondFactorTTL = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidToken is returned for access and refresh tokens that are
+	// malformed, expired, revoked or belong to a user who no longer
+	// exists, and for unknown login challenges.
+	ErrInvalidToken         = errors.New("invalid or expired token")
+	ErrSecondFactorRequired = errors.New("second factor required")
+)
+
+// SecondFactorRequiredError is ErrSecondFactorRequired with the challenge
+// that LoginSecondFactor completes.
+type SecondFactorRequiredError struct {
+	Challenge string
+	ExpiresAt time.Time
+}
+
+func (e *SecondFactorRequiredError) Error() string {
+	return ErrSecondFactorRequired.Error()
+}
+
+func (e *SecondFactorRequiredError) Unwrap() error {
+	return ErrSecondFactorRequired
+}
+
+//go:generate mockery --name AuthUseCaseItf --output mocks --filename authusecase_mock.go --outpkg mocks
+type AuthUseCaseItf interface {
+	// Login checks the password and issues a token pair. Wrong passwords
+	// and unknown emails both fail with userUC.ErrInvalidCredentials, and
+	// both count towards lockouts, which fail with a
+	// *lockoutUC.ThrottledError without checking the password. Users
+	// with two-factor authentication get a *SecondFactorRequiredError
+	// instead of tokens.
+	Login(ctx context.Context, email, password string) (*TokenPair, error)
+	// LoginSecondFactor completes a login challenge with a code or a
+	// recovery code. Wrong codes fail with twoFactorUC.ErrInvalidCode and
+	// count towards the lockout of the account; the challenge stays usable
+	// until it expires.
+	LoginSecondFactor(ctx context.Context, challenge, code string) (*TokenPair, error)
+	// CheckLogin and CheckSecondFactor are Login and LoginSecondFactor
+	// without the tokens, for callers that start another kind of session:
+	// they return the user once all factors check out.
+	CheckLogin(ctx context.Context, email, password string) (*entities.User, error)
+	CheckSecondFactor(ctx context.Context, challenge, code string) (*entities.User, error)
+	// Refresh exchanges a refresh token for a new pair. The old refresh
+	// token is revoked; the new one expires when the old one would have.
+	// Refresh tokens stop working when the password of their user is set
+	// or the user is deleted.
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// Logout revokes a refresh token. Unknown tokens are ignored.
+	Logout(ctx context.Context, refreshToken string) error
+	// Authenticate verifies an access token and returns its principal with
+	// the roles and permissions of its user. Impersonation tokens only
+	// verify while their impersonation is active, and name the admin in
+	// the principal.
+	Authenticate(ctx context.Context, accessToken string) (*entities.Principal, error)
+	JWKS() token.JWKS
+}
+
+// TokenPair is a signed access token and the opaque refresh token that
+// renews it.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Options tunes token lifetimes; zero values use the defaults.
+type Options struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// SecondFactorTTL is how long a login challenge waits for its code.
+	SecondFactorTTL time.Duration
+}
+
+type authUseCase struct {
+	users         userUC.UserUseCaseItf
+	authz         authzUC.AuthzUseCaseItf
+	lockout       lockoutUC.LockoutUseCaseItf
+	twoFactor     twoFactorUC.TwoFactorUseCaseItf
+	impersonation impersonationUC.ImpersonationUseCaseItf
+	sessions      sessionUC.SessionUseCaseItf
+	cache         cachePkg.CacheItf
+	keys          *token.KeySet
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+	factorTTL     time.Duration
+	now           func() time.Time
+}
+
+func NewAuthUseCase(users userUC.UserUseCaseItf, authz authzUC.AuthzUseCaseItf, lockout lockoutUC.LockoutUseCaseItf, twoFactor twoFactorUC.TwoFactorUseCaseItf, impersonation impersonationUC.ImpersonationUseCaseItf, sessions sessionUC.SessionUseCaseItf, cache cachePkg.CacheItf, keys *token.KeySet, opts Options) AuthUseCaseItf {
+	if opts.AccessTokenTTL <= 0 {
+		opts.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if opts.RefreshTokenTTL <= 0 {
+		opts.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+	if opts.SecondFactorTTL <= 0 {
+		opts.SecondFactorTTL = DefaultSecondFactorTTL
+	}
+	return &authUseCase{
+		users:         users,
+		authz:         authz,
+		lockout:       lockout,
+		twoFactor:     twoFactor,
+		impersonation: impersonation,
+		sessions:      sessions,
+		cache:         cache,
+		keys:          keys,
+		accessTTL:     opts.AccessTokenTTL,
+		refreshTTL:    opts.RefreshTokenTTL,
+		factorTTL:     opts.SecondFactorTTL,
+		now:           time.Now,
+	}
+}
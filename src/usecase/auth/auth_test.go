@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/config"
+	"solecode/pkg/password"
+	"solecode/pkg/secretbox"
+	"solecode/pkg/token"
+	"solecode/pkg/totp"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	impersonationUC "solecode/src/usecase/impersonation"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
+	twoFactorUC "solecode/src/usecase/twofactor"
+	"solecode/src/usecase/usecasetest"
+	userUC "solecode/src/usecase/user"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc            *authUseCase
+	users         userUC.UserUseCaseItf
+	twoFactor     twoFactorUC.TwoFactorUseCaseItf
+	impersonation impersonationUC.ImpersonationUseCaseItf
+}
+
+// newTestEnv returns an auth use case over the shared fixture, with
+// john@example.com an admin with password Secret123!.
+func newTestEnv(t *testing.T) *testEnv {
+	hasher, err := password.New(config.PasswordConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1})
+	require.NoError(t, err)
+	keys, err := token.NewEphemeralKeySet("test")
+	require.NoError(t, err)
+
+	env := &testEnv{Env: usecasetest.New(t)}
+	repo, memoryCache := env.Repo, env.Cache
+	policy := authzUC.NewPolicy(audit.NewNopLogger())
+	authz := authzUC.NewAuthzUseCase(repo, memoryCache, policy, audit.NewNopLogger())
+	sessions := sessionUC.NewSessionUseCase(repo, authz, policy, memoryCache, audit.NewNopLogger(), sessionUC.Options{})
+	lockout := lockoutUC.NewLockoutUseCase(repo, memoryCache, policy, audit.NewNopLogger(), lockoutUC.Options{})
+	env.users = userUC.NewUserUseCase(repo, memoryCache, hasher, policy, sessions, lockout)
+
+	sys := usecasetest.System()
+	require.NoError(t, env.users.SetPassword(sys, env.User.ID, "Secret123!"))
+	_, err = authz.GrantRole(sys, env.User.ID, entities.RoleAdmin)
+	require.NoError(t, err)
+
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+
+	env.twoFactor = twoFactorUC.NewTwoFactorUseCase(repo, box, policy, lockout, audit.NewNopLogger(), twoFactorUC.Options{})
+	env.impersonation = impersonationUC.NewImpersonationUseCase(repo, authz, policy, memoryCache, keys, audit.NewNopLogger(), impersonationUC.Options{})
+	env.uc = NewAuthUseCase(env.users, authz, lockout, env.twoFactor, env.impersonation, sessions, memoryCache, keys, Options{RefreshTokenTTL: time.Hour}).(*authUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	_, err := env.uc.Login(ctx, "john@example.com", "Wrong123!")
+	assert.ErrorIs(t, err, userUC.ErrInvalidCredentials)
+	_, err = env.uc.Login(ctx, "nobody@example.com", "Secret123!")
+	assert.ErrorIs(t, err, userUC.ErrInvalidCredentials)
+
+	pair, err := env.uc.Login(ctx, "John@Example.com", "Secret123!")
+	require.NoError(t, err)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.Equal(t, env.Clock.Now().Add(DefaultAccessTokenTTL), pair.AccessExpiresAt)
+	assert.Equal(t, env.Clock.Now().Add(time.Hour), pair.RefreshExpiresAt)
+
+	principal, err := env.uc.Authenticate(ctx, pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), principal.UserID)
+	assert.Equal(t, "john@example.com", principal.Email)
+	assert.NotEmpty(t, principal.TokenID)
+	assert.Equal(t, []string{entities.RoleAdmin}, principal.Roles)
+	assert.True(t, principal.Has(entities.PermUsersDelete))
+
+	_, err = env.uc.Authenticate(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = env.uc.Authenticate(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Access tokens of OAuth clients are not for the API
+	clientToken, err := env.uc.keys.Sign(&token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{"client"},
+			ExpiresAt: jwt.NewNumericDate(env.Clock.Now().Add(time.Minute)),
+		},
+		Scope: "openid",
+	})
+	require.NoError(t, err)
+	_, err = env.uc.Authenticate(ctx, clientToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthenticateImpersonation(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	sys := entities.WithPrincipal(ctx, entities.SystemPrincipal())
+	jane, err := env.users.CreateUser(sys, "Jane Doe", "jane@example.com")
+	require.NoError(t, err)
+	pair, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+	admin, err := env.uc.Authenticate(ctx, pair.AccessToken)
+	require.NoError(t, err)
+
+	impersonation, err := env.impersonation.Start(entities.WithPrincipal(ctx, admin), jane.ID, "ticket 1234")
+	require.NoError(t, err)
+
+	principal, err := env.uc.Authenticate(ctx, impersonation.Token)
+	require.NoError(t, err)
+	assert.Equal(t, jane.ID, principal.UserID)
+	assert.Equal(t, "jane@example.com", principal.Email)
+	assert.Equal(t, admin.UserID, principal.ImpersonatorID)
+	assert.Equal(t, impersonation.ID, principal.TokenID)
+	assert.True(t, principal.Impersonated())
+
+	// The token stops working with its impersonation
+	require.NoError(t, env.impersonation.Stop(entities.WithPrincipal(ctx, principal), impersonation.ID))
+	_, err = env.uc.Authenticate(ctx, impersonation.Token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// An actor claim without a matching impersonation proves nothing
+	forged, err := env.uc.keys.Sign(&token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "1",
+			ID:        "made-up",
+			ExpiresAt: jwt.NewNumericDate(env.Clock.Now().Add(time.Minute)),
+		},
+		Actor: &token.Actor{Subject: "2"},
+	})
+	require.NoError(t, err)
+	_, err = env.uc.Authenticate(ctx, forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLoginThrottled(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+		_, err := env.uc.Login(ctx, "john@example.com", "Wrong123!")
+		assert.ErrorIs(t, err, userUC.ErrInvalidCredentials)
+	}
+
+	// Once delayed, even the right password is refused unchecked, and
+	// unknown emails are refused the same way
+	_, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	var throttled *lockoutUC.ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	assert.Positive(t, throttled.RetryAfter)
+
+	for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+		env.uc.Login(ctx, "nobody@example.com", "Wrong123!")
+	}
+	_, err = env.uc.Login(ctx, "nobody@example.com", "Secret123!")
+	assert.ErrorIs(t, err, lockoutUC.ErrThrottled)
+
+	// Other accounts are unaffected
+	_, err = env.uc.Login(ctx, "jane@example.com", "Wrong123!")
+	assert.ErrorIs(t, err, userUC.ErrInvalidCredentials)
+}
+
+func TestLoginSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// Enrol john, whose app then shows the codes of secret
+	self := entities.WithPrincipal(ctx, &entities.Principal{UserID: 1})
+	enrollment, err := env.twoFactor.Enroll(self, 1)
+	require.NoError(t, err)
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	_, err = env.twoFactor.Confirm(self, 1, totp.Code(secret, step))
+	require.NoError(t, err)
+
+	_, err = env.uc.Login(ctx, "john@example.com", "Secret123!")
+	var required *SecondFactorRequiredError
+	require.ErrorAs(t, err, &required)
+	assert.ErrorIs(t, err, ErrSecondFactorRequired)
+	assert.Equal(t, env.Clock.Now().Add(DefaultSecondFactorTTL), required.ExpiresAt)
+
+	// Wrong codes count towards the lockout, which the password does not
+	// reset
+	for i := 0; i < lockoutUC.DefaultDelayAfter-1; i++ {
+		_, err = env.uc.LoginSecondFactor(ctx, required.Challenge, "000000")
+		assert.ErrorIs(t, err, twoFactorUC.ErrInvalidCode)
+	}
+	_, err = env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.ErrorAs(t, err, &required)
+	_, err = env.uc.LoginSecondFactor(ctx, required.Challenge, "000000")
+	assert.ErrorIs(t, err, twoFactorUC.ErrInvalidCode)
+	_, err = env.uc.LoginSecondFactor(ctx, required.Challenge, totp.Code(secret, step+1))
+	assert.ErrorIs(t, err, lockoutUC.ErrThrottled)
+
+	require.NoError(t, env.uc.lockout.Success(ctx, "john@example.com"))
+	pair, err := env.uc.LoginSecondFactor(ctx, required.Challenge, totp.Code(secret, step+1))
+	require.NoError(t, err)
+	principal, err := env.uc.Authenticate(ctx, pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), principal.UserID)
+
+	// Challenges are single use, and expire
+	_, err = env.uc.LoginSecondFactor(ctx, required.Challenge, totp.Code(secret, step+1))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.ErrorAs(t, err, &required)
+	env.Clock.Advance(DefaultSecondFactorTTL)
+	_, err = env.uc.LoginSecondFactor(ctx, required.Challenge, totp.Code(secret, step+1))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = env.uc.LoginSecondFactor(ctx, "", "123456")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	first, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+
+	env.Clock.Advance(10 * time.Minute)
+	second, err := env.uc.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	// Refreshing does not extend the session
+	assert.True(t, first.RefreshExpiresAt.Equal(second.RefreshExpiresAt))
+	_, err = env.uc.Authenticate(ctx, second.AccessToken)
+	assert.NoError(t, err)
+
+	// Refresh tokens are single use
+	_, err = env.uc.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = env.uc.Refresh(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = env.uc.Refresh(ctx, "made-up")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	env.Clock.Advance(time.Hour)
+	_, err = env.uc.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRefreshConcurrently(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	pair, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+
+	// Replaying a token while its owner refreshes yields one new pair
+	const callers = 16
+	var wg sync.WaitGroup
+	var refreshed atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.uc.Refresh(ctx, pair.RefreshToken)
+			if err == nil {
+				refreshed.Add(1)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), refreshed.Load())
+}
+
+func TestRefreshAfterPasswordChange(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	sys := entities.WithPrincipal(ctx, entities.SystemPrincipal())
+
+	pair, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+	require.NoError(t, env.users.SetPassword(sys, 1, "Other123!"))
+	_, err = env.uc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Tokens issued with the new password work
+	pair, err = env.uc.Login(ctx, "john@example.com", "Other123!")
+	require.NoError(t, err)
+	pair, err = env.uc.Refresh(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+
+	self := entities.WithPrincipal(ctx, &entities.Principal{UserID: 1})
+	require.NoError(t, env.users.ChangePassword(self, 1, "Other123!", "Third123!"))
+	_, err = env.uc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	pair, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+
+	require.NoError(t, env.uc.Logout(ctx, pair.RefreshToken))
+	_, err = env.uc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	assert.NoError(t, env.uc.Logout(ctx, "unknown"))
+}
+
+func TestRefreshDeletedUser(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	pair, err := env.uc.Login(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+	require.NoError(t, env.users.DeleteUser(entities.WithPrincipal(ctx, entities.SystemPrincipal()), 1))
+
+	_, err = env.uc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,190 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	"solecode/src/repository"
+)
+
+// grants is what the cache holds for a user's roles.
+type grants struct {
+	Roles       []string              `json:"roles"`
+	Permissions []entities.Permission `json:"permissions"`
+}
+
+func grantsKey(userID int64) string {
+	return fmt.Sprintf("user_grants:%d", userID)
+}
+
+func (uc *authzUseCase) Authorize(ctx context.Context, ownerID int64, permissions ...entities.Permission) error {
+	return uc.policy.Authorize(ctx, ownerID, permissions...)
+}
+
+func (uc *authzUseCase) LoadPermissions(ctx context.Context, principal *entities.Principal) error {
+	var cached grants
+	if err := uc.cache.GetJSON(grantsKey(principal.UserID), &cached); err == nil && cached.Roles != nil {
+		principal.Roles, principal.Permissions = cached.Roles, cached.Permissions
+		return nil
+	}
+
+	roles, err := uc.repo.Role.ListByUser(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+	loaded := grants{Roles: []string{}, Permissions: []entities.Permission{}}
+	for _, role := range roles {
+		loaded.Roles = append(loaded.Roles, role.Name)
+		loaded.Permissions = append(loaded.Permissions, role.Permissions...)
+	}
+	slices.Sort(loaded.Permissions)
+	loaded.Permissions = slices.Compact(loaded.Permissions)
+
+	uc.cache.SetJSON(grantsKey(principal.UserID), loaded, permissionsTTL)
+	principal.Roles, principal.Permissions = loaded.Roles, loaded.Permissions
+	return nil
+}
+
+func (uc *authzUseCase) ListRoles(ctx context.Context) ([]*entities.Role, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermRolesRead); err != nil {
+		return nil, err
+	}
+	return uc.repo.Role.List(ctx)
+}
+
+func (uc *authzUseCase) CreateRole(ctx context.Context, name, description string, permissions []entities.Permission) (*entities.Role, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermRolesManage); err != nil {
+		return nil, err
+	}
+
+	input := roleInput{Name: strings.ToLower(strings.TrimSpace(name)), Description: strings.TrimSpace(description)}
+	if err := uc.validator.ValidateStruct(&input); err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(entities.AllPermissions, permission) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+
+	role := &entities.Role{Name: input.Name, Description: input.Description, Permissions: permissions}
+	err := uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		return tx.Role.Create(ctx, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.log(ctx, "roles.create", 0, role.Name)
+	return role, nil
+}
+
+// DeleteRole removes a role. Users holding it lose its permissions once
+// their cached permissions expire.
+func (uc *authzUseCase) DeleteRole(ctx context.Context, name string) error {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermRolesManage); err != nil {
+		return err
+	}
+
+	role, err := uc.repo.Role.GetByName(ctx, strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return err
+	}
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		return tx.Role.Delete(ctx, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+	uc.log(ctx, "roles.delete", 0, role.Name)
+	return nil
+}
+
+func (uc *authzUseCase) UserRoles(ctx context.Context, userID int64) ([]*entities.Role, error) {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermRolesRead); err != nil {
+		return nil, err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return uc.repo.Role.ListByUser(ctx, userID)
+}
+
+func (uc *authzUseCase) SetUserRoles(ctx context.Context, userID int64, names []string) ([]*entities.Role, error) {
+	return uc.changeRoles(ctx, userID, func([]*entities.Role) []string { return names })
+}
+
+func (uc *authzUseCase) GrantRole(ctx context.Context, userID int64, name string) ([]*entities.Role, error) {
+	return uc.changeRoles(ctx, userID, func(current []*entities.Role) []string {
+		return append(roleNames(current), name)
+	})
+}
+
+func (uc *authzUseCase) RevokeRole(ctx context.Context, userID int64, name string) ([]*entities.Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return uc.changeRoles(ctx, userID, func(current []*entities.Role) []string {
+		return slices.DeleteFunc(roleNames(current), func(n string) bool { return n == name })
+	})
+}
+
+// changeRoles replaces the roles of a user with the names change derives
+// from the current ones, reading and writing in one transaction.
+func (uc *authzUseCase) changeRoles(ctx context.Context, userID int64, change func(current []*entities.Role) []string) ([]*entities.Role, error) {
+	// Without the owner ID, users cannot use the self rules to grant
+	// themselves roles
+	if err := uc.policy.Authorize(ctx, 0, entities.PermRolesAssign); err != nil {
+		return nil, err
+	}
+
+	var roles []*entities.Role
+	err := uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if _, err := tx.User.GetByID(ctx, userID); err != nil {
+			return err
+		}
+		current, err := tx.Role.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for _, name := range change(current) {
+			role, err := tx.Role.GetByName(ctx, strings.ToLower(strings.TrimSpace(name)))
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, name)
+			}
+			ids = append(ids, role.ID)
+		}
+		if err := tx.Role.SetUserRoles(ctx, userID, ids); err != nil {
+			return err
+		}
+		roles, err = tx.Role.ListByUser(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.cache.Delete(grantsKey(userID))
+	uc.log(ctx, "roles.assign", userID, strings.Join(roleNames(roles), ","))
+	return roles, nil
+}
+
+// log audits a successful change made by the principal of ctx.
+func (uc *authzUseCase) log(ctx context.Context, action string, targetID int64, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, TargetID: targetID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
+
+func roleNames(roles []*entities.Role) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = role.Name
+	}
+	return names
+}
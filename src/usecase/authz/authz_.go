@@ -0,0 +1,67 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+)
+
+// permissionsTTL bounds how long a user keeps permissions cached after one
+// of their roles changes; assigning roles to a user takes effect at once.
+const permissionsTTL = time.Minute
+
+// ErrUnknownPermission is returned for role permissions the application
+// does not check.
+var ErrUnknownPermission = errors.New("unknown permission")
+
+//go:generate mockery --name AuthzUseCaseItf --output mocks --filename authzusecase_mock.go --outpkg mocks
+type AuthzUseCaseItf interface {
+	// Authorize is Policy.Authorize with the use case's policy.
+	Authorize(ctx context.Context, ownerID int64, permissions ...entities.Permission) error
+	// LoadPermissions fills in the roles and permissions of principal's
+	// user. It is called while authenticating and is not authorised.
+	LoadPermissions(ctx context.Context, principal *entities.Principal) error
+
+	ListRoles(ctx context.Context) ([]*entities.Role, error)
+	CreateRole(ctx context.Context, name, description string, permissions []entities.Permission) (*entities.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+
+	// UserRoles lists the roles of a user; users may list their own.
+	UserRoles(ctx context.Context, userID int64) ([]*entities.Role, error)
+	// SetUserRoles replaces the roles of a user with the named ones.
+	SetUserRoles(ctx context.Context, userID int64, names []string) ([]*entities.Role, error)
+	// GrantRole and RevokeRole add or remove one role and return the
+	// user's roles afterwards.
+	GrantRole(ctx context.Context, userID int64, name string) ([]*entities.Role, error)
+	RevokeRole(ctx context.Context, userID int64, name string) ([]*entities.Role, error)
+}
+
+type authzUseCase struct {
+	repo      *repository.Repository
+	cache     cachePkg.CacheItf
+	policy    *Policy
+	audit     audit.Logger
+	validator *validator.Validator
+}
+
+// roleInput carries the rules for new roles.
+type roleInput struct {
+	Name        string `json:"name" validate:"required,min=2,max=64,alphanum"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+func NewAuthzUseCase(repo *repository.Repository, cache cachePkg.CacheItf, policy *Policy, auditLog audit.Logger) AuthzUseCaseItf {
+	return &authzUseCase{
+		repo:      repo,
+		cache:     cache,
+		policy:    policy,
+		audit:     auditLog,
+		validator: validator.New(),
+	}
+}
@@ -0,0 +1,215 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	roleRepository "solecode/src/repository/role"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asUser(id int64, permissions ...entities.Permission) context.Context {
+	return entities.WithPrincipal(context.Background(), &entities.Principal{UserID: id, Permissions: permissions})
+}
+
+func TestAllowed(t *testing.T) {
+	user := &entities.Principal{UserID: 1}
+	reader := &entities.Principal{UserID: 2, Permissions: []entities.Permission{entities.PermUsersRead}}
+
+	assert.True(t, Allowed(user, 1, entities.PermUsersRead))
+	assert.True(t, Allowed(user, 1, entities.PermUsersUpdate))
+	assert.False(t, Allowed(user, 1, entities.PermUsersDelete))
+	assert.False(t, Allowed(user, 2, entities.PermUsersRead))
+	assert.False(t, Allowed(user, 0, entities.PermUsersRead))
+	assert.True(t, Allowed(reader, 1, entities.PermUsersRead))
+	assert.True(t, Allowed(reader, 0, entities.PermUsersDelete, entities.PermUsersRead))
+	assert.False(t, Allowed(reader, 0))
+	assert.True(t, Allowed(entities.SystemPrincipal(), 0, entities.PermRolesManage))
+
+	// API key scopes narrow both roles and self rules
+	key := &entities.Principal{UserID: 2, Permissions: []entities.Permission{entities.PermUsersRead, entities.PermUsersDelete},
+		Scopes: []entities.Permission{entities.PermUsersRead}}
+	assert.True(t, Allowed(key, 0, entities.PermUsersRead))
+	assert.False(t, Allowed(key, 0, entities.PermUsersDelete))
+	assert.False(t, Allowed(key, 2, entities.PermUsersUpdate))
+
+	// Admins impersonating a user lose the dangerous permissions of the
+	// user, both from roles and from the self rules
+	impersonated := &entities.Principal{UserID: 2, ImpersonatorID: 1,
+		Permissions: []entities.Permission{entities.PermUsersRead, entities.PermUsersDelete}}
+	assert.True(t, Allowed(impersonated, 0, entities.PermUsersRead))
+	assert.True(t, Allowed(impersonated, 2, entities.PermUsersUpdate))
+	assert.False(t, Allowed(impersonated, 0, entities.PermUsersDelete))
+	assert.False(t, Allowed(impersonated, 2, entities.PermUsersPassword))
+}
+
+func TestPolicyAuditsRefusals(t *testing.T) {
+	log := audit.NewMemoryLogger()
+	policy := NewPolicy(log)
+
+	assert.NoError(t, policy.Authorize(asUser(1), 1, entities.PermUsersRead))
+	assert.Empty(t, log.Events())
+
+	err := policy.Authorize(asUser(1), 2, entities.PermUsersRead, entities.PermUsersUpdate)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.EqualError(t, err, "forbidden: requires users:read or users:update")
+
+	err = policy.Authorize(context.Background(), 0, entities.PermUsersRead)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	require.Len(t, log.Events(), 2)
+	assert.Equal(t, audit.Event{
+		Action: "authz.check", Outcome: audit.Denied, ActorID: 1, TargetID: 2,
+		Permission: "users:read or users:update",
+	}, log.Events()[0])
+	assert.Equal(t, "no principal", log.Events()[1].Detail)
+}
+
+func TestPolicyAuthorizeSelf(t *testing.T) {
+	log := audit.NewMemoryLogger()
+	policy := NewPolicy(log)
+
+	assert.NoError(t, policy.AuthorizeSelf(asUser(1), 1, entities.PermUsersUpdate))
+
+	// Neither roles nor the system principal stand in for the owner
+	err := policy.AuthorizeSelf(asUser(2, entities.AllPermissions...), 1, entities.PermUsersUpdate)
+	assert.EqualError(t, err, "forbidden: requires users:update as user 1")
+	assert.ErrorIs(t, policy.AuthorizeSelf(usecasetest.System(), 1, entities.PermUsersUpdate), ErrForbidden)
+	assert.ErrorIs(t, policy.AuthorizeSelf(context.Background(), 1, entities.PermUsersUpdate), ErrForbidden)
+
+	// Nor does an API key of the owner scoped to something else
+	key := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: 1, Scopes: []entities.Permission{entities.PermUsersRead}})
+	assert.ErrorIs(t, policy.AuthorizeSelf(key, 1, entities.PermUsersUpdate), ErrForbidden)
+
+	// Nor does an admin impersonating the owner
+	impersonated := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: 1, ImpersonatorID: 2})
+	assert.ErrorIs(t, policy.AuthorizeSelf(impersonated, 1, entities.PermUsersUpdate), ErrForbidden)
+
+	require.Len(t, log.Events(), 5)
+	assert.Equal(t, int64(2), log.Events()[0].ActorID)
+	assert.Equal(t, int64(1), log.Events()[0].TargetID)
+	assert.Equal(t, int64(2), log.Events()[4].ImpersonatorID)
+}
+
+func TestPolicyAuthorizeDirect(t *testing.T) {
+	log := audit.NewMemoryLogger()
+	policy := NewPolicy(log)
+
+	assert.NoError(t, policy.AuthorizeDirect(asUser(1), 1, "change email"))
+	assert.NoError(t, policy.AuthorizeDirect(usecasetest.System(), 1, "change email"))
+	assert.Empty(t, log.Events())
+
+	impersonated := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: 1, ImpersonatorID: 2})
+	err := policy.AuthorizeDirect(impersonated, 1, "change email")
+	assert.EqualError(t, err, "forbidden: requires change email without impersonation")
+	assert.ErrorIs(t, policy.AuthorizeDirect(context.Background(), 1, "change email"), ErrForbidden)
+
+	require.Len(t, log.Events(), 2)
+	assert.Equal(t, audit.Event{
+		Action: "authz.check", Outcome: audit.Denied, ActorID: 1, ImpersonatorID: 2, TargetID: 1,
+		Permission: "change email without impersonation",
+	}, log.Events()[0])
+}
+
+type testEnv struct {
+	uc    *authzUseCase
+	log   *audit.MemoryLogger
+	users []int64
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	fixture := usecasetest.New(t)
+	env := &testEnv{log: audit.NewMemoryLogger()}
+	env.users = []int64{fixture.User.ID, fixture.AddUser(t, "Jane Doe", "jane@example.com").ID}
+	env.uc = NewAuthzUseCase(fixture.Repo, fixture.Cache, NewPolicy(env.log), env.log).(*authzUseCase)
+	return env
+}
+
+func TestUserRoles(t *testing.T) {
+	env := newTestEnv(t)
+	john, jane := env.users[0], env.users[1]
+	sys := usecasetest.System()
+
+	_, err := env.uc.CreateRole(sys, "Support", "Reads users", []entities.Permission{entities.PermUsersRead})
+	require.NoError(t, err)
+
+	roles, err := env.uc.GrantRole(sys, john, "support")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, roleNames(roles))
+	roles, err = env.uc.GrantRole(sys, john, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "support"}, roleNames(roles))
+
+	principal := &entities.Principal{UserID: john}
+	require.NoError(t, env.uc.LoadPermissions(context.Background(), principal))
+	assert.Equal(t, []string{"admin", "support"}, principal.Roles)
+	assert.ElementsMatch(t, entities.AllPermissions, principal.Permissions)
+
+	// Assigning roles takes effect on the next request
+	roles, err = env.uc.RevokeRole(sys, john, "ADMIN")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, roleNames(roles))
+	principal = &entities.Principal{UserID: john}
+	require.NoError(t, env.uc.LoadPermissions(context.Background(), principal))
+	assert.Equal(t, []entities.Permission{entities.PermUsersRead}, principal.Permissions)
+
+	// Users without roles still load, with nothing granted
+	principal = &entities.Principal{UserID: jane}
+	require.NoError(t, env.uc.LoadPermissions(context.Background(), principal))
+	assert.Empty(t, principal.Permissions)
+
+	_, err = env.uc.SetUserRoles(sys, jane, []string{"nobody"})
+	assert.ErrorIs(t, err, roleRepository.ErrRoleNotFound)
+	_, err = env.uc.SetUserRoles(sys, 404, []string{"admin"})
+	assert.Error(t, err)
+
+	// Users read their own roles but cannot change them
+	roles, err = env.uc.UserRoles(asUser(john), john)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, roleNames(roles))
+	_, err = env.uc.UserRoles(asUser(john), jane)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = env.uc.GrantRole(asUser(john), john, "admin")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	var assigned []string
+	for _, event := range env.log.Events() {
+		if event.Action == "roles.assign" {
+			assigned = append(assigned, event.Detail)
+			assert.True(t, event.System)
+		}
+	}
+	assert.Equal(t, []string{"support", "admin,support", "support"}, assigned)
+}
+
+func TestManageRoles(t *testing.T) {
+	env := newTestEnv(t)
+	manager := asUser(env.users[0], entities.PermRolesManage, entities.PermRolesRead)
+
+	_, err := env.uc.CreateRole(manager, "auditor", "", []entities.Permission{"users:fly"})
+	assert.ErrorIs(t, err, ErrUnknownPermission)
+	_, err = env.uc.CreateRole(manager, "a b", "", nil)
+	assert.Error(t, err)
+	_, err = env.uc.CreateRole(manager, "admin", "", nil)
+	assert.ErrorIs(t, err, roleRepository.ErrRoleExists)
+
+	role, err := env.uc.CreateRole(manager, "auditor", "Reads everything", []entities.Permission{entities.PermUsersRead, entities.PermRolesRead})
+	require.NoError(t, err)
+	assert.Equal(t, "auditor", role.Name)
+
+	roles, err := env.uc.ListRoles(manager)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "auditor"}, roleNames(roles))
+
+	require.NoError(t, env.uc.DeleteRole(manager, "auditor"))
+	assert.ErrorIs(t, env.uc.DeleteRole(manager, "auditor"), roleRepository.ErrRoleNotFound)
+
+	_, err = env.uc.ListRoles(asUser(env.users[1]))
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, env.uc.DeleteRole(asUser(env.users[1]), "admin"), ErrForbidden)
+}
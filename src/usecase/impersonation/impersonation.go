@@ -0,0 +1,228 @@
+package impersonation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	impersonationRepository "solecode/src/repository/impersonation"
+	authzUC "solecode/src/usecase/authz"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func recordKey(id string) string {
+	return "impersonation:" + id
+}
+
+func (uc *impersonationUseCase) Start(ctx context.Context, userID int64, reason string) (*entities.Impersonation, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersImpersonate); err != nil {
+		return nil, err
+	}
+	admin, _ := entities.PrincipalFrom(ctx)
+	if admin.UserID == 0 {
+		// CLI operators are nobody to name in the token
+		return nil, fmt.Errorf("%w: impersonating needs a user to act", authzUC.ErrForbidden)
+	}
+	if userID == admin.UserID {
+		return nil, ErrSelfImpersonation
+	}
+
+	input := startInput{Reason: strings.TrimSpace(reason)}
+	if err := uc.validator.ValidateStruct(&input); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	target := &entities.Principal{UserID: userID}
+	if err := uc.authz.LoadPermissions(ctx, target); err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	for _, permission := range target.Permissions {
+		if !admin.Has(permission) {
+			return nil, fmt.Errorf("%w: %s", ErrPrivilegedTarget, permission)
+		}
+	}
+
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := uc.now()
+	impersonation := &entities.Impersonation{
+		ID:        id,
+		AdminID:   admin.UserID,
+		UserID:    userID,
+		Reason:    input.Reason,
+		StartedAt: now,
+		ExpiresAt: now.Add(uc.ttl),
+	}
+	impersonation.Token, err = uc.keys.Sign(&token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(impersonation.ExpiresAt),
+			ID:        id,
+		},
+		Email: user.Email,
+		Actor: &token.Actor{Subject: strconv.FormatInt(admin.UserID, 10)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if err := tx.Impersonation.Create(ctx, impersonation); err != nil {
+			return err
+		}
+		return tx.Impersonation.LogEvent(ctx, newEvent(impersonation, entities.ImpersonationStart))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log(ctx, "impersonation.start", impersonation, input.Reason)
+	return impersonation, nil
+}
+
+func (uc *impersonationUseCase) Stop(ctx context.Context, id string) error {
+	principal, ok := entities.PrincipalFrom(ctx)
+	if !ok || !principal.Impersonated() || principal.TokenID != id {
+		if err := uc.policy.Authorize(ctx, 0, entities.PermUsersImpersonate); err != nil {
+			return err
+		}
+	}
+
+	impersonation, err := uc.repo.Impersonation.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := uc.now()
+	if !impersonation.Active(now) {
+		return nil
+	}
+
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if err := tx.Impersonation.End(ctx, id, now); err != nil {
+			return err
+		}
+		return tx.Impersonation.LogEvent(ctx, newEvent(impersonation, entities.ImpersonationStop))
+	})
+	if err != nil {
+		return err
+	}
+	if err := uc.cache.Delete(recordKey(id)); err != nil {
+		return fmt.Errorf("failed to evict impersonation: %w", err)
+	}
+
+	uc.log(ctx, "impersonation.stop", impersonation, "")
+	return nil
+}
+
+func (uc *impersonationUseCase) Check(ctx context.Context, id string) (*entities.Impersonation, error) {
+	if id == "" {
+		return nil, ErrInvalidImpersonation
+	}
+
+	var impersonation entities.Impersonation
+	if err := uc.cache.GetJSON(recordKey(id), &impersonation); err != nil || impersonation.ID == "" {
+		stored, err := uc.repo.Impersonation.Get(ctx, id)
+		if errors.Is(err, impersonationRepository.ErrImpersonationNotFound) {
+			return nil, ErrInvalidImpersonation
+		}
+		if err != nil {
+			return nil, err
+		}
+		impersonation = *stored
+		uc.cache.SetJSON(recordKey(id), impersonation, recordTTL)
+	}
+
+	if !impersonation.Active(uc.now()) {
+		return nil, ErrInvalidImpersonation
+	}
+	return &impersonation, nil
+}
+
+func (uc *impersonationUseCase) RecordRequest(ctx context.Context, method, path string) (*entities.ImpersonationEvent, error) {
+	principal, ok := entities.PrincipalFrom(ctx)
+	if !ok || !principal.Impersonated() {
+		return nil, ErrNotImpersonating
+	}
+
+	event := &entities.ImpersonationEvent{
+		ImpersonationID: principal.TokenID,
+		AdminID:         principal.ImpersonatorID,
+		UserID:          principal.UserID,
+		Action:          entities.ImpersonationRequest,
+		Method:          method,
+		Path:            path,
+	}
+	if err := uc.repo.Impersonation.LogEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (uc *impersonationUseCase) RecordResponse(ctx context.Context, event *entities.ImpersonationEvent, status int) error {
+	if err := uc.repo.Impersonation.SetEventStatus(ctx, event.ID, status); err != nil {
+		return err
+	}
+	event.Status = status
+	return nil
+}
+
+func (uc *impersonationUseCase) List(ctx context.Context, adminID, userID int64) ([]*entities.Impersonation, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersImpersonate); err != nil {
+		return nil, err
+	}
+	return uc.repo.Impersonation.List(ctx, adminID, userID)
+}
+
+func (uc *impersonationUseCase) Events(ctx context.Context, id string) ([]*entities.ImpersonationEvent, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersImpersonate); err != nil {
+		return nil, err
+	}
+	if _, err := uc.repo.Impersonation.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return uc.repo.Impersonation.ListEvents(ctx, id)
+}
+
+// newEvent returns an event of impersonation with no request.
+func newEvent(impersonation *entities.Impersonation, action string) *entities.ImpersonationEvent {
+	return &entities.ImpersonationEvent{
+		ImpersonationID: impersonation.ID,
+		AdminID:         impersonation.AdminID,
+		UserID:          impersonation.UserID,
+		Action:          action,
+	}
+}
+
+// log audits the start or stop of impersonation by the principal of ctx.
+func (uc *impersonationUseCase) log(ctx context.Context, action string, impersonation *entities.Impersonation, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, TargetID: impersonation.UserID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System, event.ImpersonatorID = principal.UserID, principal.System, principal.ImpersonatorID
+	}
+	uc.audit.Log(ctx, event)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,104 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/token"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+)
+
+// DefaultTTL is how long an impersonation lasts when Options leaves it
+// unset.
+const DefaultTTL = 30 * time.Minute
+
+// recordTTL bounds how long a stopped impersonation keeps working on
+// instances that do not share the cache of the one that stopped it.
+const recordTTL = time.Minute
+
+var (
+	// ErrInvalidImpersonation is returned for impersonations that are
+	// unknown, stopped or expired.
+	ErrInvalidImpersonation = errors.New("impersonation stopped or expired")
+	ErrSelfImpersonation    = errors.New("admins cannot impersonate themselves")
+	// ErrPrivilegedTarget is returned for users holding a permission the
+	// admin lacks, whom impersonating would raise the admin's privileges.
+	ErrPrivilegedTarget = errors.New("user holds permissions the admin lacks")
+	ErrNotImpersonating = errors.New("request is not impersonating a user")
+)
+
+//go:generate mockery --name ImpersonationUseCaseItf --output mocks --filename impersonationusecase_mock.go --outpkg mocks
+type ImpersonationUseCaseItf interface {
+	// Start lets the admin of ctx act as userID until the impersonation
+	// expires, for reason. The returned impersonation carries in Token an
+	// access token naming both; it is not refreshable. Admins cannot
+	// impersonate themselves, users with permissions they lack, or anyone
+	// while impersonating.
+	Start(ctx context.Context, userID int64, reason string) (*entities.Impersonation, error)
+	// Stop ends an impersonation early. Its own token may stop it; anyone
+	// else needs users:impersonate. Stopping an impersonation that is
+	// over succeeds.
+	Stop(ctx context.Context, id string) error
+	// Check returns the impersonation id while it is active. It is called
+	// while authenticating and is not authorised.
+	Check(ctx context.Context, id string) (*entities.Impersonation, error)
+
+	// RecordRequest writes to the impersonation log that the principal of
+	// ctx, impersonating, makes a request that may change state, and
+	// returns the event for RecordResponse. It is not authorised, and
+	// fails with ErrNotImpersonating for other principals.
+	RecordRequest(ctx context.Context, method, path string) (*entities.ImpersonationEvent, error)
+	// RecordResponse adds the response status to a request event.
+	RecordResponse(ctx context.Context, event *entities.ImpersonationEvent, status int) error
+
+	// List returns the impersonations by adminID of userID, newest first;
+	// either may be 0 to match anyone.
+	List(ctx context.Context, adminID, userID int64) ([]*entities.Impersonation, error)
+	// Events returns the log of an impersonation, oldest first.
+	Events(ctx context.Context, id string) ([]*entities.ImpersonationEvent, error)
+}
+
+// Options tunes impersonations; zero values use the defaults.
+type Options struct {
+	TTL time.Duration
+}
+
+type impersonationUseCase struct {
+	repo      *repository.Repository
+	authz     authzUC.AuthzUseCaseItf
+	policy    *authzUC.Policy
+	cache     cachePkg.CacheItf
+	keys      *token.KeySet
+	audit     audit.Logger
+	validator *validator.Validator
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// startInput carries the rules for starting an impersonation.
+type startInput struct {
+	Reason string `json:"reason" validate:"required,min=3,max=255"`
+}
+
+func NewImpersonationUseCase(repo *repository.Repository, authz authzUC.AuthzUseCaseItf, policy *authzUC.Policy, cache cachePkg.CacheItf, keys *token.KeySet, auditLog audit.Logger, opts Options) ImpersonationUseCaseItf {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	return &impersonationUseCase{
+		repo:      repo,
+		authz:     authz,
+		policy:    policy,
+		cache:     cache,
+		keys:      keys,
+		audit:     auditLog,
+		validator: validator.New(),
+		ttl:       opts.TTL,
+		now:       time.Now,
+	}
+}
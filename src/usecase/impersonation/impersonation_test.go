@@ -0,0 +1,224 @@
+package impersonation
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/token"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	impersonationRepository "solecode/src/repository/impersonation"
+	userRepository "solecode/src/repository/user"
+	authzUC "solecode/src/usecase/authz"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc      *impersonationUseCase
+	keys    *token.KeySet
+	log     *audit.MemoryLogger
+	admin   context.Context
+	adminID int64
+	users   []int64
+}
+
+// newTestEnv returns an impersonation use case over the shared fixture,
+// holding an admin and two users, john@example.com and a support agent.
+func newTestEnv(t *testing.T) *testEnv {
+	keys, err := token.NewEphemeralKeySet("test")
+	require.NoError(t, err)
+	env := &testEnv{Env: usecasetest.New(t), keys: keys, log: audit.NewMemoryLogger()}
+	policy := authzUC.NewPolicy(env.log)
+	authz := authzUC.NewAuthzUseCase(env.Repo, env.Cache, policy, env.log)
+
+	sys := usecasetest.System()
+	env.adminID = env.AddUser(t, "Admin User", "admin@example.com").ID
+	env.users = []int64{env.User.ID, env.AddUser(t, "Jane Doe", "jane@example.com").ID}
+	_, err = authz.GrantRole(sys, env.adminID, entities.RoleAdmin)
+	require.NoError(t, err)
+	_, err = authz.CreateRole(sys, "support", "Impersonates users", []entities.Permission{entities.PermUsersRead, entities.PermUsersImpersonate})
+	require.NoError(t, err)
+	_, err = authz.GrantRole(sys, env.users[1], "support")
+	require.NoError(t, err)
+
+	admin := &entities.Principal{UserID: env.adminID}
+	require.NoError(t, authz.LoadPermissions(sys, admin))
+	env.admin = entities.WithPrincipal(context.Background(), admin)
+	env.log.Reset()
+
+	env.uc = NewImpersonationUseCase(env.Repo, authz, policy, env.Cache, keys, env.log, Options{}).(*impersonationUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+// as returns a context impersonating the user of impersonation.
+func as(impersonation *entities.Impersonation) context.Context {
+	return entities.WithPrincipal(context.Background(), &entities.Principal{
+		UserID:         impersonation.UserID,
+		TokenID:        impersonation.ID,
+		ImpersonatorID: impersonation.AdminID,
+	})
+}
+
+func TestStart(t *testing.T) {
+	env := newTestEnv(t)
+	john := env.users[0]
+
+	impersonation, err := env.uc.Start(env.admin, john, " ticket 1234 ")
+	require.NoError(t, err)
+	assert.Equal(t, john, impersonation.UserID)
+	assert.Equal(t, "ticket 1234", impersonation.Reason)
+	assert.Equal(t, env.Clock.Now().Add(DefaultTTL), impersonation.ExpiresAt)
+
+	claims, err := env.keys.Verify(impersonation.Token, env.Clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(john, 10), claims.Subject)
+	assert.Equal(t, "john@example.com", claims.Email)
+	assert.Equal(t, impersonation.ID, claims.ID)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, strconv.FormatInt(env.adminID, 10), claims.Actor.Subject)
+
+	checked, err := env.uc.Check(context.Background(), impersonation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, impersonation.AdminID, checked.AdminID)
+
+	events, err := env.uc.Events(env.admin, impersonation.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, entities.ImpersonationStart, events[0].Action)
+
+	require.Len(t, env.log.Events(), 1)
+	assert.Equal(t, audit.Event{Action: "impersonation.start", Outcome: audit.Success, ActorID: env.adminID, TargetID: john,
+		Detail: "ticket 1234"}, env.log.Events()[0])
+
+	// Expired impersonations fail the check
+	env.Clock.Advance(DefaultTTL)
+	_, err = env.uc.Check(context.Background(), impersonation.ID)
+	assert.ErrorIs(t, err, ErrInvalidImpersonation)
+	_, err = env.uc.Check(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidImpersonation)
+}
+
+func TestStartRefusals(t *testing.T) {
+	env := newTestEnv(t)
+	john, jane := env.users[0], env.users[1]
+
+	_, err := env.uc.Start(env.admin, env.adminID, "ticket 1234")
+	assert.ErrorIs(t, err, ErrSelfImpersonation)
+	_, err = env.uc.Start(env.admin, 42, "ticket 1234")
+	assert.ErrorIs(t, err, userRepository.ErrUserNotFound)
+	_, err = env.uc.Start(env.admin, john, "")
+	var validationErrors validator.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+
+	// Users without the permission, and support agents acting as someone
+	// with more permissions than they hold, are refused
+	asJohn := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john})
+	_, err = env.uc.Start(asJohn, jane, "ticket 1234")
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	support := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: jane,
+		Permissions: []entities.Permission{entities.PermUsersRead, entities.PermUsersImpersonate}})
+	_, err = env.uc.Start(support, env.adminID, "ticket 1234")
+	assert.ErrorIs(t, err, ErrPrivilegedTarget)
+	_, err = env.uc.Start(support, john, "ticket 1234")
+	assert.NoError(t, err)
+
+	// Nobody starts another impersonation while impersonating
+	impersonation, err := env.uc.Start(env.admin, jane, "ticket 1234")
+	require.NoError(t, err)
+	_, err = env.uc.Start(as(impersonation), john, "ticket 1234")
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.Start(entities.WithPrincipal(context.Background(), entities.SystemPrincipal()), john, "ticket 1234")
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+}
+
+func TestStop(t *testing.T) {
+	env := newTestEnv(t)
+	john, jane := env.users[0], env.users[1]
+
+	// The impersonation stops itself
+	impersonation, err := env.uc.Start(env.admin, john, "ticket 1234")
+	require.NoError(t, err)
+	_, err = env.uc.Check(context.Background(), impersonation.ID)
+	require.NoError(t, err)
+	require.NoError(t, env.uc.Stop(as(impersonation), impersonation.ID))
+	_, err = env.uc.Check(context.Background(), impersonation.ID)
+	assert.ErrorIs(t, err, ErrInvalidImpersonation)
+	require.NoError(t, env.uc.Stop(env.admin, impersonation.ID))
+
+	events, err := env.uc.Events(env.admin, impersonation.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, entities.ImpersonationStop, events[1].Action)
+
+	// Others need the permission, which impersonating does not lend
+	other, err := env.uc.Start(env.admin, jane, "ticket 1234")
+	require.NoError(t, err)
+	assert.ErrorIs(t, env.uc.Stop(as(impersonation), other.ID), authzUC.ErrForbidden)
+	asJohn := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john})
+	assert.ErrorIs(t, env.uc.Stop(asJohn, other.ID), authzUC.ErrForbidden)
+	require.NoError(t, env.uc.Stop(env.admin, other.ID))
+
+	assert.ErrorIs(t, env.uc.Stop(env.admin, "unknown"), impersonationRepository.ErrImpersonationNotFound)
+}
+
+func TestRecordRequest(t *testing.T) {
+	env := newTestEnv(t)
+	john := env.users[0]
+
+	impersonation, err := env.uc.Start(env.admin, john, "ticket 1234")
+	require.NoError(t, err)
+
+	event, err := env.uc.RecordRequest(as(impersonation), "PUT", "/api/v1/users/2")
+	require.NoError(t, err)
+	require.NoError(t, env.uc.RecordResponse(as(impersonation), event, 200))
+	_, err = env.uc.RecordRequest(env.admin, "PUT", "/api/v1/users/2")
+	assert.ErrorIs(t, err, ErrNotImpersonating)
+
+	events, err := env.uc.Events(env.admin, impersonation.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, entities.ImpersonationEvent{
+		ID: events[1].ID, ImpersonationID: impersonation.ID, AdminID: env.adminID, UserID: john,
+		Action: entities.ImpersonationRequest, Method: "PUT", Path: "/api/v1/users/2", Status: 200,
+		CreatedAt: events[1].CreatedAt,
+	}, *events[1])
+
+	// Reading the log needs the permission, which impersonating does not lend
+	_, err = env.uc.Events(as(impersonation), impersonation.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.Events(env.admin, "unknown")
+	assert.ErrorIs(t, err, impersonationRepository.ErrImpersonationNotFound)
+}
+
+func TestList(t *testing.T) {
+	env := newTestEnv(t)
+	john, jane := env.users[0], env.users[1]
+
+	first, err := env.uc.Start(env.admin, john, "ticket 1234")
+	require.NoError(t, err)
+	env.Clock.Advance(time.Minute)
+	second, err := env.uc.Start(env.admin, jane, "ticket 5678")
+	require.NoError(t, err)
+
+	impersonations, err := env.uc.List(env.admin, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, impersonations, 2)
+	assert.Equal(t, second.ID, impersonations[0].ID)
+	assert.Empty(t, impersonations[0].Token)
+
+	impersonations, err = env.uc.List(env.admin, env.adminID, john)
+	require.NoError(t, err)
+	require.Len(t, impersonations, 1)
+	assert.Equal(t, first.ID, impersonations[0].ID)
+
+	_, err = env.uc.List(entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john}), 0, 0)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+}
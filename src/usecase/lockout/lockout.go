@@ -0,0 +1,243 @@
+package lockout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+)
+
+// record is what the cache holds for an account or a client IP. The
+// window slides: failures older than it are dropped whenever the record is
+// read. Records are read and written without a lock, so failures racing
+// each other may count once; the limits are approximate.
+type record struct {
+	Failures    []time.Time `json:"failures,omitempty"`
+	LockedUntil time.Time   `json:"locked_until"`
+}
+
+// limit is a counter an attempt is held to. Only account limits delay
+// attempts; client IP limits just lock.
+type limit struct {
+	key     string
+	name    string // for audit events
+	max     int
+	account bool
+}
+
+// accountKey keys accounts by a hash of the normalised email, so that the
+// cache does not collect the emails people type.
+func (uc *lockoutUseCase) accountKey(account string) string {
+	sum := sha256.Sum256([]byte(normalize(account)))
+	return uc.opts.Scope + ":account:" + hex.EncodeToString(sum[:])
+}
+
+func (uc *lockoutUseCase) ipKey(ip string) string {
+	return uc.opts.Scope + ":ip:" + ip
+}
+
+func normalize(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// normalizeIP returns the address attempts from ip count against, or ""
+// if ip is not an address. IPv6 clients count by their /64, which one
+// client usually holds in full.
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	if addr.Is6() {
+		prefix, _ := addr.Prefix(64)
+		return prefix.String()
+	}
+	return addr.String()
+}
+
+func (uc *lockoutUseCase) Check(ctx context.Context, account string) error {
+	now := uc.now()
+	var wait time.Duration
+	for _, l := range uc.limits(ctx, account) {
+		r, err := uc.load(l.key, now)
+		if err != nil {
+			return err
+		}
+		wait = max(wait, uc.wait(r, now, l.account))
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (uc *lockoutUseCase) Failure(ctx context.Context, account string) error {
+	now := uc.now()
+	for _, l := range uc.limits(ctx, account) {
+		r, err := uc.load(l.key, now)
+		if err != nil {
+			return err
+		}
+		r.Failures = append(r.Failures, now)
+		if len(r.Failures) >= l.max {
+			// A fresh window starts once the lockout ends
+			r.Failures = nil
+			r.LockedUntil = now.Add(uc.opts.LockoutDuration)
+			uc.logLockout(ctx, account, l)
+		}
+		if err := uc.save(l.key, r, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *lockoutUseCase) Success(ctx context.Context, account string) error {
+	if err := uc.cache.Delete(uc.accountKey(account)); err != nil {
+		return fmt.Errorf("failed to clear failed attempts: %w", err)
+	}
+	return nil
+}
+
+func (uc *lockoutUseCase) Status(ctx context.Context, userID int64) (*Status, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	r, err := uc.load(uc.accountKey(user.Email), now)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Failures: len(r.Failures), RetryAfter: uc.wait(r, now, true)}
+	if now.Before(r.LockedUntil) {
+		status.LockedUntil = r.LockedUntil
+	}
+	return status, nil
+}
+
+func (uc *lockoutUseCase) Unlock(ctx context.Context, userID int64) error {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.cache.Delete(uc.accountKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	uc.log(ctx, audit.Event{Action: "auth.unlock", Outcome: audit.Success, TargetID: user.ID})
+	return nil
+}
+
+func (uc *lockoutUseCase) UnlockIP(ctx context.Context, ip string) error {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+	ip = normalizeIP(ip)
+	if ip == "" {
+		return ErrInvalidIP
+	}
+	if err := uc.cache.Delete(uc.ipKey(ip)); err != nil {
+		return fmt.Errorf("failed to unlock client IP: %w", err)
+	}
+
+	uc.log(ctx, audit.Event{Action: "auth.unlock", Outcome: audit.Success, Detail: "client IP " + ip})
+	return nil
+}
+
+// limits returns the counters an attempt on account from ctx is held to.
+func (uc *lockoutUseCase) limits(ctx context.Context, account string) []limit {
+	limits := []limit{{key: uc.accountKey(account), name: "account " + normalize(account), max: uc.opts.MaxAccountFailures, account: true}}
+	if ip := normalizeIP(ClientIP(ctx)); ip != "" {
+		limits = append(limits, limit{key: uc.ipKey(ip), name: "client IP " + ip, max: uc.opts.MaxIPFailures})
+	}
+	return limits
+}
+
+// load returns the record under key with the failures that fell out of the
+// window dropped.
+func (uc *lockoutUseCase) load(key string, now time.Time) (*record, error) {
+	var r record
+	if err := uc.cache.GetJSON(key, &r); err != nil {
+		return nil, fmt.Errorf("failed to read failed attempts: %w", err)
+	}
+	since := now.Add(-uc.opts.Window)
+	kept := r.Failures[:0]
+	for _, at := range r.Failures {
+		if at.After(since) {
+			kept = append(kept, at)
+		}
+	}
+	r.Failures = kept
+	return &r, nil
+}
+
+// save stores r until both its failures and its lockout have expired.
+func (uc *lockoutUseCase) save(key string, r *record, now time.Time) error {
+	ttl := max(uc.opts.Window, r.LockedUntil.Sub(now))
+	if err := uc.cache.SetJSON(key, r, ttl); err != nil {
+		return fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+	return nil
+}
+
+// wait returns how long the next attempt held to r has to wait.
+func (uc *lockoutUseCase) wait(r *record, now time.Time, delays bool) time.Duration {
+	if now.Before(r.LockedUntil) {
+		return r.LockedUntil.Sub(now)
+	}
+	if !delays || len(r.Failures) < uc.opts.DelayAfter {
+		return 0
+	}
+	delay := uc.opts.BaseDelay
+	for i := uc.opts.DelayAfter; i < len(r.Failures) && delay < uc.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, uc.opts.MaxDelay)
+	if next := r.Failures[len(r.Failures)-1].Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// logLockout audits a new lockout. Account lockouts name the user when
+// there is one; the email stays in the detail either way, as attempts on
+// unknown emails are worth knowing about too.
+func (uc *lockoutUseCase) logLockout(ctx context.Context, account string, l limit) {
+	event := audit.Event{
+		Action:  "auth.lockout",
+		Outcome: audit.Denied,
+		Detail:  fmt.Sprintf("%s locked for %s after %d failed attempts", l.name, uc.opts.LockoutDuration, l.max),
+	}
+	if uc.opts.Scope != DefaultScope {
+		event.Detail = uc.opts.Scope + ": " + event.Detail
+	}
+	if l.account {
+		if user, err := uc.repo.User.GetByEmail(ctx, normalize(account)); err == nil && user != nil {
+			event.TargetID = user.ID
+		}
+	}
+	uc.audit.Log(ctx, event)
+}
+
+// log audits a change made by the principal of ctx.
+func (uc *lockoutUseCase) log(ctx context.Context, event audit.Event) {
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
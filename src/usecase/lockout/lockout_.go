@@ -0,0 +1,155 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultMaxAccountFailures = 5
+	DefaultMaxIPFailures      = 50
+	DefaultWindow             = 15 * time.Minute
+	DefaultLockoutDuration    = 15 * time.Minute
+	DefaultDelayAfter         = 3
+	DefaultBaseDelay          = time.Second
+	DefaultMaxDelay           = 30 * time.Second
+	DefaultScope              = "lockout"
+)
+
+var (
+	// ErrThrottled is what callers that must wait are told. It reads the
+	// same for every email, known or not, and for delays and lockouts.
+	ErrThrottled = errors.New("too many failed attempts, try again later")
+	ErrInvalidIP = errors.New("invalid IP address")
+)
+
+// ThrottledError is ErrThrottled with the time until the next attempt is
+// allowed.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrThrottled.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrThrottled
+}
+
+//go:generate mockery --name LockoutUseCaseItf --output mocks --filename lockoutusecase_mock.go --outpkg mocks
+type LockoutUseCaseItf interface {
+	// Check fails with a *ThrottledError while account, or the client IP
+	// of ctx, has to wait out a progressive delay or a lockout. account is
+	// whatever the caller typed, so unknown emails are throttled exactly
+	// like real ones.
+	Check(ctx context.Context, account string) error
+	// Failure counts a failed attempt against account and the client IP
+	// of ctx, and locks either once it reaches its limit.
+	Failure(ctx context.Context, account string) error
+	// Success forgets the failures of account.
+	Success(ctx context.Context, account string) error
+	// Status reports the failures and lockout of a user's account.
+	Status(ctx context.Context, userID int64) (*Status, error)
+	// Unlock clears the failures and lockout of a user's account.
+	Unlock(ctx context.Context, userID int64) error
+	// UnlockIP clears the failures and lockout of a client IP.
+	UnlockIP(ctx context.Context, ip string) error
+}
+
+// Status is the lockout state of an account.
+type Status struct {
+	// Failures counts the failed attempts within the window.
+	Failures int
+	// LockedUntil is zero unless the account is locked.
+	LockedUntil time.Time
+	// RetryAfter is how long the next attempt has to wait, whether for a
+	// lockout or for a delay.
+	RetryAfter time.Duration
+}
+
+// Options tunes the limits; zero values use the defaults.
+type Options struct {
+	// MaxAccountFailures and MaxIPFailures failed attempts within Window
+	// lock an account or a client IP for LockoutDuration.
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration
+	LockoutDuration    time.Duration
+	// From the DelayAfter-th failure on, an account waits BaseDelay
+	// before its next attempt, doubling with each further failure up to
+	// MaxDelay. Client IPs are not delayed, so that users behind a shared
+	// address do not slow each other down.
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// Scope namespaces the counters, so that instances limiting other
+	// attempts than logins, such as password reset requests, neither
+	// delay nor lock logins. The default scope is the one of logins.
+	Scope string
+}
+
+type lockoutUseCase struct {
+	repo   *repository.Repository
+	cache  cachePkg.CacheItf
+	policy *authzUC.Policy
+	audit  audit.Logger
+	opts   Options
+	now    func() time.Time
+}
+
+func NewLockoutUseCase(repo *repository.Repository, cache cachePkg.CacheItf, policy *authzUC.Policy, auditLog audit.Logger, opts Options) LockoutUseCaseItf {
+	if opts.MaxAccountFailures <= 0 {
+		opts.MaxAccountFailures = DefaultMaxAccountFailures
+	}
+	if opts.MaxIPFailures <= 0 {
+		opts.MaxIPFailures = DefaultMaxIPFailures
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.LockoutDuration <= 0 {
+		opts.LockoutDuration = DefaultLockoutDuration
+	}
+	if opts.DelayAfter <= 0 {
+		opts.DelayAfter = DefaultDelayAfter
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = DefaultBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.Scope == "" {
+		opts.Scope = DefaultScope
+	}
+	return &lockoutUseCase{
+		repo:   repo,
+		cache:  cache,
+		policy: policy,
+		audit:  auditLog,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context whose attempts count against ip.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the client IP of ctx, or "" for callers outside HTTP
+// requests, which are only limited per account.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,210 @@
+package lockout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc    *lockoutUseCase
+	audit *bytes.Buffer
+}
+
+// newTestEnv returns a lockout use case over the shared fixture, with
+// small limits.
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{Env: usecasetest.New(t), audit: &bytes.Buffer{}}
+	auditLog := audit.NewJSONLogger(env.audit)
+	env.uc = NewLockoutUseCase(env.Repo, env.Cache, authzUC.NewPolicy(audit.NewNopLogger()), auditLog, Options{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      8,
+		Window:             10 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		DelayAfter:         3,
+		BaseDelay:          time.Second,
+		MaxDelay:           4 * time.Second,
+	}).(*lockoutUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+// retryAfter returns how long Check makes an attempt wait, or 0.
+func (env *testEnv) retryAfter(t *testing.T, ctx context.Context, account string) time.Duration {
+	err := env.uc.Check(ctx, account)
+	if err == nil {
+		return 0
+	}
+	var throttled *ThrottledError
+	require.True(t, errors.As(err, &throttled), "unexpected error %v", err)
+	assert.ErrorIs(t, err, ErrThrottled)
+	return throttled.RetryAfter
+}
+
+// fail records a failure after waiting out any delay, as a patient
+// attacker would.
+func (env *testEnv) fail(t *testing.T, ctx context.Context, account string) {
+	env.Clock.Advance(env.retryAfter(t, ctx, account))
+	require.NoError(t, env.uc.Failure(ctx, account))
+}
+
+func TestProgressiveDelaysAndLockout(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	env.fail(t, ctx, "john@example.com")
+	env.fail(t, ctx, "john@example.com")
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+
+	// From the third failure on each attempt waits twice as long
+	require.NoError(t, env.uc.Failure(ctx, "john@example.com"))
+	assert.Equal(t, time.Second, env.retryAfter(t, ctx, "John@Example.com "))
+	env.Clock.Advance(time.Second)
+	require.NoError(t, env.uc.Failure(ctx, "john@example.com"))
+	assert.Equal(t, 2*time.Second, env.retryAfter(t, ctx, "john@example.com"))
+
+	// The fifth failure locks the account
+	env.fail(t, ctx, "john@example.com")
+	assert.Equal(t, 15*time.Minute, env.retryAfter(t, ctx, "john@example.com"))
+	assert.Contains(t, env.audit.String(), `"action":"auth.lockout","outcome":"denied","target_id":1,"detail":"account john@example.com locked for 15m0s after 5 failed attempts"`)
+
+	// Other accounts are unaffected
+	assert.Zero(t, env.retryAfter(t, ctx, "jane@example.com"))
+
+	// After the lockout the account starts over
+	env.Clock.Advance(15 * time.Minute)
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+	require.NoError(t, env.uc.Failure(ctx, "john@example.com"))
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	env.fail(t, ctx, "john@example.com")
+	env.Clock.Advance(6 * time.Minute)
+	env.fail(t, ctx, "john@example.com")
+	env.fail(t, ctx, "john@example.com")
+	env.fail(t, ctx, "john@example.com")
+
+	// The first failure has left the window, so the next is the fourth
+	env.Clock.Advance(5 * time.Minute)
+	env.fail(t, ctx, "john@example.com")
+	status, err := env.uc.Status(entities.WithPrincipal(ctx, entities.SystemPrincipal()), env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, status.Failures)
+	assert.True(t, status.LockedUntil.IsZero())
+}
+
+func TestUnknownEmailsAreThrottledAlike(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	for i := 0; i < 5; i++ {
+		env.fail(t, ctx, "john@example.com")
+		env.fail(t, ctx, "nobody@example.com")
+	}
+	known := env.uc.Check(ctx, "john@example.com")
+	unknown := env.uc.Check(ctx, "nobody@example.com")
+	require.Error(t, known)
+	assert.Equal(t, known, unknown)
+	assert.Contains(t, env.audit.String(), `"detail":"account nobody@example.com locked`)
+}
+
+func TestSuccessClearsAccount(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	env.fail(t, ctx, "john@example.com")
+	env.fail(t, ctx, "john@example.com")
+	env.fail(t, ctx, "john@example.com")
+	require.NoError(t, env.uc.Success(ctx, "John@example.com"))
+	env.fail(t, ctx, "john@example.com")
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+}
+
+func TestClientIPLockout(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+
+	// Spreading attempts over many accounts trips the IP limit without
+	// delays
+	for i := 0; i < 7; i++ {
+		require.NoError(t, env.uc.Failure(ctx, strings.Repeat("x", i+1)+"@example.com"))
+		assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+	}
+	require.NoError(t, env.uc.Failure(ctx, "last@example.com"))
+	assert.Equal(t, 15*time.Minute, env.retryAfter(t, ctx, "john@example.com"))
+	assert.Zero(t, env.retryAfter(t, context.Background(), "john@example.com"))
+	assert.Zero(t, env.retryAfter(t, WithClientIP(context.Background(), "203.0.113.8"), "john@example.com"))
+	assert.Contains(t, env.audit.String(), `"detail":"client IP 203.0.113.7 locked for 15m0s after 8 failed attempts"`)
+
+	sys := usecasetest.System()
+	assert.ErrorIs(t, env.uc.UnlockIP(sys, "not an ip"), ErrInvalidIP)
+	require.NoError(t, env.uc.UnlockIP(sys, "203.0.113.7"))
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+}
+
+func TestScopesAreApart(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+	other := NewLockoutUseCase(env.Repo, env.Cache, env.uc.policy, audit.NewNopLogger(), Options{
+		Scope:         "other",
+		MaxIPFailures: 8,
+	})
+
+	// Tripping both limits of one scope leaves the other untouched
+	for i := 0; i < 8; i++ {
+		require.NoError(t, other.Failure(ctx, "john@example.com"))
+	}
+	var throttled *ThrottledError
+	assert.ErrorAs(t, other.Check(ctx, "john@example.com"), &throttled)
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+}
+
+func TestIPv6CountsBySubnet(t *testing.T) {
+	assert.Equal(t, "2001:db8:1:2::/64", normalizeIP("2001:db8:1:2:aaaa::1"))
+	assert.Equal(t, "2001:db8:1:2::/64", normalizeIP("2001:db8:1:2:bbbb::2"))
+	assert.Equal(t, "192.0.2.1", normalizeIP("::ffff:192.0.2.1"))
+	assert.Equal(t, "", normalizeIP(""))
+}
+
+func TestStatusAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	for i := 0; i < 5; i++ {
+		env.fail(t, ctx, "john@example.com")
+	}
+
+	// Only admins see and lift lockouts, not even the user themselves
+	self := entities.WithPrincipal(ctx, &entities.Principal{UserID: env.User.ID})
+	_, err := env.uc.Status(self, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	assert.ErrorIs(t, env.uc.Unlock(self, env.User.ID), authzUC.ErrForbidden)
+
+	admin := entities.WithPrincipal(ctx, &entities.Principal{UserID: 9, Permissions: []entities.Permission{entities.PermUsersUpdate}})
+	status, err := env.uc.Status(admin, env.User.ID)
+	require.NoError(t, err)
+	assert.True(t, env.Clock.Now().Add(15*time.Minute).Equal(status.LockedUntil))
+	assert.Equal(t, 15*time.Minute, status.RetryAfter)
+
+	require.NoError(t, env.uc.Unlock(admin, env.User.ID))
+	assert.Zero(t, env.retryAfter(t, ctx, "john@example.com"))
+	assert.Contains(t, env.audit.String(), `"action":"auth.unlock","outcome":"success","actor_id":9,"target_id":1`)
+
+	_, err = env.uc.Status(admin, 42)
+	assert.Error(t, err)
+}
@@ -0,0 +1,533 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	oauthRepository "solecode/src/repository/oauth"
+	userRepository "solecode/src/repository/user"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxNonce bounds the nonce kept with a code.
+const maxNonce = 255
+
+// codeRecord is what the cache holds for a code, under its hash, until it
+// is redeemed or expires.
+type codeRecord struct {
+	ClientID    string    `json:"client_id"`
+	UserID      int64     `json:"user_id"`
+	RedirectURI string    `json:"redirect_uri"`
+	Scopes      []string  `json:"scopes"`
+	Nonce       string    `json:"nonce,omitempty"`
+	Challenge   string    `json:"challenge"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// idClaims are the claims of an ID token.
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce,omitempty"`
+	Name          string `json:"name,omitempty"`
+	UpdatedAt     int64  `json:"updated_at,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+func codeKey(code string) string {
+	return "oauth_code:" + hash(code)
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (uc *oidcUseCase) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*entities.OAuthClient, string, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermClientsManage); err != nil {
+		return nil, "", err
+	}
+
+	input := clientInput{Name: strings.TrimSpace(name)}
+	if err := uc.validator.ValidateStruct(&input); err != nil {
+		return nil, "", err
+	}
+	if len(redirectURIs) == 0 {
+		return nil, "", ErrNoRedirectURIs
+	}
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidRedirectURI, uri)
+		}
+	}
+
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &entities.OAuthClient{ID: id, Name: input.Name, RedirectURIs: slices.Compact(slices.Clone(redirectURIs))}
+	var secret string
+	if !public {
+		if secret, err = randomToken(32); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = hash(secret)
+	}
+	if err := uc.repo.OAuth.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	uc.log(ctx, "oauth.client_register", 0, "client "+client.ID)
+	return client, secret, nil
+}
+
+func (uc *oidcUseCase) ListClients(ctx context.Context) ([]*entities.OAuthClient, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermClientsManage); err != nil {
+		return nil, err
+	}
+	return uc.repo.OAuth.ListClients(ctx)
+}
+
+func (uc *oidcUseCase) DeleteClient(ctx context.Context, id string) error {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermClientsManage); err != nil {
+		return err
+	}
+	if err := uc.repo.OAuth.DeleteClient(ctx, id); err != nil {
+		return err
+	}
+
+	uc.log(ctx, "oauth.client_delete", 0, "client "+id)
+	return nil
+}
+
+func (uc *oidcUseCase) ListConsents(ctx context.Context, userID int64) ([]*entities.OAuthConsent, error) {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return uc.repo.OAuth.ListConsents(ctx, userID)
+}
+
+func (uc *oidcUseCase) RevokeConsent(ctx context.Context, userID int64, clientID string) error {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+	if err := uc.repo.OAuth.DeleteConsent(ctx, userID, clientID); err != nil {
+		return err
+	}
+
+	uc.log(ctx, "oauth.consent_revoke", userID, "client "+clientID)
+	return nil
+}
+
+func (uc *oidcUseCase) Authorize(ctx context.Context, req AuthorizationRequest) (*Authorization, error) {
+	client, scopes, err := uc.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uc.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := uc.repo.OAuth.GetConsent(ctx, userID, client.ID)
+	if errors.Is(err, oauthRepository.ErrConsentNotFound) || (err == nil && !covers(consent.Scopes, scopes)) {
+		return &Authorization{Client: client, Scopes: scopes, ConsentRequired: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return uc.issueCode(userID, client, req, scopes)
+}
+
+func (uc *oidcUseCase) Consent(ctx context.Context, req AuthorizationRequest, approve bool) (*Authorization, error) {
+	client, scopes, err := uc.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uc.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !approve {
+		return nil, &Error{Code: CodeAccessDenied, Description: "the user denied access"}
+	}
+
+	// Consent adds up, so that a client asking for less later does not
+	// take back what was granted before
+	granted := slices.Clone(scopes)
+	consent, err := uc.repo.OAuth.GetConsent(ctx, userID, client.ID)
+	if err == nil {
+		granted = append(granted, consent.Scopes...)
+	} else if !errors.Is(err, oauthRepository.ErrConsentNotFound) {
+		return nil, err
+	}
+	granted = sortScopes(granted)
+	err = uc.repo.OAuth.SaveConsent(ctx, &entities.OAuthConsent{
+		UserID:    userID,
+		ClientID:  client.ID,
+		Scopes:    granted,
+		GrantedAt: uc.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log(ctx, "oauth.consent", userID, fmt.Sprintf("client %s: %s", client.ID, strings.Join(granted, " ")))
+	return uc.issueCode(userID, client, req, scopes)
+}
+
+func (uc *oidcUseCase) Exchange(ctx context.Context, req TokenRequest) (*Tokens, error) {
+	cfg, err := uc.Configuration()
+	if err != nil {
+		return nil, err
+	}
+	if req.GrantType != "authorization_code" {
+		return nil, &Error{Code: CodeUnsupportedGrantType, Description: "only authorization_code is supported"}
+	}
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if req.Code == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Description: "code is required"}
+	}
+
+	// A code works once, whether or not the rest of the request checks
+	// out, so a stolen code cannot be tried with guessed verifiers. Taking
+	// it reads and deletes it in one step, so racing exchanges cannot both
+	// have it.
+	var record codeRecord
+	found, err := uc.cache.TakeJSON(codeKey(req.Code), &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem code: %w", err)
+	}
+
+	switch {
+	case !found || record.UserID == 0 || !uc.now().Before(record.ExpiresAt) || record.ClientID != client.ID:
+		return nil, &Error{Code: CodeInvalidGrant, Description: "invalid or expired code"}
+	case req.RedirectURI != record.RedirectURI:
+		return nil, &Error{Code: CodeInvalidGrant, Description: "redirect_uri does not match the authorization request"}
+	case !verifyChallenge(req.CodeVerifier, record.Challenge):
+		return nil, &Error{Code: CodeInvalidGrant, Description: "code_verifier does not match the code_challenge"}
+	}
+
+	user, err := uc.repo.User.GetByID(ctx, record.UserID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, &Error{Code: CodeInvalidGrant, Description: "the user no longer exists"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	consent, err := uc.repo.OAuth.GetConsent(ctx, user.ID, client.ID)
+	if errors.Is(err, oauthRepository.ErrConsentNotFound) || (err == nil && !covers(consent.Scopes, record.Scopes)) {
+		return nil, &Error{Code: CodeInvalidGrant, Description: "consent was revoked"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return uc.issueTokens(cfg, client, user, record)
+}
+
+func (uc *oidcUseCase) UserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	if _, err := uc.Configuration(); err != nil {
+		return nil, err
+	}
+	claims, err := uc.keys.Verify(accessToken, uc.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	scopes := strings.Fields(claims.Scope)
+	if len(claims.Audience) != 1 || !slices.Contains(scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%w: not issued to a client", ErrInvalidToken)
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return nil, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+
+	// Revoking consent or deleting the client cuts the token off at once
+	consent, err := uc.repo.OAuth.GetConsent(ctx, userID, claims.Audience[0])
+	if errors.Is(err, oauthRepository.ErrConsentNotFound) || (err == nil && !covers(consent.Scopes, scopes)) {
+		return nil, fmt.Errorf("%w: consent was revoked", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return userInfo(user, scopes), nil
+}
+
+func (uc *oidcUseCase) Configuration() (*Configuration, error) {
+	issuer, err := url.Parse(uc.keys.Issuer())
+	if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" ||
+		issuer.RawQuery != "" || issuer.Fragment != "" {
+		return nil, fmt.Errorf("%w: auth.issuer must be the URL of the server", ErrUnavailable)
+	}
+	if uc.keys.Algorithm() == token.HS256 {
+		return nil, fmt.Errorf("%w: ID tokens need an RS256 or EdDSA signing key", ErrUnavailable)
+	}
+	return &Configuration{
+		Issuer:           uc.keys.Issuer(),
+		SigningAlgorithm: uc.keys.Algorithm(),
+		Scopes:           SupportedScopes,
+		Claims:           []string{"iss", "sub", "aud", "exp", "iat", "nonce", "name", "updated_at", "email", "email_verified"},
+	}, nil
+}
+
+// validate checks an authorisation request and returns its client and the
+// supported scopes it asks for.
+func (uc *oidcUseCase) validate(ctx context.Context, req AuthorizationRequest) (*entities.OAuthClient, []string, error) {
+	if _, err := uc.Configuration(); err != nil {
+		return nil, nil, err
+	}
+	if req.ClientID == "" {
+		return nil, nil, ErrUnknownClient
+	}
+	client, err := uc.repo.OAuth.GetClient(ctx, req.ClientID)
+	if errors.Is(err, oauthRepository.ErrClientNotFound) {
+		return nil, nil, ErrUnknownClient
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, nil, ErrRedirectURIMismatch
+	}
+
+	// From here on errors go back to the client
+	if req.ResponseType != "code" {
+		return nil, nil, &Error{Code: CodeUnsupportedResponseType, Description: "only the code response type is supported"}
+	}
+	scopes := sortScopes(strings.Fields(req.Scope))
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, nil, &Error{Code: CodeInvalidScope, Description: "scope must include openid"}
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return nil, nil, &Error{Code: CodeInvalidRequest, Description: "PKCE with code_challenge_method S256 is required"}
+	}
+	if challenge, err := base64.RawURLEncoding.DecodeString(req.CodeChallenge); err != nil || len(challenge) != sha256.Size {
+		return nil, nil, &Error{Code: CodeInvalidRequest, Description: "code_challenge must be a base64url SHA-256 hash"}
+	}
+	if len(req.Nonce) > maxNonce {
+		return nil, nil, &Error{Code: CodeInvalidRequest, Description: fmt.Sprintf("nonce must be at most %d characters", maxNonce)}
+	}
+	return client, scopes, nil
+}
+
+// caller returns the user the caller is, who may only authorise clients
+// for themselves.
+func (uc *oidcUseCase) caller(ctx context.Context) (int64, error) {
+	var userID int64
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		userID = principal.UserID
+	}
+	if err := uc.policy.AuthorizeSelf(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// authenticateClient checks the secret of a confidential client; public
+// clients present none.
+func (uc *oidcUseCase) authenticateClient(ctx context.Context, id, secret string) (*entities.OAuthClient, error) {
+	invalid := &Error{Code: CodeInvalidClient, Description: "unknown client or wrong secret"}
+	if id == "" {
+		return nil, invalid
+	}
+	client, err := uc.repo.OAuth.GetClient(ctx, id)
+	if errors.Is(err, oauthRepository.ErrClientNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.Public() {
+		if secret != "" {
+			return nil, invalid
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalid
+	}
+	return client, nil
+}
+
+// issueCode stores a code for the client to redeem on behalf of userID.
+func (uc *oidcUseCase) issueCode(userID int64, client *entities.OAuthClient, req AuthorizationRequest, scopes []string) (*Authorization, error) {
+	code, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	record := codeRecord{
+		ClientID:    client.ID,
+		UserID:      userID,
+		RedirectURI: req.RedirectURI,
+		Scopes:      scopes,
+		Nonce:       req.Nonce,
+		Challenge:   req.CodeChallenge,
+		ExpiresAt:   uc.now().Add(uc.opts.CodeTTL),
+	}
+	if err := uc.cache.SetJSON(codeKey(code), record, uc.opts.CodeTTL); err != nil {
+		return nil, fmt.Errorf("failed to store code: %w", err)
+	}
+	return &Authorization{Client: client, Scopes: scopes, Code: code}, nil
+}
+
+// issueTokens signs the access and ID tokens a code is redeemed for.
+func (uc *oidcUseCase) issueTokens(cfg *Configuration, client *entities.OAuthClient, user *entities.User, record codeRecord) (*Tokens, error) {
+	now := uc.now()
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	subject := strconv.FormatInt(user.ID, 10)
+
+	tokens := &Tokens{Scopes: record.Scopes, ExpiresAt: now.Add(uc.opts.AccessTokenTTL)}
+	tokens.AccessToken, err = uc.keys.Sign(&token.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(tokens.ExpiresAt),
+			ID:        jti,
+		},
+		Scope: strings.Join(record.Scopes, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := userInfo(user, record.Scopes)
+	tokens.IDToken, err = uc.keys.SignClaims(&idClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(uc.opts.IDTokenTTL)),
+		},
+		Nonce:         record.Nonce,
+		Name:          info.Name,
+		UpdatedAt:     info.UpdatedAt,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// log audits a change to clients or to the consents of userID.
+func (uc *oidcUseCase) log(ctx context.Context, action string, userID int64, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, TargetID: userID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
+
+// userInfo returns the claims of user that scopes reveal.
+func userInfo(user *entities.User, scopes []string) *UserInfo {
+	info := &UserInfo{Subject: strconv.FormatInt(user.ID, 10)}
+	if slices.Contains(scopes, ScopeProfile) {
+		info.Name, info.UpdatedAt = user.Name, user.UpdatedAt.Unix()
+	}
+	if slices.Contains(scopes, ScopeEmail) {
+		verified := user.Verified()
+		info.Email, info.EmailVerified = user.Email, &verified
+	}
+	return info
+}
+
+// sortScopes keeps the supported scopes of scopes, once each, in the
+// order of SupportedScopes.
+func sortScopes(scopes []string) []string {
+	sorted := []string{}
+	for _, scope := range SupportedScopes {
+		if slices.Contains(scopes, scope) {
+			sorted = append(sorted, scope)
+		}
+	}
+	return sorted
+}
+
+// covers reports whether granted includes every scope of requested.
+func covers(granted, requested []string) bool {
+	for _, scope := range requested {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// validRedirectURI admits absolute https URLs, and http ones on loopback
+// hosts for native and development clients, without fragments.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Fragment != "" || strings.ContainsAny(raw, "# \t\r\n") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return false
+	}
+}
+
+// verifyChallenge checks a PKCE verifier, 43 to 128 unreserved
+// characters, against the S256 challenge of the authorisation request.
+func verifyChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		unreserved := c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune("-._~", c)
+		if !unreserved {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
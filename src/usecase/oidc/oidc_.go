@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/token"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultCodeTTL        = time.Minute
+	DefaultAccessTokenTTL = 15 * time.Minute
+	DefaultIDTokenTTL     = time.Hour
+)
+
+// Scopes the provider understands; others are ignored.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// SupportedScopes lists the scopes in the order they are reported.
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+var (
+	// ErrUnavailable is returned while auth.issuer is not an http(s) URL,
+	// which ID tokens and the discovery document need, or tokens are
+	// signed with HS256, whose secret clients cannot verify with.
+	ErrUnavailable = errors.New("openid connect is not configured")
+	// ErrUnknownClient and ErrRedirectURIMismatch fail authorisation
+	// requests that must not be redirected back, as the redirect URI
+	// cannot be trusted.
+	ErrUnknownClient       = errors.New("unknown client")
+	ErrRedirectURIMismatch = errors.New("redirect_uri is not registered for the client")
+	// ErrInvalidRedirectURI is returned when registering a redirect URI
+	// that is not absolute https, or http on a loopback host, or that has
+	// a fragment.
+	ErrInvalidRedirectURI = errors.New("redirect URIs must be absolute https URLs without a fragment, or http on a loopback host")
+	ErrNoRedirectURIs     = errors.New("a client needs at least one redirect URI")
+	// ErrInvalidToken is returned by UserInfo for access tokens that are
+	// malformed, expired, not issued to a client, or whose client, user
+	// or consent is gone.
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// OAuth error codes, as RFC 6749 and OpenID Connect name them.
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidClient           = "invalid_client"
+	CodeInvalidGrant            = "invalid_grant"
+	CodeInvalidScope            = "invalid_scope"
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeUnsupportedGrantType    = "unsupported_grant_type"
+	CodeAccessDenied            = "access_denied"
+)
+
+// Error is an OAuth error for the client: the authorisation endpoint
+// redirects it back, the token endpoint answers it.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+//go:generate mockery --name OIDCUseCaseItf --output mocks --filename oidcusecase_mock.go --outpkg mocks
+type OIDCUseCaseItf interface {
+	// RegisterClient registers an application that may sign users in,
+	// sending codes to redirectURIs only. Confidential clients get a
+	// secret, returned once; public clients get none and rely on PKCE.
+	RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (*entities.OAuthClient, string, error)
+	ListClients(ctx context.Context) ([]*entities.OAuthClient, error)
+	// DeleteClient removes a client with the consents given to it; the
+	// tokens it holds stop working at userinfo.
+	DeleteClient(ctx context.Context, id string) error
+
+	// ListConsents returns the clients a user consented to; users may
+	// list their own.
+	ListConsents(ctx context.Context, userID int64) ([]*entities.OAuthConsent, error)
+	// RevokeConsent withdraws the consent of a user to a client, so the
+	// next sign-in asks again and the tokens it holds stop working.
+	RevokeConsent(ctx context.Context, userID int64, clientID string) error
+
+	// Authorize checks an authorisation request of the caller's principal.
+	// With consent to the requested scopes on record, it issues a code;
+	// otherwise it reports that consent is required. Requests naming an
+	// unknown client or redirect URI fail with ErrUnknownClient or
+	// ErrRedirectURIMismatch; other invalid requests fail with an *Error
+	// for the client.
+	Authorize(ctx context.Context, req AuthorizationRequest) (*Authorization, error)
+	// Consent records the caller's answer to a consent prompt. Approval
+	// is stored and a code issued; refusal fails with an *Error with
+	// CodeAccessDenied.
+	Consent(ctx context.Context, req AuthorizationRequest, approve bool) (*Authorization, error)
+	// Exchange redeems a code for tokens. Every failure is an *Error.
+	Exchange(ctx context.Context, req TokenRequest) (*Tokens, error)
+	// UserInfo returns the claims an access token of a client may see.
+	UserInfo(ctx context.Context, accessToken string) (*UserInfo, error)
+	// Configuration describes the provider for its discovery document.
+	Configuration() (*Configuration, error)
+}
+
+// AuthorizationRequest carries the parameters of the authorisation
+// endpoint. Only the code flow with PKCE using S256 is supported.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Authorization is the outcome of an authorisation request: a code for
+// the redirect URI, or the scopes to ask the user about.
+type Authorization struct {
+	Client          *entities.OAuthClient
+	Scopes          []string
+	ConsentRequired bool
+	Code            string
+}
+
+// TokenRequest carries the parameters of the token endpoint; the client
+// secret may come from either the body or HTTP Basic authentication.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	ClientID     string
+	ClientSecret string
+}
+
+// Tokens are what a code is redeemed for.
+type Tokens struct {
+	AccessToken string
+	IDToken     string
+	Scopes      []string
+	ExpiresAt   time.Time
+}
+
+// UserInfo holds the standard claims of a user; scopes decide which are
+// set.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	UpdatedAt     int64  `json:"updated_at,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// Configuration is what the discovery document tells clients about the
+// provider besides its endpoints.
+type Configuration struct {
+	Issuer           string
+	SigningAlgorithm string
+	Scopes           []string
+	Claims           []string
+}
+
+// Options tunes lifetimes; zero values use the defaults.
+type Options struct {
+	CodeTTL        time.Duration
+	AccessTokenTTL time.Duration
+	IDTokenTTL     time.Duration
+}
+
+type oidcUseCase struct {
+	repo      *repository.Repository
+	policy    *authzUC.Policy
+	cache     cachePkg.CacheItf
+	keys      *token.KeySet
+	audit     audit.Logger
+	validator *validator.Validator
+	opts      Options
+	now       func() time.Time
+}
+
+// clientInput carries the rules for new clients.
+type clientInput struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+func NewOIDCUseCase(repo *repository.Repository, policy *authzUC.Policy, cache cachePkg.CacheItf, keys *token.KeySet, auditLog audit.Logger, opts Options) OIDCUseCaseItf {
+	if opts.CodeTTL <= 0 {
+		opts.CodeTTL = DefaultCodeTTL
+	}
+	if opts.AccessTokenTTL <= 0 {
+		opts.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if opts.IDTokenTTL <= 0 {
+		opts.IDTokenTTL = DefaultIDTokenTTL
+	}
+	return &oidcUseCase{
+		repo:      repo,
+		policy:    policy,
+		cache:     cache,
+		keys:      keys,
+		audit:     auditLog,
+		validator: validator.New(),
+		opts:      opts,
+		now:       time.Now,
+	}
+}
@@ -0,0 +1,428 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/cache"
+	"solecode/pkg/config"
+	"solecode/pkg/token"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRedirect = "https://app.example.com/callback"
+	testVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc     *oidcUseCase
+	keys   *token.KeySet
+	log    *audit.MemoryLogger
+	admin  context.Context
+	john   context.Context
+	client *entities.OAuthClient
+	secret string
+}
+
+// newTestEnv returns a provider issuing for https://id.example.com over the
+// shared fixture, with john@example.com verified and a confidential
+// client.
+func newTestEnv(t *testing.T) *testEnv {
+	keys, err := token.NewEphemeralKeySet("https://id.example.com")
+	require.NoError(t, err)
+
+	env := &testEnv{
+		Env:   usecasetest.New(t),
+		keys:  keys,
+		log:   audit.NewMemoryLogger(),
+		admin: entities.WithPrincipal(context.Background(), &entities.Principal{UserID: 99, Permissions: []entities.Permission{entities.PermClientsManage}}),
+	}
+	env.john = usecasetest.As(env.User.ID)
+	ctx := context.Background()
+	require.NoError(t, env.Repo.User.MarkVerified(ctx, env.User.ID, env.User.Email, time.Now().Add(-time.Hour)))
+	env.User, err = env.Repo.User.GetByID(ctx, env.User.ID)
+	require.NoError(t, err)
+
+	env.uc = NewOIDCUseCase(env.Repo, authzUC.NewPolicy(audit.NewNopLogger()), env.Cache, keys, env.log, Options{}).(*oidcUseCase)
+	env.uc.now = env.Clock.Now
+
+	env.client, env.secret, err = env.uc.RegisterClient(env.admin, "Wiki", []string{testRedirect}, false)
+	require.NoError(t, err)
+	return env
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (env *testEnv) request(scope string) AuthorizationRequest {
+	return AuthorizationRequest{
+		ClientID:            env.client.ID,
+		RedirectURI:         testRedirect,
+		ResponseType:        "code",
+		Scope:               scope,
+		State:               "xyz",
+		Nonce:               "n-0S6_WzA2Mj",
+		CodeChallenge:       challenge(testVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func (env *testEnv) tokenRequest(code string) TokenRequest {
+	return TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  testRedirect,
+		CodeVerifier: testVerifier,
+		ClientID:     env.client.ID,
+		ClientSecret: env.secret,
+	}
+}
+
+// code runs the authorisation request for scope, consenting if asked.
+func (env *testEnv) code(t *testing.T, scope string) string {
+	authorization, err := env.uc.Authorize(env.john, env.request(scope))
+	require.NoError(t, err)
+	if authorization.ConsentRequired {
+		authorization, err = env.uc.Consent(env.john, env.request(scope), true)
+		require.NoError(t, err)
+	}
+	require.NotEmpty(t, authorization.Code)
+	return authorization.Code
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *Error
+	if assert.ErrorAs(t, err, &oauthErr) {
+		assert.Equal(t, code, oauthErr.Code)
+	}
+}
+
+func TestRegisterClient(t *testing.T) {
+	env := newTestEnv(t)
+	assert.False(t, env.client.Public())
+	assert.NotEmpty(t, env.secret)
+
+	public, secret, err := env.uc.RegisterClient(env.admin, "CLI", []string{"http://127.0.0.1:8400/cb", "http://localhost/cb"}, true)
+	require.NoError(t, err)
+	assert.True(t, public.Public())
+	assert.Empty(t, secret)
+
+	_, _, err = env.uc.RegisterClient(env.john, "Mine", []string{testRedirect}, false)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, _, err = env.uc.RegisterClient(env.admin, "No URIs", nil, false)
+	assert.ErrorIs(t, err, ErrNoRedirectURIs)
+	for _, uri := range []string{"http://app.example.com/cb", "https://app.example.com/cb#x", "/callback", "javascript:alert(1)"} {
+		_, _, err = env.uc.RegisterClient(env.admin, "Bad", []string{uri}, false)
+		assert.ErrorIs(t, err, ErrInvalidRedirectURI, uri)
+	}
+
+	clients, err := env.uc.ListClients(env.admin)
+	require.NoError(t, err)
+	assert.Len(t, clients, 2)
+
+	require.NoError(t, env.uc.DeleteClient(env.admin, public.ID))
+	assert.Equal(t, []string{"oauth.client_register", "oauth.client_register", "oauth.client_delete"}, env.log.Actions())
+}
+
+func TestCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// The first sign-in asks for consent
+	authorization, err := env.uc.Authorize(env.john, env.request("openid email unknown"))
+	require.NoError(t, err)
+	assert.True(t, authorization.ConsentRequired)
+	assert.Empty(t, authorization.Code)
+	assert.Equal(t, []string{ScopeOpenID, ScopeEmail}, authorization.Scopes)
+
+	authorization, err = env.uc.Consent(env.john, env.request("openid email"), true)
+	require.NoError(t, err)
+	tokens, err := env.uc.Exchange(ctx, env.tokenRequest(authorization.Code))
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeOpenID, ScopeEmail}, tokens.Scopes)
+	assert.Equal(t, env.Clock.Now().Add(DefaultAccessTokenTTL), tokens.ExpiresAt)
+
+	// The ID token carries the nonce and the claims of the scopes
+	public, err := base64.RawURLEncoding.DecodeString(env.keys.JWKS().Keys[0].X)
+	require.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, claims, func(*jwt.Token) (interface{}, error) {
+		return ed25519.PublicKey(public), nil
+	}, jwt.WithValidMethods([]string{token.EdDSA}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://id.example.com", claims["iss"])
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, []interface{}{env.client.ID}, claims["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.Equal(t, "john@example.com", claims["email"])
+	assert.Equal(t, true, claims["email_verified"])
+	assert.NotContains(t, claims, "name")
+
+	info, err := env.uc.UserInfo(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1", info.Subject)
+	assert.Equal(t, "john@example.com", info.Email)
+	assert.Empty(t, info.Name)
+
+	// The code works once
+	_, err = env.uc.Exchange(ctx, env.tokenRequest(authorization.Code))
+	assertOAuthError(t, err, CodeInvalidGrant)
+
+	// With consent on record, the next sign-in gets a code right away;
+	// asking for more asks again
+	authorization, err = env.uc.Authorize(env.john, env.request("openid"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, authorization.Code)
+	authorization, err = env.uc.Authorize(env.john, env.request("openid profile"))
+	require.NoError(t, err)
+	assert.True(t, authorization.ConsentRequired)
+
+	// Consent adds up
+	env.code(t, "openid profile")
+	consents, err := env.uc.ListConsents(env.john, env.User.ID)
+	require.NoError(t, err)
+	require.Len(t, consents, 1)
+	assert.Equal(t, []string{ScopeOpenID, ScopeProfile, ScopeEmail}, consents[0].Scopes)
+
+	// Revoking consent cuts tokens off
+	require.NoError(t, env.uc.RevokeConsent(env.john, env.User.ID, env.client.ID))
+	_, err = env.uc.UserInfo(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	assert.Equal(t, []string{"oauth.client_register", "oauth.consent", "oauth.consent", "oauth.consent_revoke"}, env.log.Actions())
+}
+
+// slowCache stretches every read, so that requests racing for an entry
+// overlap.
+type slowCache struct {
+	cache.CacheItf
+}
+
+func (c slowCache) GetJSON(key string, v any) error {
+	err := c.CacheItf.GetJSON(key, v)
+	time.Sleep(10 * time.Millisecond)
+	return err
+}
+
+func TestExchangeConcurrently(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	code := env.code(t, "openid")
+	env.uc.cache = slowCache{env.uc.cache}
+
+	// Racing exchanges of one code yield one set of tokens
+	const callers = 16
+	var wg sync.WaitGroup
+	var exchanged atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.uc.Exchange(ctx, env.tokenRequest(code))
+			if err == nil {
+				exchanged.Add(1)
+			} else {
+				assertOAuthError(t, err, CodeInvalidGrant)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), exchanged.Load())
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name   string
+		modify func(*AuthorizationRequest)
+		err    error
+		code   string
+	}{
+		{"unknown client", func(r *AuthorizationRequest) { r.ClientID = "nope" }, ErrUnknownClient, ""},
+		{"unregistered redirect", func(r *AuthorizationRequest) { r.RedirectURI = "https://evil.example.com/cb" }, ErrRedirectURIMismatch, ""},
+		{"missing redirect", func(r *AuthorizationRequest) { r.RedirectURI = "" }, ErrRedirectURIMismatch, ""},
+		{"implicit flow", func(r *AuthorizationRequest) { r.ResponseType = "token" }, nil, CodeUnsupportedResponseType},
+		{"no openid", func(r *AuthorizationRequest) { r.Scope = "profile" }, nil, CodeInvalidScope},
+		{"no PKCE", func(r *AuthorizationRequest) { r.CodeChallenge, r.CodeChallengeMethod = "", "" }, nil, CodeInvalidRequest},
+		{"plain PKCE", func(r *AuthorizationRequest) { r.CodeChallenge, r.CodeChallengeMethod = testVerifier, "plain" }, nil, CodeInvalidRequest},
+		{"long nonce", func(r *AuthorizationRequest) { r.Nonce = strings.Repeat("n", 256) }, nil, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := env.request("openid")
+			tt.modify(&req)
+			_, err := env.uc.Authorize(env.john, req)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assertOAuthError(t, err, tt.code)
+			}
+		})
+	}
+
+	// Only users sign in, and only as themselves
+	_, err := env.uc.Authorize(context.Background(), env.request("openid"))
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.Authorize(entities.WithPrincipal(context.Background(), entities.SystemPrincipal()), env.request("openid"))
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+
+	_, err = env.uc.Consent(env.john, env.request("openid"), false)
+	assertOAuthError(t, err, CodeAccessDenied)
+}
+
+func TestExchangeErrors(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name   string
+		modify func(*TokenRequest)
+		code   string
+	}{
+		{"grant type", func(r *TokenRequest) { r.GrantType = "password" }, CodeUnsupportedGrantType},
+		{"unknown client", func(r *TokenRequest) { r.ClientID = "nope" }, CodeInvalidClient},
+		{"wrong secret", func(r *TokenRequest) { r.ClientSecret = "guess" }, CodeInvalidClient},
+		{"no secret", func(r *TokenRequest) { r.ClientSecret = "" }, CodeInvalidClient},
+		{"wrong verifier", func(r *TokenRequest) { r.CodeVerifier = strings.Repeat("a", 43) }, CodeInvalidGrant},
+		{"short verifier", func(r *TokenRequest) { r.CodeVerifier = "abc" }, CodeInvalidGrant},
+		{"wrong redirect", func(r *TokenRequest) { r.RedirectURI = "https://app.example.com/other" }, CodeInvalidGrant},
+		{"unknown code", func(r *TokenRequest) { r.Code = "nope" }, CodeInvalidGrant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := env.tokenRequest(env.code(t, "openid"))
+			tt.modify(&req)
+			_, err := env.uc.Exchange(ctx, req)
+			assertOAuthError(t, err, tt.code)
+		})
+	}
+
+	// A failed attempt burns the code
+	code := env.code(t, "openid")
+	req := env.tokenRequest(code)
+	req.CodeVerifier = strings.Repeat("a", 43)
+	_, err := env.uc.Exchange(ctx, req)
+	assertOAuthError(t, err, CodeInvalidGrant)
+	_, err = env.uc.Exchange(ctx, env.tokenRequest(code))
+	assertOAuthError(t, err, CodeInvalidGrant)
+
+	// Codes expire
+	code = env.code(t, "openid")
+	env.Clock.Advance(DefaultCodeTTL)
+	_, err = env.uc.Exchange(ctx, env.tokenRequest(code))
+	assertOAuthError(t, err, CodeInvalidGrant)
+	env.Clock.Set(time.Now())
+
+	// Codes outlive neither the client's consent nor the client
+	code = env.code(t, "openid")
+	require.NoError(t, env.uc.RevokeConsent(env.john, env.User.ID, env.client.ID))
+	_, err = env.uc.Exchange(ctx, env.tokenRequest(code))
+	assertOAuthError(t, err, CodeInvalidGrant)
+	code = env.code(t, "openid")
+	require.NoError(t, env.uc.DeleteClient(env.admin, env.client.ID))
+	_, err = env.uc.Exchange(ctx, env.tokenRequest(code))
+	assertOAuthError(t, err, CodeInvalidClient)
+}
+
+func TestPublicClient(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	client, _, err := env.uc.RegisterClient(env.admin, "CLI", []string{"http://127.0.0.1:8400/cb"}, true)
+	require.NoError(t, err)
+	env.client, env.secret = client, ""
+
+	req := env.request("openid profile")
+	req.RedirectURI = "http://127.0.0.1:8400/cb"
+	_, err = env.uc.Consent(env.john, req, true)
+	require.NoError(t, err)
+	authorization, err := env.uc.Authorize(env.john, req)
+	require.NoError(t, err)
+
+	exchange := env.tokenRequest(authorization.Code)
+	exchange.RedirectURI = req.RedirectURI
+	tokens, err := env.uc.Exchange(ctx, exchange)
+	require.NoError(t, err)
+
+	info, err := env.uc.UserInfo(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", info.Name)
+	assert.Equal(t, env.User.UpdatedAt.Unix(), info.UpdatedAt)
+	assert.Empty(t, info.Email)
+	assert.Nil(t, info.EmailVerified)
+
+	// Public clients have no secret to present
+	authorization, err = env.uc.Authorize(env.john, req)
+	require.NoError(t, err)
+	exchange = env.tokenRequest(authorization.Code)
+	exchange.RedirectURI, exchange.ClientSecret = req.RedirectURI, "secret"
+	_, err = env.uc.Exchange(ctx, exchange)
+	assertOAuthError(t, err, CodeInvalidClient)
+}
+
+func TestUserInfoRejectsOtherTokens(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// Access tokens of the API itself have no audience
+	signed, err := env.keys.Sign(&token.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(env.Clock.Now().Add(time.Minute)),
+	}})
+	require.NoError(t, err)
+	_, err = env.uc.UserInfo(ctx, signed)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = env.uc.UserInfo(ctx, "garbage")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	tokens, err := env.uc.Exchange(ctx, env.tokenRequest(env.code(t, "openid")))
+	require.NoError(t, err)
+	env.Clock.Advance(DefaultAccessTokenTTL + time.Minute)
+	_, err = env.uc.UserInfo(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUnavailable(t *testing.T) {
+	hs256, err := token.NewKeySet(config.AuthConfig{
+		Issuer: "https://id.example.com",
+		Keys:   []config.SigningKeyConfig{{ID: "k1", Algorithm: token.HS256, Secret: strings.Repeat("s", 32)}},
+	})
+	require.NoError(t, err)
+	ephemeral, err := token.NewEphemeralKeySet("")
+	require.NoError(t, err)
+
+	for _, keys := range []*token.KeySet{hs256, ephemeral} {
+		uc := NewOIDCUseCase(repository.NewMemoryRepository(), authzUC.NewPolicy(audit.NewNopLogger()), cache.NewMemoryCache(), keys, audit.NewNopLogger(), Options{})
+		_, err := uc.Configuration()
+		assert.ErrorIs(t, err, ErrUnavailable)
+		_, err = uc.Authorize(context.Background(), AuthorizationRequest{})
+		assert.ErrorIs(t, err, ErrUnavailable)
+	}
+
+	env := newTestEnv(t)
+	cfg, err := env.uc.Configuration()
+	require.NoError(t, err)
+	assert.Equal(t, "https://id.example.com", cfg.Issuer)
+	assert.Equal(t, token.EdDSA, cfg.SigningAlgorithm)
+}
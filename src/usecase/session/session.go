@@ -0,0 +1,466 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	userRepo "solecode/src/repository/user"
+)
+
+// maxUserAgent bounds the user agent kept with a session.
+const maxUserAgent = 256
+
+// Sessions live in the cache under the hash of their token, so that a
+// leaked cache does not leak usable sessions. An index per user maps
+// session IDs to those hashes for listing and revoking. The index is
+// updated read-modify-write like the other cache records; a session lost
+// from it by concurrent logins is added back the next time it is used.
+func sessionKey(hash string) string {
+	return "session:" + hash
+}
+
+func indexKey(userID int64) string {
+	return "user_sessions:" + strconv.FormatInt(userID, 10)
+}
+
+// generationKey caches the sign-in generation of a user, which is kept
+// with the user row since refresh tokens issued under it outlive both
+// sessions and cache entries. Entries expire after generationTTL, so that
+// one a racing read filled with a replaced generation does not last.
+func generationKey(userID int64) string {
+	return "signin_generation:" + strconv.FormatInt(userID, 10)
+}
+
+const generationTTL = time.Minute
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// privileges fingerprints the roles and permissions of principal.
+func privileges(principal *entities.Principal) string {
+	roles := slices.Clone(principal.Roles)
+	slices.Sort(roles)
+	permissions := make([]string, len(principal.Permissions))
+	for i, permission := range principal.Permissions {
+		permissions[i] = string(permission)
+	}
+	slices.Sort(permissions)
+	sum := sha256.Sum256([]byte(strings.Join(roles, ",") + "|" + strings.Join(permissions, ",")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (uc *sessionUseCase) Create(ctx context.Context, userID int64, client Client) (*entities.Session, error) {
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	principal := &entities.Principal{UserID: user.ID}
+	if err := uc.authz.LoadPermissions(ctx, principal); err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	generation, err := uc.Generation(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	session := &entities.Session{
+		ID:         id,
+		UserID:     user.ID,
+		Email:      user.Email,
+		CSRFToken:  csrf,
+		IP:         client.IP,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(uc.opts.AbsoluteTimeout),
+		Privileges: privileges(principal),
+		Generation: generation,
+	}
+	if err := uc.issue(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (uc *sessionUseCase) Authenticate(ctx context.Context, token string) (*entities.Principal, *entities.Session, error) {
+	session, hash, err := uc.load(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Setting the password or deleting the user ends their sessions
+	generation, err := uc.Generation(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Generation != generation {
+		return nil, nil, ErrInvalidSession
+	}
+
+	principal := &entities.Principal{
+		UserID:    session.UserID,
+		Email:     session.Email,
+		TokenID:   session.ID,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := uc.authz.LoadPermissions(ctx, principal); err != nil {
+		return nil, nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	// A new token for new privileges, so a token planted or seen before
+	// does not carry them
+	if fingerprint := privileges(principal); fingerprint != session.Privileges {
+		session.Privileges = fingerprint
+		if err := uc.rotate(session, hash); err != nil {
+			return nil, nil, err
+		}
+		return principal, session, nil
+	}
+
+	if uc.now().Sub(session.LastSeenAt) >= min(touchInterval, uc.opts.IdleTimeout/2) {
+		session.LastSeenAt = uc.now()
+		if err := uc.store(hash, session); err != nil {
+			return nil, nil, err
+		}
+		if err := uc.updateIndex(session.UserID, func(index map[string]string) { index[session.ID] = hash }); err != nil {
+			return nil, nil, err
+		}
+	}
+	return principal, session, nil
+}
+
+func (uc *sessionUseCase) Rotate(ctx context.Context, token string) (*entities.Session, error) {
+	session, hash, err := uc.load(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.rotate(session, hash); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (uc *sessionUseCase) Revoke(ctx context.Context, token string) error {
+	session, hash, err := uc.load(token)
+	if errors.Is(err, ErrInvalidSession) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return uc.remove(session.UserID, session.ID, hash)
+}
+
+func (uc *sessionUseCase) List(ctx context.Context, userID int64) ([]*entities.Session, error) {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	sessions, _, err := uc.live(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.CSRFToken = ""
+	}
+	slices.SortFunc(sessions, func(a, b *entities.Session) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (uc *sessionUseCase) RevokeSession(ctx context.Context, userID int64, id string) error {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+	_, hashes, err := uc.live(userID)
+	if err != nil {
+		return err
+	}
+	hash, ok := hashes[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if err := uc.remove(userID, id, hash); err != nil {
+		return err
+	}
+
+	uc.log(ctx, "session.revoke", userID, "session "+id)
+	return nil
+}
+
+func (uc *sessionUseCase) RevokeAll(ctx context.Context, userID int64) (int, error) {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return 0, err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return 0, err
+	}
+	_, hashes, err := uc.live(userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, hash := range hashes {
+		if err := uc.cache.Delete(sessionKey(hash)); err != nil {
+			return 0, fmt.Errorf("failed to revoke session: %w", err)
+		}
+	}
+	if err := uc.cache.Delete(indexKey(userID)); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	uc.log(ctx, "session.revoke_all", userID, fmt.Sprintf("%d sessions", len(hashes)))
+	return len(hashes), nil
+}
+
+func (uc *sessionUseCase) Generation(ctx context.Context, userID int64) (string, error) {
+	val, err := uc.cache.Get(generationKey(userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to read sign-in generation: %w", err)
+	}
+	if generation, ok := val.(string); ok {
+		return generation, nil
+	}
+
+	generation, err := uc.repo.User.SignInGeneration(ctx, userID)
+	if errors.Is(err, userRepo.ErrUserNotFound) {
+		// Nobody to sign in; checks of the user fail on their own
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read sign-in generation: %w", err)
+	}
+	if err := uc.cache.Set(generationKey(userID), generation, generationTTL); err != nil {
+		return "", fmt.Errorf("failed to cache sign-in generation: %w", err)
+	}
+	return generation, nil
+}
+
+func (uc *sessionUseCase) EndSignIns(ctx context.Context, userID int64, keepID string) error {
+	generation, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	// The new generation goes first, so that sessions missing from the
+	// index end too
+	if err := uc.repo.User.SetSignInGeneration(ctx, userID, generation); err != nil {
+		return fmt.Errorf("failed to store sign-in generation: %w", err)
+	}
+	if err := uc.cache.Set(generationKey(userID), generation, generationTTL); err != nil {
+		return fmt.Errorf("failed to cache sign-in generation: %w", err)
+	}
+
+	sessions, hashes, err := uc.live(userID)
+	if err != nil {
+		return err
+	}
+	kept := map[string]string{}
+	for _, session := range sessions {
+		hash := hashes[session.ID]
+		if session.ID == keepID {
+			session.Generation = generation
+			if err := uc.store(hash, session); err != nil {
+				return err
+			}
+			kept[session.ID] = hash
+			continue
+		}
+		if err := uc.cache.Delete(sessionKey(hash)); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+	}
+	err = uc.updateIndex(userID, func(index map[string]string) {
+		for id := range index {
+			if _, ok := kept[id]; !ok {
+				delete(index, id)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log(ctx, "session.revoke_all", userID, fmt.Sprintf("%d sessions", len(sessions)-len(kept)))
+	return nil
+}
+
+// load returns the live session of token and the hash it is stored
+// under.
+func (uc *sessionUseCase) load(token string) (*entities.Session, string, error) {
+	if token == "" {
+		return nil, "", ErrInvalidSession
+	}
+	hash := hashToken(token)
+	var session entities.Session
+	if err := uc.cache.GetJSON(sessionKey(hash), &session); err != nil {
+		return nil, "", fmt.Errorf("failed to read session: %w", err)
+	}
+	if !uc.alive(&session) {
+		return nil, "", ErrInvalidSession
+	}
+	return &session, hash, nil
+}
+
+// alive reports whether session has neither idled out nor expired; the
+// cache would drop it soon anyway.
+func (uc *sessionUseCase) alive(session *entities.Session) bool {
+	now := uc.now()
+	return session.UserID != 0 && now.Before(session.ExpiresAt) &&
+		now.Sub(session.LastSeenAt) < uc.opts.IdleTimeout
+}
+
+// live returns the live sessions of a user and their hashes by ID,
+// dropping dead ones from the index.
+func (uc *sessionUseCase) live(userID int64) ([]*entities.Session, map[string]string, error) {
+	index, err := uc.index(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sessions []*entities.Session
+	var dead []string
+	for id, hash := range index {
+		var session entities.Session
+		if err := uc.cache.GetJSON(sessionKey(hash), &session); err != nil {
+			return nil, nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		if !uc.alive(&session) || session.ID != id {
+			dead = append(dead, id)
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if len(dead) > 0 {
+		err := uc.updateIndex(userID, func(index map[string]string) {
+			for _, id := range dead {
+				delete(index, id)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range dead {
+			delete(index, id)
+		}
+	}
+	return sessions, index, nil
+}
+
+// issue gives session a new token and stores it. The CSRF token stays
+// for the life of the session, so that requests already sent with it
+// pass.
+func (uc *sessionUseCase) issue(session *entities.Session) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	session.Token = token
+
+	hash := hashToken(token)
+	if err := uc.store(hash, session); err != nil {
+		return err
+	}
+	return uc.updateIndex(session.UserID, func(index map[string]string) { index[session.ID] = hash })
+}
+
+// rotate moves session from the token hashed as oldHash to a new one.
+func (uc *sessionUseCase) rotate(session *entities.Session, oldHash string) error {
+	if err := uc.cache.Delete(sessionKey(oldHash)); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	session.LastSeenAt = uc.now()
+	return uc.issue(session)
+}
+
+// store saves session until it would idle out or expire.
+func (uc *sessionUseCase) store(hash string, session *entities.Session) error {
+	ttl := min(uc.opts.IdleTimeout, session.ExpiresAt.Sub(uc.now()))
+	if ttl <= 0 {
+		return ErrInvalidSession
+	}
+	if err := uc.cache.SetJSON(sessionKey(hash), session, ttl); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// remove deletes a session and its index entry.
+func (uc *sessionUseCase) remove(userID int64, id, hash string) error {
+	if err := uc.cache.Delete(sessionKey(hash)); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return uc.updateIndex(userID, func(index map[string]string) { delete(index, id) })
+}
+
+// index returns the session IDs of a user mapped to their token hashes.
+func (uc *sessionUseCase) index(userID int64) (map[string]string, error) {
+	index := map[string]string{}
+	if err := uc.cache.GetJSON(indexKey(userID), &index); err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	return index, nil
+}
+
+// updateIndex applies fn to the index of a user. Every session ends
+// within AbsoluteTimeout from now, and so does the index.
+func (uc *sessionUseCase) updateIndex(userID int64, fn func(index map[string]string)) error {
+	index, err := uc.index(userID)
+	if err != nil {
+		return err
+	}
+	fn(index)
+	if len(index) == 0 {
+		err = uc.cache.Delete(indexKey(userID))
+	} else {
+		err = uc.cache.SetJSON(indexKey(userID), index, uc.opts.AbsoluteTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update sessions: %w", err)
+	}
+	return nil
+}
+
+// log audits a change to the sessions of userID.
+func (uc *sessionUseCase) log(ctx context.Context, action string, userID int64, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, TargetID: userID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	cachePkg "solecode/pkg/cache"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultIdleTimeout     = 30 * time.Minute
+	DefaultAbsoluteTimeout = 12 * time.Hour
+)
+
+// touchInterval is how stale LastSeenAt may get before a request records
+// activity; sessions in steady use write once per interval.
+const touchInterval = time.Minute
+
+var (
+	// ErrInvalidSession is returned for session tokens that are unknown,
+	// idle for too long, expired or revoked.
+	ErrInvalidSession  = errors.New("invalid or expired session")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+//go:generate mockery --name SessionUseCaseItf --output mocks --filename sessionusecase_mock.go --outpkg mocks
+type SessionUseCaseItf interface {
+	// Create starts a session for a user who has just logged in. The
+	// returned session carries the token for the cookie.
+	Create(ctx context.Context, userID int64, client Client) (*entities.Session, error)
+	// Authenticate returns the principal and session of a token and keeps
+	// the session alive. When the roles or permissions of the user changed
+	// since the session was issued, it is rotated first: the returned
+	// session then carries a new Token for the client, and the old token
+	// stops working. Otherwise its Token is empty. Sessions end when the
+	// password of their user is set or the user is deleted.
+	Authenticate(ctx context.Context, token string) (*entities.Principal, *entities.Session, error)
+	// Rotate replaces the token of a session.
+	Rotate(ctx context.Context, token string) (*entities.Session, error)
+	// Revoke ends the session of token, as on logout. Unknown tokens are
+	// ignored.
+	Revoke(ctx context.Context, token string) error
+
+	// List returns the live sessions of a user, most recently active
+	// first, without their CSRF tokens.
+	List(ctx context.Context, userID int64) ([]*entities.Session, error)
+	// RevokeSession ends one session of a user, named by its ID.
+	RevokeSession(ctx context.Context, userID int64, id string) error
+	// RevokeAll ends every session of a user and returns how many there
+	// were.
+	RevokeAll(ctx context.Context, userID int64) (int, error)
+
+	// Generation returns the sign-in generation of a user. Sessions and
+	// refresh tokens are issued under the current generation and stop
+	// working once it changes.
+	Generation(ctx context.Context, userID int64) (string, error)
+	// EndSignIns starts a new generation for a user and ends every session
+	// of theirs but the one named keepID, which moves to the new
+	// generation. It is not authorised: use cases call it after changing
+	// the password of the user or deleting them.
+	EndSignIns(ctx context.Context, userID int64, keepID string) error
+}
+
+// Client describes where a session was started from, for listings.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// Options tunes session lifetimes; zero values use the defaults.
+type Options struct {
+	// IdleTimeout ends sessions without requests for that long.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends sessions that long after the login.
+	AbsoluteTimeout time.Duration
+}
+
+type sessionUseCase struct {
+	repo   *repository.Repository
+	authz  authzUC.AuthzUseCaseItf
+	policy *authzUC.Policy
+	cache  cachePkg.CacheItf
+	audit  audit.Logger
+	opts   Options
+	now    func() time.Time
+}
+
+func NewSessionUseCase(repo *repository.Repository, authz authzUC.AuthzUseCaseItf, policy *authzUC.Policy, cache cachePkg.CacheItf, auditLog audit.Logger, opts Options) SessionUseCaseItf {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.AbsoluteTimeout <= 0 {
+		opts.AbsoluteTimeout = DefaultAbsoluteTimeout
+	}
+	return &sessionUseCase{
+		repo:   repo,
+		authz:  authz,
+		policy: policy,
+		cache:  cache,
+		audit:  auditLog,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
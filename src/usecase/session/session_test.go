@@ -0,0 +1,234 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc    *sessionUseCase
+	authz authzUC.AuthzUseCaseItf
+	audit *bytes.Buffer
+}
+
+// newTestEnv returns a session use case over the shared fixture.
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{Env: usecasetest.New(t), audit: &bytes.Buffer{}}
+	policy := authzUC.NewPolicy(audit.NewNopLogger())
+	env.authz = authzUC.NewAuthzUseCase(env.Repo, env.Cache, policy, audit.NewNopLogger())
+	env.uc = NewSessionUseCase(env.Repo, env.authz, policy, env.Cache, audit.NewJSONLogger(env.audit), Options{
+		IdleTimeout:     10 * time.Minute,
+		AbsoluteTimeout: time.Hour,
+	}).(*sessionUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+func (env *testEnv) create(t *testing.T) *entities.Session {
+	session, err := env.uc.Create(context.Background(), env.User.ID, Client{IP: "192.0.2.1", UserAgent: "Firefox"})
+	require.NoError(t, err)
+	return session
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	session := env.create(t)
+	assert.NotEmpty(t, session.Token)
+	assert.NotEmpty(t, session.CSRFToken)
+	assert.Equal(t, env.Clock.Now().Add(time.Hour), session.ExpiresAt)
+
+	principal, current, err := env.uc.Authenticate(ctx, session.Token)
+	require.NoError(t, err)
+	assert.Equal(t, env.User.ID, principal.UserID)
+	assert.Equal(t, "john@example.com", principal.Email)
+	assert.Equal(t, session.ID, principal.TokenID)
+	assert.Equal(t, session.CSRFToken, current.CSRFToken)
+	// Not rotated, so no new token
+	assert.Empty(t, current.Token)
+
+	_, _, err = env.uc.Authenticate(ctx, session.ID)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, _, err = env.uc.Authenticate(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestTimeouts(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// Requests keep a session alive until its absolute timeout
+	session := env.create(t)
+	for i := 0; i < 6; i++ {
+		env.Clock.Advance(9 * time.Minute)
+		_, _, err := env.uc.Authenticate(ctx, session.Token)
+		require.NoError(t, err)
+	}
+	env.Clock.Advance(6 * time.Minute)
+	_, _, err := env.uc.Authenticate(ctx, session.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	// Idle sessions end early
+	idle := env.create(t)
+	env.Clock.Advance(10 * time.Minute)
+	_, _, err = env.uc.Authenticate(ctx, idle.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestRotationOnPrivilegeChange(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	session := env.create(t)
+
+	_, err := env.authz.GrantRole(entities.WithPrincipal(ctx, entities.SystemPrincipal()), env.User.ID, entities.RoleAdmin)
+	require.NoError(t, err)
+
+	principal, rotated, err := env.uc.Authenticate(ctx, session.Token)
+	require.NoError(t, err)
+	assert.True(t, principal.Has(entities.PermUsersDelete))
+	assert.Equal(t, session.ID, rotated.ID)
+	assert.NotEqual(t, session.Token, rotated.Token)
+	assert.Equal(t, session.CSRFToken, rotated.CSRFToken)
+
+	_, _, err = env.uc.Authenticate(ctx, session.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, current, err := env.uc.Authenticate(ctx, rotated.Token)
+	require.NoError(t, err)
+	assert.Empty(t, current.Token)
+
+	// Explicit rotations work the same way
+	again, err := env.uc.Rotate(ctx, rotated.Token)
+	require.NoError(t, err)
+	_, _, err = env.uc.Authenticate(ctx, rotated.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, _, err = env.uc.Authenticate(ctx, again.Token)
+	assert.NoError(t, err)
+
+	sessions, err := env.uc.List(entities.WithPrincipal(ctx, principal), env.User.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestListAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	self := entities.WithPrincipal(ctx, &entities.Principal{UserID: env.User.ID})
+
+	first := env.create(t)
+	env.Clock.Advance(time.Minute)
+	second := env.create(t)
+
+	sessions, err := env.uc.List(self, env.User.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, second.ID, sessions[0].ID)
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+	assert.Empty(t, sessions[0].CSRFToken)
+	assert.Empty(t, sessions[0].Token)
+
+	// Logging out ends one session
+	require.NoError(t, env.uc.Revoke(ctx, first.Token))
+	require.NoError(t, env.uc.Revoke(ctx, first.Token))
+	sessions, err = env.uc.List(self, env.User.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	third := env.create(t)
+	assert.ErrorIs(t, env.uc.RevokeSession(self, env.User.ID, first.ID), ErrSessionNotFound)
+	require.NoError(t, env.uc.RevokeSession(self, env.User.ID, second.ID))
+	_, _, err = env.uc.Authenticate(ctx, second.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, _, err = env.uc.Authenticate(ctx, third.Token)
+	assert.NoError(t, err)
+	assert.Contains(t, env.audit.String(), `"action":"session.revoke"`)
+
+	env.create(t)
+	count, err := env.uc.RevokeAll(self, env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	_, _, err = env.uc.Authenticate(ctx, third.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	sessions, err = env.uc.List(self, env.User.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestEndSignIns(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	kept, ended := env.create(t), env.create(t)
+	before, err := env.uc.Generation(ctx, env.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, env.uc.EndSignIns(ctx, env.User.ID, kept.ID))
+
+	after, err := env.uc.Generation(ctx, env.User.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+	_, _, err = env.uc.Authenticate(ctx, ended.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, current, err := env.uc.Authenticate(ctx, kept.Token)
+	require.NoError(t, err)
+	assert.Equal(t, after, current.Generation)
+
+	// Sessions missing from the index end too
+	missed := env.create(t)
+	require.NoError(t, env.uc.cache.Delete(indexKey(env.User.ID)))
+	require.NoError(t, env.uc.EndSignIns(ctx, env.User.ID, ""))
+	for _, session := range []*entities.Session{kept, missed} {
+		_, _, err = env.uc.Authenticate(ctx, session.Token)
+		assert.ErrorIs(t, err, ErrInvalidSession)
+	}
+}
+
+func TestGenerationOutlivesTheCache(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// A session that escaped the sweep, such as one written back by a
+	// racing request, still ends once the cached generation is gone
+	old := env.create(t)
+	require.NoError(t, env.uc.EndSignIns(ctx, env.User.ID, ""))
+	after, err := env.uc.Generation(ctx, env.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, env.uc.store(hashToken(old.Token), old))
+	require.NoError(t, env.uc.cache.Delete(generationKey(env.User.ID)))
+
+	generation, err := env.uc.Generation(ctx, env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, after, generation)
+	_, _, err = env.uc.Authenticate(ctx, old.Token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestManagingOthersSessionsRequiresPermission(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	session := env.create(t)
+
+	other := entities.WithPrincipal(ctx, &entities.Principal{UserID: 9})
+	_, err := env.uc.List(other, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	assert.ErrorIs(t, env.uc.RevokeSession(other, env.User.ID, session.ID), authzUC.ErrForbidden)
+	_, err = env.uc.RevokeAll(other, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+
+	admin := entities.WithPrincipal(ctx, &entities.Principal{UserID: 9, Permissions: []entities.Permission{entities.PermUsersUpdate}})
+	sessions, err := env.uc.List(admin, env.User.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	require.NoError(t, env.uc.RevokeSession(admin, env.User.ID, session.ID))
+	assert.Contains(t, env.audit.String(), `"actor_id":9`)
+}
@@ -0,0 +1,352 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/totp"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	totpRepository "solecode/src/repository/totp"
+)
+
+// recoveryCodeLen is the length of a recovery code without its dash: ten
+// base32 characters carry 50 random bits, plenty for a code that works
+// once and sits behind the login lockout.
+const recoveryCodeLen = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// secretData binds a sealed secret to its user, so that copying it to
+// another user's row does not make it open there.
+func secretData(userID int64) []byte {
+	return []byte("totp:" + strconv.FormatInt(userID, 10))
+}
+
+// normalizeCode drops the spaces and dashes people type codes with.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hashRecoveryCode hashes a normalised code. Codes are random enough that
+// a fast hash does not make guessing them feasible.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns n codes formatted as "xxxxx-xxxxx" and
+// their hashes.
+func generateRecoveryCodes(n int) (codes, hashes []string, err error) {
+	raw := make([]byte, 7)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(raw))[:recoveryCodeLen]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func (uc *twoFactorUseCase) Enroll(ctx context.Context, userID int64) (*Enrollment, error) {
+	if err := uc.policy.AuthorizeSelf(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	if uc.box == nil {
+		return nil, ErrUnavailable
+	}
+	existing, err := uc.repo.TOTP.Get(ctx, userID)
+	if err == nil && existing.Enabled() {
+		return nil, ErrAlreadyEnabled
+	}
+	if err != nil && !errors.Is(err, totpRepository.ErrTOTPNotFound) {
+		return nil, err
+	}
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := uc.box.Seal(secret, secretData(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if err := tx.TOTP.Delete(ctx, userID); err != nil {
+			return err
+		}
+		return tx.TOTP.Create(ctx, &entities.TOTP{UserID: userID, Secret: sealed})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(uc.opts.Issuer, user.Email, secret),
+	}, nil
+}
+
+func (uc *twoFactorUseCase) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := uc.policy.AuthorizeSelf(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	enrolment, err := uc.enrolment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrolment.Enabled() {
+		return nil, ErrAlreadyEnabled
+	}
+	secret, err := uc.open(enrolment)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, normalizeCode(code), uc.now(), uc.opts.Skew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes(uc.opts.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		if err := tx.TOTP.Confirm(ctx, userID, step, uc.now()); err != nil {
+			return err
+		}
+		return tx.TOTP.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if errors.Is(err, totpRepository.ErrTOTPNotFound) {
+		// Confirmed or reset meanwhile
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log(ctx, "two_factor.enable", userID, "")
+	return codes, nil
+}
+
+func (uc *twoFactorUseCase) Disable(ctx context.Context, userID int64, code string) error {
+	if err := uc.policy.AuthorizeSelf(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return err
+	}
+	if err := uc.verifyThrottled(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := uc.repo.TOTP.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.log(ctx, "two_factor.disable", userID, "")
+	return nil
+}
+
+func (uc *twoFactorUseCase) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := uc.policy.AuthorizeSelf(ctx, userID, entities.PermUsersUpdate); err != nil {
+		return nil, err
+	}
+	if err := uc.verifyThrottled(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes(uc.opts.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.TOTP.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	uc.log(ctx, "two_factor.recovery_codes", userID, "")
+	return codes, nil
+}
+
+// verifyThrottled is Verify for a user proving they hold their second
+// factor, counting wrong codes towards the lockout of their account the
+// way failed logins do.
+func (uc *twoFactorUseCase) verifyThrottled(ctx context.Context, userID int64, code string) error {
+	user, err := uc.repo.User.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.lockout.Check(ctx, user.Email); err != nil {
+		return err
+	}
+	err = uc.Verify(ctx, userID, code)
+	if errors.Is(err, ErrInvalidCode) {
+		if err := uc.lockout.Failure(ctx, user.Email); err != nil {
+			return err
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return uc.lockout.Success(ctx, user.Email)
+}
+
+func (uc *twoFactorUseCase) Reset(ctx context.Context, userID int64) error {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersTwoFactor); err != nil {
+		return err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := uc.enrolment(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.repo.TOTP.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.log(ctx, "two_factor.reset", userID, "")
+	return nil
+}
+
+func (uc *twoFactorUseCase) Status(ctx context.Context, userID int64) (*Status, error) {
+	if err := uc.policy.Authorize(ctx, userID, entities.PermUsersUpdate, entities.PermUsersTwoFactor); err != nil {
+		return nil, err
+	}
+	if _, err := uc.repo.User.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	enrolment, err := uc.repo.TOTP.Get(ctx, userID)
+	if errors.Is(err, totpRepository.ErrTOTPNotFound) {
+		return &Status{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Enabled: enrolment.Enabled(), Pending: !enrolment.Enabled()}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = uc.repo.TOTP.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (uc *twoFactorUseCase) Enabled(ctx context.Context, userID int64) (bool, error) {
+	enrolment, err := uc.repo.TOTP.Get(ctx, userID)
+	if errors.Is(err, totpRepository.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrolment.Enabled(), nil
+}
+
+func (uc *twoFactorUseCase) Verify(ctx context.Context, userID int64, code string) error {
+	enrolment, err := uc.enrolment(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrolment.Enabled() {
+		return ErrNotEnrolled
+	}
+
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		return uc.useRecoveryCode(ctx, userID, code)
+	}
+	secret, err := uc.open(enrolment)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, uc.now(), uc.opts.Skew)
+	if !ok {
+		return ErrInvalidCode
+	}
+	// Each step's code works once, even for concurrent logins
+	advanced, err := uc.repo.TOTP.AdvanceStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (uc *twoFactorUseCase) useRecoveryCode(ctx context.Context, userID int64, code string) error {
+	if len(code) != recoveryCodeLen {
+		return ErrInvalidCode
+	}
+	used, err := uc.repo.TOTP.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), uc.now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+
+	left, err := uc.repo.TOTP.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	uc.log(ctx, "two_factor.recovery_code_used", userID, fmt.Sprintf("%d recovery codes left", left))
+	return nil
+}
+
+// enrolment returns the enrolment of a user, confirmed or not.
+func (uc *twoFactorUseCase) enrolment(ctx context.Context, userID int64) (*entities.TOTP, error) {
+	enrolment, err := uc.repo.TOTP.Get(ctx, userID)
+	if errors.Is(err, totpRepository.ErrTOTPNotFound) {
+		return nil, ErrNotEnrolled
+	}
+	return enrolment, err
+}
+
+// open decrypts the secret of an enrolment.
+func (uc *twoFactorUseCase) open(enrolment *entities.TOTP) ([]byte, error) {
+	if uc.box == nil {
+		return nil, ErrUnavailable
+	}
+	secret, err := uc.box.Open(enrolment.Secret, secretData(enrolment.UserID))
+	if err != nil {
+		// Most likely the encryption key changed
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+	return secret, nil
+}
+
+// log audits a change to the two-factor state of userID. Without a
+// principal, during logins, the user acts on their own account.
+func (uc *twoFactorUseCase) log(ctx context.Context, action string, userID int64, detail string) {
+	event := audit.Event{Action: action, Outcome: audit.Success, ActorID: userID, TargetID: userID, Detail: detail}
+	if principal, ok := entities.PrincipalFrom(ctx); ok {
+		event.ActorID, event.System = principal.UserID, principal.System
+	}
+	uc.audit.Log(ctx, event)
+}
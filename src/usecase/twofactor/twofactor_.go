@@ -0,0 +1,120 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/secretbox"
+	"solecode/src/repository"
+	authzUC "solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+)
+
+// Defaults for zero Options.
+const (
+	DefaultIssuer        = "userapi"
+	DefaultSkew          = 1
+	DefaultRecoveryCodes = 10
+)
+
+var (
+	// ErrUnavailable is returned for enrolments while no encryption key
+	// is configured to store secrets with.
+	ErrUnavailable    = errors.New("two-factor authentication is not configured")
+	ErrAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrNotEnrolled    = errors.New("two-factor authentication not enrolled")
+	ErrInvalidCode    = errors.New("invalid two-factor code")
+	ErrInvalidSecret  = errors.New("stored two-factor secret cannot be decrypted")
+)
+
+//go:generate mockery --name TwoFactorUseCaseItf --output mocks --filename twofactorusecase_mock.go --outpkg mocks
+type TwoFactorUseCaseItf interface {
+	// Enroll starts enrolling the caller's own account with a new secret,
+	// replacing an unconfirmed enrolment. Only the owner may enrol, as
+	// whoever sees the secret can produce codes.
+	Enroll(ctx context.Context, userID int64) (*Enrollment, error)
+	// Confirm enables an enrolment with a code from the app and returns
+	// the recovery codes, which are shown this once.
+	Confirm(ctx context.Context, userID int64, code string) ([]string, error)
+	// Disable turns two-factor authentication of the caller's own account
+	// off. It takes a current code or a recovery code, so a stolen access
+	// token is not enough. Wrong codes count towards the lockout of the
+	// account like failed logins, and a locked account fails with a
+	// *lockoutUC.ThrottledError; so do those of RegenerateRecoveryCodes.
+	Disable(ctx context.Context, userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of the caller's
+	// own account, given a current code or an unused recovery code.
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error)
+	// Reset turns two-factor authentication of a user off for an admin,
+	// for users who lost both their app and their recovery codes.
+	Reset(ctx context.Context, userID int64) error
+	Status(ctx context.Context, userID int64) (*Status, error)
+
+	// Enabled reports whether logins of a user need a second factor.
+	// Verify checks a code or a recovery code of a user; it fails with
+	// ErrInvalidCode for wrong, reused and spent codes. Both are for the
+	// login flow, which has no principal yet, and authorise nothing.
+	Enabled(ctx context.Context, userID int64) (bool, error)
+	Verify(ctx context.Context, userID int64, code string) error
+}
+
+// Enrollment is what a user needs to set up their app.
+type Enrollment struct {
+	// Secret is the base32 secret for typing in.
+	Secret string
+	// URI is the otpauth:// URI for showing as a QR code.
+	URI string
+}
+
+// Status is the two-factor state of a user.
+type Status struct {
+	Enabled bool
+	// Pending is set between Enroll and Confirm.
+	Pending           bool
+	RecoveryCodesLeft int
+}
+
+// Options tunes enrolments; zero values use the defaults.
+type Options struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// Skew is how many 30 second steps codes may be off either way.
+	Skew int
+	// RecoveryCodes is how many recovery codes a user gets.
+	RecoveryCodes int
+}
+
+type twoFactorUseCase struct {
+	repo    *repository.Repository
+	box     *secretbox.Box
+	policy  *authzUC.Policy
+	lockout lockoutUC.LockoutUseCaseItf
+	audit   audit.Logger
+	opts    Options
+	now     func() time.Time
+}
+
+// NewTwoFactorUseCase returns the use case; box encrypts secrets at rest.
+// Without a box, enrolling fails with ErrUnavailable.
+func NewTwoFactorUseCase(repo *repository.Repository, box *secretbox.Box, policy *authzUC.Policy, lockout lockoutUC.LockoutUseCaseItf, auditLog audit.Logger, opts Options) TwoFactorUseCaseItf {
+	if opts.Issuer == "" {
+		opts.Issuer = DefaultIssuer
+	}
+	if opts.Skew <= 0 {
+		opts.Skew = DefaultSkew
+	}
+	if opts.RecoveryCodes <= 0 {
+		opts.RecoveryCodes = DefaultRecoveryCodes
+	}
+	return &twoFactorUseCase{
+		repo:    repo,
+		box:     box,
+		policy:  policy,
+		lockout: lockout,
+		audit:   auditLog,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
@@ -0,0 +1,258 @@
+package twofactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/secretbox"
+	"solecode/pkg/totp"
+	"solecode/src/entities"
+	authzUC "solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+	"solecode/src/usecase/usecasetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	*usecasetest.Env
+	uc    *twoFactorUseCase
+	audit *bytes.Buffer
+	self  context.Context
+}
+
+// newTestEnv returns a two-factor use case over the shared fixture, with
+// the clock at a fixed time.
+func newTestEnv(t *testing.T) *testEnv {
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+
+	env := &testEnv{Env: usecasetest.New(t), audit: &bytes.Buffer{}}
+	env.self = usecasetest.As(env.User.ID)
+	env.Clock.Set(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	policy := authzUC.NewPolicy(audit.NewNopLogger())
+	lockout := lockoutUC.NewLockoutUseCase(env.Repo, env.Cache, policy, audit.NewNopLogger(), lockoutUC.Options{})
+	env.uc = NewTwoFactorUseCase(env.Repo, box, policy, lockout, audit.NewJSONLogger(env.audit), Options{
+		Issuer:        "Example",
+		RecoveryCodes: 3,
+	}).(*twoFactorUseCase)
+	env.uc.now = env.Clock.Now
+	return env
+}
+
+// code returns the code of secret at the current test time, offset by
+// steps.
+func (env *testEnv) code(t *testing.T, secret string, steps int64) string {
+	raw, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	return totp.Code(raw, totp.Step(env.Clock.Now())+steps)
+}
+
+// enable enrols and confirms the test user, returning the secret and the
+// recovery codes.
+func (env *testEnv) enable(t *testing.T) (string, []string) {
+	enrollment, err := env.uc.Enroll(env.self, env.User.ID)
+	require.NoError(t, err)
+	codes, err := env.uc.Confirm(env.self, env.User.ID, env.code(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	env.Clock.Advance(totp.Period)
+	return enrollment.Secret, codes
+}
+
+func TestEnrollAndConfirm(t *testing.T) {
+	env := newTestEnv(t)
+
+	enrollment, err := env.uc.Enroll(env.self, env.User.ID)
+	require.NoError(t, err)
+	assert.Len(t, enrollment.Secret, 32)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Example:john@example.com?"))
+
+	// Pending enrolments do not affect logins
+	enabled, err := env.uc.Enabled(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	status, err := env.uc.Status(env.self, env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Pending: true}, status)
+
+	// The secret is stored encrypted
+	stored, err := env.Repo.TOTP.Get(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, stored.Secret, enrollment.Secret)
+
+	_, err = env.uc.Confirm(env.self, env.User.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	codes, err := env.uc.Confirm(env.self, env.User.ID, env.code(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	assert.Len(t, codes, 3)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+
+	enabled, err = env.uc.Enabled(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	status, err = env.uc.Status(env.self, env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Enabled: true, RecoveryCodesLeft: 3}, status)
+	assert.Contains(t, env.audit.String(), `"action":"two_factor.enable"`)
+
+	_, err = env.uc.Enroll(env.self, env.User.ID)
+	assert.ErrorIs(t, err, ErrAlreadyEnabled)
+	_, err = env.uc.Confirm(env.self, env.User.ID, env.code(t, enrollment.Secret, 0))
+	assert.ErrorIs(t, err, ErrAlreadyEnabled)
+}
+
+func TestReenrollReplacesPendingSecret(t *testing.T) {
+	env := newTestEnv(t)
+
+	first, err := env.uc.Enroll(env.self, env.User.ID)
+	require.NoError(t, err)
+	second, err := env.uc.Enroll(env.self, env.User.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Secret, second.Secret)
+
+	_, err = env.uc.Confirm(env.self, env.User.ID, env.code(t, first.Secret, 0))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	_, err = env.uc.Confirm(env.self, env.User.ID, env.code(t, second.Secret, 0))
+	assert.NoError(t, err)
+}
+
+func TestVerifyCodes(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	secret, _ := env.enable(t)
+	env.Clock.Advance(totp.Period)
+
+	// One step of skew either way is accepted
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, env.code(t, secret, 2)), ErrInvalidCode)
+	assert.NoError(t, env.uc.Verify(ctx, env.User.ID, env.code(t, secret, -1)))
+
+	// Codes work once, and not after a later one
+	code := env.code(t, secret, 0)
+	assert.NoError(t, env.uc.Verify(ctx, env.User.ID, code[:3]+" "+code[3:]))
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, code), ErrInvalidCode)
+	assert.NoError(t, env.uc.Verify(ctx, env.User.ID, env.code(t, secret, 1)))
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, env.code(t, secret, 0)), ErrInvalidCode)
+
+	assert.ErrorIs(t, env.uc.Verify(ctx, 99, code), ErrNotEnrolled)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	secret, codes := env.enable(t)
+
+	assert.NoError(t, env.uc.Verify(ctx, env.User.ID, strings.ToUpper(codes[0])))
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, codes[0]), ErrInvalidCode)
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, "aaaaa-aaaaa"), ErrInvalidCode)
+	assert.Contains(t, env.audit.String(), `"detail":"2 recovery codes left"`)
+
+	fresh, err := env.uc.RegenerateRecoveryCodes(env.self, env.User.ID, env.code(t, secret, 0))
+	require.NoError(t, err)
+	assert.Len(t, fresh, 3)
+	assert.ErrorIs(t, env.uc.Verify(ctx, env.User.ID, codes[1]), ErrInvalidCode)
+	assert.NoError(t, env.uc.Verify(ctx, env.User.ID, fresh[1]))
+
+	status, err := env.uc.Status(env.self, env.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.RecoveryCodesLeft)
+}
+
+func TestDisable(t *testing.T) {
+	env := newTestEnv(t)
+	_, codes := env.enable(t)
+
+	assert.ErrorIs(t, env.uc.Disable(env.self, env.User.ID, "123456"), ErrInvalidCode)
+	require.NoError(t, env.uc.Disable(env.self, env.User.ID, codes[0]))
+
+	enabled, err := env.uc.Enabled(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	count, err := env.Repo.TOTP.CountRecoveryCodes(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Contains(t, env.audit.String(), `"action":"two_factor.disable"`)
+}
+
+func TestWrongCodesLockAccount(t *testing.T) {
+	env := newTestEnv(t)
+	secret, _ := env.enable(t)
+
+	// Wrong codes delay further attempts like failed logins, right ones
+	// included
+	for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+		_, err := env.uc.RegenerateRecoveryCodes(env.self, env.User.ID, "123456")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+	var throttled *lockoutUC.ThrottledError
+	_, err := env.uc.RegenerateRecoveryCodes(env.self, env.User.ID, env.code(t, secret, 0))
+	assert.ErrorAs(t, err, &throttled)
+	assert.ErrorAs(t, env.uc.Disable(env.self, env.User.ID, env.code(t, secret, 0)), &throttled)
+
+	enabled, err := env.uc.Enabled(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+}
+
+func TestOnlyOwnerManagesTwoFactor(t *testing.T) {
+	env := newTestEnv(t)
+	admin := entities.WithPrincipal(context.Background(), &entities.Principal{
+		UserID:      9,
+		Permissions: entities.AllPermissions,
+	})
+	scoped := entities.WithPrincipal(context.Background(), &entities.Principal{
+		UserID: env.User.ID,
+		Scopes: []entities.Permission{entities.PermUsersRead},
+	})
+
+	_, err := env.uc.Enroll(admin, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.Enroll(scoped, env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+	_, err = env.uc.Enroll(context.Background(), env.User.ID)
+	assert.ErrorIs(t, err, authzUC.ErrForbidden)
+
+	_, codes := env.enable(t)
+	assert.ErrorIs(t, env.uc.Disable(admin, env.User.ID, codes[0]), authzUC.ErrForbidden)
+
+	// Admins may look, and reset
+	status, err := env.uc.Status(admin, env.User.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.ErrorIs(t, env.uc.Reset(env.self, env.User.ID), authzUC.ErrForbidden)
+}
+
+func TestReset(t *testing.T) {
+	env := newTestEnv(t)
+	sys := usecasetest.System()
+
+	assert.ErrorIs(t, env.uc.Reset(sys, env.User.ID), ErrNotEnrolled)
+	env.enable(t)
+	require.NoError(t, env.uc.Reset(sys, env.User.ID))
+
+	enabled, err := env.uc.Enabled(context.Background(), env.User.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	assert.Contains(t, env.audit.String(), `"action":"two_factor.reset"`)
+}
+
+func TestUnavailableWithoutKey(t *testing.T) {
+	env := newTestEnv(t)
+	secret, _ := env.enable(t)
+	env.uc.box = nil
+
+	_, err := env.uc.Enroll(env.self, env.User.ID)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, env.uc.Verify(context.Background(), env.User.ID, env.code(t, secret, 0)), ErrUnavailable)
+
+	// A different key cannot open the stored secret
+	env.uc.box, err = secretbox.New(bytes.Repeat([]byte{8}, secretbox.KeySize))
+	require.NoError(t, err)
+	assert.ErrorIs(t, env.uc.Verify(context.Background(), env.User.ID, env.code(t, secret, 0)), ErrInvalidSecret)
+}
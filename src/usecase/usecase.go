@@ -1,26 +1,184 @@
 package usecases
 
 import (
+	"solecode/pkg/audit"
 	"solecode/pkg/cache"
+	"solecode/pkg/mail"
+	"solecode/pkg/password"
+	"solecode/pkg/secretbox"
+	"solecode/pkg/token"
 	repo "solecode/src/repository"
+	accountUC "solecode/src/usecase/account"
+	apiKeyUC "solecode/src/usecase/apikey"
+	authUC "solecode/src/usecase/auth"
+	authzUC "solecode/src/usecase/authz"
+	impersonationUC "solecode/src/usecase/impersonation"
+	lockoutUC "solecode/src/usecase/lockout"
+	oidcUC "solecode/src/usecase/oidc"
+	sessionUC "solecode/src/usecase/session"
+	twoFactorUC "solecode/src/usecase/twofactor"
 	userUC "solecode/src/usecase/user"
 )
 
 type UseCases struct {
-	User userUC.UserUseCaseItf
+	User          userUC.UserUseCaseItf
+	Auth          authUC.AuthUseCaseItf
+	Authz         authzUC.AuthzUseCaseItf
+	APIKey        apiKeyUC.APIKeyUseCaseItf
+	Account       accountUC.AccountUseCaseItf
+	Lockout       lockoutUC.LockoutUseCaseItf
+	TwoFactor     twoFactorUC.TwoFactorUseCaseItf
+	Session       sessionUC.SessionUseCaseItf
+	OIDC          oidcUC.OIDCUseCaseItf
+	Impersonation impersonationUC.ImpersonationUseCaseItf
 }
 
-func InitUsecase(
-	repo repo.Repository,
-	cache cache.CacheItf,
-) *UseCases {
+// Dependencies is what InitUsecase wires the use cases from. Zero Options
+// use their package defaults; a nil Box leaves two-factor enrolment
+// unavailable.
+type Dependencies struct {
+	Repo      repo.Repository
+	Cache     cache.CacheItf
+	Hasher    *password.Hasher
+	Keys      *token.KeySet
+	AuditLog  audit.Logger
+	Mailer    mail.Mailer
+	Templates *mail.Templates
+	Signer    *token.ActionSigner
+	Box       *secretbox.Box
+
+	Auth          authUC.Options
+	Lockout       lockoutUC.Options
+	Account       accountUC.Options
+	TwoFactor     twoFactorUC.Options
+	Session       sessionUC.Options
+	OIDC          oidcUC.Options
+	Impersonation impersonationUC.Options
+}
+
+func InitUsecase(deps Dependencies) *UseCases {
+	repo, cache, auditLog := deps.Repo, deps.Cache, deps.AuditLog
+
+	// One policy authorises every use case
+	policy := authzUC.NewPolicy(auditLog)
+
+	// Initialize authorization use case
+	authzUseCase := authzUC.NewAuthzUseCase(
+		&repo,
+		cache,
+		policy,
+		auditLog,
+	)
+
+	// Initialize session use case; password changes and deletes end
+	// sessions and refresh tokens through it
+	sessionUseCase := sessionUC.NewSessionUseCase(
+		&repo,
+		authzUseCase,
+		policy,
+		cache,
+		auditLog,
+		deps.Session,
+	)
+
+	// Initialize lockout use case; logins, password checks and account
+	// tokens are throttled through it
+	lockoutUseCase := lockoutUC.NewLockoutUseCase(
+		&repo,
+		cache,
+		policy,
+		auditLog,
+		deps.Lockout,
+	)
+
 	// Initialize user use case
 	userUseCase := userUC.NewUserUseCase(
-		repo.User,
+		&repo,
+		cache,
+		deps.Hasher,
+		policy,
+		sessionUseCase,
+		lockoutUseCase,
+	)
+
+	// Initialize two-factor use case
+	twoFactorUseCase := twoFactorUC.NewTwoFactorUseCase(
+		&repo,
+		deps.Box,
+		policy,
+		lockoutUseCase,
+		auditLog,
+		deps.TwoFactor,
+	)
+
+	// Initialize impersonation use case; the auth use case checks its
+	// tokens
+	impersonationUseCase := impersonationUC.NewImpersonationUseCase(
+		&repo,
+		authzUseCase,
+		policy,
+		cache,
+		deps.Keys,
+		auditLog,
+		deps.Impersonation,
+	)
+
+	// Initialize auth use case
+	authUseCase := authUC.NewAuthUseCase(
+		userUseCase,
+		authzUseCase,
+		lockoutUseCase,
+		twoFactorUseCase,
+		impersonationUseCase,
+		sessionUseCase,
+		cache,
+		deps.Keys,
+		deps.Auth,
+	)
+
+	// Initialize API key use case
+	apiKeyUseCase := apiKeyUC.NewAPIKeyUseCase(
+		&repo,
+		authzUseCase,
+		cache,
+		auditLog,
+	)
+
+	// Initialize account use case
+	accountUseCase := accountUC.NewAccountUseCase(
+		&repo,
+		cache,
+		deps.Hasher,
+		policy,
+		sessionUseCase,
+		lockoutUseCase,
+		auditLog,
+		deps.Mailer,
+		deps.Templates,
+		deps.Signer,
+		deps.Account,
+	)
+
+	// Initialize OpenID Connect use case
+	oidcUseCase := oidcUC.NewOIDCUseCase(
+		&repo,
+		policy,
 		cache,
+		deps.Keys,
+		auditLog,
+		deps.OIDC,
 	)
 
 	return &UseCases{
-		User: userUseCase,
+		User:          userUseCase,
+		Auth:          authUseCase,
+		Authz:         authzUseCase,
+		APIKey:        apiKeyUseCase,
+		Account:       accountUseCase,
+		Lockout:       lockoutUseCase,
+		TwoFactor:     twoFactorUseCase,
+		Session:       sessionUseCase,
+		OIDC:          oidcUseCase,
+		Impersonation: impersonationUseCase,
 	}
 }
@@ -0,0 +1,83 @@
+// Package usecasetest holds the fixture shared by the use case tests: a
+// memory repository seeded with a user, and a clock tests can move.
+package usecasetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"solecode/pkg/cache"
+	"solecode/src/entities"
+	"solecode/src/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Env is a memory repository holding User, John Doe with email
+// john@example.com, a memory cache and a clock starting now. Use cases
+// under test read the time from Clock.Now.
+type Env struct {
+	Repo  *repository.Repository
+	Cache *cache.MemoryCache
+	User  *entities.User
+	Clock *Clock
+}
+
+func New(t *testing.T) *Env {
+	env := &Env{
+		Repo:  repository.NewMemoryRepository(),
+		Cache: cache.NewMemoryCache(),
+		Clock: NewClock(time.Now()),
+	}
+	env.User = env.AddUser(t, "John Doe", "john@example.com")
+	return env
+}
+
+// AddUser stores another active user.
+func (env *Env) AddUser(t *testing.T, name, email string) *entities.User {
+	user := &entities.User{Name: name, Email: email}
+	require.NoError(t, env.Repo.User.Create(context.Background(), user))
+	return user
+}
+
+// System returns a context acting as the CLI, which may do anything.
+func System() context.Context {
+	return entities.WithPrincipal(context.Background(), entities.SystemPrincipal())
+}
+
+// As returns a context for user id without roles.
+func As(id int64) context.Context {
+	return entities.WithPrincipal(context.Background(), &entities.Principal{UserID: id})
+}
+
+// Clock is a time that only moves when told to.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock d ahead.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
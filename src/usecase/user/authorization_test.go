@@ -0,0 +1,97 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"solecode/src/entities"
+	userRepository "solecode/src/repository/user"
+	"solecode/src/usecase/authz"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorization(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	sys := systemContext()
+	john, err := uc.CreateUser(sys, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	jane, err := uc.CreateUser(sys, "Jane Doe", "jane@example.com")
+	require.NoError(t, err)
+
+	asJohn := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john.ID})
+	admin := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: 99, Permissions: entities.AllPermissions})
+
+	t.Run("users read and update themselves", func(t *testing.T) {
+		_, err := uc.GetUser(asJohn, john.ID)
+		assert.NoError(t, err)
+		_, err = uc.UpdateUser(asJohn, john.ID, "John Smith", "john@example.com")
+		assert.NoError(t, err)
+		assert.NoError(t, uc.ChangePassword(asJohn, john.ID, "", "Secret123!"))
+	})
+
+	t.Run("users cannot touch anyone else", func(t *testing.T) {
+		_, err := uc.GetUser(asJohn, jane.ID)
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		_, err = uc.UpdateUser(asJohn, jane.ID, "Jane Smith", "jane@example.com")
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		assert.ErrorIs(t, uc.ChangePassword(asJohn, jane.ID, "", "Secret123!"), authz.ErrForbidden)
+		assert.ErrorIs(t, uc.DeleteUser(asJohn, jane.ID), authz.ErrForbidden)
+		_, err = uc.CreateUser(asJohn, "Jim Doe", "jim@example.com")
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		_, err = uc.ListUsers(asJohn, userRepository.ListFilter{})
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		_, err = uc.ExportUsers(asJohn, &bytes.Buffer{}, FormatJSONL, userRepository.ListFilter{})
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		_, err = uc.ImportUsers(asJohn, strings.NewReader(""), ImportOptions{Format: FormatJSONL})
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+	})
+
+	t.Run("users cannot delete themselves or skip the password check", func(t *testing.T) {
+		assert.ErrorIs(t, uc.DeleteUser(asJohn, john.ID), authz.ErrForbidden)
+		assert.ErrorIs(t, uc.SetPassword(asJohn, john.ID, "Other123!"), authz.ErrForbidden)
+	})
+
+	t.Run("admins impersonating a user cannot take over the account", func(t *testing.T) {
+		impersonated := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john.ID, ImpersonatorID: 99})
+		_, err := uc.UpdateUser(impersonated, john.ID, "John Smith", "john@example.com")
+		assert.NoError(t, err)
+		_, err = uc.UpdateUser(impersonated, john.ID, "John Smith", "mallory@example.com")
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+		assert.ErrorIs(t, uc.ChangePassword(impersonated, john.ID, "Secret123!", "Other123!"), authz.ErrForbidden)
+
+		user, err := uc.GetUser(impersonated, john.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.com", user.Email)
+	})
+
+	t.Run("callers without a principal are refused", func(t *testing.T) {
+		_, err := uc.GetUser(context.Background(), john.ID)
+		assert.ErrorIs(t, err, authz.ErrForbidden)
+	})
+
+	t.Run("batch operations are authorised one by one", func(t *testing.T) {
+		results, err := uc.BatchUsers(asJohn, []BatchOperation{
+			{Op: BatchUpdate, ID: john.ID, Name: "John Doe", Email: "john@example.com"},
+			{Op: BatchUpdate, ID: jane.ID, Name: "Jane Smith", Email: "jane@example.com"},
+			{Op: BatchDelete, ID: jane.ID},
+		}, false)
+		require.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		assert.ErrorIs(t, results[1].Err, authz.ErrForbidden)
+		assert.ErrorIs(t, results[2].Err, authz.ErrForbidden)
+	})
+
+	t.Run("admins manage everyone", func(t *testing.T) {
+		_, err := uc.UpdateUser(admin, jane.ID, "Jane Smith", "jane@example.com")
+		assert.NoError(t, err)
+		assert.NoError(t, uc.SetPassword(admin, jane.ID, "Secret123!"))
+		users, err := uc.ListUsers(admin, userRepository.ListFilter{})
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.NoError(t, uc.DeleteUser(admin, jane.ID))
+	})
+}
@@ -0,0 +1,337 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"solecode/src/entities"
+	"solecode/src/repository"
+	userRepository "solecode/src/repository/user"
+	"solecode/src/usecase/authz"
+)
+
+// Batch operation kinds.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// ErrBatchRolledBack is the result of an operation that succeeded but was
+// undone because another operation of the same atomic batch failed.
+var ErrBatchRolledBack = errors.New("rolled back: another operation in the batch failed")
+
+// errBatchAborted unwinds the transaction of an atomic batch.
+var errBatchAborted = errors.New("batch aborted")
+
+// BatchOperation is one create, update or delete of a batch. Create needs
+// Name and Email, update needs all three fields and delete only ID.
+type BatchOperation struct {
+	Op    string
+	ID    int64
+	Name  string
+	Email string
+}
+
+// BatchResult is the outcome of the operation at the same index. User is
+// the created or updated user and nil for deletes and failures.
+type BatchResult struct {
+	User *entities.User
+	Err  error
+}
+
+// BatchUsers runs ops in order with the same validation as the single-user
+// methods. Consecutive operations of the same kind are written together
+// with one statement per chunk; when such a group fails, its operations
+// are retried one at a time so that each gets its own result.
+//
+// With atomic set, everything runs in one transaction that is rolled back
+// when any operation fails; operations that had succeeded then report
+// ErrBatchRolledBack. The returned error is for failures of the batch as a
+// whole, such as a lost database connection.
+func (uc *userUseCase) BatchUsers(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	valid := make([]BatchOperation, len(ops))
+	for i, op := range ops {
+		if results[i].Err = uc.authorizeOperation(ctx, op); results[i].Err == nil {
+			valid[i], results[i].Err = uc.normalizeOperation(op)
+		}
+		if results[i].Err == nil && op.Op == BatchUpdate {
+			results[i].Err = uc.authorizeEmailChange(ctx, valid[i])
+		}
+	}
+
+	if !atomic {
+		if err := uc.applyBatch(ctx, uc.repo, valid, results); err != nil {
+			return nil, err
+		}
+		if err := uc.invalidateBatch(ctx, ops, results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			markRolledBack(results)
+			return results, nil
+		}
+	}
+
+	err := uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		// A retried transaction starts over
+		for i := range results {
+			results[i] = BatchResult{}
+		}
+		if err := uc.applyBatch(ctx, tx, valid, results); err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Err != nil {
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchAborted) {
+		markRolledBack(results)
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.invalidateBatch(ctx, ops, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// authorizeOperation checks op against the permission of the matching
+// single-user method.
+func (uc *userUseCase) authorizeOperation(ctx context.Context, op BatchOperation) error {
+	switch op.Op {
+	case BatchCreate:
+		return uc.policy.Authorize(ctx, 0, entities.PermUsersCreate)
+	case BatchUpdate:
+		return uc.policy.Authorize(ctx, op.ID, entities.PermUsersUpdate)
+	case BatchDelete:
+		return uc.policy.Authorize(ctx, op.ID, entities.PermUsersDelete)
+	default:
+		return nil
+	}
+}
+
+// authorizeEmailChange refuses an update that changes the stored email to
+// admins impersonating a user, like UpdateUser does, so that they cannot
+// take over the account with a password reset. Users that do not exist
+// are left for the update to report.
+func (uc *userUseCase) authorizeEmailChange(ctx context.Context, op BatchOperation) error {
+	if principal, ok := entities.PrincipalFrom(ctx); ok && !principal.Impersonated() {
+		return nil
+	}
+	user, err := uc.userRepo.GetByID(ctx, op.ID)
+	if errors.Is(err, userRepository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == op.Email {
+		return nil
+	}
+	return uc.policy.AuthorizeDirect(ctx, op.ID, "changing the email")
+}
+
+// normalizeOperation validates op like the matching single-user method.
+func (uc *userUseCase) normalizeOperation(op BatchOperation) (BatchOperation, error) {
+	switch op.Op {
+	case BatchCreate, BatchUpdate:
+		if op.Op == BatchUpdate && op.ID <= 0 {
+			return op, fmt.Errorf("invalid user ID")
+		}
+		name, email, err := uc.normalize(op.Name, op.Email)
+		if err != nil {
+			return op, err
+		}
+		op.Name, op.Email = name, email
+		return op, nil
+	case BatchDelete:
+		if op.ID <= 0 {
+			return op, fmt.Errorf("invalid user ID")
+		}
+		return op, nil
+	default:
+		return op, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// applyBatch runs the operations without an error in results, a group of
+// consecutive operations of the same kind at a time.
+func (uc *userUseCase) applyBatch(ctx context.Context, repo *repository.Repository, ops []BatchOperation, results []BatchResult) error {
+	var group []int
+	flush := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+		err := uc.applyGroup(ctx, repo, ops, results, group)
+		group = group[:0]
+		return err
+	}
+
+	ids := make(map[int64]bool)
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+		// A group touches each user at most once, so that one statement
+		// can apply it
+		if len(group) > 0 && (ops[group[0]].Op != op.Op || (op.Op != BatchCreate && ids[op.ID])) {
+			if err := flush(); err != nil {
+				return err
+			}
+			clear(ids)
+		}
+		group = append(group, i)
+		ids[op.ID] = true
+	}
+	return flush()
+}
+
+// applyGroup writes a group of operations of one kind in a transaction, or
+// a savepoint when repo is already in one, so a failed group leaves
+// nothing behind. It then falls back to one operation at a time to find
+// the failing ones.
+func (uc *userUseCase) applyGroup(ctx context.Context, repo *repository.Repository, ops []BatchOperation, results []BatchResult, group []int) error {
+	users := make([]*entities.User, len(group))
+	err := repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		switch ops[group[0]].Op {
+		case BatchCreate:
+			for n, i := range group {
+				users[n] = &entities.User{Name: ops[i].Name, Email: ops[i].Email}
+			}
+			return tx.User.CreateBatch(ctx, users)
+
+		case BatchUpdate:
+			current, err := usersByID(ctx, tx, ops, group)
+			if err != nil {
+				return err
+			}
+			for n, i := range group {
+				user, ok := current[ops[i].ID]
+				if !ok {
+					return userRepository.ErrUserNotFound
+				}
+				if user.Email != ops[i].Email {
+					// Checked before the batch too; this catches emails
+					// changed since
+					if err := uc.policy.AuthorizeDirect(ctx, user.ID, "changing the email"); err != nil {
+						return err
+					}
+					user.VerifiedAt = nil
+				}
+				user.Name, user.Email = ops[i].Name, ops[i].Email
+				users[n] = user
+			}
+			return tx.User.UpdateBatch(ctx, users)
+
+		default:
+			ids := make([]int64, len(group))
+			for n, i := range group {
+				ids[n] = ops[i].ID
+			}
+			return tx.User.DeleteBatch(ctx, ids)
+		}
+	})
+	if err == nil {
+		for n, i := range group {
+			results[i].User = users[n]
+			if ops[i].Op == BatchDelete {
+				results[i].User = nil
+			}
+		}
+		return nil
+	}
+	if !isOperationError(err) {
+		return err
+	}
+
+	for _, i := range group {
+		op := ops[i]
+		err := repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+			var err error
+			switch op.Op {
+			case BatchCreate:
+				user := &entities.User{Name: op.Name, Email: op.Email}
+				err = tx.User.Create(ctx, user)
+				results[i].User = user
+			case BatchUpdate:
+				results[i].User, err = uc.updateUser(ctx, tx, op.ID, op.Name, op.Email)
+			default:
+				err = tx.User.Delete(ctx, op.ID)
+			}
+			return err
+		})
+		if err != nil && !isOperationError(err) {
+			return err
+		}
+		if err != nil {
+			results[i] = BatchResult{Err: err}
+		}
+	}
+	return nil
+}
+
+// usersByID reads the users a group of updates targets, a chunk at a time.
+func usersByID(ctx context.Context, repo *repository.Repository, ops []BatchOperation, group []int) (map[int64]*entities.User, error) {
+	current := make(map[int64]*entities.User, len(group))
+	for start := 0; start < len(group); start += exportPageSize {
+		chunk := group[start:min(start+exportPageSize, len(group))]
+		ids := make([]int64, len(chunk))
+		for n, i := range chunk {
+			ids[n] = ops[i].ID
+		}
+
+		users, err := repo.User.List(ctx, userRepository.ListFilter{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			current[user.ID] = user
+		}
+	}
+	return current, nil
+}
+
+// isOperationError reports whether err is the fault of an operation rather
+// than of the database.
+func isOperationError(err error) bool {
+	return errors.Is(err, userRepository.ErrUserNotFound) || errors.Is(err, userRepository.ErrEmailExists) ||
+		errors.Is(err, authz.ErrForbidden)
+}
+
+func markRolledBack(results []BatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BatchResult{Err: ErrBatchRolledBack}
+		}
+	}
+}
+
+// invalidateBatch drops the cached copies of updated and deleted users,
+// and ends the sign-ins of deleted ones.
+func (uc *userUseCase) invalidateBatch(ctx context.Context, ops []BatchOperation, results []BatchResult) error {
+	for i, op := range ops {
+		if op.Op == BatchCreate || results[i].Err != nil {
+			continue
+		}
+		uc.cache.Delete(fmt.Sprintf("user:%d", op.ID))
+		if op.Op == BatchDelete {
+			if err := uc.sessions.EndSignIns(ctx, op.ID, ""); err != nil {
+				return fmt.Errorf("failed to end sign-ins: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package user
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"solecode/docs/migrations"
+	cacheMocks "solecode/pkg/cache/mocks"
+	"solecode/pkg/config"
+	"solecode/pkg/database"
+	"solecode/pkg/migrate"
+	"solecode/pkg/password"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	userRepository "solecode/src/repository/user"
+	"solecode/src/usecase/authz"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteUseCase returns a use case on a migrated SQLite database, for
+// tests that need real transactions.
+func newSQLiteUseCase(t *testing.T) UserUseCaseItf {
+	cfg := &config.DatabaseConfig{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "users.db")}
+	db, err := database.NewCluster(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = migrate.New(db.Primary(), database.SQLite, migrations.FS, migrate.Options{GoMigrations: migrations.Go}).Up(context.Background(), "")
+	require.NoError(t, err)
+
+	cache := &cacheMocks.CacheItf{}
+	cache.On("Delete", mock.Anything).Return(nil).Maybe()
+	return NewUserUseCase(repository.InitRepository(db), cache, newTestHasher(t, password.Argon2id), newTestPolicy(), newTestSessions(), newTestLockout())
+}
+
+func TestBatchUsers(t *testing.T) {
+	ctx := systemContext()
+
+	for name, newUseCase := range map[string]func(t *testing.T) UserUseCaseItf{
+		"memory": func(t *testing.T) UserUseCaseItf { uc, _ := newTestUseCase(t); return uc },
+		"sqlite": newSQLiteUseCase,
+	} {
+		t.Run(name, func(t *testing.T) {
+			uc := newUseCase(t)
+			john, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+			require.NoError(t, err)
+			jane, err := uc.CreateUser(ctx, "Jane Doe", "jane@example.com")
+			require.NoError(t, err)
+
+			results, err := uc.BatchUsers(ctx, []BatchOperation{
+				{Op: BatchCreate, Name: "Ann Lee", Email: " Ann@Example.com "},
+				{Op: BatchCreate, Name: "Another John", Email: "john@example.com"},
+				{Op: BatchCreate, Name: "J4ne", Email: "bad"},
+				{Op: BatchUpdate, ID: john.ID, Name: "Johnny Doe", Email: "john@example.com"},
+				{Op: BatchUpdate, ID: 999999, Name: "Nobody", Email: "nobody@example.com"},
+				{Op: BatchDelete, ID: jane.ID},
+				{Op: BatchDelete, ID: jane.ID},
+				{Op: "upsert"},
+			}, false)
+			require.NoError(t, err)
+			require.Len(t, results, 8)
+
+			require.NoError(t, results[0].Err)
+			assert.Equal(t, "ann@example.com", results[0].User.Email)
+			assert.NotZero(t, results[0].User.ID)
+			assert.ErrorIs(t, results[1].Err, userRepository.ErrEmailExists)
+			assert.Error(t, results[2].Err)
+			require.NoError(t, results[3].Err)
+			assert.Equal(t, "Johnny Doe", results[3].User.Name)
+			assert.Equal(t, john.CreatedAt.Unix(), results[3].User.CreatedAt.Unix())
+			assert.ErrorIs(t, results[4].Err, userRepository.ErrUserNotFound)
+			assert.NoError(t, results[5].Err)
+			assert.Nil(t, results[5].User)
+			assert.ErrorIs(t, results[6].Err, userRepository.ErrUserNotFound)
+			assert.ErrorContains(t, results[7].Err, "unknown operation")
+
+			users, err := uc.ListUsers(ctx, userRepository.ListFilter{})
+			require.NoError(t, err)
+			assert.Len(t, users, 2)
+		})
+	}
+}
+
+func TestBatchUsersAtomic(t *testing.T) {
+	ctx := systemContext()
+	uc := newSQLiteUseCase(t)
+
+	john, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	results, err := uc.BatchUsers(ctx, []BatchOperation{
+		{Op: BatchCreate, Name: "Ann Lee", Email: "ann@example.com"},
+		{Op: BatchUpdate, ID: john.ID, Name: "Johnny Doe", Email: "john@example.com"},
+		{Op: BatchCreate, Name: "Another John", Email: "john@example.com"},
+	}, true)
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrBatchRolledBack)
+	assert.ErrorIs(t, results[1].Err, ErrBatchRolledBack)
+	assert.ErrorIs(t, results[2].Err, userRepository.ErrEmailExists)
+
+	users, err := uc.ListUsers(ctx, userRepository.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "John Doe", users[0].Name)
+
+	results, err = uc.BatchUsers(ctx, []BatchOperation{
+		{Op: BatchCreate, Name: "Ann Lee", Email: "ann@example.com"},
+		{Op: BatchDelete, ID: john.ID},
+	}, true)
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+
+	users, err = uc.ListUsers(ctx, userRepository.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "ann@example.com", users[0].Email)
+
+	// Invalid input fails the batch before it reaches the database
+	results, err = uc.BatchUsers(ctx, []BatchOperation{
+		{Op: BatchCreate, Name: "Bob Ray", Email: "bob@example.com"},
+		{Op: BatchDelete},
+	}, true)
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrBatchRolledBack)
+	assert.ErrorContains(t, results[1].Err, "invalid user ID")
+}
+
+func TestBatchUsersImpersonated(t *testing.T) {
+	uc := newSQLiteUseCase(t)
+	john, err := uc.CreateUser(systemContext(), "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	// An admin acting as John may rename him but not hand the account to
+	// another email, whether or not the batch is atomic
+	impersonated := entities.WithPrincipal(context.Background(), &entities.Principal{UserID: john.ID, ImpersonatorID: 99})
+	for _, atomic := range []bool{false, true} {
+		results, err := uc.BatchUsers(impersonated, []BatchOperation{
+			{Op: BatchUpdate, ID: john.ID, Name: "John Smith", Email: "mallory@example.com"},
+		}, atomic)
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, authz.ErrForbidden)
+	}
+	results, err := uc.BatchUsers(impersonated, []BatchOperation{
+		{Op: BatchUpdate, ID: john.ID, Name: "John Smith", Email: " John@Example.com "},
+	}, true)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+
+	users, err := uc.ListUsers(systemContext(), userRepository.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "John Smith", users[0].Name)
+	assert.Equal(t, "john@example.com", users[0].Email)
+}
@@ -3,9 +3,16 @@
 package mocks
 
 import (
+	context "context"
 	entities "solecode/src/entities"
 
+	io "io"
+
 	mock "github.com/stretchr/testify/mock"
+
+	repositoryuser "solecode/src/repository/user"
+
+	user "solecode/src/usecase/user"
 )
 
 // UserUseCaseItf is an autogenerated mock type for the UserUseCaseItf type
@@ -13,9 +20,87 @@ type UserUseCaseItf struct {
 	mock.Mock
 }
 
-// CreateUser provides a mock function with given fields: name, email
-func (_m *UserUseCaseItf) CreateUser(name string, email string) (*entities.User, error) {
-	ret := _m.Called(name, email)
+// Authenticate provides a mock function with given fields: ctx, email, password
+func (_m *UserUseCaseItf) Authenticate(ctx context.Context, email string, password string) (*entities.User, error) {
+	ret := _m.Called(ctx, email, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authenticate")
+	}
+
+	var r0 *entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*entities.User, error)); ok {
+		return rf(ctx, email, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entities.User); ok {
+		r0 = rf(ctx, email, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchUsers provides a mock function with given fields: ctx, ops, atomic
+func (_m *UserUseCaseItf) BatchUsers(ctx context.Context, ops []user.BatchOperation, atomic bool) ([]user.BatchResult, error) {
+	ret := _m.Called(ctx, ops, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BatchUsers")
+	}
+
+	var r0 []user.BatchResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []user.BatchOperation, bool) ([]user.BatchResult, error)); ok {
+		return rf(ctx, ops, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []user.BatchOperation, bool) []user.BatchResult); ok {
+		r0 = rf(ctx, ops, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]user.BatchResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []user.BatchOperation, bool) error); ok {
+		r1 = rf(ctx, ops, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword provides a mock function with given fields: ctx, id, current, password
+func (_m *UserUseCaseItf) ChangePassword(ctx context.Context, id int64, current string, password string) error {
+	ret := _m.Called(ctx, id, current, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, id, current, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateUser provides a mock function with given fields: ctx, name, email
+func (_m *UserUseCaseItf) CreateUser(ctx context.Context, name string, email string) (*entities.User, error) {
+	ret := _m.Called(ctx, name, email)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateUser")
@@ -23,19 +108,19 @@ func (_m *UserUseCaseItf) CreateUser(name string, email string) (*entities.User,
 
 	var r0 *entities.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (*entities.User, error)); ok {
-		return rf(name, email)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*entities.User, error)); ok {
+		return rf(ctx, name, email)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) *entities.User); ok {
-		r0 = rf(name, email)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entities.User); ok {
+		r0 = rf(ctx, name, email)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(name, email)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, name, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -43,17 +128,17 @@ func (_m *UserUseCaseItf) CreateUser(name string, email string) (*entities.User,
 	return r0, r1
 }
 
-// DeleteUser provides a mock function with given fields: id
-func (_m *UserUseCaseItf) DeleteUser(id int64) error {
-	ret := _m.Called(id)
+// DeleteUser provides a mock function with given fields: ctx, id
+func (_m *UserUseCaseItf) DeleteUser(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteUser")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -61,9 +146,37 @@ func (_m *UserUseCaseItf) DeleteUser(id int64) error {
 	return r0
 }
 
-// GetUser provides a mock function with given fields: id
-func (_m *UserUseCaseItf) GetUser(id int64) (*entities.User, error) {
-	ret := _m.Called(id)
+// ExportUsers provides a mock function with given fields: ctx, w, format, filter
+func (_m *UserUseCaseItf) ExportUsers(ctx context.Context, w io.Writer, format string, filter repositoryuser.ListFilter) (int, error) {
+	ret := _m.Called(ctx, w, format, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportUsers")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string, repositoryuser.ListFilter) (int, error)); ok {
+		return rf(ctx, w, format, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string, repositoryuser.ListFilter) int); ok {
+		r0 = rf(ctx, w, format, filter)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, io.Writer, string, repositoryuser.ListFilter) error); ok {
+		r1 = rf(ctx, w, format, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, id
+func (_m *UserUseCaseItf) GetUser(ctx context.Context, id int64) (*entities.User, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetUser")
@@ -71,19 +184,19 @@ func (_m *UserUseCaseItf) GetUser(id int64) (*entities.User, error) {
 
 	var r0 *entities.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (*entities.User, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*entities.User, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) *entities.User); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entities.User); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -91,9 +204,117 @@ func (_m *UserUseCaseItf) GetUser(id int64) (*entities.User, error) {
 	return r0, r1
 }
 
-// UpdateUser provides a mock function with given fields: id, name, email
-func (_m *UserUseCaseItf) UpdateUser(id int64, name string, email string) (*entities.User, error) {
-	ret := _m.Called(id, name, email)
+// ImportUsers provides a mock function with given fields: ctx, r, opts
+func (_m *UserUseCaseItf) ImportUsers(ctx context.Context, r io.Reader, opts user.ImportOptions) (*user.ImportReport, error) {
+	ret := _m.Called(ctx, r, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportUsers")
+	}
+
+	var r0 *user.ImportReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, io.Reader, user.ImportOptions) (*user.ImportReport, error)); ok {
+		return rf(ctx, r, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, io.Reader, user.ImportOptions) *user.ImportReport); ok {
+		r0 = rf(ctx, r, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.ImportReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, io.Reader, user.ImportOptions) error); ok {
+		r1 = rf(ctx, r, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, filter
+func (_m *UserUseCaseItf) ListUsers(ctx context.Context, filter repositoryuser.ListFilter) ([]*entities.User, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []*entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repositoryuser.ListFilter) ([]*entities.User, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repositoryuser.ListFilter) []*entities.User); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repositoryuser.ListFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreUser provides a mock function with given fields: ctx, id
+func (_m *UserUseCaseItf) RestoreUser(ctx context.Context, id int64) (*entities.User, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreUser")
+	}
+
+	var r0 *entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*entities.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entities.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetPassword provides a mock function with given fields: ctx, id, password
+func (_m *UserUseCaseItf) SetPassword(ctx context.Context, id int64, password string) error {
+	ret := _m.Called(ctx, id, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUser provides a mock function with given fields: ctx, id, name, email
+func (_m *UserUseCaseItf) UpdateUser(ctx context.Context, id int64, name string, email string) (*entities.User, error) {
+	ret := _m.Called(ctx, id, name, email)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateUser")
@@ -101,19 +322,19 @@ func (_m *UserUseCaseItf) UpdateUser(id int64, name string, email string) (*enti
 
 	var r0 *entities.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, string, string) (*entities.User, error)); ok {
-		return rf(id, name, email)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) (*entities.User, error)); ok {
+		return rf(ctx, id, name, email)
 	}
-	if rf, ok := ret.Get(0).(func(int64, string, string) *entities.User); ok {
-		r0 = rf(id, name, email)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) *entities.User); ok {
+		r0 = rf(ctx, id, name, email)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, string, string) error); ok {
-		r1 = rf(id, name, email)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string) error); ok {
+		r1 = rf(ctx, id, name, email)
 	} else {
 		r1 = ret.Error(1)
 	}
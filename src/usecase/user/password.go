@@ -0,0 +1,175 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"solecode/src/entities"
+)
+
+var (
+	// ErrInvalidCredentials is returned for a wrong password and for an
+	// unknown email alike, so callers cannot tell which it was.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrPasswordNotSet is returned by ChangePassword when a current
+	// password is given for a user who has none.
+	ErrPasswordNotSet = errors.New("password not set")
+)
+
+// passwordInput carries the password rules; max=72 keeps passwords within
+// what bcrypt accepts.
+type passwordInput struct {
+	Password string `json:"password" validate:"required,max=72,password"`
+}
+
+// SetPassword validates password and stores its hash, replacing any
+// previous password without checking it. It is meant for administrators;
+// users change their own password with ChangePassword. Either ends the
+// sessions and refresh tokens of the user, but for the session the user
+// changes their own password from.
+func (uc *userUseCase) SetPassword(ctx context.Context, id int64, password string) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid user ID")
+	}
+	// Setting a password skips the current-password check, so users get
+	// no self rule here; they use ChangePassword
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersPassword); err != nil {
+		return err
+	}
+
+	hash, err := uc.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := uc.userRepo.SetPasswordHash(ctx, id, hash); err != nil {
+		return err
+	}
+
+	uc.cache.Delete(fmt.Sprintf("user:%d", id))
+	return uc.endSignIns(ctx, id)
+}
+
+// ChangePassword replaces the password after checking current against the
+// stored one, failing with ErrInvalidCredentials when it does not match. A
+// user without a password sets a first one by passing an empty current.
+// Wrong current passwords count towards the lockout of the user's email
+// like failed logins, and a throttled check fails with a
+// *lockout.ThrottledError.
+func (uc *userUseCase) ChangePassword(ctx context.Context, id int64, current, password string) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid user ID")
+	}
+	if err := uc.policy.Authorize(ctx, id, entities.PermUsersPassword); err != nil {
+		return err
+	}
+
+	hash, err := uc.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.HasPassword() {
+		if err := uc.checkPassword(ctx, user, current); err != nil {
+			return err
+		}
+	} else if current != "" {
+		return ErrPasswordNotSet
+	}
+
+	if err := uc.userRepo.SetPasswordHash(ctx, id, hash); err != nil {
+		return err
+	}
+
+	uc.cache.Delete(fmt.Sprintf("user:%d", id))
+	return uc.endSignIns(ctx, id)
+}
+
+// Authenticate returns the active user with email when password matches,
+// and ErrInvalidCredentials otherwise, including when no such user exists
+// or the user has no password. A hash made with other algorithm or
+// parameters than the configured ones is replaced on success.
+func (uc *userUseCase) Authenticate(ctx context.Context, email, password string) (*entities.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	if user == nil || !user.HasPassword() {
+		// Spend the time a real check takes so that response times do
+		// not reveal which emails exist
+		uc.hasher.Verify(password, uc.dummyHash())
+		return nil, ErrInvalidCredentials
+	}
+
+	ok, err := uc.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if uc.hasher.NeedsRehash(user.PasswordHash) {
+		// Best effort: the old hash keeps working if this fails
+		if hash, err := uc.hasher.Hash(password); err == nil {
+			if uc.userRepo.SetPasswordHash(ctx, user.ID, hash) == nil {
+				user.PasswordHash = hash
+				uc.cache.Delete(fmt.Sprintf("user:%d", user.ID))
+			}
+		}
+	}
+	return user, nil
+}
+
+// checkPassword checks password against the stored one of user, held to
+// the lockout of their email.
+func (uc *userUseCase) checkPassword(ctx context.Context, user *entities.User, password string) error {
+	if err := uc.lockout.Check(ctx, user.Email); err != nil {
+		return err
+	}
+	ok, err := uc.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		if err := uc.lockout.Failure(ctx, user.Email); err != nil {
+			return err
+		}
+		return ErrInvalidCredentials
+	}
+	return uc.lockout.Success(ctx, user.Email)
+}
+
+// endSignIns ends the sessions and refresh tokens of user id after a
+// password change, keeping the session of a user changing their own.
+func (uc *userUseCase) endSignIns(ctx context.Context, id int64) error {
+	keepID := ""
+	if principal, ok := entities.PrincipalFrom(ctx); ok && principal.UserID == id {
+		keepID = principal.TokenID
+	}
+	if err := uc.sessions.EndSignIns(ctx, id, keepID); err != nil {
+		return fmt.Errorf("failed to end sign-ins: %w", err)
+	}
+	return nil
+}
+
+func (uc *userUseCase) hashPassword(password string) (string, error) {
+	if err := uc.validator.ValidateStruct(&passwordInput{Password: password}); err != nil {
+		return "", err
+	}
+	return uc.hasher.Hash(password)
+}
+
+// dummyHash returns a hash made with the current settings, computed once.
+func (uc *userUseCase) dummyHash() string {
+	uc.dummyOnce.Do(func() {
+		uc.dummy, _ = uc.hasher.Hash("dummy password for timing")
+	})
+	return uc.dummy
+}
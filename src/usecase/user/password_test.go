@@ -0,0 +1,196 @@
+package user
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"solecode/pkg/cache"
+	cacheMocks "solecode/pkg/cache/mocks"
+	"solecode/pkg/password"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionMocks "solecode/src/usecase/session/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPassword(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	err = uc.SetPassword(ctx, user.ID, "weak")
+	var validationErrors validator.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Equal(t, "password", validationErrors[0].Field)
+
+	err = uc.SetPassword(ctx, user.ID, "Secret123!"+strings.Repeat("a", 70))
+	assert.ErrorAs(t, err, &validationErrors)
+
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Secret123!"))
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.NoError(t, err)
+
+	// An administrator replaces it without knowing the old one
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Other456?"))
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.Error(t, uc.SetPassword(ctx, 999, "Secret123!"))
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, uc.ChangePassword(ctx, user.ID, "Guess123!", "Secret123!"), ErrPasswordNotSet)
+	require.NoError(t, uc.ChangePassword(ctx, user.ID, "", "Secret123!"))
+
+	assert.ErrorIs(t, uc.ChangePassword(ctx, user.ID, "", "Other456?"), ErrInvalidCredentials)
+	assert.ErrorIs(t, uc.ChangePassword(ctx, user.ID, "Wrong123!", "Other456?"), ErrInvalidCredentials)
+	var validationErrors validator.ValidationErrors
+	assert.ErrorAs(t, uc.ChangePassword(ctx, user.ID, "Secret123!", "short"), &validationErrors)
+
+	require.NoError(t, uc.ChangePassword(ctx, user.ID, "Secret123!", "Other456?"))
+	_, err = uc.Authenticate(ctx, "john@example.com", "Other456?")
+	assert.NoError(t, err)
+}
+
+func TestWrongCurrentPasswordsLockAccount(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Secret123!"))
+
+	// Guessing the current password is throttled like guessing it at login,
+	// and the right one waits too
+	for i := 0; i < lockoutUC.DefaultDelayAfter; i++ {
+		require.ErrorIs(t, uc.ChangePassword(ctx, user.ID, "Wrong123!", "Other456?"), ErrInvalidCredentials)
+	}
+	var throttled *lockoutUC.ThrottledError
+	assert.ErrorAs(t, uc.ChangePassword(ctx, user.ID, "Secret123!", "Other456?"), &throttled)
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.NoError(t, err)
+}
+
+func TestPasswordChangesEndSignIns(t *testing.T) {
+	ctx := systemContext()
+	sessions := &sessionMocks.SessionUseCaseItf{}
+	uc := NewUserUseCase(repository.NewMemoryRepository(), cache.NewMemoryCache(), newTestHasher(t, password.Argon2id), newTestPolicy(), sessions, newTestLockout())
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	// Setting a password ends every session; changing one's own keeps the
+	// session it was changed from
+	sessions.On("EndSignIns", mock.Anything, user.ID, "").Return(nil).Once()
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Secret123!"))
+	self := entities.WithPrincipal(ctx, &entities.Principal{UserID: user.ID, TokenID: "current"})
+	sessions.On("EndSignIns", mock.Anything, user.ID, "current").Return(nil).Once()
+	require.NoError(t, uc.ChangePassword(self, user.ID, "Secret123!", "Other456?"))
+
+	sessions.On("EndSignIns", mock.Anything, user.ID, "").Return(assert.AnError).Once()
+	assert.ErrorIs(t, uc.SetPassword(ctx, user.ID, "Third789!"), assert.AnError)
+	sessions.AssertExpectations(t)
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	// No password yet, unknown email and wrong password look the same
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = uc.Authenticate(ctx, "nobody@example.com", "Secret123!")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Secret123!"))
+	_, err = uc.Authenticate(ctx, "john@example.com", "secret123!")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	got, err := uc.Authenticate(ctx, " John@Example.com ", "Secret123!")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+
+	require.NoError(t, uc.DeleteUser(ctx, user.ID))
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticateRehashes(t *testing.T) {
+	ctx := systemContext()
+	repo := repository.NewMemoryRepository()
+	cache := &cacheMocks.CacheItf{}
+	cache.On("Delete", mock.Anything).Return(nil).Maybe()
+
+	old := NewUserUseCase(repo, cache, newTestHasher(t, password.Bcrypt), newTestPolicy(), newTestSessions(), newTestLockout())
+	user, err := old.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	require.NoError(t, old.SetPassword(ctx, user.ID, "Secret123!"))
+
+	stored, err := repo.User.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(stored.PasswordHash, "$2a$"), stored.PasswordHash)
+
+	// A failed login leaves the hash alone
+	uc := NewUserUseCase(repo, cache, newTestHasher(t, password.Argon2id), newTestPolicy(), newTestSessions(), newTestLockout())
+	_, err = uc.Authenticate(ctx, "john@example.com", "Wrong123!")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	stored, err = repo.User.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.PasswordHash, "$2a$"))
+
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	require.NoError(t, err)
+	stored, err = repo.User.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.PasswordHash, "$argon2id$"), stored.PasswordHash)
+
+	// The new hash still verifies, with either configuration
+	_, err = uc.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.NoError(t, err)
+	_, err = old.Authenticate(ctx, "john@example.com", "Secret123!")
+	assert.NoError(t, err)
+}
+
+func TestGetUserCachesNoPasswordHash(t *testing.T) {
+	ctx := systemContext()
+	repo := repository.NewMemoryRepository()
+
+	var cached []byte
+	cache := &cacheMocks.CacheItf{}
+	cache.On("GetJSON", mock.Anything, mock.Anything).Return(assert.AnError)
+	cache.On("SetJSON", "user:1", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var err error
+		cached, err = json.Marshal(args.Get(1))
+		require.NoError(t, err)
+	}).Return(nil)
+	cache.On("Delete", mock.Anything).Return(nil).Maybe()
+
+	uc := NewUserUseCase(repo, cache, newTestHasher(t, password.Argon2id), newTestPolicy(), newTestSessions(), newTestLockout())
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	require.NoError(t, uc.SetPassword(ctx, user.ID, "Secret123!"))
+
+	got, err := uc.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, got.HasPassword())
+	require.NotEmpty(t, cached)
+	assert.NotContains(t, string(cached), "argon2id")
+	assert.NotContains(t, string(cached), "password")
+}
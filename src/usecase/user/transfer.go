@@ -0,0 +1,384 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	userRepository "solecode/src/repository/user"
+)
+
+// Formats accepted by ImportUsers and produced by ExportUsers.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const (
+	defaultImportBatchSize = 500
+	exportPageSize         = 1000
+	maxJSONLLine           = 1 << 20
+)
+
+// Reasons an import row was skipped.
+const (
+	ImportInvalid   = "invalid"
+	ImportDuplicate = "duplicate"
+)
+
+// ImportOptions tunes ImportUsers.
+type ImportOptions struct {
+	Format    string // FormatCSV or FormatJSONL
+	BatchSize int    // rows per INSERT, default 500
+	// Progress, when set, is called after every batch.
+	Progress func(ImportCounts)
+}
+
+// ImportCounts tallies the rows an import has read so far.
+type ImportCounts struct {
+	Rows       int `json:"rows"`
+	Imported   int `json:"imported"`
+	Duplicates int `json:"duplicates"`
+	Failed     int `json:"failed"`
+}
+
+// ImportReport is the outcome of an import, with one entry per skipped row.
+type ImportReport struct {
+	ImportCounts
+	Errors []ImportRowError `json:"errors"`
+}
+
+// ImportRowError explains why a row was not imported. Row is the line
+// number in the input.
+type ImportRowError struct {
+	Row     int                        `json:"row"`
+	Email   string                     `json:"email,omitempty"`
+	Reason  string                     `json:"reason"`
+	Message string                     `json:"message"`
+	Details validator.ValidationErrors `json:"details,omitempty"`
+}
+
+// importRow is one row read from the input, before validation.
+type importRow struct {
+	line  int
+	name  string
+	email string
+	err   error // the row could not be parsed
+}
+
+// ImportUsers streams users from r, validating and normalising each row
+// like CreateUser. Rows whose normalised email repeats an earlier row or
+// an existing user are skipped as duplicates; everything skipped is listed
+// in the report. The returned error is for failures that stop the import,
+// such as malformed CSV or a database error; the report then covers the
+// rows handled so far.
+func (uc *userUseCase) ImportUsers(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersCreate); err != nil {
+		return nil, err
+	}
+	next, err := importReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{Errors: []ImportRowError{}}
+	err = uc.importRows(ctx, next, opts, report)
+
+	// Rows rejected by the database are found when their batch is written,
+	// after later rows may already have been reported
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Row < report.Errors[j].Row
+	})
+	return report, err
+}
+
+func (uc *userUseCase) importRows(ctx context.Context, next func() (importRow, error), opts ImportOptions, report *ImportReport) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	seen := make(map[string]int)
+	var batch []*entities.User
+	var batchRows []int
+
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := uc.insertImported(ctx, report, batch, batchRows); err != nil {
+				return err
+			}
+			batch, batchRows = batch[:0], batchRows[:0]
+		}
+		if opts.Progress != nil {
+			opts.Progress(report.ImportCounts)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		report.Rows++
+
+		if row.err != nil {
+			report.skip(ImportRowError{Row: row.line, Reason: ImportInvalid, Message: row.err.Error()})
+			continue
+		}
+
+		name, email, err := uc.normalize(row.name, row.email)
+		if err != nil {
+			rowErr := ImportRowError{Row: row.line, Email: row.email, Reason: ImportInvalid, Message: err.Error()}
+			errors.As(err, &rowErr.Details)
+			report.skip(rowErr)
+			continue
+		}
+
+		if first, ok := seen[email]; ok {
+			report.skip(ImportRowError{
+				Row: row.line, Email: email, Reason: ImportDuplicate,
+				Message: fmt.Sprintf("email duplicates row %d", first),
+			})
+			continue
+		}
+		seen[email] = row.line
+
+		batch = append(batch, &entities.User{Name: name, Email: email})
+		batchRows = append(batchRows, row.line)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// insertImported writes a batch with multi-row INSERTs in one transaction,
+// since the repository splits large batches into several statements. When
+// an email is already taken nothing is kept and the batch is retried row by
+// row so that only the offending rows are reported.
+func (uc *userUseCase) insertImported(ctx context.Context, report *ImportReport, batch []*entities.User, rows []int) error {
+	err := uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		return tx.User.CreateBatch(ctx, batch)
+	})
+	if err == nil {
+		report.Imported += len(batch)
+		return nil
+	}
+	if !errors.Is(err, userRepository.ErrEmailExists) {
+		return fmt.Errorf("failed to import users: %w", err)
+	}
+
+	for i, user := range batch {
+		err := uc.userRepo.Create(ctx, user)
+		if errors.Is(err, userRepository.ErrEmailExists) {
+			report.skip(ImportRowError{Row: rows[i], Email: user.Email, Reason: ImportDuplicate, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import users: %w", err)
+		}
+		report.Imported++
+	}
+	return nil
+}
+
+func (r *ImportReport) skip(rowErr ImportRowError) {
+	if rowErr.Reason == ImportDuplicate {
+		r.Duplicates++
+	} else {
+		r.Failed++
+	}
+	r.Errors = append(r.Errors, rowErr)
+}
+
+// importReader returns a function yielding one row per call and io.EOF at
+// the end of the input.
+func importReader(r io.Reader, format string) (func() (importRow, error), error) {
+	switch format {
+	case FormatCSV:
+		return csvImportReader(r)
+	case FormatJSONL:
+		return jsonlImportReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+// csvImportReader reads CSV with a header row naming the name and email
+// columns in any order; other columns are ignored.
+func csvImportReader(r io.Reader) (func() (importRow, error), error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty CSV: a header row with name and email is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	nameCol, emailCol := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+		case "name":
+			nameCol = i
+		case "email":
+			emailCol = i
+		}
+	}
+	if nameCol < 0 || emailCol < 0 {
+		return nil, fmt.Errorf("CSV header must name the name and email columns")
+	}
+
+	return func() (importRow, error) {
+		record, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("malformed CSV: %w", err)
+			}
+			return importRow{}, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) <= nameCol || len(record) <= emailCol {
+			return importRow{line: line, err: fmt.Errorf("row has %d columns, expected at least %d", len(record), max(nameCol, emailCol)+1)}, nil
+		}
+		return importRow{line: line, name: record[nameCol], email: record[emailCol]}, nil
+	}, nil
+}
+
+// jsonlImportReader reads one JSON object per line; fields other than name
+// and email are ignored. A malformed line is reported for that row only.
+func jsonlImportReader(r io.Reader) func() (importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLine)
+	line := 0
+
+	return func() (importRow, error) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			var input userInput
+			if err := json.Unmarshal([]byte(text), &input); err != nil {
+				return importRow{line: line, err: fmt.Errorf("invalid JSON: %w", err)}, nil
+			}
+			return importRow{line: line, name: input.Name, email: input.Email}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return importRow{}, fmt.Errorf("failed to read line %d: %w", line+1, err)
+		}
+		return importRow{}, io.EOF
+	}
+}
+
+// ExportUsers streams the users matching filter to w in format, reading
+// them a page at a time so memory use does not grow with the table. The
+// filter's Limit caps the total; Offset is not supported, use AfterID. It
+// returns the number of users written.
+func (uc *userUseCase) ExportUsers(ctx context.Context, w io.Writer, format string, filter userRepository.ListFilter) (int, error) {
+	if filter.Limit < 0 || filter.Offset != 0 {
+		return 0, fmt.Errorf("export takes a non-negative limit and no offset")
+	}
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersRead); err != nil {
+		return 0, err
+	}
+
+	var write func(*entities.User) error
+	var flush func() error
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"id", "name", "email", "created_at", "updated_at", "deleted_at"}); err != nil {
+			return 0, err
+		}
+		write = func(user *entities.User) error {
+			deletedAt := ""
+			if user.DeletedAt != nil {
+				deletedAt = user.DeletedAt.Format(time.RFC3339)
+			}
+			return writer.Write([]string{
+				strconv.FormatInt(user.ID, 10), csvText(user.Name), csvText(user.Email),
+				user.CreatedAt.Format(time.RFC3339), user.UpdatedAt.Format(time.RFC3339), deletedAt,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		write = func(user *entities.User) error { return encoder.Encode(user) }
+		flush = buffered.Flush
+	default:
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+
+	limit := filter.Limit
+	written := 0
+	for limit == 0 || written < limit {
+		page := filter
+		page.Limit = exportPageSize
+		if limit > 0 {
+			page.Limit = min(exportPageSize, limit-written)
+		}
+
+		users, err := uc.userRepo.List(ctx, page)
+		if err != nil {
+			return written, err
+		}
+		for _, user := range users {
+			if err := write(user); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := flush(); err != nil {
+			return written, err
+		}
+
+		if len(users) < page.Limit {
+			break
+		}
+		filter.AfterID = users[len(users)-1].ID
+	}
+
+	return written, flush()
+}
+
+// csvText keeps spreadsheets from running user-supplied text as a formula
+// by prefixing cells that start like one with a quote.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
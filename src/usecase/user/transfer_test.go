@@ -0,0 +1,182 @@
+package user
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	userRepository "solecode/src/repository/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportUsers(t *testing.T) {
+	ctx := systemContext()
+
+	t.Run("CSV", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+		_, err := uc.CreateUser(ctx, "Existing User", "existing@example.com")
+		require.NoError(t, err)
+
+		input := "\ufeffEmail,Name,Plan\n" +
+			"john@example.com,John Doe,pro\n" +
+			"JOHN@example.com ,Johnny Doe,free\n" +
+			"not-an-email,Jane Doe,free\n" +
+			"Existing@Example.com,Someone Else,pro\n" +
+			"short-row\n" +
+			"jim@example.com,Jim Doe,free\n"
+
+		var progress []ImportCounts
+		report, err := uc.ImportUsers(ctx, strings.NewReader(input), ImportOptions{
+			Format:    FormatCSV,
+			BatchSize: 2,
+			Progress:  func(c ImportCounts) { progress = append(progress, c) },
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, ImportCounts{Rows: 6, Imported: 2, Duplicates: 2, Failed: 2}, report.ImportCounts)
+		require.Len(t, report.Errors, 4)
+		assert.Equal(t, 3, report.Errors[0].Row)
+		assert.Equal(t, "email duplicates row 2", report.Errors[0].Message)
+		assert.Equal(t, ImportInvalid, report.Errors[1].Reason)
+		assert.Equal(t, "email", report.Errors[1].Details[0].Field)
+		assert.Equal(t, ImportDuplicate, report.Errors[2].Reason)
+		assert.Equal(t, "existing@example.com", report.Errors[2].Email)
+		assert.Equal(t, ImportInvalid, report.Errors[3].Reason)
+		assert.Equal(t, 6, report.Errors[3].Row)
+
+		require.NotEmpty(t, progress)
+		assert.Equal(t, report.ImportCounts, progress[len(progress)-1])
+
+		users, err := uc.ListUsers(ctx, userRepository.ListFilter{})
+		require.NoError(t, err)
+		assert.Len(t, users, 3)
+	})
+
+	t.Run("JSON Lines", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		input := `{"name": "John Doe", "email": "john@example.com", "source": "crm"}
+
+{"name": "Jane Doe", "email":
+{"name": "Jane Doe", "email": "jane@example.com"}
+`
+		report, err := uc.ImportUsers(ctx, strings.NewReader(input), ImportOptions{Format: FormatJSONL})
+		require.NoError(t, err)
+		assert.Equal(t, ImportCounts{Rows: 3, Imported: 2, Failed: 1}, report.ImportCounts)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, 3, report.Errors[0].Row)
+	})
+
+	t.Run("batches larger than one INSERT are written atomically", func(t *testing.T) {
+		uc := newSQLiteUseCase(t)
+		_, err := uc.CreateUser(ctx, "Existing User", "existing@example.com")
+		require.NoError(t, err)
+
+		// The taken email lands in the second statement of the batch
+		var input strings.Builder
+		input.WriteString("name,email\n")
+		for i := 0; i < 1500; i++ {
+			email := fmt.Sprintf("user%d@example.com", i)
+			if i == 1200 {
+				email = "existing@example.com"
+			}
+			fmt.Fprintf(&input, "Jane Doe,%s\n", email)
+		}
+
+		report, err := uc.ImportUsers(ctx, strings.NewReader(input.String()), ImportOptions{Format: FormatCSV, BatchSize: 2000})
+		require.NoError(t, err)
+		assert.Equal(t, ImportCounts{Rows: 1500, Imported: 1499, Duplicates: 1}, report.ImportCounts)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, 1202, report.Errors[0].Row)
+
+		users, err := uc.ListUsers(ctx, userRepository.ListFilter{Limit: 2000})
+		require.NoError(t, err)
+		assert.Len(t, users, 1500)
+	})
+
+	t.Run("rejects CSV without the required columns", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		_, err := uc.ImportUsers(ctx, strings.NewReader("name,mail\n"), ImportOptions{Format: FormatCSV})
+		assert.ErrorContains(t, err, "header")
+
+		_, err = uc.ImportUsers(ctx, strings.NewReader(""), ImportOptions{Format: "xml"})
+		assert.ErrorContains(t, err, "unknown import format")
+	})
+}
+
+func TestExportUsers(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	for i := 0; i < exportPageSize+5; i++ {
+		domain := "example.com"
+		if i%2 == 1 {
+			domain = "example.org"
+		}
+		_, err := uc.CreateUser(ctx, "John Doe", fmt.Sprintf("john%d@%s", i, domain))
+		require.NoError(t, err)
+	}
+	require.NoError(t, uc.DeleteUser(ctx, 1))
+
+	var buf bytes.Buffer
+	n, err := uc.ExportUsers(ctx, &buf, FormatCSV, userRepository.ListFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, exportPageSize+4, n)
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, n+1)
+	assert.Equal(t, "2", records[1][0])
+
+	buf.Reset()
+	n, err = uc.ExportUsers(ctx, &buf, FormatJSONL, userRepository.ListFilter{
+		IncludeDeleted: true, EmailDomain: "example.com", Limit: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	var first struct {
+		ID        int64   `json:"id"`
+		DeletedAt *string `json:"deleted_at"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.EqualValues(t, 1, first.ID)
+	assert.NotNil(t, first.DeletedAt)
+
+	_, err = uc.ExportUsers(ctx, &buf, FormatCSV, userRepository.ListFilter{Offset: 1})
+	assert.Error(t, err)
+}
+
+func TestExportUsersEscapesFormulas(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+	john, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	for _, name := range []string{`=HYPERLINK("http://evil.example","x")`, "+1 Doe", "-1 Doe", "@SUM Doe", "\tJohn Doe", "\rJohn Doe"} {
+		// Validation lets through a leading hyphen; older rows or direct
+		// database writes may hold the rest
+		john.Name = name
+		require.NoError(t, uc.(*userUseCase).userRepo.Update(ctx, john))
+
+		buf.Reset()
+		_, err = uc.ExportUsers(ctx, &buf, FormatCSV, userRepository.ListFilter{})
+		require.NoError(t, err)
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, "'"+name, records[1][1])
+	}
+
+	// JSON Lines is not read by spreadsheets and stays as stored
+	buf.Reset()
+	_, err = uc.ExportUsers(ctx, &buf, FormatJSONL, userRepository.ListFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"name":"\rJohn Doe"`)
+}
@@ -1,38 +1,53 @@
 package user
 
 import (
+	"context"
 	"fmt"
 	"solecode/src/entities"
+	"solecode/src/repository"
+	userRepository "solecode/src/repository/user"
 	"strings"
 	"time"
 )
 
-func (uc *userUseCase) CreateUser(name, email string) (*entities.User, error) {
+func (uc *userUseCase) CreateUser(ctx context.Context, name, email string) (*entities.User, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersCreate); err != nil {
+		return nil, err
+	}
+
+	name, email, err := uc.normalize(name, email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
-	existingUser, err := uc.userRepo.GetByEmail(email)
+	existingUser, err := uc.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check email existence: %w", err)
 	}
 	if existingUser != nil {
-		return nil, fmt.Errorf("email already exists")
+		return nil, userRepository.ErrEmailExists
 	}
 
 	user := &entities.User{
-		Name:  strings.TrimSpace(name),
-		Email: strings.ToLower(strings.TrimSpace(email)),
+		Name:  name,
+		Email: email,
 	}
 
-	if err := uc.userRepo.Create(user); err != nil {
+	if err := uc.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (uc *userUseCase) GetUser(id int64) (*entities.User, error) {
+func (uc *userUseCase) GetUser(ctx context.Context, id int64) (*entities.User, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid user ID")
 	}
+	if err := uc.policy.Authorize(ctx, id, entities.PermUsersRead); err != nil {
+		return nil, err
+	}
 
 	// Try to get from cache first
 	cacheKey := fmt.Sprintf("user:%d", id)
@@ -44,7 +59,7 @@ func (uc *userUseCase) GetUser(id int64) (*entities.User, error) {
 	}
 
 	// If not in cache, get from database
-	userPtr, err := uc.userRepo.GetByID(id)
+	userPtr, err := uc.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -55,32 +70,28 @@ func (uc *userUseCase) GetUser(id int64) (*entities.User, error) {
 	return userPtr, nil
 }
 
-func (uc *userUseCase) UpdateUser(id int64, name, email string) (*entities.User, error) {
+func (uc *userUseCase) UpdateUser(ctx context.Context, id int64, name, email string) (*entities.User, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid user ID")
 	}
-
-	// Get existing user
-	user, err := uc.userRepo.GetByID(id)
-	if err != nil {
+	if err := uc.policy.Authorize(ctx, id, entities.PermUsersUpdate); err != nil {
 		return nil, err
 	}
 
-	// Check if email is being changed and if it's already taken by another user
-	if user.Email != strings.ToLower(email) {
-		existingUser, err := uc.userRepo.GetByEmail(email)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check email existence: %w", err)
-		}
-		if existingUser != nil && existingUser.ID != id {
-			return nil, fmt.Errorf("email already exists")
-		}
+	name, email, err := uc.normalize(name, email)
+	if err != nil {
+		return nil, err
 	}
 
-	user.Name = strings.TrimSpace(name)
-	user.Email = strings.ToLower(strings.TrimSpace(email))
-
-	if err := uc.userRepo.Update(user); err != nil {
+	// Read and write in one transaction so the email check and the update
+	// see the same state
+	var user *entities.User
+	err = uc.repo.WithinTx(ctx, func(ctx context.Context, tx *repository.Repository) error {
+		var err error
+		user, err = uc.updateUser(ctx, tx, id, name, email)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -91,12 +102,15 @@ func (uc *userUseCase) UpdateUser(id int64, name, email string) (*entities.User,
 	return user, nil
 }
 
-func (uc *userUseCase) DeleteUser(id int64) error {
+func (uc *userUseCase) DeleteUser(ctx context.Context, id int64) error {
 	if id <= 0 {
 		return fmt.Errorf("invalid user ID")
 	}
+	if err := uc.policy.Authorize(ctx, id, entities.PermUsersDelete); err != nil {
+		return err
+	}
 
-	if err := uc.userRepo.Delete(id); err != nil {
+	if err := uc.userRepo.Delete(ctx, id); err != nil {
 		return err
 	}
 
@@ -104,5 +118,85 @@ func (uc *userUseCase) DeleteUser(id int64) error {
 	cacheKey := fmt.Sprintf("user:%d", id)
 	uc.cache.Delete(cacheKey)
 
+	if err := uc.sessions.EndSignIns(ctx, id, ""); err != nil {
+		return fmt.Errorf("failed to end sign-ins: %w", err)
+	}
 	return nil
 }
+
+func (uc *userUseCase) RestoreUser(ctx context.Context, id int64) (*entities.User, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+	// Undoing a delete takes the same permission as deleting
+	if err := uc.policy.Authorize(ctx, id, entities.PermUsersDelete); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Invalidate cache
+	cacheKey := fmt.Sprintf("user:%d", id)
+	uc.cache.Delete(cacheKey)
+
+	return uc.userRepo.GetByID(ctx, id)
+}
+
+func (uc *userUseCase) ListUsers(ctx context.Context, filter userRepository.ListFilter) ([]*entities.User, error) {
+	if err := uc.policy.Authorize(ctx, 0, entities.PermUsersRead); err != nil {
+		return nil, err
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	return uc.userRepo.List(ctx, filter)
+}
+
+// updateUser applies a normalised update through repo, which should be
+// bound to a transaction.
+func (uc *userUseCase) updateUser(ctx context.Context, repo *repository.Repository, id int64, name, email string) (*entities.User, error) {
+	user, err := repo.User.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if email is being changed and if it's already taken by another user
+	if user.Email != email {
+		// A new email would let an impersonating admin reset the password
+		if err := uc.policy.AuthorizeDirect(ctx, id, "changing the email"); err != nil {
+			return nil, err
+		}
+		existingUser, err := repo.User.GetByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check email existence: %w", err)
+		}
+		if existingUser != nil && existingUser.ID != id {
+			return nil, userRepository.ErrEmailExists
+		}
+		// The repository clears it too; this keeps the returned user right
+		user.VerifiedAt = nil
+	}
+
+	user.Name = name
+	user.Email = email
+
+	if err := repo.User.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalize trims the name, lower-cases the email and validates both.
+func (uc *userUseCase) normalize(name, email string) (string, string, error) {
+	input := userInput{
+		Name:  strings.TrimSpace(name),
+		Email: strings.ToLower(strings.TrimSpace(email)),
+	}
+	if err := uc.validator.ValidateStruct(&input); err != nil {
+		return "", "", err
+	}
+	return input.Name, input.Email, nil
+}
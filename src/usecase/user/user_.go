@@ -1,27 +1,69 @@
 package user
 
 import (
+	"context"
+	"io"
+	"sync"
+
 	cachePkg "solecode/pkg/cache"
+	"solecode/pkg/password"
+	"solecode/pkg/validator"
 	"solecode/src/entities"
+	"solecode/src/repository"
 	userRepository "solecode/src/repository/user"
+	"solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionUC "solecode/src/usecase/session"
 )
 
+// Every method but Authenticate is authorised against the principal of ctx
+// and fails with authz.ErrForbidden when it lacks the permission.
+//
 //go:generate mockery --name UserUseCaseItf --output mocks --filename userusecase_mock.go --outpkg mocks
 type UserUseCaseItf interface {
-	CreateUser(name, email string) (*entities.User, error)
-	GetUser(id int64) (*entities.User, error)
-	UpdateUser(id int64, name, email string) (*entities.User, error)
-	DeleteUser(id int64) error
+	CreateUser(ctx context.Context, name, email string) (*entities.User, error)
+	GetUser(ctx context.Context, id int64) (*entities.User, error)
+	UpdateUser(ctx context.Context, id int64, name, email string) (*entities.User, error)
+	DeleteUser(ctx context.Context, id int64) error
+	RestoreUser(ctx context.Context, id int64) (*entities.User, error)
+	ListUsers(ctx context.Context, filter userRepository.ListFilter) ([]*entities.User, error)
+	BatchUsers(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+	ImportUsers(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+	ExportUsers(ctx context.Context, w io.Writer, format string, filter userRepository.ListFilter) (int, error)
+	SetPassword(ctx context.Context, id int64, password string) error
+	ChangePassword(ctx context.Context, id int64, current, password string) error
+	Authenticate(ctx context.Context, email, password string) (*entities.User, error)
 }
 
 type userUseCase struct {
-	userRepo userRepository.UserRepositoryItf
-	cache    cachePkg.CacheItf
+	repo      *repository.Repository
+	userRepo  userRepository.UserRepositoryItf
+	cache     cachePkg.CacheItf
+	validator *validator.Validator
+	hasher    *password.Hasher
+	policy    *authz.Policy
+	sessions  sessionUC.SessionUseCaseItf
+	lockout   lockoutUC.LockoutUseCaseItf
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// userInput carries the rules every caller, HTTP or CLI, must satisfy.
+type userInput struct {
+	Name  string `json:"name" validate:"required,min=2,max=100,name"`
+	Email string `json:"email" validate:"required,email"`
 }
 
-func NewUserUseCase(userRepo userRepository.UserRepositoryItf, cache cachePkg.CacheItf) UserUseCaseItf {
+func NewUserUseCase(repo *repository.Repository, cache cachePkg.CacheItf, hasher *password.Hasher, policy *authz.Policy, sessions sessionUC.SessionUseCaseItf, lockout lockoutUC.LockoutUseCaseItf) UserUseCaseItf {
 	return &userUseCase{
-		userRepo: userRepo,
-		cache:    cache,
+		repo:      repo,
+		userRepo:  repo.User,
+		cache:     cache,
+		validator: validator.New(),
+		hasher:    hasher,
+		policy:    policy,
+		sessions:  sessions,
+		lockout:   lockout,
 	}
 }
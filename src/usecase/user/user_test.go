@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"solecode/pkg/audit"
+	"solecode/pkg/cache"
+	cacheMocks "solecode/pkg/cache/mocks"
+	"solecode/pkg/config"
+	"solecode/pkg/password"
+	"solecode/pkg/validator"
+	"solecode/src/entities"
+	"solecode/src/repository"
+	userRepository "solecode/src/repository/user"
+	"solecode/src/usecase/authz"
+	lockoutUC "solecode/src/usecase/lockout"
+	sessionMocks "solecode/src/usecase/session/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUseCase(t *testing.T) (UserUseCaseItf, *cacheMocks.CacheItf) {
+	cache := &cacheMocks.CacheItf{}
+	cache.On("GetJSON", mock.Anything, mock.Anything).Return(nil).Maybe()
+	cache.On("SetJSON", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	cache.On("Delete", mock.Anything).Return(nil).Maybe()
+
+	return NewUserUseCase(repository.NewMemoryRepository(), cache, newTestHasher(t, password.Argon2id), newTestPolicy(), newTestSessions(), newTestLockout()), cache
+}
+
+// newTestSessions returns a session use case that has no sessions to end.
+func newTestSessions() *sessionMocks.SessionUseCaseItf {
+	sessions := &sessionMocks.SessionUseCaseItf{}
+	sessions.On("EndSignIns", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return sessions
+}
+
+// newTestLockout returns a lockout use case with default limits.
+func newTestLockout() lockoutUC.LockoutUseCaseItf {
+	return lockoutUC.NewLockoutUseCase(repository.NewMemoryRepository(), cache.NewMemoryCache(), newTestPolicy(), audit.NewNopLogger(), lockoutUC.Options{})
+}
+
+func newTestPolicy() *authz.Policy {
+	return authz.NewPolicy(audit.NewNopLogger())
+}
+
+// systemContext returns a context acting as the CLI, which may do anything.
+func systemContext() context.Context {
+	return entities.WithPrincipal(context.Background(), entities.SystemPrincipal())
+}
+
+// newTestHasher returns a hasher with parameters cheap enough for tests.
+func newTestHasher(t *testing.T, algorithm string) *password.Hasher {
+	hasher, err := password.New(config.PasswordConfig{
+		Algorithm:         algorithm,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        4,
+	})
+	require.NoError(t, err)
+	return hasher
+}
+
+func TestCreateUser(t *testing.T) {
+	ctx := systemContext()
+
+	t.Run("normalises name and email", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		user, err := uc.CreateUser(ctx, "  John Doe ", " John@Example.com ")
+		require.NoError(t, err)
+		assert.Equal(t, "John Doe", user.Name)
+		assert.Equal(t, "john@example.com", user.Email)
+	})
+
+	t.Run("rejects an email that is already taken", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		_, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+		require.NoError(t, err)
+
+		_, err = uc.CreateUser(ctx, "Jane Doe", "John@Example.com")
+		assert.ErrorIs(t, err, userRepository.ErrEmailExists)
+	})
+
+	t.Run("validates name and email", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		_, err := uc.CreateUser(ctx, "J4ne", "not-an-email")
+		var validationErrors validator.ValidationErrors
+		require.ErrorAs(t, err, &validationErrors)
+		assert.Len(t, validationErrors, 2)
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	ctx := systemContext()
+
+	t.Run("updates the user and invalidates the cache", func(t *testing.T) {
+		uc, cache := newTestUseCase(t)
+
+		user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+		require.NoError(t, err)
+
+		updated, err := uc.UpdateUser(ctx, user.ID, "Johnny Doe", "johnny@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny Doe", updated.Name)
+		assert.Equal(t, "johnny@example.com", updated.Email)
+		cache.AssertCalled(t, "Delete", "user:1")
+	})
+
+	t.Run("rejects another user's email", func(t *testing.T) {
+		uc, _ := newTestUseCase(t)
+
+		john, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+		require.NoError(t, err)
+		_, err = uc.CreateUser(ctx, "Jane Doe", "jane@example.com")
+		require.NoError(t, err)
+
+		_, err = uc.UpdateUser(ctx, john.ID, "John Doe", "jane@example.com")
+		assert.ErrorIs(t, err, userRepository.ErrEmailExists)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, uc.DeleteUser(ctx, user.ID))
+
+	_, err = uc.GetUser(ctx, user.ID)
+	assert.ErrorIs(t, err, userRepository.ErrUserNotFound)
+	assert.ErrorIs(t, uc.DeleteUser(ctx, user.ID), userRepository.ErrUserNotFound)
+}
+
+func TestDeletingUsersEndsSignIns(t *testing.T) {
+	ctx := systemContext()
+	sessions := &sessionMocks.SessionUseCaseItf{}
+	uc := NewUserUseCase(repository.NewMemoryRepository(), cache.NewMemoryCache(), newTestHasher(t, password.Argon2id), newTestPolicy(), sessions, newTestLockout())
+
+	john, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	jane, err := uc.CreateUser(ctx, "Jane Doe", "jane@example.com")
+	require.NoError(t, err)
+
+	sessions.On("EndSignIns", mock.Anything, john.ID, "").Return(nil).Once()
+	require.NoError(t, uc.DeleteUser(ctx, john.ID))
+	sessions.On("EndSignIns", mock.Anything, jane.ID, "").Return(nil).Once()
+	_, err = uc.BatchUsers(ctx, []BatchOperation{{Op: BatchDelete, ID: jane.ID}, {Op: BatchDelete, ID: 999}}, false)
+	require.NoError(t, err)
+	sessions.AssertExpectations(t)
+}
+
+func TestRestoreUser(t *testing.T) {
+	ctx := systemContext()
+	uc, cache := newTestUseCase(t)
+
+	user, err := uc.CreateUser(ctx, "John Doe", "john@example.com")
+	require.NoError(t, err)
+	require.NoError(t, uc.DeleteUser(ctx, user.ID))
+
+	restored, err := uc.RestoreUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, restored.ID)
+	cache.AssertNumberOfCalls(t, "Delete", 2)
+
+	_, err = uc.RestoreUser(ctx, user.ID)
+	assert.ErrorIs(t, err, userRepository.ErrUserNotFound)
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := systemContext()
+	uc, _ := newTestUseCase(t)
+
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		_, err := uc.CreateUser(ctx, "John Doe", email)
+		require.NoError(t, err)
+	}
+
+	users, err := uc.ListUsers(ctx, userRepository.ListFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "a@example.com", users[0].Email)
+
+	_, err = uc.ListUsers(ctx, userRepository.ListFilter{Offset: -1})
+	assert.Error(t, err)
+}